@@ -6,12 +6,17 @@ import (
 
 func main() {
 	viper := config.NewViper("./../../")
-	fiber := config.NewFiber()
+	fiber := config.NewFiber(viper)
 	log := config.NewLogrus()
 	validator := config.NewValidator()
 	database := config.NewGorm(viper)
+	keySet := config.NewKeySet(viper)
+	mailer := config.NewMailSender(viper, log)
+	passwordHasher := config.NewHasher(viper)
+	oidcProvider := config.NewOidcProvider(viper)
+	fileStorage := config.NewStorage(viper)
 
-	app := config.NewApp(fiber, validator, database, viper, log)
+	app := config.NewApp(fiber, validator, database, viper, log, keySet, mailer, passwordHasher, oidcProvider, fileStorage)
 	app.Setup()
 	app.StartServer()
 
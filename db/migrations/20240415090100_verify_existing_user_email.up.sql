@@ -0,0 +1 @@
+UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN totp_secret VARCHAR(64) NOT NULL DEFAULT '' AFTER email_verified_at, ADD COLUMN totp_enabled_at TIMESTAMP NULL AFTER totp_secret, ADD COLUMN totp_last_step BIGINT NOT NULL DEFAULT 0 AFTER totp_enabled_at
\ No newline at end of file
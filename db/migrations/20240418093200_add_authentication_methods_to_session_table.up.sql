@@ -0,0 +1 @@
+ALTER TABLE sessions ADD COLUMN authentication_methods VARCHAR(255) NOT NULL DEFAULT 'pwd' AFTER ip_address
\ No newline at end of file
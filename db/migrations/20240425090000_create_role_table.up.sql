@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS roles(
+    id VARCHAR(255) PRIMARY KEY,
+    name VARCHAR(50) NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+)
\ No newline at end of file
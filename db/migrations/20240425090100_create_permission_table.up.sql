@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS permissions(
+    id VARCHAR(255) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+)
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE role_permissions;
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE user_roles;
\ No newline at end of file
@@ -0,0 +1 @@
+DELETE FROM roles WHERE id = 'admin';
\ No newline at end of file
@@ -0,0 +1 @@
+INSERT INTO roles(id, name) VALUES ('admin', 'admin')
\ No newline at end of file
@@ -0,0 +1 @@
+DELETE FROM permissions WHERE id IN ('product:update:any', 'product:delete:any');
\ No newline at end of file
@@ -0,0 +1 @@
+INSERT INTO permissions(id, name) VALUES ('product:update:any', 'product:update:any'), ('product:delete:any', 'product:delete:any')
\ No newline at end of file
@@ -0,0 +1 @@
+DELETE FROM role_permissions WHERE role_id = 'admin' AND permission_id IN ('product:update:any', 'product:delete:any');
\ No newline at end of file
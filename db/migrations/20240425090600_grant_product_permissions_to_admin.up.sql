@@ -0,0 +1 @@
+INSERT INTO role_permissions(role_id, permission_id) VALUES ('admin', 'product:update:any'), ('admin', 'product:delete:any')
\ No newline at end of file
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS login_attempts(
+    attempt_key VARCHAR(255) PRIMARY KEY,
+    failures INT NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMP NULL,
+    locked_until TIMESTAMP NULL
+)
\ No newline at end of file
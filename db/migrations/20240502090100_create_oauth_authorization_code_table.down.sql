@@ -0,0 +1 @@
+DROP TABLE oauth_authorization_codes;
\ No newline at end of file
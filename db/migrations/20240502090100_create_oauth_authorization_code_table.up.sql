@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS oauth_authorization_codes(
+    id VARCHAR(255) PRIMARY KEY,
+    code_hash CHAR(64) NOT NULL UNIQUE,
+    client_id VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    redirect_uri TEXT NOT NULL,
+    scopes VARCHAR(255) NOT NULL,
+    authentication_methods VARCHAR(255) NOT NULL,
+    code_challenge VARCHAR(128) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(client_id) REFERENCES oauth_clients(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE
+)
\ No newline at end of file
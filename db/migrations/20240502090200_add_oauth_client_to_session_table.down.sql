@@ -0,0 +1 @@
+ALTER TABLE sessions DROP FOREIGN KEY sessions_client_id_foreign, DROP COLUMN client_id, DROP COLUMN scopes;
\ No newline at end of file
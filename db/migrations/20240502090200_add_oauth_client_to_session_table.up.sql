@@ -0,0 +1 @@
+ALTER TABLE sessions ADD COLUMN client_id VARCHAR(255) NULL AFTER user_id, ADD COLUMN scopes VARCHAR(255) NOT NULL DEFAULT '' AFTER client_id, ADD CONSTRAINT sessions_client_id_foreign FOREIGN KEY(client_id) REFERENCES oauth_clients(id) ON DELETE CASCADE ON UPDATE CASCADE
\ No newline at end of file
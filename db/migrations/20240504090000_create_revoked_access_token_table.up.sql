@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS revoked_access_tokens(
+    jti VARCHAR(255) PRIMARY KEY,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+)
\ No newline at end of file
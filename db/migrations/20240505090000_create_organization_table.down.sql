@@ -0,0 +1 @@
+DROP TABLE organizations;
\ No newline at end of file
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS organizations(
+    id VARCHAR(255) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+)
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE organization_invitations;
\ No newline at end of file
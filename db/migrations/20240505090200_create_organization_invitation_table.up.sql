@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS organization_invitations(
+    id VARCHAR(255) PRIMARY KEY,
+    organization_id VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    role VARCHAR(32) NOT NULL,
+    token_hash CHAR(64) UNIQUE NOT NULL,
+    invited_by VARCHAR(255) NULL,
+    expires_at TIMESTAMP NOT NULL,
+    accepted_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX organization_invitations_organization_id_index (organization_id),
+    FOREIGN KEY(organization_id) REFERENCES organizations(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY(invited_by) REFERENCES users(id) ON DELETE SET NULL ON UPDATE CASCADE
+)
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE product DROP FOREIGN KEY product_organization_id_foreign, DROP COLUMN organization_id;
\ No newline at end of file
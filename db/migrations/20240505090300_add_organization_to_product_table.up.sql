@@ -0,0 +1 @@
+ALTER TABLE product ADD COLUMN organization_id VARCHAR(255) NULL AFTER user_id, ADD CONSTRAINT product_organization_id_foreign FOREIGN KEY(organization_id) REFERENCES organizations(id) ON DELETE CASCADE ON UPDATE CASCADE
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE product ADD CONSTRAINT product_ibfk_1 FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE product DROP FOREIGN KEY product_ibfk_1
\ No newline at end of file
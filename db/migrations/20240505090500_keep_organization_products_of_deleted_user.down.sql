@@ -0,0 +1 @@
+ALTER TABLE product DROP FOREIGN KEY product_user_id_foreign;
\ No newline at end of file
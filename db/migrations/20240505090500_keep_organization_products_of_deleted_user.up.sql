@@ -0,0 +1 @@
+ALTER TABLE product ADD CONSTRAINT product_user_id_foreign FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE SET NULL ON UPDATE CASCADE
\ No newline at end of file
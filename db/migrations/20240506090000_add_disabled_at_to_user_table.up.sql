@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN disabled_at TIMESTAMP NULL AFTER totp_last_step
\ No newline at end of file
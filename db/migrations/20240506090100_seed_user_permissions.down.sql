@@ -0,0 +1 @@
+DELETE FROM permissions WHERE id IN ('user:read:any', 'user:update:any');
\ No newline at end of file
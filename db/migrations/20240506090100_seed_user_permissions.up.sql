@@ -0,0 +1 @@
+INSERT INTO permissions(id, name) VALUES ('user:read:any', 'user:read:any'), ('user:update:any', 'user:update:any')
\ No newline at end of file
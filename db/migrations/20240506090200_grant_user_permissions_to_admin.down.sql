@@ -0,0 +1 @@
+DELETE FROM role_permissions WHERE role_id = 'admin' AND permission_id IN ('user:read:any', 'user:update:any');
\ No newline at end of file
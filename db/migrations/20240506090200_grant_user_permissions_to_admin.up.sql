@@ -0,0 +1 @@
+INSERT INTO role_permissions(role_id, permission_id) VALUES ('admin', 'user:read:any'), ('admin', 'user:update:any')
\ No newline at end of file
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS audit_logs(
+    id VARCHAR(255) PRIMARY KEY,
+    actor_id VARCHAR(255) NULL,
+    action VARCHAR(64) NOT NULL,
+    target_id VARCHAR(255) NULL,
+    outcome VARCHAR(16) NOT NULL,
+    ip_address VARCHAR(45),
+    user_agent VARCHAR(512),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX audit_logs_actor_id_index (actor_id),
+    INDEX audit_logs_target_id_index (target_id),
+    INDEX audit_logs_created_at_index (created_at)
+)
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE audit_logs ADD COLUMN details VARCHAR(512) NOT NULL DEFAULT '' AFTER outcome
\ No newline at end of file
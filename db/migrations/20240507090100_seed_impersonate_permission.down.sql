@@ -0,0 +1 @@
+DELETE FROM permissions WHERE id = 'user:impersonate:any';
\ No newline at end of file
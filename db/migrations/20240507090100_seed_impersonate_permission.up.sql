@@ -0,0 +1 @@
+INSERT INTO permissions(id, name) VALUES ('user:impersonate:any', 'user:impersonate:any')
\ No newline at end of file
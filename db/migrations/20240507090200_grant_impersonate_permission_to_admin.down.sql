@@ -0,0 +1 @@
+DELETE FROM role_permissions WHERE role_id = 'admin' AND permission_id = 'user:impersonate:any';
\ No newline at end of file
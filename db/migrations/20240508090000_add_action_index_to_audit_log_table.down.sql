@@ -0,0 +1 @@
+ALTER TABLE audit_logs DROP INDEX audit_logs_action_index;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE audit_logs ADD INDEX audit_logs_action_index (action)
\ No newline at end of file
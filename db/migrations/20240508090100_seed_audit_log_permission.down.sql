@@ -0,0 +1 @@
+DELETE FROM permissions WHERE id = 'audit_log:read:any';
\ No newline at end of file
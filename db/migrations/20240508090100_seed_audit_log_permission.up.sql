@@ -0,0 +1 @@
+INSERT INTO permissions(id, name) VALUES ('audit_log:read:any', 'audit_log:read:any')
\ No newline at end of file
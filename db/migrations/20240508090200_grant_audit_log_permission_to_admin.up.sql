@@ -0,0 +1 @@
+INSERT INTO role_permissions(role_id, permission_id) VALUES ('admin', 'audit_log:read:any')
\ No newline at end of file
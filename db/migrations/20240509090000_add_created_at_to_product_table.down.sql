@@ -0,0 +1 @@
+ALTER TABLE product DROP INDEX product_created_at_index, DROP COLUMN created_at;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE product ADD COLUMN created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, ADD INDEX product_created_at_index (created_at)
\ No newline at end of file
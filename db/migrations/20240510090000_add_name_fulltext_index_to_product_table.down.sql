@@ -0,0 +1 @@
+ALTER TABLE product DROP INDEX product_name_fulltext;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE product ADD FULLTEXT INDEX product_name_fulltext (name) WITH PARSER ngram
\ No newline at end of file
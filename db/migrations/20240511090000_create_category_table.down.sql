@@ -0,0 +1 @@
+DROP TABLE categories;
\ No newline at end of file
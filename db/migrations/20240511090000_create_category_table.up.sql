@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS categories(
+    id VARCHAR(255) PRIMARY KEY,
+    parent_id VARCHAR(255) NULL,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255) UNIQUE NOT NULL,
+    path VARCHAR(1024) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX categories_path_index (path(255)),
+    FOREIGN KEY(parent_id) REFERENCES categories(id) ON DELETE RESTRICT ON UPDATE CASCADE
+)
\ No newline at end of file
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS tags(
+    id VARCHAR(255) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255) UNIQUE NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+)
\ No newline at end of file
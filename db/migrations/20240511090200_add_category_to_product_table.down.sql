@@ -0,0 +1 @@
+ALTER TABLE product DROP FOREIGN KEY product_category_id_foreign, DROP COLUMN category_id;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE product ADD COLUMN category_id VARCHAR(255) NULL AFTER organization_id, ADD CONSTRAINT product_category_id_foreign FOREIGN KEY(category_id) REFERENCES categories(id) ON DELETE SET NULL ON UPDATE CASCADE
\ No newline at end of file
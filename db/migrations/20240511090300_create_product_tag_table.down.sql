@@ -0,0 +1 @@
+DROP TABLE product_tags;
\ No newline at end of file
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS product_tags(
+    product_id VARCHAR(255) NOT NULL,
+    tag_id VARCHAR(255) NOT NULL,
+    PRIMARY KEY(product_id, tag_id),
+    INDEX product_tags_tag_id_index (tag_id),
+    FOREIGN KEY(product_id) REFERENCES product(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY(tag_id) REFERENCES tags(id) ON DELETE CASCADE ON UPDATE CASCADE
+)
\ No newline at end of file
@@ -0,0 +1 @@
+DELETE FROM permissions WHERE id IN ('category:write:any', 'tag:write:any');
\ No newline at end of file
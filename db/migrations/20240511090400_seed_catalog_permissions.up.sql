@@ -0,0 +1 @@
+INSERT INTO permissions(id, name) VALUES ('category:write:any', 'category:write:any'), ('tag:write:any', 'tag:write:any')
\ No newline at end of file
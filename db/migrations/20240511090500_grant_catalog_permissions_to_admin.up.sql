@@ -0,0 +1 @@
+INSERT INTO role_permissions(role_id, permission_id) VALUES ('admin', 'category:write:any'), ('admin', 'tag:write:any')
\ No newline at end of file
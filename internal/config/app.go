@@ -6,7 +6,12 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
+	"go-crud/internal/hasher"
 	"go-crud/internal/injector"
+	"go-crud/internal/keyset"
+	"go-crud/internal/mail"
+	"go-crud/internal/oidc"
+	"go-crud/internal/storage"
 	"gorm.io/gorm"
 )
 
@@ -16,15 +21,25 @@ type App struct {
 	Database  *gorm.DB
 	Viper     *viper.Viper
 	Logger    *logrus.Logger
+	KeySet    *keyset.KeySet
+	Mailer    mail.Sender
+	Hasher    *hasher.Hasher
+	Oidc      *oidc.Provider
+	Storage   storage.Storage
 }
 
-func NewApp(fiber *fiber.App, validator *validator.Validate, database *gorm.DB, viper *viper.Viper, logger *logrus.Logger) *App {
+func NewApp(fiber *fiber.App, validator *validator.Validate, database *gorm.DB, viper *viper.Viper, logger *logrus.Logger, keySet *keyset.KeySet, mailer mail.Sender, passwordHasher *hasher.Hasher, oidcProvider *oidc.Provider, fileStorage storage.Storage) *App {
 	return &App{
 		Fiber:     fiber,
 		Validator: validator,
 		Database:  database,
 		Viper:     viper,
 		Logger:    logger,
+		KeySet:    keySet,
+		Mailer:    mailer,
+		Hasher:    passwordHasher,
+		Oidc:      oidcProvider,
+		Storage:   fileStorage,
 	}
 }
 
@@ -38,13 +53,68 @@ func (app *App) StartServer() {
 }
 
 func (app *App) Setup() {
-	signupRoute := injector.InjectSignupRoute(app.Fiber, app.Database, app.Validator, app.Logger)
+	signupRoute := injector.InjectSignupRoute(app.Fiber, app.Database, app.Validator, app.Viper, app.Mailer, app.Hasher, app.Logger)
 	signupRoute.Setup()
 
-	authRoute := injector.InjectAuthRoute(app.Fiber, app.Database, app.Validator, app.Viper, app.Logger)
+	emailVerificationRoute := injector.InjectEmailVerificationRoute(app.Fiber, app.Database, app.Validator, app.Viper, app.Mailer, app.Logger)
+	emailVerificationRoute.Setup()
+
+	authRoute := injector.InjectAuthRoute(app.Fiber, app.Database, app.Validator, app.Viper, app.KeySet, app.Hasher, app.Logger)
 	authRoute.Setup()
 
-	productRoute := injector.InjectProductRoute(app.Fiber, app.Database, app.Validator, app.Viper, app.Logger)
+	if app.Oidc != nil {
+		oidcRoute := injector.InjectOidcRoute(app.Fiber, app.Database, app.Validator, app.Viper, app.Oidc, app.Logger)
+		oidcRoute.Setup()
+	}
+
+	if app.Viper.GetBool("auth.magic_link.enabled") {
+		magicLinkRoute := injector.InjectMagicLinkRoute(app.Fiber, app.Database, app.Validator, app.Viper, app.Mailer, app.Logger)
+		magicLinkRoute.Setup()
+	}
+
+	apiKeyRoute := injector.InjectApiKeyRoute(app.Fiber, app.Logger)
+	apiKeyRoute.Setup()
+
+	mfaRoute := injector.InjectMfaRoute(app.Fiber, app.Database, app.Validator, app.Viper, app.Logger)
+	mfaRoute.Setup()
+
+	oauthRoute := injector.InjectOAuthRoute(app.Fiber, app.Database, app.Validator, app.Viper, app.Logger)
+	oauthRoute.Setup()
+
+	passwordResetRoute := injector.InjectPasswordResetRoute(app.Fiber, app.Database, app.Validator, app.Viper, app.Mailer, app.Hasher, app.Logger)
+	passwordResetRoute.Setup()
+
+	userRoute := injector.InjectUserRoute(app.Fiber, app.Database, app.Validator, app.Viper, app.Mailer, app.Hasher, app.Storage, app.Logger)
+	userRoute.Setup()
+
+	sessionRoute := injector.InjectSessionRoute(app.Fiber, app.Database, app.Logger)
+	sessionRoute.Setup()
+
+	adminRoute := injector.InjectAdminRoute(app.Fiber, app.Database, app.Validator, app.Viper, app.Mailer, app.Hasher, app.Logger)
+	adminRoute.Setup()
+
+	auditLogRoute := injector.InjectAuditLogRoute(app.Fiber, app.Logger)
+	auditLogRoute.Setup()
+
+	organizationRoute := injector.InjectOrganizationRoute(app.Fiber, app.Database, app.Validator, app.Viper, app.Mailer, app.Storage, app.Logger)
+	organizationRoute.Setup()
+
+	productRoute := injector.InjectProductRoute(app.Fiber, app.Database, app.Validator, app.Viper, app.Storage, app.Logger)
 	productRoute.Setup()
 
+	productImageRoute := injector.InjectProductImageRoute(app.Fiber, app.Database, app.Validator, app.Viper, app.Storage, app.Logger)
+	productImageRoute.Setup()
+
+	// Files of the local storage are served by the application itself, other
+	// storages serve their own.
+	if local, ok := app.Storage.(*storage.LocalStorage); ok {
+		app.Fiber.Static(app.Viper.GetString("storage.local.path"), local.Directory)
+	}
+
+	categoryRoute := injector.InjectCategoryRoute(app.Fiber, app.Database, app.Validator, app.Logger)
+	categoryRoute.Setup()
+
+	tagRoute := injector.InjectTagRoute(app.Fiber, app.Database, app.Validator, app.Logger)
+	tagRoute.Setup()
+
 }
@@ -3,6 +3,7 @@ package config
 import (
 	"errors"
 	"github.com/gofiber/fiber/v2"
+	"github.com/spf13/viper"
 	"go-crud/internal/models"
 )
 
@@ -24,8 +25,20 @@ func errorHandlerConfig(ctx *fiber.Ctx, err error) error {
 		status = "Not Found"
 	case 408:
 		status = "Request Timeout"
+	case 409:
+		status = "Conflict"
+	case 413:
+		status = "Request Entity Too Large"
+	case 415:
+		status = "Unsupported Media Type"
+	case 423:
+		status = "Locked"
+	case 429:
+		status = "Too Many Requests"
 	case 500:
 		status = "Internal Server Error"
+	case 502:
+		status = "Bad Gateway"
 
 	}
 	return ctx.Status(code).JSON(models.ErrorResponse{
@@ -33,9 +46,13 @@ func errorHandlerConfig(ctx *fiber.Ctx, err error) error {
 		Message: e.Message,
 	})
 }
-func NewFiber() *fiber.App {
+
+// NewFiber accepts bodies large enough for an image upload, with room for
+// the rest of the multipart form.
+func NewFiber(viper *viper.Viper) *fiber.App {
 	fiber := fiber.New(fiber.Config{
 		ErrorHandler: errorHandlerConfig,
+		BodyLimit:    viper.GetInt("image.max_size") + 64*1024,
 	})
 	return fiber
 }
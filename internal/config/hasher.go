@@ -0,0 +1,32 @@
+package config
+
+import (
+	"fmt"
+	"github.com/spf13/viper"
+	"go-crud/internal/hasher"
+)
+
+// NewHasher hashes new passwords with password.algorithm. Hashes of the
+// other supported algorithm are still accepted and upgraded on sign in.
+func NewHasher(viper *viper.Viper) *hasher.Hasher {
+	argon2id := &hasher.Argon2id{
+		Memory:      viper.GetUint32("password.argon2id.memory"),
+		Iterations:  viper.GetUint32("password.argon2id.iterations"),
+		Parallelism: uint8(viper.GetUint("password.argon2id.parallelism")),
+		SaltLength:  viper.GetUint32("password.argon2id.salt_length"),
+		KeyLength:   viper.GetUint32("password.argon2id.key_length"),
+	}
+	bcrypt := &hasher.Bcrypt{
+		Cost: viper.GetInt("password.bcrypt.cost"),
+	}
+
+	algorithm := viper.GetString("password.algorithm")
+	switch algorithm {
+	case "", "argon2id":
+		return hasher.New(argon2id, bcrypt)
+	case "bcrypt":
+		return hasher.New(bcrypt, argon2id)
+	default:
+		panic(fmt.Errorf("unsupported password algorithm %q", algorithm))
+	}
+}
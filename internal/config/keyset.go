@@ -0,0 +1,71 @@
+package config
+
+import (
+	"fmt"
+	"github.com/spf13/viper"
+	"go-crud/internal/keyset"
+	"os"
+)
+
+type signingKeyConfig struct {
+	Kid            string `mapstructure:"kid"`
+	Alg            string `mapstructure:"alg"`
+	Secret         string `mapstructure:"secret"`
+	PrivateKeyFile string `mapstructure:"private_key_file"`
+	Retired        bool   `mapstructure:"retired"`
+}
+
+// NewKeySet builds the access token signing keys from token.signing. Without
+// any configured key it falls back to HS256 with token.key.access, which is
+// how tokens were signed before key rotation existed.
+func NewKeySet(viper *viper.Viper) *keyset.KeySet {
+	var keyConfigs []signingKeyConfig
+	err := viper.UnmarshalKey("token.signing.keys", &keyConfigs)
+	if err != nil {
+		panic(err)
+	}
+
+	if len(keyConfigs) == 0 {
+		set, err := keyset.New("default", &keyset.Key{
+			Id:        "default",
+			Algorithm: keyset.AlgorithmHS256,
+			Secret:    []byte(viper.GetString("token.key.access")),
+		})
+		if err != nil {
+			panic(err)
+		}
+		return set
+	}
+
+	keys := make([]*keyset.Key, len(keyConfigs))
+	for index, keyConfig := range keyConfigs {
+		key := &keyset.Key{
+			Id:        keyConfig.Kid,
+			Algorithm: keyConfig.Alg,
+			Retired:   keyConfig.Retired,
+		}
+
+		if keyConfig.Alg == keyset.AlgorithmHS256 {
+			key.Secret = []byte(keyConfig.Secret)
+		} else {
+			data, err := os.ReadFile(keyConfig.PrivateKeyFile)
+			if err != nil {
+				panic(fmt.Errorf("reading signing key %q: %w", keyConfig.Kid, err))
+			}
+			key.Private, err = keyset.ParsePrivateKey(data)
+			if err != nil {
+				panic(fmt.Errorf("parsing signing key %q: %w", keyConfig.Kid, err))
+			}
+			key.Public = key.Private.Public()
+		}
+
+		keys[index] = key
+	}
+
+	set, err := keyset.New(viper.GetString("token.signing.active"), keys...)
+	if err != nil {
+		panic(err)
+	}
+
+	return set
+}
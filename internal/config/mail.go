@@ -0,0 +1,20 @@
+package config
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"go-crud/internal/mail"
+)
+
+func NewMailSender(viper *viper.Viper, log *logrus.Logger) mail.Sender {
+	driver := viper.GetString("mail.driver")
+	switch driver {
+	case "", "log":
+		return mail.NewLogSender(log)
+	case "file":
+		return mail.NewFileSender(viper.GetString("mail.file.directory"))
+	default:
+		panic(fmt.Errorf("unsupported mail driver %q", driver))
+	}
+}
@@ -0,0 +1,24 @@
+package config
+
+import (
+	"github.com/spf13/viper"
+	"go-crud/internal/oidc"
+	"net/http"
+	"time"
+)
+
+// NewOidcProvider builds the external identity provider from auth.oidc. It
+// returns nil when sign in with OpenID Connect is disabled.
+func NewOidcProvider(viper *viper.Viper) *oidc.Provider {
+	if !viper.GetBool("auth.oidc.enabled") {
+		return nil
+	}
+
+	return oidc.New(oidc.Config{
+		Issuer:       viper.GetString("auth.oidc.issuer"),
+		ClientId:     viper.GetString("auth.oidc.client_id"),
+		ClientSecret: viper.GetString("auth.oidc.client_secret"),
+		RedirectUrl:  viper.GetString("auth.oidc.redirect_url"),
+		Scopes:       viper.GetStringSlice("auth.oidc.scopes"),
+	}, &http.Client{Timeout: time.Duration(viper.GetInt("auth.oidc.timeout")) * time.Second})
+}
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"fmt"
+	"github.com/spf13/viper"
+	"go-crud/internal/storage"
+	"time"
+)
+
+func NewStorage(viper *viper.Viper) storage.Storage {
+	driver := viper.GetString("storage.driver")
+	switch driver {
+	case "", "local":
+		return storage.NewLocalStorage(viper.GetString("storage.local.directory"), viper.GetString("web.url")+viper.GetString("storage.local.path"))
+	case "s3":
+		return storage.NewS3Storage(
+			viper.GetString("storage.s3.endpoint"),
+			viper.GetString("storage.s3.region"),
+			viper.GetString("storage.s3.bucket"),
+			viper.GetString("storage.s3.access_key"),
+			viper.GetString("storage.s3.secret_key"),
+			viper.GetString("storage.s3.url"),
+			time.Duration(viper.GetInt("storage.s3.timeout"))*time.Second,
+		)
+	default:
+		panic(fmt.Errorf("unsupported storage driver %q", driver))
+	}
+}
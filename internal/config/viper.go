@@ -1,6 +1,9 @@
 package config
 
-import "github.com/spf13/viper"
+import (
+	"fmt"
+	"github.com/spf13/viper"
+)
 
 func NewViper(path string) *viper.Viper {
 	config := viper.New()
@@ -14,5 +17,17 @@ func NewViper(path string) *viper.Viper {
 		panic(err)
 	}
 
+	ValidateViper(config)
 	return config
 }
+
+// ValidateViper refuses settings that are only read while serving requests,
+// where a typo would otherwise go unnoticed.
+func ValidateViper(viper *viper.Viper) {
+	unverifiedSignIn := viper.GetString("auth.email_verification.unverified_sign_in")
+	switch unverifiedSignIn {
+	case "", "allow", "refuse":
+	default:
+		panic(fmt.Errorf("unsupported unverified sign in policy %q, use refuse or allow", unverifiedSignIn))
+	}
+}
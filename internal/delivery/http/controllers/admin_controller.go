@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"go-crud/internal/models"
+	"go-crud/internal/usecase"
+)
+
+type AdminController struct {
+	Log          *logrus.Logger
+	AdminUsecase *usecase.AdminUsecase
+}
+
+func NewAdminController(log *logrus.Logger, adminUsecase *usecase.AdminUsecase) *AdminController {
+	return &AdminController{
+		Log:          log,
+		AdminUsecase: adminUsecase,
+	}
+}
+
+func (c *AdminController) actor(ctx *fiber.Ctx) models.Actor {
+	return models.Actor{
+		UserId: ctx.Locals("user_id").(string),
+		Client: models.ClientInfo{
+			UserAgent: ctx.Get(fiber.HeaderUserAgent),
+			IpAddress: ctx.IP(),
+		},
+	}
+}
+
+func (c *AdminController) GetUsers(ctx *fiber.Ctx) error {
+	limit := ctx.QueryInt("limit", 50)
+	if limit > 100 {
+		return fiber.NewError(fiber.StatusBadRequest, "Max limit is 100")
+	}
+	if limit < 1 {
+		return fiber.NewError(fiber.StatusBadRequest, "Min limit is 1")
+	}
+	page := ctx.QueryInt("page", 1)
+	if page < 1 {
+		return fiber.NewError(fiber.StatusBadRequest, "Min page is 1")
+	}
+	search := ctx.Query("q")
+
+	users, err := c.AdminUsecase.GetUsers(c.actor(ctx), search, (page-1)*limit, limit)
+	if err != nil {
+		return handleError(c.Log, err, "Error while getting users")
+	}
+
+	metadata, err := c.AdminUsecase.GetMetadataPagination(search, page, limit)
+	if err != nil {
+		return handleError(c.Log, err, "Error while getting users pagination")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[*[]models.AdminUserResponse]{
+		Message:  "Get users successfully",
+		Metadata: metadata,
+		Data:     users,
+	})
+}
+
+func (c *AdminController) GetUser(ctx *fiber.Ctx) error {
+	result, err := c.AdminUsecase.GetUser(c.actor(ctx), ctx.Params("id"))
+	if err != nil {
+		return handleError(c.Log, err, "Error while getting user")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[*models.AdminUserResponse]{
+		Message: "Get user successfully",
+		Data:    result,
+	})
+}
+
+func (c *AdminController) DisableUser(ctx *fiber.Ctx) error {
+	err := c.AdminUsecase.DisableUser(c.actor(ctx), ctx.Params("id"))
+	if err != nil {
+		return handleError(c.Log, err, "Error while disabling user")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[any]{
+		Message: "User disabled",
+	})
+}
+
+func (c *AdminController) EnableUser(ctx *fiber.Ctx) error {
+	err := c.AdminUsecase.EnableUser(c.actor(ctx), ctx.Params("id"))
+	if err != nil {
+		return handleError(c.Log, err, "Error while enabling user")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[any]{
+		Message: "User enabled",
+	})
+}
+
+func (c *AdminController) LogoutUser(ctx *fiber.Ctx) error {
+	err := c.AdminUsecase.LogoutUser(c.actor(ctx), ctx.Params("id"))
+	if err != nil {
+		return handleError(c.Log, err, "Error while signing user out")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[any]{
+		Message: "User signed out of every session",
+	})
+}
+
+func (c *AdminController) SendPasswordReset(ctx *fiber.Ctx) error {
+	err := c.AdminUsecase.SendPasswordReset(c.actor(ctx), ctx.Params("id"))
+	if err != nil {
+		return handleError(c.Log, err, "Error while sending password reset")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[any]{
+		Message: "Password reset email sent",
+	})
+}
+
+func (c *AdminController) Impersonate(ctx *fiber.Ctx) error {
+	sessionID, _ := ctx.Locals("session_id").(string)
+	methods, _ := ctx.Locals("authentication_methods").([]string)
+	result, err := c.AdminUsecase.Impersonate(c.actor(ctx), &models.ImpersonateRequest{
+		UserId:    ctx.Params("id"),
+		SessionId: sessionID,
+		Methods:   methods,
+	})
+	if err != nil {
+		return handleError(c.Log, err, "Error while impersonating user")
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(&models.Response[*models.ImpersonationResponse]{
+		Message: "Impersonation token issued",
+		Data:    result,
+	})
+}
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"go-crud/internal/models"
+	"go-crud/internal/usecase"
+)
+
+type ApiKeyController struct {
+	Log           *logrus.Logger
+	ApiKeyUsecase *usecase.ApiKeyUsecase
+}
+
+func NewApiKeyController(log *logrus.Logger, apiKeyUsecase *usecase.ApiKeyUsecase) *ApiKeyController {
+	return &ApiKeyController{
+		Log:           log,
+		ApiKeyUsecase: apiKeyUsecase,
+	}
+}
+
+func (c *ApiKeyController) CreateApiKey(ctx *fiber.Ctx) error {
+	request := new(models.CreateApiKeyRequest)
+	if err := parseBody(c.Log, ctx, request); err != nil {
+		return err
+	}
+
+	userID := ctx.Locals("user_id").(string)
+	result, err := c.ApiKeyUsecase.CreateApiKey(userID, request)
+	if err != nil {
+		return handleError(c.Log, err, "Error while creating api key")
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(&models.Response[*models.ApiKeyCreatedResponse]{
+		Message: "API key created. Copy the key now, it won't be shown again",
+		Data:    result,
+	})
+}
+
+func (c *ApiKeyController) GetApiKeys(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(string)
+	result, err := c.ApiKeyUsecase.GetApiKeys(userID)
+	if err != nil {
+		return handleError(c.Log, err, "Error while getting api keys")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[*[]models.ApiKeyResponse]{
+		Message: "Get API keys successfully",
+		Data:    result,
+	})
+}
+
+func (c *ApiKeyController) RevokeApiKey(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(string)
+	err := c.ApiKeyUsecase.RevokeApiKey(userID, ctx.Params("id"))
+	if err != nil {
+		return handleError(c.Log, err, "Error while revoking api key")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[any]{
+		Message: "API key revoked",
+	})
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"go-crud/internal/entity"
+	"go-crud/internal/models"
+	"go-crud/internal/usecase"
+	"time"
+)
+
+type AuditLogController struct {
+	Log             *logrus.Logger
+	AuditLogUsecase *usecase.AuditLogUsecase
+}
+
+func NewAuditLogController(log *logrus.Logger, auditLogUsecase *usecase.AuditLogUsecase) *AuditLogController {
+	return &AuditLogController{
+		Log:             log,
+		AuditLogUsecase: auditLogUsecase,
+	}
+}
+
+func (c *AuditLogController) GetAuditLogs(ctx *fiber.Ctx) error {
+	limit := ctx.QueryInt("limit", 50)
+	if limit > 100 {
+		return fiber.NewError(fiber.StatusBadRequest, "Max limit is 100")
+	}
+	if limit < 1 {
+		return fiber.NewError(fiber.StatusBadRequest, "Min limit is 1")
+	}
+	page := ctx.QueryInt("page", 1)
+	if page < 1 {
+		return fiber.NewError(fiber.StatusBadRequest, "Min page is 1")
+	}
+
+	filter := &models.AuditLogFilter{
+		ActorId:  ctx.Query("actor_id"),
+		TargetId: ctx.Query("target_id"),
+		Action:   ctx.Query("action"),
+		Outcome:  ctx.Query("outcome"),
+	}
+	if filter.Outcome != "" && filter.Outcome != entity.AuditOutcomeSuccess && filter.Outcome != entity.AuditOutcomeFailure {
+		return fiber.NewError(fiber.StatusBadRequest, "outcome must be success or failure")
+	}
+	var err error
+	filter.From, err = queryTime(ctx, "from")
+	if err != nil {
+		return err
+	}
+	filter.To, err = queryTime(ctx, "to")
+	if err != nil {
+		return err
+	}
+
+	actor := models.Actor{
+		UserId: ctx.Locals("user_id").(string),
+		Client: clientInfo(ctx),
+	}
+	entries, err := c.AuditLogUsecase.GetAuditLogs(actor, filter, (page-1)*limit, limit)
+	if err != nil {
+		return handleError(c.Log, err, "Error while getting audit logs")
+	}
+
+	metadata, err := c.AuditLogUsecase.GetMetadataPagination(filter, page, limit)
+	if err != nil {
+		return handleError(c.Log, err, "Error while getting audit logs pagination")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[*[]models.AuditLogResponse]{
+		Message:  "Get audit logs successfully",
+		Metadata: metadata,
+		Data:     entries,
+	})
+}
+
+// queryTime reads an optional RFC 3339 time from the query string.
+func queryTime(ctx *fiber.Ctx, key string) (*time.Time, error) {
+	value := ctx.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("%s must be an RFC 3339 time", key))
+	}
+	return &parsed, nil
+}
@@ -1,7 +1,6 @@
 package controllers
 
 import (
-	"fmt"
 	"github.com/gofiber/fiber/v2"
 	"github.com/sirupsen/logrus"
 	"go-crud/internal/models"
@@ -24,35 +23,24 @@ func NewAuthController(log *logrus.Logger, authUsecase *usecase.AuthUsecase) *Au
 func (c *AuthController) SignIn(ctx *fiber.Ctx) error {
 	ctx.Accepts("application/json")
 	req := new(models.SignInRequest)
-	err := ctx.BodyParser(req)
-
-	if err != nil {
-		c.Log.WithError(err).Error(err)
-		if e, ok := err.(*fiber.UnmarshalTypeError); ok {
-			return fiber.NewError(400, fmt.Sprintf("%s must be string", e.Field))
-		}
-
-		return fiber.NewError(500, "Something Error")
-
+	if err := parseBody(c.Log, ctx, req); err != nil {
+		return err
 	}
 
+	req.Client = clientInfo(ctx)
 	result, err := c.AuthUsecase.SignIn(req)
 	if err != nil {
-		if e, ok := err.(*models.ErrorResponse); ok {
-			return fiber.NewError(e.Code, e.Message)
-		}
-
-		c.Log.Errorf("%v", err)
-		return fiber.NewError(500, "Something Error")
+		return handleError(c.Log, err, "Error while signing in")
 	}
 
-	cookie := new(fiber.Cookie)
-	cookie.Name = "refresh_token"
-	cookie.Value = result.RefreshToken
-	cookie.Expires = time.Now().Add(3 * (24 * time.Hour))
-	cookie.HTTPOnly = true
+	if result.MfaRequired {
+		return ctx.Status(fiber.StatusOK).JSON(models.Response[*models.AuthResponse]{
+			Message: "Two-factor authentication required",
+			Data:    result,
+		})
+	}
 
-	ctx.Cookie(cookie)
+	setRefreshTokenCookie(ctx, result.RefreshToken)
 
 	return ctx.Status(fiber.StatusOK).JSON(models.Response[*models.AuthResponse]{
 		Message: "Signin successfully",
@@ -62,6 +50,11 @@ func (c *AuthController) SignIn(ctx *fiber.Ctx) error {
 }
 
 func (c *AuthController) SignOut(ctx *fiber.Ctx) error {
+	err := c.AuthUsecase.SignOut(ctx.Cookies("refresh_token", ""), clientInfo(ctx))
+	if err != nil {
+		return handleError(c.Log, err, "Error while signing out")
+	}
+
 	ctx.ClearCookie()
 	return ctx.Status(fiber.StatusOK).JSON(&models.Response[any]{
 		Message: "Sign out successfully",
@@ -70,17 +63,39 @@ func (c *AuthController) SignOut(ctx *fiber.Ctx) error {
 
 func (c *AuthController) RefreshToken(ctx *fiber.Ctx) error {
 	refreshToken := ctx.Cookies("refresh_token", "")
-	result, err := c.AuthUsecase.RefreshToken(refreshToken)
+	result, err := c.AuthUsecase.RefreshToken(refreshToken, clientInfo(ctx))
 	if err != nil {
-		c.Log.WithError(err).Error("Error while getting token")
-		if e, ok := err.(*models.ErrorResponse); ok {
-			return fiber.NewError(e.Code, e.Message)
-		}
-		return fiber.NewError(500, "Something Error")
+		return handleError(c.Log, err, "Error while getting token")
 	}
 
+	setRefreshTokenCookie(ctx, result.RefreshToken)
+
 	return ctx.Status(fiber.StatusOK).JSON(&models.Response[*models.AuthResponse]{
 		Message: "Access token successfully generated",
 		Data:    result,
 	})
 }
+
+func (c *AuthController) GetJWKS(ctx *fiber.Ctx) error {
+	ctx.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return ctx.Status(fiber.StatusOK).JSON(c.AuthUsecase.KeySet.JWKS())
+}
+
+// clientInfo describes the device the request comes from, for sessions and
+// the audit log.
+func clientInfo(ctx *fiber.Ctx) models.ClientInfo {
+	return models.ClientInfo{
+		UserAgent: ctx.Get(fiber.HeaderUserAgent),
+		IpAddress: ctx.IP(),
+	}
+}
+
+func setRefreshTokenCookie(ctx *fiber.Ctx, refreshToken string) {
+	cookie := new(fiber.Cookie)
+	cookie.Name = "refresh_token"
+	cookie.Value = refreshToken
+	cookie.Expires = time.Now().Add(3 * (24 * time.Hour))
+	cookie.HTTPOnly = true
+
+	ctx.Cookie(cookie)
+}
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"go-crud/internal/models"
+	"go-crud/internal/usecase"
+)
+
+type CategoryController struct {
+	Log             *logrus.Logger
+	CategoryUsecase *usecase.CategoryUsecase
+}
+
+func NewCategoryController(log *logrus.Logger, categoryUsecase *usecase.CategoryUsecase) *CategoryController {
+	return &CategoryController{
+		Log:             log,
+		CategoryUsecase: categoryUsecase,
+	}
+}
+
+func (c *CategoryController) GetCategories(ctx *fiber.Ctx) error {
+	result, err := c.CategoryUsecase.GetCategoryTree()
+	if err != nil {
+		return handleError(c.Log, err, "Error while getting categories")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[*[]models.CategoryResponse]{
+		Message: "Get categories successfully",
+		Data:    result,
+	})
+}
+
+func (c *CategoryController) GetCategory(ctx *fiber.Ctx) error {
+	result, err := c.CategoryUsecase.GetCategory(ctx.Params("slug"))
+	if err != nil {
+		return handleError(c.Log, err, "Error while getting category")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[*models.CategoryResponse]{
+		Message: "Get category successfully",
+		Data:    result,
+	})
+}
+
+func (c *CategoryController) CreateCategory(ctx *fiber.Ctx) error {
+	request := new(models.CategoryRequest)
+	err := parseBody(c.Log, ctx, request)
+	if err != nil {
+		return err
+	}
+
+	result, err := c.CategoryUsecase.CreateCategory(request)
+	if err != nil {
+		return handleError(c.Log, err, "Error while creating category")
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(&models.Response[*models.CategoryResponse]{
+		Message: "Category created",
+		Data:    result,
+	})
+}
+
+func (c *CategoryController) UpdateCategory(ctx *fiber.Ctx) error {
+	request := new(models.CategoryRequest)
+	err := parseBody(c.Log, ctx, request)
+	if err != nil {
+		return err
+	}
+
+	result, err := c.CategoryUsecase.UpdateCategory(ctx.Params("id"), request)
+	if err != nil {
+		return handleError(c.Log, err, "Error while updating category")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[*models.CategoryResponse]{
+		Message: "Category updated",
+		Data:    result,
+	})
+}
+
+func (c *CategoryController) DeleteCategory(ctx *fiber.Ctx) error {
+	err := c.CategoryUsecase.DeleteCategory(ctx.Params("id"))
+	if err != nil {
+		return handleError(c.Log, err, "Error while deleting category")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[any]{
+		Message: "Category deleted",
+	})
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"go-crud/internal/models"
+	"go-crud/internal/usecase"
+)
+
+type EmailVerificationController struct {
+	Log                      *logrus.Logger
+	EmailVerificationUsecase *usecase.EmailVerificationUsecase
+}
+
+func NewEmailVerificationController(log *logrus.Logger, emailVerificationUsecase *usecase.EmailVerificationUsecase) *EmailVerificationController {
+	return &EmailVerificationController{
+		Log:                      log,
+		EmailVerificationUsecase: emailVerificationUsecase,
+	}
+}
+
+func (c *EmailVerificationController) VerifyEmail(ctx *fiber.Ctx) error {
+	request := new(models.VerifyEmailRequest)
+	if err := parseBody(c.Log, ctx, request); err != nil {
+		return err
+	}
+
+	err := c.EmailVerificationUsecase.VerifyEmail(request)
+	if err != nil {
+		return handleError(c.Log, err, "Error while verifying email")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[any]{
+		Message: "Email verified",
+	})
+}
+
+func (c *EmailVerificationController) ConfirmEmailChange(ctx *fiber.Ctx) error {
+	request := new(models.VerifyEmailRequest)
+	if err := parseBody(c.Log, ctx, request); err != nil {
+		return err
+	}
+
+	err := c.EmailVerificationUsecase.ConfirmEmailChange(request)
+	if err != nil {
+		return handleError(c.Log, err, "Error while confirming email change")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[any]{
+		Message: "Email changed",
+	})
+}
+
+func (c *EmailVerificationController) ResendVerification(ctx *fiber.Ctx) error {
+	request := new(models.ResendVerificationRequest)
+	if err := parseBody(c.Log, ctx, request); err != nil {
+		return err
+	}
+
+	err := c.EmailVerificationUsecase.ResendVerification(request)
+	if err != nil {
+		return handleError(c.Log, err, "Error while resending verification email")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[any]{
+		Message: "If the email is registered and not verified yet, a verification link has been sent",
+	})
+}
@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"go-crud/internal/models"
+)
+
+// parseBody reads the JSON body into request. A malformed body is the
+// client's fault, so it is refused with a 400.
+func parseBody(log *logrus.Logger, ctx *fiber.Ctx, request any) error {
+	err := ctx.BodyParser(request)
+	if err != nil {
+		log.WithError(err).Error("Error while parsing body request")
+		if e, ok := err.(*fiber.UnmarshalTypeError); ok {
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("%s has an invalid type", e.Field))
+		}
+
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+	return nil
+}
+
+// handleError turns the errors of the usecases into HTTP errors. Anything
+// else is logged with message and hidden behind a 500.
+func handleError(log *logrus.Logger, err error, message string) error {
+	if e, ok := err.(*models.ErrorResponse); ok {
+		return fiber.NewError(e.Code, e.Message)
+	}
+	log.WithError(err).Error(message)
+	return fiber.NewError(fiber.StatusInternalServerError, "Something Error")
+}
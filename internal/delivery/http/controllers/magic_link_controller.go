@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"go-crud/internal/models"
+	"go-crud/internal/usecase"
+)
+
+type MagicLinkController struct {
+	Log              *logrus.Logger
+	MagicLinkUsecase *usecase.MagicLinkUsecase
+}
+
+func NewMagicLinkController(log *logrus.Logger, magicLinkUsecase *usecase.MagicLinkUsecase) *MagicLinkController {
+	return &MagicLinkController{
+		Log:              log,
+		MagicLinkUsecase: magicLinkUsecase,
+	}
+}
+
+func (c *MagicLinkController) RequestLink(ctx *fiber.Ctx) error {
+	request := new(models.MagicLinkRequest)
+	if err := parseBody(c.Log, ctx, request); err != nil {
+		return err
+	}
+
+	err := c.MagicLinkUsecase.RequestLink(request)
+	if err != nil {
+		return handleError(c.Log, err, "Error while requesting magic link")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[any]{
+		Message: "If the email is registered, a sign in link has been sent",
+	})
+}
+
+func (c *MagicLinkController) SignIn(ctx *fiber.Ctx) error {
+	request := new(models.MagicLinkSignInRequest)
+	if err := parseBody(c.Log, ctx, request); err != nil {
+		return err
+	}
+
+	request.Client = models.ClientInfo{
+		UserAgent: ctx.Get(fiber.HeaderUserAgent),
+		IpAddress: ctx.IP(),
+	}
+	result, err := c.MagicLinkUsecase.SignIn(request)
+	if err != nil {
+		return handleError(c.Log, err, "Error while signing in with magic link")
+	}
+
+	if result.MfaRequired {
+		return ctx.Status(fiber.StatusOK).JSON(models.Response[*models.AuthResponse]{
+			Message: "Two-factor authentication required",
+			Data:    result,
+		})
+	}
+
+	setRefreshTokenCookie(ctx, result.RefreshToken)
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[*models.AuthResponse]{
+		Message: "Signin successfully",
+		Data:    result,
+	})
+}
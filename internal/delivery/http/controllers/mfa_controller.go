@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"go-crud/internal/models"
+	"go-crud/internal/usecase"
+)
+
+type MfaController struct {
+	Log        *logrus.Logger
+	MfaUsecase *usecase.MfaUsecase
+}
+
+func NewMfaController(log *logrus.Logger, mfaUsecase *usecase.MfaUsecase) *MfaController {
+	return &MfaController{
+		Log:        log,
+		MfaUsecase: mfaUsecase,
+	}
+}
+
+func (c *MfaController) Enroll(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(string)
+	result, err := c.MfaUsecase.Enroll(userID)
+	if err != nil {
+		return handleError(c.Log, err, "Error while enrolling totp")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[*models.TotpEnrollResponse]{
+		Message: "Scan the secret with your authenticator app, then confirm with a code",
+		Data:    result,
+	})
+}
+
+func (c *MfaController) Confirm(ctx *fiber.Ctx) error {
+	request := new(models.TotpCodeRequest)
+	if err := parseBody(c.Log, ctx, request); err != nil {
+		return err
+	}
+
+	userID := ctx.Locals("user_id").(string)
+	result, err := c.MfaUsecase.Confirm(userID, request)
+	if err != nil {
+		return handleError(c.Log, err, "Error while confirming totp")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[*models.RecoveryCodesResponse]{
+		Message: "Two-factor authentication enabled. Store the recovery codes somewhere safe, they are shown only once",
+		Data:    result,
+	})
+}
+
+func (c *MfaController) Disable(ctx *fiber.Ctx) error {
+	request := new(models.SecondFactorRequest)
+	if err := parseBody(c.Log, ctx, request); err != nil {
+		return err
+	}
+
+	userID := ctx.Locals("user_id").(string)
+	err := c.MfaUsecase.Disable(userID, request)
+	if err != nil {
+		return handleError(c.Log, err, "Error while disabling totp")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[any]{
+		Message: "Two-factor authentication disabled",
+	})
+}
+
+func (c *MfaController) RegenerateRecoveryCodes(ctx *fiber.Ctx) error {
+	request := new(models.TotpCodeRequest)
+	if err := parseBody(c.Log, ctx, request); err != nil {
+		return err
+	}
+
+	userID := ctx.Locals("user_id").(string)
+	result, err := c.MfaUsecase.RegenerateRecoveryCodes(userID, request)
+	if err != nil {
+		return handleError(c.Log, err, "Error while regenerating recovery codes")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[*models.RecoveryCodesResponse]{
+		Message: "Recovery codes regenerated, the previous ones no longer work",
+		Data:    result,
+	})
+}
+
+func (c *MfaController) Verify(ctx *fiber.Ctx) error {
+	request := new(models.MfaVerifyRequest)
+	if err := parseBody(c.Log, ctx, request); err != nil {
+		return err
+	}
+
+	request.Client = models.ClientInfo{
+		UserAgent: ctx.Get(fiber.HeaderUserAgent),
+		IpAddress: ctx.IP(),
+	}
+	result, err := c.MfaUsecase.Verify(request)
+	if err != nil {
+		return handleError(c.Log, err, "Error while verifying second factor")
+	}
+
+	setRefreshTokenCookie(ctx, result.RefreshToken)
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[*models.AuthResponse]{
+		Message: "Signin successfully",
+		Data:    result,
+	})
+}
@@ -0,0 +1,241 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"go-crud/internal/models"
+	"go-crud/internal/usecase"
+	"net/url"
+	"strings"
+)
+
+type OAuthController struct {
+	Log          *logrus.Logger
+	OAuthUsecase *usecase.OAuthUsecase
+}
+
+func NewOAuthController(log *logrus.Logger, oauthUsecase *usecase.OAuthUsecase) *OAuthController {
+	return &OAuthController{
+		Log:          log,
+		OAuthUsecase: oauthUsecase,
+	}
+}
+
+func (c *OAuthController) CreateClient(ctx *fiber.Ctx) error {
+	request := new(models.CreateOAuthClientRequest)
+	if err := parseBody(c.Log, ctx, request); err != nil {
+		return err
+	}
+
+	userID := ctx.Locals("user_id").(string)
+	result, err := c.OAuthUsecase.CreateClient(userID, request)
+	if err != nil {
+		return handleError(c.Log, err, "Error while creating oauth client")
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(&models.Response[*models.OAuthClientCreatedResponse]{
+		Message: "OAuth client created. Copy the secret now, it won't be shown again",
+		Data:    result,
+	})
+}
+
+func (c *OAuthController) GetClients(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(string)
+	result, err := c.OAuthUsecase.GetClients(userID)
+	if err != nil {
+		return handleError(c.Log, err, "Error while getting oauth clients")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[*[]models.OAuthClientResponse]{
+		Message: "Get OAuth clients successfully",
+		Data:    result,
+	})
+}
+
+func (c *OAuthController) RevokeClient(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(string)
+	err := c.OAuthUsecase.RevokeClient(userID, ctx.Params("id"))
+	if err != nil {
+		return handleError(c.Log, err, "Error while revoking oauth client")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[any]{
+		Message: "OAuth client revoked",
+	})
+}
+
+// Authorize returns what the consent screen has to show for the
+// authorization request in the query string.
+func (c *OAuthController) Authorize(ctx *fiber.Ctx) error {
+	request := new(models.AuthorizeRequest)
+	err := ctx.QueryParser(request)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while parsing query")
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid authorization request")
+	}
+
+	result, err := c.OAuthUsecase.Authorize(request)
+	if err != nil {
+		return handleError(c.Log, err, "Error while checking authorization request")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[*models.ConsentResponse]{
+		Message: "Ask the user to consent",
+		Data:    result,
+	})
+}
+
+// Decide records the consent of the user. The consent screen sends the
+// browser to the returned redirect_uri.
+func (c *OAuthController) Decide(ctx *fiber.Ctx) error {
+	request := new(models.AuthorizeDecisionRequest)
+	if err := parseBody(c.Log, ctx, request); err != nil {
+		return err
+	}
+
+	userID := ctx.Locals("user_id").(string)
+	methods, _ := ctx.Locals("authentication_methods").([]string)
+	result, err := c.OAuthUsecase.Decide(userID, methods, request)
+	if err != nil {
+		return handleError(c.Log, err, "Error while recording consent")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[*models.AuthorizeResponse]{
+		Message: "Redirect the user back to the application",
+		Data:    result,
+	})
+}
+
+// Token answers in the format of RFC 6749 rather than with the usual
+// response envelope, so standard OAuth client libraries can use it.
+func (c *OAuthController) Token(ctx *fiber.Ctx) error {
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+	ctx.Set(fiber.HeaderPragma, "no-cache")
+
+	request := new(models.TokenRequest)
+	err := ctx.BodyParser(request)
+	if err != nil {
+		c.Log.WithError(err).Warn("Error while parsing token request")
+		return ctx.Status(fiber.StatusBadRequest).JSON(&models.OAuthError{
+			Err:         "invalid_request",
+			Description: "Token request must be form encoded",
+		})
+	}
+
+	basic := false
+	if clientID, clientSecret, ok := basicAuth(ctx.Get(fiber.HeaderAuthorization)); ok {
+		basic = true
+		request.ClientId = clientID
+		request.ClientSecret = clientSecret
+	}
+	request.Client = models.ClientInfo{
+		UserAgent: ctx.Get(fiber.HeaderUserAgent),
+		IpAddress: ctx.IP(),
+	}
+
+	result, err := c.OAuthUsecase.Token(request)
+	if err != nil {
+		return c.oauthError(ctx, err, basic, "Error while issuing oauth token")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(result)
+}
+
+// Introspect answers in the format of RFC 7662.
+func (c *OAuthController) Introspect(ctx *fiber.Ctx) error {
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+
+	request, basic, err := c.parseTokenHintRequest(ctx)
+	if err != nil {
+		return c.oauthError(ctx, err, false, "")
+	}
+
+	result, err := c.OAuthUsecase.Introspect(request)
+	if err != nil {
+		return c.oauthError(ctx, err, basic, "Error while introspecting token")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(result)
+}
+
+// Revoke answers in the format of RFC 7009, with an empty 200 whether or not
+// the token was known.
+func (c *OAuthController) Revoke(ctx *fiber.Ctx) error {
+	request, basic, err := c.parseTokenHintRequest(ctx)
+	if err != nil {
+		return c.oauthError(ctx, err, false, "")
+	}
+
+	err = c.OAuthUsecase.Revoke(request)
+	if err != nil {
+		return c.oauthError(ctx, err, basic, "Error while revoking token")
+	}
+
+	return ctx.SendStatus(fiber.StatusOK)
+}
+
+func (c *OAuthController) parseTokenHintRequest(ctx *fiber.Ctx) (*models.TokenHintRequest, bool, error) {
+	request := new(models.TokenHintRequest)
+	err := ctx.BodyParser(request)
+	if err != nil {
+		c.Log.WithError(err).Warn("Error while parsing token request")
+		return nil, false, &models.OAuthError{
+			Code:        fiber.StatusBadRequest,
+			Err:         "invalid_request",
+			Description: "Request must be form encoded",
+		}
+	}
+
+	basic := false
+	if clientID, clientSecret, ok := basicAuth(ctx.Get(fiber.HeaderAuthorization)); ok {
+		basic = true
+		request.ClientId = clientID
+		request.ClientSecret = clientSecret
+	}
+
+	return request, basic, nil
+}
+
+// oauthError answers with the OAuthError, asking for HTTP Basic again when
+// the client authenticated with it and failed.
+func (c *OAuthController) oauthError(ctx *fiber.Ctx, err error, basic bool, message string) error {
+	e, ok := err.(*models.OAuthError)
+	if !ok {
+		c.Log.WithError(err).Error(message)
+		e = &models.OAuthError{Code: fiber.StatusInternalServerError, Err: "server_error"}
+	}
+	if e.Code == fiber.StatusUnauthorized && basic {
+		ctx.Set(fiber.HeaderWWWAuthenticate, `Basic realm="oauth"`)
+	}
+	return ctx.Status(e.Code).JSON(e)
+}
+
+// basicAuth decodes client credentials sent with HTTP Basic authentication,
+// where both parts are form encoded first (RFC 6749 section 2.3.1).
+func basicAuth(authorization string) (string, string, bool) {
+	if !strings.HasPrefix(authorization, "Basic ") {
+		return "", "", false
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(authorization, "Basic "))
+	if err != nil {
+		return "", "", false
+	}
+
+	clientID, clientSecret, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return "", "", false
+	}
+
+	clientID, err = url.QueryUnescape(clientID)
+	if err != nil {
+		return "", "", false
+	}
+	clientSecret, err = url.QueryUnescape(clientSecret)
+	if err != nil {
+		return "", "", false
+	}
+
+	return clientID, clientSecret, true
+}
@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"go-crud/internal/models"
+	"go-crud/internal/usecase"
+	"time"
+)
+
+const oidcStateCookie = "oidc_state"
+
+type OidcController struct {
+	Log         *logrus.Logger
+	OidcUsecase *usecase.OidcUsecase
+}
+
+func NewOidcController(log *logrus.Logger, oidcUsecase *usecase.OidcUsecase) *OidcController {
+	return &OidcController{
+		Log:         log,
+		OidcUsecase: oidcUsecase,
+	}
+}
+
+// Login sends the browser to the identity provider. The state cookie is
+// SameSite=Lax so it comes back with the provider's redirect.
+func (c *OidcController) Login(ctx *fiber.Ctx) error {
+	result, err := c.OidcUsecase.Login()
+	if err != nil {
+		return handleError(c.Log, err, "Error while starting oidc sign in")
+	}
+
+	ctx.Cookie(&fiber.Cookie{
+		Name:     oidcStateCookie,
+		Value:    result.StateToken,
+		Path:     "/auth/oidc",
+		Expires:  time.Now().Add(time.Duration(c.OidcUsecase.Viper.GetInt("auth.oidc.state_expiration")) * time.Second),
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+
+	return ctx.Redirect(result.AuthorizationUrl, fiber.StatusFound)
+}
+
+func (c *OidcController) Callback(ctx *fiber.Ctx) error {
+	request := new(models.OidcCallbackRequest)
+	err := ctx.QueryParser(request)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while parsing query")
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid callback request")
+	}
+
+	request.StateToken = ctx.Cookies(oidcStateCookie)
+	request.Client = models.ClientInfo{
+		UserAgent: ctx.Get(fiber.HeaderUserAgent),
+		IpAddress: ctx.IP(),
+	}
+	// The state is single use whatever the outcome.
+	ctx.Cookie(&fiber.Cookie{
+		Name:     oidcStateCookie,
+		Path:     "/auth/oidc",
+		Expires:  time.Unix(0, 0),
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+
+	result, err := c.OidcUsecase.Callback(request)
+	if err != nil {
+		return handleError(c.Log, err, "Error while finishing oidc sign in")
+	}
+
+	if result.MfaRequired {
+		return ctx.Status(fiber.StatusOK).JSON(models.Response[*models.AuthResponse]{
+			Message: "Two-factor authentication required",
+			Data:    result,
+		})
+	}
+
+	setRefreshTokenCookie(ctx, result.RefreshToken)
+
+	return ctx.Status(fiber.StatusOK).JSON(models.Response[*models.AuthResponse]{
+		Message: "Signin successfully",
+		Data:    result,
+	})
+}
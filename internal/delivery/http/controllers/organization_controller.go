@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"go-crud/internal/models"
+	"go-crud/internal/usecase"
+)
+
+type OrganizationController struct {
+	Log                 *logrus.Logger
+	OrganizationUsecase *usecase.OrganizationUsecase
+}
+
+func NewOrganizationController(log *logrus.Logger, organizationUsecase *usecase.OrganizationUsecase) *OrganizationController {
+	return &OrganizationController{
+		Log:                 log,
+		OrganizationUsecase: organizationUsecase,
+	}
+}
+
+func (c *OrganizationController) CreateOrganization(ctx *fiber.Ctx) error {
+	request := new(models.CreateOrganizationRequest)
+	err := parseBody(c.Log, ctx, request)
+	if err != nil {
+		return err
+	}
+
+	userID := ctx.Locals("user_id").(string)
+	result, err := c.OrganizationUsecase.CreateOrganization(userID, request)
+	if err != nil {
+		return handleError(c.Log, err, "Error while creating organization")
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(&models.Response[*models.OrganizationResponse]{
+		Message: "Organization created",
+		Data:    result,
+	})
+}
+
+func (c *OrganizationController) GetOrganizations(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(string)
+	result, err := c.OrganizationUsecase.GetOrganizations(userID)
+	if err != nil {
+		return handleError(c.Log, err, "Error while getting organizations")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[*[]models.OrganizationResponse]{
+		Message: "Get organizations successfully",
+		Data:    result,
+	})
+}
+
+func (c *OrganizationController) GetOrganization(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(string)
+	result, err := c.OrganizationUsecase.GetOrganization(userID, ctx.Params("id"))
+	if err != nil {
+		return handleError(c.Log, err, "Error while getting organization")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[*models.OrganizationResponse]{
+		Message: "Get organization successfully",
+		Data:    result,
+	})
+}
+
+func (c *OrganizationController) DeleteOrganization(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(string)
+	err := c.OrganizationUsecase.DeleteOrganization(userID, ctx.Params("id"))
+	if err != nil {
+		return handleError(c.Log, err, "Error while deleting organization")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[any]{
+		Message: "Organization deleted",
+	})
+}
+
+func (c *OrganizationController) GetMembers(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(string)
+	result, err := c.OrganizationUsecase.GetMembers(userID, ctx.Params("id"))
+	if err != nil {
+		return handleError(c.Log, err, "Error while getting organization members")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[*[]models.OrganizationMemberResponse]{
+		Message: "Get members successfully",
+		Data:    result,
+	})
+}
+
+func (c *OrganizationController) UpdateMember(ctx *fiber.Ctx) error {
+	request := new(models.UpdateOrganizationMemberRequest)
+	err := parseBody(c.Log, ctx, request)
+	if err != nil {
+		return err
+	}
+
+	userID := ctx.Locals("user_id").(string)
+	err = c.OrganizationUsecase.UpdateMember(userID, ctx.Params("id"), ctx.Params("userId"), request)
+	if err != nil {
+		return handleError(c.Log, err, "Error while updating organization member")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[any]{
+		Message: "Member updated",
+	})
+}
+
+func (c *OrganizationController) RemoveMember(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(string)
+	err := c.OrganizationUsecase.RemoveMember(userID, ctx.Params("id"), ctx.Params("userId"))
+	if err != nil {
+		return handleError(c.Log, err, "Error while removing organization member")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[any]{
+		Message: "Member removed",
+	})
+}
+
+func (c *OrganizationController) CreateInvitation(ctx *fiber.Ctx) error {
+	request := new(models.CreateInvitationRequest)
+	err := parseBody(c.Log, ctx, request)
+	if err != nil {
+		return err
+	}
+
+	userID := ctx.Locals("user_id").(string)
+	result, err := c.OrganizationUsecase.CreateInvitation(userID, ctx.Params("id"), request)
+	if err != nil {
+		return handleError(c.Log, err, "Error while creating invitation")
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(&models.Response[*models.InvitationResponse]{
+		Message: "Invitation sent",
+		Data:    result,
+	})
+}
+
+func (c *OrganizationController) GetInvitations(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(string)
+	result, err := c.OrganizationUsecase.GetInvitations(userID, ctx.Params("id"))
+	if err != nil {
+		return handleError(c.Log, err, "Error while getting invitations")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[*[]models.InvitationResponse]{
+		Message: "Get invitations successfully",
+		Data:    result,
+	})
+}
+
+func (c *OrganizationController) RevokeInvitation(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(string)
+	err := c.OrganizationUsecase.RevokeInvitation(userID, ctx.Params("id"), ctx.Params("invitationId"))
+	if err != nil {
+		return handleError(c.Log, err, "Error while revoking invitation")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[any]{
+		Message: "Invitation revoked",
+	})
+}
+
+func (c *OrganizationController) AcceptInvitation(ctx *fiber.Ctx) error {
+	request := new(models.AcceptInvitationRequest)
+	err := parseBody(c.Log, ctx, request)
+	if err != nil {
+		return err
+	}
+
+	userID := ctx.Locals("user_id").(string)
+	result, err := c.OrganizationUsecase.AcceptInvitation(userID, request)
+	if err != nil {
+		return handleError(c.Log, err, "Error while accepting invitation")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[*models.OrganizationResponse]{
+		Message: "Invitation accepted",
+		Data:    result,
+	})
+}
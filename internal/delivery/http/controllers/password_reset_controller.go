@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"go-crud/internal/models"
+	"go-crud/internal/usecase"
+)
+
+type PasswordResetController struct {
+	Log                  *logrus.Logger
+	PasswordResetUsecase *usecase.PasswordResetUsecase
+}
+
+func NewPasswordResetController(log *logrus.Logger, passwordResetUsecase *usecase.PasswordResetUsecase) *PasswordResetController {
+	return &PasswordResetController{
+		Log:                  log,
+		PasswordResetUsecase: passwordResetUsecase,
+	}
+}
+
+func (c *PasswordResetController) ForgotPassword(ctx *fiber.Ctx) error {
+	request := new(models.ForgotPasswordRequest)
+	if err := parseBody(c.Log, ctx, request); err != nil {
+		return err
+	}
+
+	err := c.PasswordResetUsecase.ForgotPassword(request)
+	if err != nil {
+		return handleError(c.Log, err, "Error while requesting password reset")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[any]{
+		Message: "If the email is registered, a reset link has been sent",
+	})
+}
+
+func (c *PasswordResetController) ResetPassword(ctx *fiber.Ctx) error {
+	request := new(models.ResetPasswordRequest)
+	if err := parseBody(c.Log, ctx, request); err != nil {
+		return err
+	}
+
+	request.Client = clientInfo(ctx)
+	err := c.PasswordResetUsecase.ResetPassword(request)
+	if err != nil {
+		return handleError(c.Log, err, "Error while resetting password")
+	}
+
+	ctx.ClearCookie()
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[any]{
+		Message: "Password has been reset",
+	})
+}
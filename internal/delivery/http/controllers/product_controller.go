@@ -1,7 +1,6 @@
 package controllers
 
 import (
-	"fmt"
 	"github.com/gofiber/fiber/v2"
 	"github.com/sirupsen/logrus"
 	"go-crud/internal/models"
@@ -23,25 +22,13 @@ func NewProductController(log *logrus.Logger, usecase *usecase.ProductUsecase) *
 func (c *ProductController) CreateProduct(ctx *fiber.Ctx) error {
 	var body = new(models.ProductRequest)
 	userId := ctx.Locals("user_id").(string)
-	err := ctx.BodyParser(body)
-	if err != nil {
-		c.Log.WithError(err).Error("Error while parsing body request")
-		if e, ok := err.(*fiber.UnmarshalTypeError); ok {
-			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("%s must be a string", e.Field))
-		}
-
-		return fiber.NewError(fiber.StatusInternalServerError, "Something Error")
+	if err := parseBody(c.Log, ctx, body); err != nil {
+		return err
 	}
 
 	result, err := c.ProductUsecase.CreateProduct(body, userId)
 	if err != nil {
-		c.Log.WithError(err).Error("Error while creating product")
-		if e, ok := err.(*models.ErrorResponse); ok {
-			return fiber.NewError(e.Code, e.Message)
-		}
-
-		return fiber.NewError(fiber.StatusInternalServerError, "Something Error")
-
+		return handleError(c.Log, err, "Error while creating product")
 	}
 
 	return ctx.Status(fiber.StatusCreated).JSON(&models.Response[*models.ProductResponse]{
@@ -52,25 +39,14 @@ func (c *ProductController) CreateProduct(ctx *fiber.Ctx) error {
 
 func (c *ProductController) UpdateProduct(ctx *fiber.Ctx) error {
 	request := new(models.ProductRequest)
-	err := ctx.BodyParser(request)
-	if err != nil {
-		c.Log.WithError(err).Error("Error while unmarshal request body")
-		if e, ok := err.(*fiber.UnmarshalTypeError); ok {
-			return fiber.NewError(400, fmt.Sprintf("Invalid type %s type for %s field", e.Type, e.Field))
-		}
-
-		return fiber.NewError(fiber.StatusInternalServerError, "Something Wrong")
-
+	if err := parseBody(c.Log, ctx, request); err != nil {
+		return err
 	}
 
 	productID := ctx.Params("id")
 	result, err := c.ProductUsecase.UpdateProduct(request, productID)
 	if err != nil {
-		if e, ok := err.(*models.ErrorResponse); ok {
-			return fiber.NewError(e.Code, e.Message)
-		}
-		c.Log.WithError(err).Error("Unknown error while updating product")
-		return fiber.NewError(fiber.StatusInternalServerError, "Something Wrong")
+		return handleError(c.Log, err, "Unknown error while updating product")
 	}
 
 	return ctx.Status(fiber.StatusOK).JSON(&models.Response[*models.ProductResponse]{
@@ -84,12 +60,7 @@ func (c *ProductController) DeleteProduct(ctx *fiber.Ctx) error {
 	productID := ctx.Params("id")
 	err := c.ProductUsecase.DeleteProduct(productID)
 	if err != nil {
-		if e, ok := err.(*models.ErrorResponse); ok {
-			return fiber.NewError(e.Code, e.Message)
-		}
-		c.Log.WithError(err).Error("Unknown error while deleting product")
-		return fiber.NewError(fiber.StatusInternalServerError, "Something Wrong")
-
+		return handleError(c.Log, err, "Unknown error while deleting product")
 	}
 	return ctx.Status(fiber.StatusOK).JSON(&models.Response[any]{
 		Message: "Product deleted",
@@ -101,28 +72,72 @@ func (c *ProductController) GetProducts(ctx *fiber.Ctx) error {
 	if limit > 100 {
 		return fiber.NewError(fiber.StatusBadRequest, "Max limit is 100")
 	}
+	if limit < 1 {
+		return fiber.NewError(fiber.StatusBadRequest, "Min limit is 1")
+	}
+	if _, ok := ctx.Queries()["cursor"]; ok {
+		return c.getProductsByCursor(ctx, limit)
+	}
 	page := ctx.QueryInt("page", 1)
+	if page < 1 {
+		return fiber.NewError(fiber.StatusBadRequest, "Min page is 1")
+	}
 
 	offset := (page - 1) * limit
 
-	products, err := c.ProductUsecase.GetProducts(offset, limit)
+	filter, err := c.ProductUsecase.ParseFilter(ctx.Queries())
 	if err != nil {
-		if e, ok := err.(*models.ErrorResponse); ok {
-			return fiber.NewError(e.Code, e.Message)
-		}
-		c.Log.WithError(err).Error("Error while getting products")
-		return fiber.NewError(fiber.StatusInternalServerError, "Something Error")
+		return handleError(c.Log, err, "Error while parsing products filter")
 	}
 
-	metadata, err := c.ProductUsecase.GetMetadataPagination(page, limit)
+	products, err := c.ProductUsecase.GetProducts(filter, offset, limit)
 	if err != nil {
-		if e, ok := err.(*models.ErrorResponse); ok {
-			return fiber.NewError(e.Code, e.Message)
-		}
+		return handleError(c.Log, err, "Error while getting products")
+	}
 
-		c.Log.WithError(err).Error("Error from product controller.")
-		return fiber.NewError(fiber.StatusInternalServerError, "Something Wrong")
+	metadata, err := c.ProductUsecase.GetMetadataPagination(filter, page, limit)
+	if err != nil {
+		return handleError(c.Log, err, "Error while getting products metadata")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[*[]models.ProductResponse]{
+		Message:  "Get products successfully",
+		Metadata: metadata,
+		Data:     products,
+	})
+}
 
+func (c *ProductController) SearchProducts(ctx *fiber.Ctx) error {
+	limit := ctx.QueryInt("limit", 50)
+	if limit > 100 {
+		return fiber.NewError(fiber.StatusBadRequest, "Max limit is 100")
+	}
+	if limit < 1 {
+		return fiber.NewError(fiber.StatusBadRequest, "Min limit is 1")
+	}
+	page := ctx.QueryInt("page", 1)
+	if page < 1 {
+		return fiber.NewError(fiber.StatusBadRequest, "Min page is 1")
+	}
+
+	products, metadata, err := c.ProductUsecase.SearchProducts(ctx.Query("q"), page, limit)
+	if err != nil {
+		return handleError(c.Log, err, "Error while searching products")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[*[]models.ProductSearchResponse]{
+		Message:  "Search products successfully",
+		Metadata: metadata,
+		Data:     products,
+	})
+}
+
+// getProductsByCursor lists products by cursor instead of page number, which
+// stays stable while products are added.
+func (c *ProductController) getProductsByCursor(ctx *fiber.Ctx, limit int) error {
+	products, metadata, err := c.ProductUsecase.GetProductsByCursor(ctx.Queries(), limit)
+	if err != nil {
+		return handleError(c.Log, err, "Error while getting products by cursor")
 	}
 
 	return ctx.Status(fiber.StatusOK).JSON(&models.Response[*[]models.ProductResponse]{
@@ -135,14 +150,8 @@ func (c *ProductController) GetProducts(ctx *fiber.Ctx) error {
 func (c *ProductController) GetDetail(ctx *fiber.Ctx) error {
 	productID := ctx.Params("id", "")
 	result, err := c.ProductUsecase.GetDetailProduct(productID)
-
 	if err != nil {
-		if e, ok := err.(*models.ErrorResponse); ok {
-			return fiber.NewError(e.Code, e.Message)
-		}
-
-		c.Log.WithError(err).Error("Error getting detail product")
-
+		return handleError(c.Log, err, "Error getting detail product")
 	}
 
 	return ctx.Status(fiber.StatusOK).JSON(&models.Response[*models.ProductResponse]{
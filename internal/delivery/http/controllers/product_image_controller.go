@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"go-crud/internal/models"
+	"go-crud/internal/usecase"
+)
+
+type ProductImageController struct {
+	Log                 *logrus.Logger
+	ProductImageUsecase *usecase.ProductImageUsecase
+}
+
+func NewProductImageController(log *logrus.Logger, productImageUsecase *usecase.ProductImageUsecase) *ProductImageController {
+	return &ProductImageController{
+		Log:                 log,
+		ProductImageUsecase: productImageUsecase,
+	}
+}
+
+// UploadImage takes the image from the image field of a multipart form.
+func (c *ProductImageController) UploadImage(ctx *fiber.Ctx) error {
+	header, err := ctx.FormFile("image")
+	if err != nil {
+		c.Log.WithError(err).Warn("Error while reading the uploaded image")
+		return fiber.NewError(fiber.StatusBadRequest, "image is required")
+	}
+	file, err := header.Open()
+	if err != nil {
+		return handleError(c.Log, err, "Error while opening the uploaded image")
+	}
+	defer file.Close()
+
+	result, err := c.ProductImageUsecase.UploadImage(ctx.Params("id"), file)
+	if err != nil {
+		return handleError(c.Log, err, "Error while uploading product image")
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(&models.Response[*models.ProductImageResponse]{
+		Message: "Image uploaded",
+		Data:    result,
+	})
+}
+
+func (c *ProductImageController) ReorderImages(ctx *fiber.Ctx) error {
+	request := new(models.ProductImageOrderRequest)
+	err := ctx.BodyParser(request)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while parsing body request")
+		if e, ok := err.(*fiber.UnmarshalTypeError); ok {
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("%s must be a list of strings", e.Field))
+		}
+
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	result, err := c.ProductImageUsecase.ReorderImages(ctx.Params("id"), request)
+	if err != nil {
+		return handleError(c.Log, err, "Error while ordering product images")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[*[]models.ProductImageResponse]{
+		Message: "Images ordered",
+		Data:    result,
+	})
+}
+
+func (c *ProductImageController) DeleteImage(ctx *fiber.Ctx) error {
+	err := c.ProductImageUsecase.DeleteImage(ctx.Params("id"), ctx.Params("imageId"))
+	if err != nil {
+		return handleError(c.Log, err, "Error while deleting product image")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[any]{
+		Message: "Image deleted",
+	})
+}
@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"go-crud/internal/models"
+	"go-crud/internal/usecase"
+)
+
+type SessionController struct {
+	Log            *logrus.Logger
+	SessionUsecase *usecase.SessionUsecase
+}
+
+func NewSessionController(log *logrus.Logger, sessionUsecase *usecase.SessionUsecase) *SessionController {
+	return &SessionController{
+		Log:            log,
+		SessionUsecase: sessionUsecase,
+	}
+}
+
+func (c *SessionController) GetSessions(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(string)
+	sessionID, _ := ctx.Locals("session_id").(string)
+	result, err := c.SessionUsecase.GetSessions(userID, sessionID)
+	if err != nil {
+		return handleError(c.Log, err, "Error while getting sessions")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[*[]models.SessionResponse]{
+		Message: "Get sessions successfully",
+		Data:    result,
+	})
+}
+
+func (c *SessionController) RevokeSession(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(string)
+	err := c.SessionUsecase.RevokeSession(userID, ctx.Params("id"))
+	if err != nil {
+		return handleError(c.Log, err, "Error while revoking session")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[any]{
+		Message: "Session revoked",
+	})
+}
+
+func (c *SessionController) RevokeAllSessions(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(string)
+	err := c.SessionUsecase.RevokeAllSessions(userID)
+	if err != nil {
+		return handleError(c.Log, err, "Error while revoking sessions")
+	}
+
+	ctx.ClearCookie()
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[any]{
+		Message: "Signed out from all sessions",
+	})
+}
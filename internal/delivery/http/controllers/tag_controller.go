@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"go-crud/internal/models"
+	"go-crud/internal/usecase"
+)
+
+type TagController struct {
+	Log        *logrus.Logger
+	TagUsecase *usecase.TagUsecase
+}
+
+func NewTagController(log *logrus.Logger, tagUsecase *usecase.TagUsecase) *TagController {
+	return &TagController{
+		Log:        log,
+		TagUsecase: tagUsecase,
+	}
+}
+
+func (c *TagController) GetTags(ctx *fiber.Ctx) error {
+	result, err := c.TagUsecase.GetTags()
+	if err != nil {
+		return handleError(c.Log, err, "Error while getting tags")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[*[]models.TagResponse]{
+		Message: "Get tags successfully",
+		Data:    result,
+	})
+}
+
+func (c *TagController) CreateTag(ctx *fiber.Ctx) error {
+	request := new(models.TagRequest)
+	err := parseBody(c.Log, ctx, request)
+	if err != nil {
+		return err
+	}
+
+	result, err := c.TagUsecase.CreateTag(request)
+	if err != nil {
+		return handleError(c.Log, err, "Error while creating tag")
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(&models.Response[*models.TagResponse]{
+		Message: "Tag created",
+		Data:    result,
+	})
+}
+
+func (c *TagController) UpdateTag(ctx *fiber.Ctx) error {
+	request := new(models.TagRequest)
+	err := parseBody(c.Log, ctx, request)
+	if err != nil {
+		return err
+	}
+
+	result, err := c.TagUsecase.UpdateTag(ctx.Params("id"), request)
+	if err != nil {
+		return handleError(c.Log, err, "Error while updating tag")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[*models.TagResponse]{
+		Message: "Tag updated",
+		Data:    result,
+	})
+}
+
+func (c *TagController) DeleteTag(ctx *fiber.Ctx) error {
+	err := c.TagUsecase.DeleteTag(ctx.Params("id"))
+	if err != nil {
+		return handleError(c.Log, err, "Error while deleting tag")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[any]{
+		Message: "Tag deleted",
+	})
+}
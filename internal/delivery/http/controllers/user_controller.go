@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"go-crud/internal/models"
+	"go-crud/internal/usecase"
+)
+
+type UserController struct {
+	Log         *logrus.Logger
+	UserUsecase *usecase.UserUsecase
+}
+
+func NewUserController(log *logrus.Logger, userUsecase *usecase.UserUsecase) *UserController {
+	return &UserController{
+		Log:         log,
+		UserUsecase: userUsecase,
+	}
+}
+
+func (c *UserController) GetProfile(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("user_id").(string)
+	result, err := c.UserUsecase.GetProfile(userID)
+	if err != nil {
+		return handleError(c.Log, err, "Error while getting profile")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[*models.ProfileResponse]{
+		Message: "Get profile successfully",
+		Data:    result,
+	})
+}
+
+func (c *UserController) UpdateProfile(ctx *fiber.Ctx) error {
+	request := new(models.UpdateProfileRequest)
+	if err := parseBody(c.Log, ctx, request); err != nil {
+		return err
+	}
+
+	userID := ctx.Locals("user_id").(string)
+	result, err := c.UserUsecase.UpdateProfile(userID, request)
+	if err != nil {
+		return handleError(c.Log, err, "Error while updating profile")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[*models.ProfileResponse]{
+		Message: "Profile updated",
+		Data:    result,
+	})
+}
+
+func (c *UserController) ChangePassword(ctx *fiber.Ctx) error {
+	request := new(models.ChangePasswordRequest)
+	if err := parseBody(c.Log, ctx, request); err != nil {
+		return err
+	}
+
+	userID := ctx.Locals("user_id").(string)
+	sessionID, _ := ctx.Locals("session_id").(string)
+	request.Client = clientInfo(ctx)
+	err := c.UserUsecase.ChangePassword(userID, sessionID, request)
+	if err != nil {
+		return handleError(c.Log, err, "Error while changing password")
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[any]{
+		Message: "Password changed, other sessions have been signed out",
+	})
+}
+
+func (c *UserController) DeleteAccount(ctx *fiber.Ctx) error {
+	request := new(models.DeleteAccountRequest)
+	if err := parseBody(c.Log, ctx, request); err != nil {
+		return err
+	}
+
+	userID := ctx.Locals("user_id").(string)
+	err := c.UserUsecase.DeleteAccount(userID, request)
+	if err != nil {
+		return handleError(c.Log, err, "Error while deleting account")
+	}
+
+	ctx.ClearCookie()
+	return ctx.Status(fiber.StatusOK).JSON(&models.Response[any]{
+		Message: "Account deleted",
+	})
+}
@@ -5,23 +5,44 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/sirupsen/logrus"
 	"go-crud/internal/entity"
+	"go-crud/internal/models"
 	"go-crud/internal/repository"
+	"go-crud/internal/usecase"
 	"gorm.io/gorm"
 )
 
 type ProductMiddleware struct {
 	ProductRepository repository.ProductRepositoryInterface
+	MemberRepository  repository.OrganizationMemberRepositoryInterface
+	AuditLog          *usecase.AuditLogUsecase
 	Log               *logrus.Logger
 }
 
-func NewProductMiddleware(productRepository repository.ProductRepositoryInterface, Log *logrus.Logger) *ProductMiddleware {
+func NewProductMiddleware(productRepository repository.ProductRepositoryInterface, memberRepository repository.OrganizationMemberRepositoryInterface, auditLog *usecase.AuditLogUsecase, Log *logrus.Logger) *ProductMiddleware {
 	return &ProductMiddleware{
 		ProductRepository: productRepository,
+		MemberRepository:  memberRepository,
+		AuditLog:          auditLog,
 		Log:               Log,
 	}
 }
 
+// ProductAuth only lets the owner of the product through. Products of an
+// organization are owned by its owners and admins, and by the member who
+// created them.
 func (m *ProductMiddleware) ProductAuth(ctx *fiber.Ctx) error {
+	return m.authorize(ctx, "")
+}
+
+// OwnerOrPermission lets the owner of the product through, as well as users
+// holding the permission, such as admins moderating any product.
+func (m *ProductMiddleware) OwnerOrPermission(permission string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		return m.authorize(ctx, permission)
+	}
+}
+
+func (m *ProductMiddleware) authorize(ctx *fiber.Ctx, permission string) error {
 	productID := ctx.Params("id", "")
 	userID := ctx.Locals("user_id").(string)
 	product := new(entity.Product)
@@ -35,10 +56,42 @@ func (m *ProductMiddleware) ProductAuth(ctx *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusInternalServerError, "Something Wrong")
 	}
 
-	if userID != product.UserId {
+	owner, err := m.isOwner(product, userID)
+	if err != nil {
+		return err
+	}
+
+	if !owner && (permission == "" || !HasPermission(ctx, permission)) {
+		m.AuditLog.RecordWithDetails(requestActor(ctx), entity.AuditActionAccessDenied, productID, entity.AuditOutcomeFailure, ctx.Method()+" "+ctx.OriginalURL())
 		return fiber.NewError(fiber.StatusForbidden, "You're not allowed to update/delete this resource")
 	}
 
 	return ctx.Next()
 
 }
+
+// isOwner tells whether the user owns the product. Within an organization
+// that depends on the membership role, which is read on every request so a
+// removed member loses access right away.
+func (m *ProductMiddleware) isOwner(product *entity.Product, userID string) (bool, error) {
+	if product.OrganizationId == nil {
+		return userID == product.UserId, nil
+	}
+
+	member, err := m.MemberRepository.FindOne(*product.OrganizationId, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+
+		m.Log.WithError(err).Error("Error while finding organization member")
+		return false, fiber.NewError(fiber.StatusInternalServerError, "Something Wrong")
+	}
+
+	switch member.Role {
+	case models.OrganizationRoleOwner, models.OrganizationRoleAdmin:
+		return true, nil
+	default:
+		return userID == product.UserId, nil
+	}
+}
@@ -0,0 +1,36 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go-crud/internal/delivery/http/controllers"
+	"go-crud/internal/delivery/http/middleware"
+	"go-crud/internal/models"
+)
+
+type AdminRoute struct {
+	App             *fiber.App
+	AdminController *controllers.AdminController
+	AuthMiddleware  *middleware.AuthMiddleware
+}
+
+func NewAdminRoute(app *fiber.App, adminController *controllers.AdminController, authMiddleware *middleware.AuthMiddleware) *AdminRoute {
+	return &AdminRoute{
+		App:             app,
+		AdminController: adminController,
+		AuthMiddleware:  authMiddleware,
+	}
+}
+
+func (r *AdminRoute) Setup() {
+	canRead := r.AuthMiddleware.RequirePermission(models.PermissionUserReadAny)
+	canUpdate := r.AuthMiddleware.RequirePermission(models.PermissionUserUpdateAny)
+	canImpersonate := r.AuthMiddleware.RequirePermission(models.PermissionUserImpersonateAny)
+
+	r.App.Get("/admin/users", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, canRead, r.AdminController.GetUsers)
+	r.App.Get("/admin/users/:id", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, canRead, r.AdminController.GetUser)
+	r.App.Post("/admin/users/:id/disable", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.AuthMiddleware.RequireMfa, canUpdate, r.AdminController.DisableUser)
+	r.App.Post("/admin/users/:id/enable", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.AuthMiddleware.RequireMfa, canUpdate, r.AdminController.EnableUser)
+	r.App.Post("/admin/users/:id/logout", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.AuthMiddleware.RequireMfa, canUpdate, r.AdminController.LogoutUser)
+	r.App.Post("/admin/users/:id/password-reset", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.AuthMiddleware.RequireMfa, canUpdate, r.AdminController.SendPasswordReset)
+	r.App.Post("/admin/users/:id/impersonate", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.AuthMiddleware.RequireMfa, canImpersonate, r.AdminController.Impersonate)
+}
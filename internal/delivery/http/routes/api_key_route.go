@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go-crud/internal/delivery/http/controllers"
+	"go-crud/internal/delivery/http/middleware"
+)
+
+type ApiKeyRoute struct {
+	App              *fiber.App
+	ApiKeyController *controllers.ApiKeyController
+	AuthMiddleware   *middleware.AuthMiddleware
+}
+
+func NewApiKeyRoute(app *fiber.App, apiKeyController *controllers.ApiKeyController, authMiddleware *middleware.AuthMiddleware) *ApiKeyRoute {
+	return &ApiKeyRoute{
+		App:              app,
+		ApiKeyController: apiKeyController,
+		AuthMiddleware:   authMiddleware,
+	}
+}
+
+func (r *ApiKeyRoute) Setup() {
+	r.App.Post("/auth/api-keys", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.AuthMiddleware.RequireMfa, r.ApiKeyController.CreateApiKey)
+	r.App.Get("/auth/api-keys", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.ApiKeyController.GetApiKeys)
+	r.App.Delete("/auth/api-keys/:id", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.ApiKeyController.RevokeApiKey)
+}
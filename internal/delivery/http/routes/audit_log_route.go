@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go-crud/internal/delivery/http/controllers"
+	"go-crud/internal/delivery/http/middleware"
+	"go-crud/internal/models"
+)
+
+type AuditLogRoute struct {
+	App                *fiber.App
+	AuditLogController *controllers.AuditLogController
+	AuthMiddleware     *middleware.AuthMiddleware
+}
+
+func NewAuditLogRoute(app *fiber.App, auditLogController *controllers.AuditLogController, authMiddleware *middleware.AuthMiddleware) *AuditLogRoute {
+	return &AuditLogRoute{
+		App:                app,
+		AuditLogController: auditLogController,
+		AuthMiddleware:     authMiddleware,
+	}
+}
+
+func (r *AuditLogRoute) Setup() {
+	canRead := r.AuthMiddleware.RequirePermission(models.PermissionAuditLogReadAny)
+
+	r.App.Get("/admin/audit-logs", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, canRead, r.AuditLogController.GetAuditLogs)
+}
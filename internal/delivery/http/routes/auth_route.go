@@ -21,4 +21,5 @@ func (r *AuthRoute) Setup() {
 	r.App.Post("/auth/signin", r.AuthController.SignIn)
 	r.App.Get("/signout", r.AuthController.SignOut)
 	r.App.Get("/auth/token", r.AuthController.RefreshToken)
+	r.App.Get("/.well-known/jwks.json", r.AuthController.GetJWKS)
 }
@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go-crud/internal/delivery/http/controllers"
+	"go-crud/internal/delivery/http/middleware"
+	"go-crud/internal/models"
+)
+
+type CategoryRoute struct {
+	App                *fiber.App
+	CategoryController *controllers.CategoryController
+	AuthMiddleware     *middleware.AuthMiddleware
+}
+
+func NewCategoryRoute(app *fiber.App, categoryController *controllers.CategoryController, authMiddleware *middleware.AuthMiddleware) *CategoryRoute {
+	return &CategoryRoute{
+		App:                app,
+		CategoryController: categoryController,
+		AuthMiddleware:     authMiddleware,
+	}
+}
+
+func (r *CategoryRoute) Setup() {
+	canRead := r.AuthMiddleware.RequireScope(models.ScopeProductsRead)
+	canWrite := r.AuthMiddleware.RequirePermission(models.PermissionCategoryWriteAny)
+
+	r.App.Get("/categories", r.AuthMiddleware.Auth, canRead, r.CategoryController.GetCategories)
+	r.App.Get("/categories/:slug", r.AuthMiddleware.Auth, canRead, r.CategoryController.GetCategory)
+	r.App.Post("/categories", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, canWrite, r.CategoryController.CreateCategory)
+	r.App.Put("/categories/:id", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, canWrite, r.CategoryController.UpdateCategory)
+	r.App.Delete("/categories/:id", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, canWrite, r.CategoryController.DeleteCategory)
+}
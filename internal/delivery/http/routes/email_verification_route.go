@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go-crud/internal/delivery/http/controllers"
+)
+
+type EmailVerificationRoute struct {
+	App                         *fiber.App
+	EmailVerificationController *controllers.EmailVerificationController
+}
+
+func NewEmailVerificationRoute(app *fiber.App, emailVerificationController *controllers.EmailVerificationController) *EmailVerificationRoute {
+	return &EmailVerificationRoute{
+		App:                         app,
+		EmailVerificationController: emailVerificationController,
+	}
+}
+
+func (r *EmailVerificationRoute) Setup() {
+	r.App.Post("/auth/verify-email", r.EmailVerificationController.VerifyEmail)
+	r.App.Post("/auth/verify-email/resend", r.EmailVerificationController.ResendVerification)
+	r.App.Post("/auth/verify-email/change", r.EmailVerificationController.ConfirmEmailChange)
+}
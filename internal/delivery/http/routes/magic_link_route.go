@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go-crud/internal/delivery/http/controllers"
+)
+
+type MagicLinkRoute struct {
+	App                 *fiber.App
+	MagicLinkController *controllers.MagicLinkController
+}
+
+func NewMagicLinkRoute(app *fiber.App, magicLinkController *controllers.MagicLinkController) *MagicLinkRoute {
+	return &MagicLinkRoute{
+		App:                 app,
+		MagicLinkController: magicLinkController,
+	}
+}
+
+func (r *MagicLinkRoute) Setup() {
+	r.App.Post("/auth/magic-link", r.MagicLinkController.RequestLink)
+	r.App.Post("/auth/magic-link/signin", r.MagicLinkController.SignIn)
+}
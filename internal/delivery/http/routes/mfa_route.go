@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go-crud/internal/delivery/http/controllers"
+	"go-crud/internal/delivery/http/middleware"
+)
+
+type MfaRoute struct {
+	App            *fiber.App
+	MfaController  *controllers.MfaController
+	AuthMiddleware *middleware.AuthMiddleware
+}
+
+func NewMfaRoute(app *fiber.App, mfaController *controllers.MfaController, authMiddleware *middleware.AuthMiddleware) *MfaRoute {
+	return &MfaRoute{
+		App:            app,
+		MfaController:  mfaController,
+		AuthMiddleware: authMiddleware,
+	}
+}
+
+func (r *MfaRoute) Setup() {
+	r.App.Post("/auth/mfa/verify", r.MfaController.Verify)
+	r.App.Post("/auth/mfa/totp/enroll", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.MfaController.Enroll)
+	r.App.Post("/auth/mfa/totp/confirm", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.MfaController.Confirm)
+	r.App.Post("/auth/mfa/totp/disable", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.MfaController.Disable)
+	r.App.Post("/auth/mfa/recovery-codes", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.MfaController.RegenerateRecoveryCodes)
+}
@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go-crud/internal/delivery/http/controllers"
+	"go-crud/internal/delivery/http/middleware"
+)
+
+type OAuthRoute struct {
+	App             *fiber.App
+	OAuthController *controllers.OAuthController
+	AuthMiddleware  *middleware.AuthMiddleware
+}
+
+func NewOAuthRoute(app *fiber.App, oauthController *controllers.OAuthController, authMiddleware *middleware.AuthMiddleware) *OAuthRoute {
+	return &OAuthRoute{
+		App:             app,
+		OAuthController: oauthController,
+		AuthMiddleware:  authMiddleware,
+	}
+}
+
+func (r *OAuthRoute) Setup() {
+	r.App.Post("/oauth/clients", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.AuthMiddleware.RequireMfa, r.OAuthController.CreateClient)
+	r.App.Get("/oauth/clients", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.OAuthController.GetClients)
+	r.App.Delete("/oauth/clients/:id", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.OAuthController.RevokeClient)
+	r.App.Get("/oauth/authorize", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.OAuthController.Authorize)
+	r.App.Post("/oauth/authorize", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.OAuthController.Decide)
+	r.App.Post("/oauth/token", r.OAuthController.Token)
+	r.App.Post("/auth/introspect", r.OAuthController.Introspect)
+	r.App.Post("/auth/revoke", r.OAuthController.Revoke)
+}
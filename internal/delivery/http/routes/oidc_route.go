@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go-crud/internal/delivery/http/controllers"
+)
+
+type OidcRoute struct {
+	App            *fiber.App
+	OidcController *controllers.OidcController
+}
+
+func NewOidcRoute(app *fiber.App, oidcController *controllers.OidcController) *OidcRoute {
+	return &OidcRoute{
+		App:            app,
+		OidcController: oidcController,
+	}
+}
+
+func (r *OidcRoute) Setup() {
+	r.App.Get("/auth/oidc/login", r.OidcController.Login)
+	r.App.Get("/auth/oidc/callback", r.OidcController.Callback)
+}
@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go-crud/internal/delivery/http/controllers"
+	"go-crud/internal/delivery/http/middleware"
+)
+
+type OrganizationRoute struct {
+	App                    *fiber.App
+	OrganizationController *controllers.OrganizationController
+	AuthMiddleware         *middleware.AuthMiddleware
+}
+
+func NewOrganizationRoute(app *fiber.App, organizationController *controllers.OrganizationController, authMiddleware *middleware.AuthMiddleware) *OrganizationRoute {
+	return &OrganizationRoute{
+		App:                    app,
+		OrganizationController: organizationController,
+		AuthMiddleware:         authMiddleware,
+	}
+}
+
+func (r *OrganizationRoute) Setup() {
+	r.App.Post("/organizations", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.OrganizationController.CreateOrganization)
+	r.App.Get("/organizations", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.OrganizationController.GetOrganizations)
+	r.App.Post("/organizations/invitations/accept", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.OrganizationController.AcceptInvitation)
+	r.App.Get("/organizations/:id", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.OrganizationController.GetOrganization)
+	r.App.Delete("/organizations/:id", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.AuthMiddleware.RequireMfa, r.OrganizationController.DeleteOrganization)
+	r.App.Get("/organizations/:id/members", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.OrganizationController.GetMembers)
+	r.App.Patch("/organizations/:id/members/:userId", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.OrganizationController.UpdateMember)
+	r.App.Delete("/organizations/:id/members/:userId", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.OrganizationController.RemoveMember)
+	r.App.Post("/organizations/:id/invitations", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.OrganizationController.CreateInvitation)
+	r.App.Get("/organizations/:id/invitations", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.OrganizationController.GetInvitations)
+	r.App.Delete("/organizations/:id/invitations/:invitationId", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.OrganizationController.RevokeInvitation)
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go-crud/internal/delivery/http/controllers"
+)
+
+type PasswordResetRoute struct {
+	App                     *fiber.App
+	PasswordResetController *controllers.PasswordResetController
+}
+
+func NewPasswordResetRoute(app *fiber.App, passwordResetController *controllers.PasswordResetController) *PasswordResetRoute {
+	return &PasswordResetRoute{
+		App:                     app,
+		PasswordResetController: passwordResetController,
+	}
+}
+
+func (r *PasswordResetRoute) Setup() {
+	r.App.Post("/auth/password/forgot", r.PasswordResetController.ForgotPassword)
+	r.App.Post("/auth/password/reset", r.PasswordResetController.ResetPassword)
+}
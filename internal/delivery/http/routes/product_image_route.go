@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go-crud/internal/delivery/http/controllers"
+	"go-crud/internal/delivery/http/middleware"
+	"go-crud/internal/models"
+)
+
+type ProductImageRoute struct {
+	App                    *fiber.App
+	ProductImageController *controllers.ProductImageController
+	AuthMiddleware         *middleware.AuthMiddleware
+	ProductMiddleware      *middleware.ProductMiddleware
+}
+
+func NewProductImageRoute(app *fiber.App, productImageController *controllers.ProductImageController, authMiddleware *middleware.AuthMiddleware, productMiddleware *middleware.ProductMiddleware) *ProductImageRoute {
+	return &ProductImageRoute{
+		App:                    app,
+		ProductImageController: productImageController,
+		AuthMiddleware:         authMiddleware,
+		ProductMiddleware:      productMiddleware,
+	}
+}
+
+func (r *ProductImageRoute) Setup() {
+	canWrite := r.AuthMiddleware.RequireScope(models.ScopeProductsWrite)
+
+	r.App.Post("/products/:id/images", r.AuthMiddleware.Auth, canWrite, r.AuthMiddleware.RequireMfa, r.ProductMiddleware.ProductAuth, r.ProductImageController.UploadImage)
+	r.App.Put("/products/:id/images/order", r.AuthMiddleware.Auth, canWrite, r.AuthMiddleware.RequireMfa, r.ProductMiddleware.ProductAuth, r.ProductImageController.ReorderImages)
+	r.App.Delete("/products/:id/images/:imageId", r.AuthMiddleware.Auth, canWrite, r.AuthMiddleware.RequireMfa, r.ProductMiddleware.ProductAuth, r.ProductImageController.DeleteImage)
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"go-crud/internal/delivery/http/controllers"
 	"go-crud/internal/delivery/http/middleware"
+	"go-crud/internal/models"
 )
 
 type ProductRoute struct {
@@ -23,9 +24,13 @@ func NewProductRoute(app *fiber.App, productController *controllers.ProductContr
 }
 
 func (r *ProductRoute) Setup() {
-	r.App.Post("/products", r.AuthMiddleware.Auth, r.ProductController.CreateProduct)
-	r.App.Put("/products/:id", r.AuthMiddleware.Auth, r.ProductMiddleware.ProductAuth, r.ProductController.UpdateProduct)
-	r.App.Delete("/products/:id", r.AuthMiddleware.Auth, r.ProductMiddleware.ProductAuth, r.ProductController.DeleteProduct)
-	r.App.Get("/product/:id", r.AuthMiddleware.Auth, r.ProductController.GetDetail)
-	r.App.Get("/products", r.AuthMiddleware.Auth, r.ProductController.GetProducts)
+	canRead := r.AuthMiddleware.RequireScope(models.ScopeProductsRead)
+	canWrite := r.AuthMiddleware.RequireScope(models.ScopeProductsWrite)
+
+	r.App.Post("/products", r.AuthMiddleware.Auth, canWrite, r.AuthMiddleware.RequireMfa, r.ProductController.CreateProduct)
+	r.App.Put("/products/:id", r.AuthMiddleware.Auth, canWrite, r.AuthMiddleware.RequireMfa, r.ProductMiddleware.OwnerOrPermission(models.PermissionProductUpdateAny), r.ProductController.UpdateProduct)
+	r.App.Delete("/products/:id", r.AuthMiddleware.Auth, canWrite, r.AuthMiddleware.RequireMfa, r.ProductMiddleware.OwnerOrPermission(models.PermissionProductDeleteAny), r.ProductController.DeleteProduct)
+	r.App.Get("/product/:id", r.AuthMiddleware.Auth, canRead, r.ProductController.GetDetail)
+	r.App.Get("/products", r.AuthMiddleware.Auth, canRead, r.ProductController.GetProducts)
+	r.App.Get("/products/search", r.AuthMiddleware.Auth, canRead, r.ProductController.SearchProducts)
 }
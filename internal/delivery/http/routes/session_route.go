@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go-crud/internal/delivery/http/controllers"
+	"go-crud/internal/delivery/http/middleware"
+)
+
+type SessionRoute struct {
+	App               *fiber.App
+	SessionController *controllers.SessionController
+	AuthMiddleware    *middleware.AuthMiddleware
+}
+
+func NewSessionRoute(app *fiber.App, sessionController *controllers.SessionController, authMiddleware *middleware.AuthMiddleware) *SessionRoute {
+	return &SessionRoute{
+		App:               app,
+		SessionController: sessionController,
+		AuthMiddleware:    authMiddleware,
+	}
+}
+
+func (r *SessionRoute) Setup() {
+	r.App.Get("/auth/sessions", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.SessionController.GetSessions)
+	r.App.Delete("/auth/sessions/:id", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.SessionController.RevokeSession)
+	r.App.Delete("/auth/sessions", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.SessionController.RevokeAllSessions)
+}
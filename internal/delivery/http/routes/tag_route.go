@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go-crud/internal/delivery/http/controllers"
+	"go-crud/internal/delivery/http/middleware"
+	"go-crud/internal/models"
+)
+
+type TagRoute struct {
+	App            *fiber.App
+	TagController  *controllers.TagController
+	AuthMiddleware *middleware.AuthMiddleware
+}
+
+func NewTagRoute(app *fiber.App, tagController *controllers.TagController, authMiddleware *middleware.AuthMiddleware) *TagRoute {
+	return &TagRoute{
+		App:            app,
+		TagController:  tagController,
+		AuthMiddleware: authMiddleware,
+	}
+}
+
+// Setup lets anyone writing products create the tags they need. Renaming and
+// deleting a tag changes every product using it, so it is left to admins.
+func (r *TagRoute) Setup() {
+	canRead := r.AuthMiddleware.RequireScope(models.ScopeProductsRead)
+	canWrite := r.AuthMiddleware.RequireScope(models.ScopeProductsWrite)
+	canManage := r.AuthMiddleware.RequirePermission(models.PermissionTagWriteAny)
+
+	r.App.Get("/tags", r.AuthMiddleware.Auth, canRead, r.TagController.GetTags)
+	r.App.Post("/tags", r.AuthMiddleware.Auth, canWrite, r.TagController.CreateTag)
+	r.App.Put("/tags/:id", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, canManage, r.TagController.UpdateTag)
+	r.App.Delete("/tags/:id", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, canManage, r.TagController.DeleteTag)
+}
@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go-crud/internal/delivery/http/controllers"
+	"go-crud/internal/delivery/http/middleware"
+)
+
+type UserRoute struct {
+	App            *fiber.App
+	UserController *controllers.UserController
+	AuthMiddleware *middleware.AuthMiddleware
+}
+
+func NewUserRoute(app *fiber.App, userController *controllers.UserController, authMiddleware *middleware.AuthMiddleware) *UserRoute {
+	return &UserRoute{
+		App:            app,
+		UserController: userController,
+		AuthMiddleware: authMiddleware,
+	}
+}
+
+func (r *UserRoute) Setup() {
+	r.App.Get("/me", r.AuthMiddleware.Auth, r.UserController.GetProfile)
+	r.App.Patch("/me", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.UserController.UpdateProfile)
+	r.App.Post("/me/password", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.UserController.ChangePassword)
+	r.App.Delete("/me", r.AuthMiddleware.Auth, r.AuthMiddleware.RequireSession, r.UserController.DeleteAccount)
+}
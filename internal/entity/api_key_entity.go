@@ -0,0 +1,20 @@
+package entity
+
+import "time"
+
+type ApiKey struct {
+	Id         string     `gorm:"column:id;primaryKey"`
+	UserId     string     `gorm:"column:user_id"`
+	Name       string     `gorm:"column:name"`
+	Prefix     string     `gorm:"column:prefix"`
+	KeyHash    string     `gorm:"column:key_hash"`
+	Scopes     string     `gorm:"column:scopes"`
+	ExpiresAt  *time.Time `gorm:"column:expires_at"`
+	LastUsedAt *time.Time `gorm:"column:last_used_at"`
+	CreatedAt  time.Time  `gorm:"column:created_at"`
+	RevokedAt  *time.Time `gorm:"column:revoked_at"`
+}
+
+func (k *ApiKey) TableName() string {
+	return "api_keys"
+}
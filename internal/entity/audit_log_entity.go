@@ -0,0 +1,48 @@
+package entity
+
+import "time"
+
+const (
+	AuditActionAdminUserList          = "admin.user.list"
+	AuditActionAdminUserView          = "admin.user.view"
+	AuditActionAdminUserDisable       = "admin.user.disable"
+	AuditActionAdminUserEnable        = "admin.user.enable"
+	AuditActionAdminUserLogout        = "admin.user.logout"
+	AuditActionAdminUserPasswordReset = "admin.user.password_reset"
+	AuditActionAdminUserImpersonate   = "admin.user.impersonate"
+	AuditActionImpersonatedRequest    = "impersonation.request"
+	AuditActionAuditLogList           = "admin.audit_log.list"
+)
+
+const (
+	AuditActionSignIn         = "auth.sign_in"
+	AuditActionRefresh        = "auth.refresh"
+	AuditActionRefreshReuse   = "auth.refresh_reuse"
+	AuditActionSignOut        = "auth.sign_out"
+	AuditActionPasswordChange = "auth.password_change"
+	AuditActionPasswordReset  = "auth.password_reset"
+	AuditActionAccessDenied   = "access.denied"
+)
+
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+// AuditLog is an entry of the append-only audit trail. Entries aren't tied
+// to the users table, so they outlive the accounts they mention.
+type AuditLog struct {
+	Id        string    `gorm:"column:id;primaryKey"`
+	ActorId   *string   `gorm:"column:actor_id"`
+	Action    string    `gorm:"column:action"`
+	TargetId  *string   `gorm:"column:target_id"`
+	Outcome   string    `gorm:"column:outcome"`
+	Details   string    `gorm:"column:details"`
+	IpAddress string    `gorm:"column:ip_address"`
+	UserAgent string    `gorm:"column:user_agent"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+}
+
+func (a *AuditLog) TableName() string {
+	return "audit_logs"
+}
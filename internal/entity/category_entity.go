@@ -0,0 +1,20 @@
+package entity
+
+import "time"
+
+// Category is a node of the catalog tree. Path lists the slugs from the root
+// down to the category, like clothing/shoes, so a whole subtree is found with
+// a prefix match.
+type Category struct {
+	Id        string    `gorm:"column:id;primaryKey"`
+	ParentId  *string   `gorm:"column:parent_id"`
+	Name      string    `gorm:"column:name"`
+	Slug      string    `gorm:"column:slug"`
+	Path      string    `gorm:"column:path"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at"`
+}
+
+func (c *Category) TableName() string {
+	return "categories"
+}
@@ -0,0 +1,16 @@
+package entity
+
+import "time"
+
+// LoginAttempt counts recent failed sign ins for one key, such as an email
+// or an IP address.
+type LoginAttempt struct {
+	Key          string     `gorm:"column:attempt_key;primaryKey"`
+	Failures     int        `gorm:"column:failures"`
+	LastFailedAt time.Time  `gorm:"column:last_failed_at"`
+	LockedUntil  *time.Time `gorm:"column:locked_until"`
+}
+
+func (a *LoginAttempt) TableName() string {
+	return "login_attempts"
+}
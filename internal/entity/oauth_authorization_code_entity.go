@@ -0,0 +1,23 @@
+package entity
+
+import "time"
+
+// OAuthAuthorizationCode is handed to a client after the user consents and
+// exchanged once for tokens. Only the hash of the code is stored.
+type OAuthAuthorizationCode struct {
+	Id            string     `gorm:"column:id;primaryKey"`
+	CodeHash      string     `gorm:"column:code_hash"`
+	ClientId      string     `gorm:"column:client_id"`
+	UserId        string     `gorm:"column:user_id"`
+	RedirectUri   string     `gorm:"column:redirect_uri"`
+	Scopes        string     `gorm:"column:scopes"`
+	Methods       string     `gorm:"column:authentication_methods"`
+	CodeChallenge string     `gorm:"column:code_challenge"`
+	ExpiresAt     time.Time  `gorm:"column:expires_at"`
+	UsedAt        *time.Time `gorm:"column:used_at"`
+	CreatedAt     time.Time  `gorm:"column:created_at"`
+}
+
+func (c *OAuthAuthorizationCode) TableName() string {
+	return "oauth_authorization_codes"
+}
@@ -0,0 +1,24 @@
+package entity
+
+import "time"
+
+// OAuthClient is a third-party application registered by a user. Public
+// clients, such as mobile apps, have no secret and rely on PKCE alone.
+type OAuthClient struct {
+	Id           string     `gorm:"column:id;primaryKey"`
+	UserId       string     `gorm:"column:user_id"`
+	Name         string     `gorm:"column:name"`
+	SecretHash   string     `gorm:"column:secret_hash"`
+	RedirectUris string     `gorm:"column:redirect_uris"`
+	Scopes       string     `gorm:"column:scopes"`
+	CreatedAt    time.Time  `gorm:"column:created_at"`
+	RevokedAt    *time.Time `gorm:"column:revoked_at"`
+}
+
+func (c *OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+func (c *OAuthClient) Confidential() bool {
+	return c.SecretHash != ""
+}
@@ -0,0 +1,46 @@
+package entity
+
+import "time"
+
+type Organization struct {
+	Id        string    `gorm:"column:id;primaryKey"`
+	Name      string    `gorm:"column:name"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+}
+
+func (o *Organization) TableName() string {
+	return "organizations"
+}
+
+// OrganizationMember gives a user a role in an organization: owner, admin or
+// member.
+type OrganizationMember struct {
+	OrganizationId string       `gorm:"column:organization_id;primaryKey"`
+	UserId         string       `gorm:"column:user_id;primaryKey"`
+	Role           string       `gorm:"column:role"`
+	CreatedAt      time.Time    `gorm:"column:created_at"`
+	Organization   Organization `gorm:"foreignKey:organization_id;references:id"`
+	User           User         `gorm:"foreignKey:user_id;references:id"`
+}
+
+func (m *OrganizationMember) TableName() string {
+	return "organization_members"
+}
+
+// OrganizationInvitation is mailed to an email address. Only the hash of its
+// token is stored, and the user accepting it must own the email.
+type OrganizationInvitation struct {
+	Id             string     `gorm:"column:id;primaryKey"`
+	OrganizationId string     `gorm:"column:organization_id"`
+	Email          string     `gorm:"column:email"`
+	Role           string     `gorm:"column:role"`
+	TokenHash      string     `gorm:"column:token_hash"`
+	InvitedBy      *string    `gorm:"column:invited_by"`
+	ExpiresAt      time.Time  `gorm:"column:expires_at"`
+	AcceptedAt     *time.Time `gorm:"column:accepted_at"`
+	CreatedAt      time.Time  `gorm:"column:created_at"`
+}
+
+func (i *OrganizationInvitation) TableName() string {
+	return "organization_invitations"
+}
@@ -1,5 +1,7 @@
 package entity
 
+import "time"
+
 type Product struct {
 	Id     string `gorm:"column:id;primaryKey"`
 	Name   string `gorm:"column:name"`
@@ -7,6 +9,14 @@ type Product struct {
 	Stock  int    `gorm:"column:stock"`
 	UserId string `gorm:"column:user_id;"`
 	User   User   `gorm:"foreignKey:user_id;references:id"`
+	// OrganizationId is set on products owned by an organization. UserId is
+	// then only who created it, and is cleared when that user is deleted.
+	OrganizationId *string        `gorm:"column:organization_id"`
+	CategoryId     *string        `gorm:"column:category_id"`
+	Category       *Category      `gorm:"foreignKey:category_id;references:id"`
+	Tags           []Tag          `gorm:"many2many:product_tags;joinForeignKey:product_id;joinReferences:tag_id"`
+	Images         []ProductImage `gorm:"foreignKey:product_id;references:id"`
+	CreatedAt      time.Time      `gorm:"column:created_at"`
 }
 
 func (p *Product) TableName() string {
@@ -0,0 +1,22 @@
+package entity
+
+import "time"
+
+// ProductImage is an image of a product. The first image by Position is the
+// primary one.
+type ProductImage struct {
+	Id          string `gorm:"column:id;primaryKey"`
+	ProductId   string `gorm:"column:product_id"`
+	Position    int    `gorm:"column:position"`
+	ContentType string `gorm:"column:content_type"`
+	Width       int    `gorm:"column:width"`
+	Height      int    `gorm:"column:height"`
+	Size        int    `gorm:"column:size"`
+	// Variants maps original and every thumbnail size to its storage key.
+	Variants  map[string]string `gorm:"column:variants;serializer:json"`
+	CreatedAt time.Time         `gorm:"column:created_at"`
+}
+
+func (i *ProductImage) TableName() string {
+	return "product_images"
+}
@@ -0,0 +1,15 @@
+package entity
+
+import "time"
+
+type RecoveryCode struct {
+	Id        string     `gorm:"column:id;primaryKey"`
+	UserId    string     `gorm:"column:user_id"`
+	CodeHash  string     `gorm:"column:code_hash"`
+	UsedAt    *time.Time `gorm:"column:used_at"`
+	CreatedAt time.Time  `gorm:"column:created_at"`
+}
+
+func (c *RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
@@ -0,0 +1,18 @@
+package entity
+
+import "time"
+
+type RefreshToken struct {
+	Id        string     `gorm:"column:id;primaryKey"`
+	UserId    string     `gorm:"column:user_id"`
+	FamilyId  string     `gorm:"column:family_id"`
+	TokenHash string     `gorm:"column:token_hash"`
+	ExpiresAt time.Time  `gorm:"column:expires_at"`
+	UsedAt    *time.Time `gorm:"column:used_at"`
+	RevokedAt *time.Time `gorm:"column:revoked_at"`
+	CreatedAt time.Time  `gorm:"column:created_at"`
+}
+
+func (r *RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
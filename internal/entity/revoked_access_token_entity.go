@@ -0,0 +1,15 @@
+package entity
+
+import "time"
+
+// RevokedAccessToken is an entry of the access token deny-list. It only has
+// to outlive the token, so it can be dropped once ExpiresAt has passed.
+type RevokedAccessToken struct {
+	Jti       string    `gorm:"column:jti;primaryKey"`
+	ExpiresAt time.Time `gorm:"column:expires_at"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+}
+
+func (r *RevokedAccessToken) TableName() string {
+	return "revoked_access_tokens"
+}
@@ -0,0 +1,24 @@
+package entity
+
+import "time"
+
+type Role struct {
+	Id          string       `gorm:"column:id;primaryKey"`
+	Name        string       `gorm:"column:name"`
+	CreatedAt   time.Time    `gorm:"column:created_at"`
+	Permissions []Permission `gorm:"many2many:role_permissions"`
+}
+
+func (r *Role) TableName() string {
+	return "roles"
+}
+
+type Permission struct {
+	Id        string    `gorm:"column:id;primaryKey"`
+	Name      string    `gorm:"column:name"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+}
+
+func (p *Permission) TableName() string {
+	return "permissions"
+}
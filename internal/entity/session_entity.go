@@ -0,0 +1,22 @@
+package entity
+
+import "time"
+
+// Session is a signed in device. A session with a ClientId is a grant to a
+// third-party application, which may only act within its Scopes.
+type Session struct {
+	Id         string     `gorm:"column:id;primaryKey"`
+	UserId     string     `gorm:"column:user_id"`
+	ClientId   *string    `gorm:"column:client_id"`
+	Scopes     string     `gorm:"column:scopes"`
+	UserAgent  string     `gorm:"column:user_agent"`
+	IpAddress  string     `gorm:"column:ip_address"`
+	Methods    string     `gorm:"column:authentication_methods"`
+	CreatedAt  time.Time  `gorm:"column:created_at"`
+	LastSeenAt time.Time  `gorm:"column:last_seen_at"`
+	RevokedAt  *time.Time `gorm:"column:revoked_at"`
+}
+
+func (s *Session) TableName() string {
+	return "sessions"
+}
@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+type Tag struct {
+	Id        string    `gorm:"column:id;primaryKey"`
+	Name      string    `gorm:"column:name"`
+	Slug      string    `gorm:"column:slug"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+}
+
+func (t *Tag) TableName() string {
+	return "tags"
+}
@@ -3,11 +3,18 @@ package entity
 import "time"
 
 type User struct {
-	Id        string    `gorm:"column:id;primaryKey"`
-	Name      string    `gorm:"column:name"`
-	Email     string    `gorm:"column:email"`
-	Password  string    `gorm:"column:password"`
-	CreatedAt time.Time `gorm:"column:created_at"`
-	UpdatedAt time.Time `gorm:"column:updated_at"`
-	Product   []Product `gorm:"foreignKey:user_id;references:id"`
+	Id              string     `gorm:"column:id;primaryKey"`
+	Name            string     `gorm:"column:name"`
+	Email           string     `gorm:"column:email"`
+	Password        string     `gorm:"column:password"`
+	EmailVerifiedAt *time.Time `gorm:"column:email_verified_at"`
+	PendingEmail    *string    `gorm:"column:pending_email"`
+	TotpSecret      string     `gorm:"column:totp_secret"`
+	TotpEnabledAt   *time.Time `gorm:"column:totp_enabled_at"`
+	TotpLastStep    int64      `gorm:"column:totp_last_step"`
+	DisabledAt      *time.Time `gorm:"column:disabled_at"`
+	CreatedAt       time.Time  `gorm:"column:created_at"`
+	UpdatedAt       time.Time  `gorm:"column:updated_at"`
+	Product         []Product  `gorm:"foreignKey:user_id;references:id"`
+	Roles           []Role     `gorm:"many2many:user_roles"`
 }
@@ -0,0 +1,17 @@
+package entity
+
+import "time"
+
+// UserIdentity links a user to the subject an external identity provider
+// knows them by. The subject is stable where the email may change.
+type UserIdentity struct {
+	Id        string    `gorm:"column:id;primaryKey"`
+	UserId    string    `gorm:"column:user_id"`
+	Issuer    string    `gorm:"column:issuer"`
+	Subject   string    `gorm:"column:subject"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+}
+
+func (i *UserIdentity) TableName() string {
+	return "user_identities"
+}
@@ -0,0 +1,26 @@
+package entity
+
+import "time"
+
+const (
+	UserTokenPasswordReset     = "password_reset"
+	UserTokenEmailVerification = "email_verification"
+	UserTokenMagicLink         = "magic_link"
+	UserTokenEmailChange       = "email_change"
+)
+
+// UserToken is a single-use secret mailed to a user, such as a password reset
+// link. Only the hash of the secret is stored.
+type UserToken struct {
+	Id        string     `gorm:"column:id;primaryKey"`
+	UserId    string     `gorm:"column:user_id"`
+	Purpose   string     `gorm:"column:purpose"`
+	TokenHash string     `gorm:"column:token_hash"`
+	ExpiresAt time.Time  `gorm:"column:expires_at"`
+	UsedAt    *time.Time `gorm:"column:used_at"`
+	CreatedAt time.Time  `gorm:"column:created_at"`
+}
+
+func (t *UserToken) TableName() string {
+	return "user_tokens"
+}
@@ -0,0 +1,64 @@
+package hasher
+
+import (
+	"errors"
+)
+
+var ErrUnknownHash = errors.New("unknown password hash format")
+
+// Algorithm hashes passwords in one format and recognises its own hashes.
+type Algorithm interface {
+	// Identifies reports whether encoded was produced by this algorithm.
+	Identifies(encoded string) bool
+	Hash(password string) (string, error)
+	Verify(encoded string, password string) (bool, error)
+	// Outdated reports whether encoded was hashed with other parameters than
+	// the ones currently configured.
+	Outdated(encoded string) bool
+}
+
+// Hasher hashes new passwords with the current algorithm and verifies any
+// hash one of its algorithms identifies, so stored hashes can be moved to a
+// new algorithm or new parameters one sign in at a time.
+type Hasher struct {
+	current    Algorithm
+	algorithms []Algorithm
+}
+
+// New returns a Hasher hashing with current. Hashes of the other algorithms
+// are still verified.
+func New(current Algorithm, others ...Algorithm) *Hasher {
+	return &Hasher{
+		current:    current,
+		algorithms: append([]Algorithm{current}, others...),
+	}
+}
+
+func (h *Hasher) Hash(password string) (string, error) {
+	return h.current.Hash(password)
+}
+
+func (h *Hasher) Verify(encoded string, password string) (bool, error) {
+	algorithm := h.find(encoded)
+	if algorithm == nil {
+		return false, ErrUnknownHash
+	}
+
+	return algorithm.Verify(encoded, password)
+}
+
+// NeedsRehash reports whether encoded should be replaced by a new hash of
+// the same password, because it uses another algorithm or outdated
+// parameters.
+func (h *Hasher) NeedsRehash(encoded string) bool {
+	return !h.current.Identifies(encoded) || h.current.Outdated(encoded)
+}
+
+func (h *Hasher) find(encoded string) Algorithm {
+	for _, algorithm := range h.algorithms {
+		if algorithm.Identifies(encoded) {
+			return algorithm
+		}
+	}
+	return nil
+}
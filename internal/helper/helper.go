@@ -3,6 +3,8 @@ package helper
 import (
 	"fmt"
 	"github.com/go-playground/validator/v10"
+	"net/url"
+	"strconv"
 )
 
 func GetFirstValidationErrorAndConvert(validationError error) string {
@@ -56,3 +58,21 @@ func FormatPrevURLPagination(path string, page int, limit int) string {
 	}
 	return fmt.Sprintf("http://localhost:8080/%s?page=%d&limit=%d", path, page-1, limit)
 }
+
+// FormatCursorURLPagination links to the page of cursor, or to nothing when
+// there is no such page.
+func FormatCursorURLPagination(path string, cursor string, limit int) string {
+	if cursor == "" {
+		return ""
+	}
+	return fmt.Sprintf("http://localhost:8080/%s?%s", path, url.Values{"cursor": {cursor}, "limit": {strconv.Itoa(limit)}}.Encode())
+}
+
+// AppendQuery adds query to a pagination link, keeping the filters of the
+// current page on the next and previous ones. Empty links stay empty.
+func AppendQuery(link string, query url.Values) string {
+	if link == "" || len(query) == 0 {
+		return link
+	}
+	return link + "&" + query.Encode()
+}
@@ -0,0 +1,71 @@
+package helper
+
+import (
+	"image"
+	"image/draw"
+)
+
+// Thumbnail scales img down to fit in a size by size square, keeping its
+// aspect ratio. Each pixel of the thumbnail averages the pixels it covers, so
+// thin lines don't vanish as with nearest neighbour scaling. Images already
+// fitting are returned as they are.
+func Thumbnail(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= size && height <= size {
+		return img
+	}
+
+	thumbnailWidth, thumbnailHeight := size, size
+	if width > height {
+		thumbnailHeight = maxInt(1, height*size/width)
+	} else {
+		thumbnailWidth = maxInt(1, width*size/height)
+	}
+
+	// Averaging premultiplied colors keeps transparent pixels from darkening
+	// their neighbours.
+	source, ok := img.(*image.RGBA)
+	if !ok {
+		source = image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(source, source.Bounds(), img, bounds.Min, draw.Src)
+	}
+	origin := source.Bounds().Min
+
+	thumbnail := image.NewRGBA(image.Rect(0, 0, thumbnailWidth, thumbnailHeight))
+	for y := 0; y < thumbnailHeight; y++ {
+		top := y * height / thumbnailHeight
+		bottom := maxInt(top+1, (y+1)*height/thumbnailHeight)
+		for x := 0; x < thumbnailWidth; x++ {
+			left := x * width / thumbnailWidth
+			right := maxInt(left+1, (x+1)*width/thumbnailWidth)
+
+			var sum [4]int
+			for sourceY := top; sourceY < bottom; sourceY++ {
+				offset := source.PixOffset(origin.X+left, origin.Y+sourceY)
+				for sourceX := left; sourceX < right; sourceX++ {
+					sum[0] += int(source.Pix[offset])
+					sum[1] += int(source.Pix[offset+1])
+					sum[2] += int(source.Pix[offset+2])
+					sum[3] += int(source.Pix[offset+3])
+					offset += 4
+				}
+			}
+			count := (bottom - top) * (right - left)
+			offset := thumbnail.PixOffset(x, y)
+			for channel := range sum {
+				thumbnail.Pix[offset+channel] = uint8((sum[channel] + count/2) / count)
+			}
+		}
+	}
+	return thumbnail
+}
+
+func maxInt(first int, others ...int) int {
+	for _, other := range others {
+		if other > first {
+			first = other
+		}
+	}
+	return first
+}
@@ -0,0 +1,170 @@
+package helper
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// SearchTerms splits text into lower case words, dropping punctuation and
+// repeated words.
+func SearchTerms(text string) []string {
+	var terms []string
+	seen := map[string]bool{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), isNotWordRune) {
+		if !seen[word] {
+			seen[word] = true
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+func isNotWordRune(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// MatchSearchTerm scores how well word, in lower case, matches term. Exact
+// words score 1, words starting with term a bit less the more is missing, and
+// words a typo or two away from term at most 0.5. Unrelated words score 0.
+func MatchSearchTerm(term string, word string) float64 {
+	if word == term {
+		return 1
+	}
+	if strings.HasPrefix(word, term) {
+		return 0.5 + 0.5*float64(len([]rune(term)))/float64(len([]rune(word)))
+	}
+
+	typos := allowedTypos(term)
+	if typos == 0 {
+		return 0
+	}
+	distance := editDistance([]rune(term), []rune(word), typos)
+	if distance > typos {
+		return 0
+	}
+	return 0.5 / float64(1+distance)
+}
+
+// allowedTypos grows with the term, short words are too easily confused.
+func allowedTypos(term string) int {
+	length := len([]rune(term))
+	switch {
+	case length >= 8:
+		return 2
+	case length >= 4:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// editDistance counts the insertions, deletions, substitutions and swaps of
+// neighbouring letters turning a into b. It gives up with max+1 as soon as
+// the distance is known to be larger than max.
+func editDistance(a []rune, b []rune, max int) int {
+	if abs(len(a)-len(b)) > max {
+		return max + 1
+	}
+
+	beforePrevious := make([]int, len(b)+1)
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		smallest := current[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				current[j] = minInt(current[j], beforePrevious[j-2]+1)
+			}
+			smallest = minInt(smallest, current[j])
+		}
+		if smallest > max {
+			return max + 1
+		}
+		beforePrevious, previous, current = previous, current, beforePrevious
+	}
+	return previous[len(b)]
+}
+
+// ScoreSearch ranks text against the terms of a search. Every term has to
+// match a word of text, otherwise the score is 0.
+func ScoreSearch(terms []string, text string) float64 {
+	words := SearchTerms(text)
+	var score float64
+	for _, term := range terms {
+		var best float64
+		for _, word := range words {
+			if match := MatchSearchTerm(term, word); match > best {
+				best = match
+			}
+		}
+		if best == 0 {
+			return 0
+		}
+		score += best
+	}
+	return score
+}
+
+// HighlightSearch wraps the words of text matching one of terms in <mark>
+// tags. The rest of text is HTML escaped so the result can be shown as is.
+func HighlightSearch(terms []string, text string) string {
+	var builder strings.Builder
+	var word []rune
+	flush := func() {
+		if len(word) == 0 {
+			return
+		}
+		escaped := html.EscapeString(string(word))
+		lower := strings.ToLower(string(word))
+		for _, term := range terms {
+			if MatchSearchTerm(term, lower) > 0 {
+				escaped = "<mark>" + escaped + "</mark>"
+				break
+			}
+		}
+		builder.WriteString(escaped)
+		word = word[:0]
+	}
+
+	for _, r := range text {
+		if isNotWordRune(r) {
+			flush()
+			builder.WriteString(html.EscapeString(string(r)))
+			continue
+		}
+		word = append(word, r)
+	}
+	flush()
+	return builder.String()
+}
+
+func minInt(first int, others ...int) int {
+	for _, other := range others {
+		if other < first {
+			first = other
+		}
+	}
+	return first
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
+
+// Slugify turns text into lower case words joined by dashes, fit for a URL.
+func Slugify(text string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(text), isNotWordRune), "-")
+}
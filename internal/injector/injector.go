@@ -1,6 +1,7 @@
 package injector
 
 import (
+	"fmt"
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 	"github.com/sirupsen/logrus"
@@ -8,16 +9,72 @@ import (
 	"go-crud/internal/delivery/http/controllers"
 	"go-crud/internal/delivery/http/middleware"
 	"go-crud/internal/delivery/http/routes"
+	"go-crud/internal/entity"
+	"go-crud/internal/hasher"
+	"go-crud/internal/keyset"
+	"go-crud/internal/mail"
+	"go-crud/internal/models"
+	"go-crud/internal/oidc"
 	"go-crud/internal/repository"
+	"go-crud/internal/storage"
 	"go-crud/internal/usecase"
 	"gorm.io/gorm"
+	"math"
 )
 
 var authUsecase *usecase.AuthUsecase
+var apiKeyUsecase *usecase.ApiKeyUsecase
+var loginAttemptUsecase *usecase.LoginAttemptUsecase
+var auditLogUsecase *usecase.AuditLogUsecase
+var productSearchRepository repository.ProductSearchRepositoryInterface
 
-func InjectSignupRoute(app *fiber.App, database *gorm.DB, validator *validator.Validate, log *logrus.Logger) *routes.SignupRoute {
+func newLoginAttemptRepository(database *gorm.DB, viper *viper.Viper) repository.LoginAttemptRepositoryInterface {
+	if viper.GetString("auth.lockout.store") == "memory" {
+		return repository.NewMemoryLoginAttemptRepository()
+	}
+
+	return repository.NewLoginAttemptRepository(database)
+}
+
+// newProductSearchRepository picks the search index of search.driver. The
+// memory index starts with every product already saved.
+func newProductSearchRepository(database *gorm.DB, productRepository repository.ProductRepositoryInterface, viper *viper.Viper) repository.ProductSearchRepositoryInterface {
+	if viper.GetString("search.driver") != "memory" {
+		return repository.NewProductSearchRepository(database)
+	}
+
+	index := repository.NewMemoryProductSearchRepository()
+	var products []entity.Product
+	err := productRepository.FindMany(&products, &models.ProductFilter{}, 0, math.MaxInt32)
+	if err != nil {
+		panic(fmt.Errorf("failed to fill the product search index: %w", err))
+	}
+	for i := range products {
+		_ = index.Index(&products[i])
+	}
+	return index
+}
+
+// newProductUsecase builds the product usecase of every route changing
+// products. They share one search index, a memory index would otherwise miss
+// the products deleted through another route.
+func newProductUsecase(database *gorm.DB, validator *validator.Validate, viper *viper.Viper, fileStorage storage.Storage, log *logrus.Logger) *usecase.ProductUsecase {
+	productRepository := repository.NewProductRepository(database)
+	if productSearchRepository == nil {
+		productSearchRepository = newProductSearchRepository(database, productRepository, viper)
+	}
+	organizationMemberRepository := repository.NewOrganizationMemberRepository(database)
+	categoryRepository := repository.NewCategoryRepository(database)
+	tagRepository := repository.NewTagRepository(database)
+	productImageRepository := repository.NewProductImageRepository(database)
+	return usecase.NewProductUsecase(productRepository, productSearchRepository, organizationMemberRepository, categoryRepository, tagRepository, productImageRepository, fileStorage, validator, viper, log)
+}
+
+func InjectSignupRoute(app *fiber.App, database *gorm.DB, validator *validator.Validate, viper *viper.Viper, mailSender mail.Sender, passwordHasher *hasher.Hasher, log *logrus.Logger) *routes.SignupRoute {
 	userRepository := repository.NewUserRepository(database)
-	signupUsecase := usecase.NewSignUpUsecase(userRepository, validator, log)
+	userTokenRepository := repository.NewUserTokenRepository(database)
+	emailVerificationUsecase := usecase.NewEmailVerificationUsecase(userRepository, userTokenRepository, mailSender, validator, viper, log)
+	signupUsecase := usecase.NewSignUpUsecase(userRepository, emailVerificationUsecase, passwordHasher, validator, log)
 	signupController := controllers.NewSignupController(log, signupUsecase)
 	signupRoute := routes.NewSignupRoute(app, signupController)
 
@@ -25,22 +82,198 @@ func InjectSignupRoute(app *fiber.App, database *gorm.DB, validator *validator.V
 
 }
 
-func InjectAuthRoute(app *fiber.App, database *gorm.DB, validator *validator.Validate, viper *viper.Viper, log *logrus.Logger) *routes.AuthRoute {
+func InjectEmailVerificationRoute(app *fiber.App, database *gorm.DB, validator *validator.Validate, viper *viper.Viper, mailSender mail.Sender, log *logrus.Logger) *routes.EmailVerificationRoute {
+	userRepository := repository.NewUserRepository(database)
+	userTokenRepository := repository.NewUserTokenRepository(database)
+	emailVerificationUsecase := usecase.NewEmailVerificationUsecase(userRepository, userTokenRepository, mailSender, validator, viper, log)
+	emailVerificationController := controllers.NewEmailVerificationController(log, emailVerificationUsecase)
+	emailVerificationRoute := routes.NewEmailVerificationRoute(app, emailVerificationController)
+
+	return emailVerificationRoute
+}
+
+func InjectAuthRoute(app *fiber.App, database *gorm.DB, validator *validator.Validate, viper *viper.Viper, keySet *keyset.KeySet, passwordHasher *hasher.Hasher, log *logrus.Logger) *routes.AuthRoute {
 	userRepository := repository.NewUserRepository(database)
-	authUsecase = usecase.NewAuthUsecase(userRepository, validator, viper, log)
+	refreshTokenRepository := repository.NewRefreshTokenRepository(database)
+	sessionRepository := repository.NewSessionRepository(database)
+	revokedAccessTokenRepository := repository.NewRevokedAccessTokenRepository(database)
+	auditLogUsecase = usecase.NewAuditLogUsecase(repository.NewAuditLogRepository(database), log)
+	loginAttemptUsecase = usecase.NewLoginAttemptUsecase(newLoginAttemptRepository(database, viper), viper, log)
+	authUsecase = usecase.NewAuthUsecase(userRepository, refreshTokenRepository, sessionRepository, revokedAccessTokenRepository, loginAttemptUsecase, auditLogUsecase, passwordHasher, keySet, validator, viper, log)
+	apiKeyUsecase = usecase.NewApiKeyUsecase(repository.NewApiKeyRepository(database), validator, log)
 	authController := controllers.NewAuthController(log, authUsecase)
 	authRoute := routes.NewAuthRoute(app, authController)
 
 	return authRoute
 }
 
-func InjectProductRoute(app *fiber.App, database *gorm.DB, validator *validator.Validate, viper *viper.Viper, log *logrus.Logger) *routes.ProductRoute {
+func InjectProductRoute(app *fiber.App, database *gorm.DB, validator *validator.Validate, viper *viper.Viper, fileStorage storage.Storage, log *logrus.Logger) *routes.ProductRoute {
 	productRepository := repository.NewProductRepository(database)
-	productUsecase := usecase.NewProductUsecase(productRepository, validator, viper, log)
+	organizationMemberRepository := repository.NewOrganizationMemberRepository(database)
+	productUsecase := newProductUsecase(database, validator, viper, fileStorage, log)
 	productController := controllers.NewProductController(log, productUsecase)
-	authMiddleware := middleware.NewAuthMiddleware(authUsecase, log)
-	productMiddleware := middleware.NewProductMiddleware(productRepository, log)
+	authMiddleware := middleware.NewAuthMiddleware(authUsecase, apiKeyUsecase, auditLogUsecase, log)
+	productMiddleware := middleware.NewProductMiddleware(productRepository, organizationMemberRepository, auditLogUsecase, log)
 	productRoute := routes.NewProductRoute(app, productController, authMiddleware, productMiddleware)
 
 	return productRoute
 }
+
+func InjectProductImageRoute(app *fiber.App, database *gorm.DB, validator *validator.Validate, viper *viper.Viper, fileStorage storage.Storage, log *logrus.Logger) *routes.ProductImageRoute {
+	productRepository := repository.NewProductRepository(database)
+	organizationMemberRepository := repository.NewOrganizationMemberRepository(database)
+	productImageUsecase := usecase.NewProductImageUsecase(repository.NewProductImageRepository(database), fileStorage, validator, viper, log)
+	productImageController := controllers.NewProductImageController(log, productImageUsecase)
+	authMiddleware := middleware.NewAuthMiddleware(authUsecase, apiKeyUsecase, auditLogUsecase, log)
+	productMiddleware := middleware.NewProductMiddleware(productRepository, organizationMemberRepository, auditLogUsecase, log)
+	productImageRoute := routes.NewProductImageRoute(app, productImageController, authMiddleware, productMiddleware)
+
+	return productImageRoute
+}
+
+func InjectCategoryRoute(app *fiber.App, database *gorm.DB, validator *validator.Validate, log *logrus.Logger) *routes.CategoryRoute {
+	categoryRepository := repository.NewCategoryRepository(database)
+	categoryUsecase := usecase.NewCategoryUsecase(categoryRepository, validator, log)
+	categoryController := controllers.NewCategoryController(log, categoryUsecase)
+	authMiddleware := middleware.NewAuthMiddleware(authUsecase, apiKeyUsecase, auditLogUsecase, log)
+	categoryRoute := routes.NewCategoryRoute(app, categoryController, authMiddleware)
+
+	return categoryRoute
+}
+
+func InjectTagRoute(app *fiber.App, database *gorm.DB, validator *validator.Validate, log *logrus.Logger) *routes.TagRoute {
+	tagRepository := repository.NewTagRepository(database)
+	tagUsecase := usecase.NewTagUsecase(tagRepository, validator, log)
+	tagController := controllers.NewTagController(log, tagUsecase)
+	authMiddleware := middleware.NewAuthMiddleware(authUsecase, apiKeyUsecase, auditLogUsecase, log)
+	tagRoute := routes.NewTagRoute(app, tagController, authMiddleware)
+
+	return tagRoute
+}
+
+func InjectSessionRoute(app *fiber.App, database *gorm.DB, log *logrus.Logger) *routes.SessionRoute {
+	sessionRepository := repository.NewSessionRepository(database)
+	refreshTokenRepository := repository.NewRefreshTokenRepository(database)
+	sessionUsecase := usecase.NewSessionUsecase(sessionRepository, refreshTokenRepository, log)
+	sessionController := controllers.NewSessionController(log, sessionUsecase)
+	authMiddleware := middleware.NewAuthMiddleware(authUsecase, apiKeyUsecase, auditLogUsecase, log)
+	sessionRoute := routes.NewSessionRoute(app, sessionController, authMiddleware)
+
+	return sessionRoute
+}
+
+func InjectPasswordResetRoute(app *fiber.App, database *gorm.DB, validator *validator.Validate, viper *viper.Viper, mailSender mail.Sender, passwordHasher *hasher.Hasher, log *logrus.Logger) *routes.PasswordResetRoute {
+	userRepository := repository.NewUserRepository(database)
+	userTokenRepository := repository.NewUserTokenRepository(database)
+	sessionRepository := repository.NewSessionRepository(database)
+	refreshTokenRepository := repository.NewRefreshTokenRepository(database)
+	passwordResetUsecase := usecase.NewPasswordResetUsecase(userRepository, userTokenRepository, sessionRepository, refreshTokenRepository, loginAttemptUsecase, auditLogUsecase, passwordHasher, mailSender, validator, viper, log)
+	passwordResetController := controllers.NewPasswordResetController(log, passwordResetUsecase)
+	passwordResetRoute := routes.NewPasswordResetRoute(app, passwordResetController)
+
+	return passwordResetRoute
+}
+
+func InjectMfaRoute(app *fiber.App, database *gorm.DB, validator *validator.Validate, viper *viper.Viper, log *logrus.Logger) *routes.MfaRoute {
+	userRepository := repository.NewUserRepository(database)
+	recoveryCodeRepository := repository.NewRecoveryCodeRepository(database)
+	mfaUsecase := usecase.NewMfaUsecase(userRepository, recoveryCodeRepository, authUsecase, validator, viper, log)
+	mfaController := controllers.NewMfaController(log, mfaUsecase)
+	authMiddleware := middleware.NewAuthMiddleware(authUsecase, apiKeyUsecase, auditLogUsecase, log)
+	mfaRoute := routes.NewMfaRoute(app, mfaController, authMiddleware)
+
+	return mfaRoute
+}
+
+func InjectApiKeyRoute(app *fiber.App, log *logrus.Logger) *routes.ApiKeyRoute {
+	apiKeyController := controllers.NewApiKeyController(log, apiKeyUsecase)
+	authMiddleware := middleware.NewAuthMiddleware(authUsecase, apiKeyUsecase, auditLogUsecase, log)
+	apiKeyRoute := routes.NewApiKeyRoute(app, apiKeyController, authMiddleware)
+
+	return apiKeyRoute
+}
+
+func InjectAuditLogRoute(app *fiber.App, log *logrus.Logger) *routes.AuditLogRoute {
+	auditLogController := controllers.NewAuditLogController(log, auditLogUsecase)
+	authMiddleware := middleware.NewAuthMiddleware(authUsecase, apiKeyUsecase, auditLogUsecase, log)
+	auditLogRoute := routes.NewAuditLogRoute(app, auditLogController, authMiddleware)
+
+	return auditLogRoute
+}
+
+func InjectUserRoute(app *fiber.App, database *gorm.DB, validator *validator.Validate, viper *viper.Viper, mailSender mail.Sender, passwordHasher *hasher.Hasher, fileStorage storage.Storage, log *logrus.Logger) *routes.UserRoute {
+	userRepository := repository.NewUserRepository(database)
+	userTokenRepository := repository.NewUserTokenRepository(database)
+	sessionRepository := repository.NewSessionRepository(database)
+	refreshTokenRepository := repository.NewRefreshTokenRepository(database)
+	organizationMemberRepository := repository.NewOrganizationMemberRepository(database)
+	emailVerificationUsecase := usecase.NewEmailVerificationUsecase(userRepository, userTokenRepository, mailSender, validator, viper, log)
+	productUsecase := newProductUsecase(database, validator, viper, fileStorage, log)
+	userUsecase := usecase.NewUserUsecase(userRepository, userTokenRepository, sessionRepository, refreshTokenRepository, organizationMemberRepository, emailVerificationUsecase, loginAttemptUsecase, auditLogUsecase, productUsecase, passwordHasher, validator, log)
+	userController := controllers.NewUserController(log, userUsecase)
+	authMiddleware := middleware.NewAuthMiddleware(authUsecase, apiKeyUsecase, auditLogUsecase, log)
+	userRoute := routes.NewUserRoute(app, userController, authMiddleware)
+
+	return userRoute
+}
+
+func InjectOAuthRoute(app *fiber.App, database *gorm.DB, validator *validator.Validate, viper *viper.Viper, log *logrus.Logger) *routes.OAuthRoute {
+	oauthClientRepository := repository.NewOAuthClientRepository(database)
+	oauthAuthorizationCodeRepository := repository.NewOAuthAuthorizationCodeRepository(database)
+	sessionRepository := repository.NewSessionRepository(database)
+	oauthUsecase := usecase.NewOAuthUsecase(oauthClientRepository, oauthAuthorizationCodeRepository, sessionRepository, authUsecase, validator, viper, log)
+	oauthController := controllers.NewOAuthController(log, oauthUsecase)
+	authMiddleware := middleware.NewAuthMiddleware(authUsecase, apiKeyUsecase, auditLogUsecase, log)
+	oauthRoute := routes.NewOAuthRoute(app, oauthController, authMiddleware)
+
+	return oauthRoute
+}
+
+func InjectOidcRoute(app *fiber.App, database *gorm.DB, validator *validator.Validate, viper *viper.Viper, provider *oidc.Provider, log *logrus.Logger) *routes.OidcRoute {
+	userRepository := repository.NewUserRepository(database)
+	userIdentityRepository := repository.NewUserIdentityRepository(database)
+	oidcUsecase := usecase.NewOidcUsecase(provider, userRepository, userIdentityRepository, authUsecase, validator, viper, log)
+	oidcController := controllers.NewOidcController(log, oidcUsecase)
+	oidcRoute := routes.NewOidcRoute(app, oidcController)
+
+	return oidcRoute
+}
+
+func InjectMagicLinkRoute(app *fiber.App, database *gorm.DB, validator *validator.Validate, viper *viper.Viper, mailSender mail.Sender, log *logrus.Logger) *routes.MagicLinkRoute {
+	userRepository := repository.NewUserRepository(database)
+	userTokenRepository := repository.NewUserTokenRepository(database)
+	magicLinkUsecase := usecase.NewMagicLinkUsecase(userRepository, userTokenRepository, authUsecase, mailSender, validator, viper, log)
+	magicLinkController := controllers.NewMagicLinkController(log, magicLinkUsecase)
+	magicLinkRoute := routes.NewMagicLinkRoute(app, magicLinkController)
+
+	return magicLinkRoute
+}
+
+func InjectOrganizationRoute(app *fiber.App, database *gorm.DB, validator *validator.Validate, viper *viper.Viper, mailSender mail.Sender, fileStorage storage.Storage, log *logrus.Logger) *routes.OrganizationRoute {
+	organizationRepository := repository.NewOrganizationRepository(database)
+	organizationMemberRepository := repository.NewOrganizationMemberRepository(database)
+	organizationInvitationRepository := repository.NewOrganizationInvitationRepository(database)
+	userRepository := repository.NewUserRepository(database)
+	productUsecase := newProductUsecase(database, validator, viper, fileStorage, log)
+	organizationUsecase := usecase.NewOrganizationUsecase(organizationRepository, organizationMemberRepository, organizationInvitationRepository, userRepository, productUsecase, mailSender, validator, viper, log)
+	organizationController := controllers.NewOrganizationController(log, organizationUsecase)
+	authMiddleware := middleware.NewAuthMiddleware(authUsecase, apiKeyUsecase, auditLogUsecase, log)
+	organizationRoute := routes.NewOrganizationRoute(app, organizationController, authMiddleware)
+
+	return organizationRoute
+}
+
+func InjectAdminRoute(app *fiber.App, database *gorm.DB, validator *validator.Validate, viper *viper.Viper, mailSender mail.Sender, passwordHasher *hasher.Hasher, log *logrus.Logger) *routes.AdminRoute {
+	userRepository := repository.NewUserRepository(database)
+	userTokenRepository := repository.NewUserTokenRepository(database)
+	sessionRepository := repository.NewSessionRepository(database)
+	refreshTokenRepository := repository.NewRefreshTokenRepository(database)
+	sessionUsecase := usecase.NewSessionUsecase(sessionRepository, refreshTokenRepository, log)
+	passwordResetUsecase := usecase.NewPasswordResetUsecase(userRepository, userTokenRepository, sessionRepository, refreshTokenRepository, loginAttemptUsecase, auditLogUsecase, passwordHasher, mailSender, validator, viper, log)
+	adminUsecase := usecase.NewAdminUsecase(userRepository, repository.NewProductRepository(database), authUsecase, sessionUsecase, passwordResetUsecase, auditLogUsecase, viper, log)
+	adminController := controllers.NewAdminController(log, adminUsecase)
+	authMiddleware := middleware.NewAuthMiddleware(authUsecase, apiKeyUsecase, auditLogUsecase, log)
+	adminRoute := routes.NewAdminRoute(app, adminController, authMiddleware)
+
+	return adminRoute
+}
@@ -0,0 +1,179 @@
+package keyset
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+var (
+	ErrUnknownKey = errors.New("unknown signing key")
+	ErrRetiredKey = errors.New("signing key is retired")
+)
+
+// Key is a single signing key. For HS256 the secret lives in Secret, for the
+// asymmetric algorithms in Private and Public.
+type Key struct {
+	Id        string
+	Algorithm string
+	Secret    []byte
+	Private   crypto.Signer
+	Public    crypto.PublicKey
+	Retired   bool
+}
+
+// KeySet holds every key tokens may be signed with. Only the active key signs
+// new tokens; any key that isn't retired is still accepted for verification,
+// which lets a new key be rolled out before the old one is dropped.
+type KeySet struct {
+	keys     map[string]*Key
+	order    []string
+	activeId string
+}
+
+func New(activeID string, keys ...*Key) (*KeySet, error) {
+	set := &KeySet{
+		keys:     make(map[string]*Key, len(keys)),
+		activeId: activeID,
+	}
+
+	for _, key := range keys {
+		if _, ok := set.keys[key.Id]; ok {
+			return nil, fmt.Errorf("duplicate key id %q", key.Id)
+		}
+		if err := validateKey(key); err != nil {
+			return nil, fmt.Errorf("key %q: %w", key.Id, err)
+		}
+		set.keys[key.Id] = key
+		set.order = append(set.order, key.Id)
+	}
+
+	active, ok := set.keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("active key %q is not configured", activeID)
+	}
+	if active.Retired {
+		return nil, fmt.Errorf("active key %q is retired", activeID)
+	}
+
+	return set, nil
+}
+
+func validateKey(key *Key) error {
+	switch key.Algorithm {
+	case AlgorithmHS256:
+		if len(key.Secret) == 0 {
+			return errors.New("HS256 key needs a secret")
+		}
+	case AlgorithmRS256:
+		if _, ok := key.Public.(*rsa.PublicKey); !ok {
+			return errors.New("RS256 key needs an RSA key pair")
+		}
+	case AlgorithmEdDSA:
+		if _, ok := key.Public.(ed25519.PublicKey); !ok {
+			return errors.New("EdDSA key needs an Ed25519 key pair")
+		}
+	default:
+		return fmt.Errorf("unsupported algorithm %q", key.Algorithm)
+	}
+
+	return nil
+}
+
+func (s *KeySet) Active() *Key {
+	return s.keys[s.activeId]
+}
+
+// Sign signs the claims with the active key and stamps its id in the kid
+// header.
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	key := s.Active()
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.Id
+
+	if key.Algorithm == AlgorithmHS256 {
+		return token.SignedString(key.Secret)
+	}
+	return token.SignedString(key.Private)
+}
+
+// Keyfunc resolves the verification key of a token for jwt.Parse. Tokens
+// without a kid were issued before key rotation existed and are checked
+// against the active key.
+func (s *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	key := s.Active()
+	if kid, ok := token.Header["kid"].(string); ok {
+		key, ok = s.keys[kid]
+		if !ok {
+			return nil, ErrUnknownKey
+		}
+	}
+
+	if key.Retired {
+		return nil, ErrRetiredKey
+	}
+
+	// The algorithm is pinned to the key, otherwise a public RSA key could be
+	// abused as an HMAC secret.
+	if token.Method.Alg() != key.Algorithm {
+		return nil, jwt.ErrTokenSignatureInvalid
+	}
+
+	if key.Algorithm == AlgorithmHS256 {
+		return key.Secret, nil
+	}
+	return key.Public, nil
+}
+
+// Algorithms lists the algorithms of the keys accepted for verification, to
+// be passed to jwt.WithValidMethods.
+func (s *KeySet) Algorithms() []string {
+	seen := make(map[string]bool)
+	var algorithms []string
+	for _, id := range s.order {
+		key := s.keys[id]
+		if key.Retired || seen[key.Algorithm] {
+			continue
+		}
+		seen[key.Algorithm] = true
+		algorithms = append(algorithms, key.Algorithm)
+	}
+
+	return algorithms
+}
+
+// ParsePrivateKey reads an RSA or Ed25519 private key from PEM, either PKCS#8
+// or the older PKCS#1 RSA encoding.
+func ParsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported private key type")
+	}
+
+	return signer, nil
+}
@@ -0,0 +1,73 @@
+package mail
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileSender stores every message as a file in Directory, named after the
+// time it was sent and its recipient. Handy for local development and tests
+// that need to read what was sent.
+type FileSender struct {
+	Directory string
+	mutex     sync.Mutex
+	sequence  int
+}
+
+func NewFileSender(directory string) *FileSender {
+	return &FileSender{
+		Directory: directory,
+	}
+}
+
+func (s *FileSender) Send(message *Message) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	err := os.MkdirAll(s.Directory, 0700)
+	if err != nil {
+		return err
+	}
+
+	s.sequence++
+	recipient := strings.NewReplacer("/", "_", "\\", "_").Replace(message.To)
+	name := fmt.Sprintf("%s-%04d-%s.eml", time.Now().Format("20060102T150405"), s.sequence, recipient)
+	content := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n", message.From, message.To, message.Subject, message.Body)
+
+	return os.WriteFile(filepath.Join(s.Directory, name), []byte(content), 0600)
+}
+
+// Last returns the content of the most recently sent message for a recipient.
+func (s *FileSender) Last(to string) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	recipient := strings.NewReplacer("/", "_", "\\", "_").Replace(to)
+	matches, err := filepath.Glob(filepath.Join(s.Directory, "*-"+recipient+".eml"))
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "", os.ErrNotExist
+	}
+
+	// File names start with the send time and a sequence number, so the
+	// lexically greatest one is the newest.
+	latest := matches[0]
+	for _, match := range matches[1:] {
+		if match > latest {
+			latest = match
+		}
+	}
+
+	content, err := os.ReadFile(latest)
+	if err != nil {
+		return "", err
+	}
+
+	return string(content), nil
+}
@@ -0,0 +1,24 @@
+package mail
+
+import "github.com/sirupsen/logrus"
+
+// LogSender writes messages to the application log instead of sending them.
+// Meant for local development only, the log then contains the message body.
+type LogSender struct {
+	Log *logrus.Logger
+}
+
+func NewLogSender(log *logrus.Logger) *LogSender {
+	return &LogSender{
+		Log: log,
+	}
+}
+
+func (s *LogSender) Send(message *Message) error {
+	s.Log.WithFields(logrus.Fields{
+		"from":    message.From,
+		"to":      message.To,
+		"subject": message.Subject,
+	}).Info(message.Body)
+	return nil
+}
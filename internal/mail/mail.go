@@ -0,0 +1,14 @@
+package mail
+
+type Message struct {
+	From    string
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers a message to its recipient. Implementations must not keep
+// the message around after Send returns, bodies carry one-time secrets.
+type Sender interface {
+	Send(message *Message) error
+}
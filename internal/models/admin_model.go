@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Actor is the user performing an audited action and the device they act
+// from. It is filled by the controller from the request.
+type Actor struct {
+	UserId string
+	Client ClientInfo
+}
+
+type AdminUserResponse struct {
+	Id               string     `json:"id,omitempty"`
+	Name             string     `json:"name,omitempty"`
+	Email            string     `json:"email,omitempty"`
+	EmailVerified    bool       `json:"email_verified"`
+	TwoFactorEnabled bool       `json:"two_factor_enabled"`
+	Disabled         bool       `json:"disabled"`
+	DisabledAt       *time.Time `json:"disabled_at,omitempty"`
+	// ProductCount is only filled when a single user is fetched.
+	ProductCount *int64    `json:"product_count,omitempty"`
+	CreatedAt    time.Time `json:"created_at,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at,omitempty"`
+}
+
+// ImpersonateRequest is filled by the controller from the access token of
+// the admin, whose session and authentication methods the impersonation
+// token inherits.
+type ImpersonateRequest struct {
+	UserId    string
+	SessionId string
+	Methods   []string
+}
+
+type ImpersonationResponse struct {
+	AccessToken string    `json:"access_token,omitempty"`
+	ExpiresAt   time.Time `json:"expires_at,omitempty"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+const (
+	ScopeProductsRead  = "products:read"
+	ScopeProductsWrite = "products:write"
+)
+
+// AllScopes are granted to sessions of the user that didn't ask for fewer.
+var AllScopes = []string{ScopeProductsRead, ScopeProductsWrite}
+
+type CreateApiKeyRequest struct {
+	Name      string     `json:"name" validate:"required,max=100"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1,dive,oneof=products:read products:write"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type ApiKeyResponse struct {
+	Id         string     `json:"id,omitempty"`
+	Name       string     `json:"name,omitempty"`
+	Prefix     string     `json:"prefix,omitempty"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at,omitempty"`
+}
+
+type ApiKeyCreatedResponse struct {
+	ApiKeyResponse
+	Key string `json:"key,omitempty"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// AuditLogFilter narrows the audit log down. Empty fields match every entry,
+// From is inclusive and To exclusive.
+type AuditLogFilter struct {
+	ActorId  string
+	TargetId string
+	Action   string
+	Outcome  string
+	From     *time.Time
+	To       *time.Time
+}
+
+type AuditLogResponse struct {
+	Id        string    `json:"id,omitempty"`
+	ActorId   *string   `json:"actor_id,omitempty"`
+	Action    string    `json:"action,omitempty"`
+	TargetId  *string   `json:"target_id,omitempty"`
+	Outcome   string    `json:"outcome,omitempty"`
+	Details   string    `json:"details,omitempty"`
+	IpAddress string    `json:"ip_address,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+}
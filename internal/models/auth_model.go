@@ -1,11 +1,56 @@
 package models
 
+import "time"
+
 type SignInRequest struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required"`
+	// Scopes narrow down what the tokens of the session may do, every scope
+	// is granted when empty.
+	Scopes []string   `json:"scopes" validate:"omitempty,dive,oneof=products:read products:write"`
+	Client ClientInfo `json:"-"`
+}
+
+// ClientInfo describes the device a session is started from. It is filled by
+// the controller from the request, never from the body.
+type ClientInfo struct {
+	UserAgent string
+	IpAddress string
 }
 
 type AuthResponse struct {
 	AccessToken  string `json:"access_token,omitempty"`
 	RefreshToken string `json:"refresh_token,omitempty"`
+	MfaRequired  bool   `json:"mfa_required,omitempty"`
+	MfaToken     string `json:"mfa_token,omitempty"`
+}
+
+type AccessTokenClaims struct {
+	// TokenId is the jti the token is put on the deny-list with when it is
+	// revoked on its own.
+	TokenId   string
+	Subject   string
+	SessionId string
+	// Methods lists how the user authenticated (RFC 8176 amr values), e.g.
+	// "pwd" for a password and "otp" for a second factor.
+	Methods []string
+	// Roles and Permissions are copied from the user when the token is
+	// issued, so changes apply from the next refresh.
+	Roles       []string
+	Permissions []string
+	// ClientId is set on tokens issued to a third-party application, which
+	// never carries roles.
+	ClientId string
+	// Scopes limit what the token may do. They are nil on tokens issued
+	// before the scope claim existed, which keep full rights until they
+	// expire.
+	Scopes []string
+	// Actor is set on impersonation tokens to the admin acting as Subject
+	// (the RFC 8693 act claim).
+	Actor string
+	// IssuedAt is only filled when a token is verified. ExpiresAt is filled
+	// when a token is verified, and shortens the lifetime of a new token when
+	// set.
+	IssuedAt  time.Time
+	ExpiresAt time.Time
 }
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+type CategoryRequest struct {
+	Name string `json:"name" validate:"required,max=255"`
+	// Slug defaults to the name. Either way it is turned into lower case
+	// words joined by dashes.
+	Slug string `json:"slug" validate:"omitempty,max=255"`
+	// ParentId places the category under another one, at the root when empty.
+	ParentId string `json:"parent_id" validate:"omitempty,max=255"`
+}
+
+type CategoryResponse struct {
+	Id        string    `json:"id,omitempty"`
+	ParentId  string    `json:"parent_id,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	Slug      string    `json:"slug,omitempty"`
+	Path      string    `json:"path,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	// Children is only set when browsing the tree.
+	Children []CategoryResponse `json:"children,omitempty"`
+	// Ancestors lists the categories above, from the root down. It is only
+	// set on a single category.
+	Ancestors []CategoryResponse `json:"ancestors,omitempty"`
+}
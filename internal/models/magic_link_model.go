@@ -0,0 +1,10 @@
+package models
+
+type MagicLinkRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type MagicLinkSignInRequest struct {
+	Token  string     `json:"token" validate:"required"`
+	Client ClientInfo `json:"-"`
+}
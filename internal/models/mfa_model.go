@@ -0,0 +1,26 @@
+package models
+
+type TotpEnrollResponse struct {
+	Secret     string `json:"secret,omitempty"`
+	OtpauthURI string `json:"otpauth_uri,omitempty"`
+}
+
+type TotpCodeRequest struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+type SecondFactorRequest struct {
+	Code         string `json:"code" validate:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode string `json:"recovery_code" validate:"required_without=Code"`
+}
+
+type MfaVerifyRequest struct {
+	MfaToken     string     `json:"mfa_token" validate:"required"`
+	Code         string     `json:"code" validate:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode string     `json:"recovery_code" validate:"required_without=Code"`
+	Client       ClientInfo `json:"-"`
+}
+
+type RecoveryCodesResponse struct {
+	Codes []string `json:"codes"`
+}
@@ -17,6 +17,9 @@ type Metadata struct {
 	TotalItemCount int64  `json:"total_item_count,omitempty"`
 	Next           string `json:"next"`
 	Prev           string `json:"prev"`
+	// NextCursor and PrevCursor are only set on lists paged by cursor.
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
 }
 
 func (e ErrorResponse) Error() string {
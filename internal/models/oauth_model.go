@@ -0,0 +1,113 @@
+package models
+
+import "time"
+
+type CreateOAuthClientRequest struct {
+	Name         string   `json:"name" validate:"required,max=100"`
+	RedirectUris []string `json:"redirect_uris" validate:"required,min=1,dive,required,max=2000"`
+	Scopes       []string `json:"scopes" validate:"required,min=1,dive,oneof=products:read products:write"`
+	// Public clients, such as mobile and single-page apps, can't keep a
+	// secret and get none.
+	Public bool `json:"public"`
+}
+
+type OAuthClientResponse struct {
+	Id           string    `json:"client_id,omitempty"`
+	Name         string    `json:"name,omitempty"`
+	RedirectUris []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	Public       bool      `json:"public"`
+	CreatedAt    time.Time `json:"created_at,omitempty"`
+}
+
+type OAuthClientCreatedResponse struct {
+	OAuthClientResponse
+	Secret string `json:"client_secret,omitempty"`
+}
+
+// AuthorizeRequest carries the parameters of an authorization request, from
+// the query string when asking for consent and from the body when the user
+// answers.
+type AuthorizeRequest struct {
+	ResponseType        string `json:"response_type" query:"response_type" validate:"required"`
+	ClientId            string `json:"client_id" query:"client_id" validate:"required"`
+	RedirectUri         string `json:"redirect_uri" query:"redirect_uri" validate:"required"`
+	Scope               string `json:"scope" query:"scope"`
+	State               string `json:"state" query:"state"`
+	CodeChallenge       string `json:"code_challenge" query:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method" query:"code_challenge_method"`
+}
+
+type AuthorizeDecisionRequest struct {
+	AuthorizeRequest
+	Approved bool `json:"approved"`
+}
+
+// ConsentResponse is what the consent screen shows the user.
+type ConsentResponse struct {
+	ClientId    string   `json:"client_id"`
+	ClientName  string   `json:"client_name"`
+	RedirectUri string   `json:"redirect_uri"`
+	Scopes      []string `json:"scopes"`
+}
+
+type AuthorizeResponse struct {
+	RedirectUri string `json:"redirect_uri"`
+}
+
+// TokenRequest is the form posted to the token endpoint (RFC 6749 section
+// 4). The client credentials may also come from HTTP Basic authentication.
+type TokenRequest struct {
+	GrantType    string     `form:"grant_type"`
+	Code         string     `form:"code"`
+	RedirectUri  string     `form:"redirect_uri"`
+	CodeVerifier string     `form:"code_verifier"`
+	RefreshToken string     `form:"refresh_token"`
+	Scope        string     `form:"scope"`
+	ClientId     string     `form:"client_id"`
+	ClientSecret string     `form:"client_secret"`
+	Client       ClientInfo `form:"-"`
+}
+
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// TokenHintRequest is the form posted to the introspection (RFC 7662) and
+// revocation (RFC 7009) endpoints.
+type TokenHintRequest struct {
+	Token         string `form:"token"`
+	TokenTypeHint string `form:"token_type_hint"`
+	ClientId      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+}
+
+// IntrospectionResponse describes a token as in RFC 7662 section 2.2. An
+// inactive token only has active set to false.
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientId  string `json:"client_id,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Jti       string `json:"jti,omitempty"`
+}
+
+// OAuthError is an error of the token endpoint, which has to answer in the
+// format of RFC 6749 section 5.2 rather than with an ErrorResponse. Routes
+// refusing a token for its scope answer with it too (RFC 6750 section 3.1).
+type OAuthError struct {
+	Code        int    `json:"-"`
+	Err         string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e OAuthError) Error() string {
+	return e.Err + ": " + e.Description
+}
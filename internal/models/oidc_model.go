@@ -0,0 +1,20 @@
+package models
+
+// OidcLoginResponse is where to send the browser to sign in with the
+// identity provider. StateToken binds the callback to this browser and is
+// only ever handed out in a cookie.
+type OidcLoginResponse struct {
+	AuthorizationUrl string
+	StateToken       string
+}
+
+// OidcCallbackRequest is what the identity provider sends the browser back
+// with. StateToken comes from the cookie set by the login endpoint.
+type OidcCallbackRequest struct {
+	Code             string     `query:"code" validate:"required"`
+	State            string     `query:"state" validate:"required"`
+	Error            string     `query:"error"`
+	ErrorDescription string     `query:"error_description"`
+	StateToken       string     `query:"-"`
+	Client           ClientInfo `query:"-"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// Roles of an organization member. Owners manage the organization itself,
+// admins manage its members and every product, members manage the products
+// they created.
+const (
+	OrganizationRoleOwner  = "owner"
+	OrganizationRoleAdmin  = "admin"
+	OrganizationRoleMember = "member"
+)
+
+type CreateOrganizationRequest struct {
+	Name string `json:"name" validate:"required,max=255"`
+}
+
+type OrganizationResponse struct {
+	Id        string    `json:"id,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	Role      string    `json:"role,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+}
+
+type OrganizationMemberResponse struct {
+	UserId    string    `json:"user_id,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	Email     string    `json:"email,omitempty"`
+	Role      string    `json:"role,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+}
+
+type UpdateOrganizationMemberRequest struct {
+	Role string `json:"role" validate:"required,oneof=owner admin member"`
+}
+
+type CreateInvitationRequest struct {
+	Email string `json:"email" validate:"required,max=255,email"`
+	Role  string `json:"role" validate:"required,oneof=owner admin member"`
+}
+
+type InvitationResponse struct {
+	Id        string    `json:"id,omitempty"`
+	Email     string    `json:"email,omitempty"`
+	Role      string    `json:"role,omitempty"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+}
+
+type AcceptInvitationRequest struct {
+	Token string `json:"token" validate:"required"`
+}
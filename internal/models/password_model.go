@@ -0,0 +1,11 @@
+package models
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string     `json:"token" validate:"required"`
+	Password string     `json:"password" validate:"required,max=255,min=8"`
+	Client   ClientInfo `json:"-"`
+}
@@ -0,0 +1,14 @@
+package models
+
+// Permissions granted through roles. They are seeded by the migrations and
+// must stay in sync with the permissions table.
+const (
+	PermissionProductUpdateAny   = "product:update:any"
+	PermissionProductDeleteAny   = "product:delete:any"
+	PermissionUserReadAny        = "user:read:any"
+	PermissionUserUpdateAny      = "user:update:any"
+	PermissionUserImpersonateAny = "user:impersonate:any"
+	PermissionAuditLogReadAny    = "audit_log:read:any"
+	PermissionCategoryWriteAny   = "category:write:any"
+	PermissionTagWriteAny        = "tag:write:any"
+)
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+type ProductImageOrderRequest struct {
+	// ImageIds lists every image of the product once, primary first.
+	ImageIds []string `json:"image_ids" validate:"required,max=100,dive,required,max=255"`
+}
+
+type ProductImageResponse struct {
+	Id          string `json:"id,omitempty"`
+	Url         string `json:"url,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Width       int    `json:"width,omitempty"`
+	Height      int    `json:"height,omitempty"`
+	Size        int    `json:"size,omitempty"`
+	Primary     bool   `json:"primary"`
+	// Thumbnails maps every thumbnail size, such as small, to its URL.
+	Thumbnails map[string]string `json:"thumbnails,omitempty"`
+	CreatedAt  time.Time         `json:"created_at,omitempty"`
+}
@@ -8,6 +8,15 @@ type ProductRequest struct {
 	Name  string `json:"name" validate:"required,max=255"`
 	Price int    `json:"price" validate:"min=0"`
 	Stock int    `json:"stock" validate:"min=0"`
+	// OrganizationId creates the product for an organization the user is a
+	// member of. It is ignored on update.
+	OrganizationId string `json:"organization_id" validate:"omitempty,max=255"`
+	// CategoryId puts the product in a category. On update it is kept when
+	// left out, and an empty string takes the product out of its category.
+	CategoryId *string `json:"category_id" validate:"omitempty,max=255"`
+	// TagIds are the tags of the product. On update they are kept when left
+	// out, and an empty list removes them all.
+	TagIds []string `json:"tag_ids" validate:"omitempty,max=20,dive,required,max=255"`
 }
 
 type ProductResponse struct {
@@ -18,4 +27,64 @@ type ProductResponse struct {
 	CreatedAt time.Time    `json:"created_at,omitempty"`
 	UpdatedAt time.Time    `json:"updated_at,omitempty"`
 	User      UserResponse `json:"user,omitempty"`
+	// OrganizationId is set on products owned by an organization.
+	OrganizationId string            `json:"organization_id,omitempty"`
+	Category       *CategoryResponse `json:"category,omitempty"`
+	Tags           []TagResponse     `json:"tags,omitempty"`
+	// Images are ordered, the primary image first.
+	Images []ProductImageResponse `json:"images,omitempty"`
+}
+
+// ProductCondition is one filter of the product list, such as price[gte]=100.
+// Value is an int for price and stock, and a string for name and owner.
+type ProductCondition struct {
+	Field    string
+	Operator string
+	Value    any
+}
+
+type ProductSort struct {
+	Field      string
+	Descending bool
+}
+
+// ProductCursor is a position in the product list: the sort values and the id
+// of a product. Values holds an int, a string or a time.Time per sort field.
+type ProductCursor struct {
+	Values []any
+	Id     string
+	// Backward lists the products before the position instead of after it.
+	Backward bool
+}
+
+// ProductFilter narrows and orders the product list. Products are listed
+// newest first when Sort is empty.
+type ProductFilter struct {
+	Conditions []ProductCondition
+	Sort       []ProductSort
+	// Cursor, when set, lists the products next to it instead of from the
+	// start of the list.
+	Cursor *ProductCursor
+}
+
+// SortOrder returns the sort of the list, newest first by default.
+func (f *ProductFilter) SortOrder() []ProductSort {
+	if len(f.Sort) == 0 {
+		return []ProductSort{{Field: "created_at", Descending: true}}
+	}
+	return f.Sort
+}
+
+// ProductSearchHit is a product found by a search and its relevance.
+type ProductSearchHit struct {
+	ProductId string
+	Score     float64
+}
+
+type ProductSearchResponse struct {
+	ProductResponse
+	// Highlight is the HTML escaped name with the matching words wrapped in
+	// <mark> tags.
+	Highlight string  `json:"highlight"`
+	Score     float64 `json:"score"`
 }
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+type SessionResponse struct {
+	Id         string    `json:"id,omitempty"`
+	ClientId   string    `json:"client_id,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	IpAddress  string    `json:"ip_address,omitempty"`
+	Current    bool      `json:"current"`
+	CreatedAt  time.Time `json:"created_at,omitempty"`
+	LastSeenAt time.Time `json:"last_seen_at,omitempty"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+type TagRequest struct {
+	Name string `json:"name" validate:"required,max=255"`
+	// Slug defaults to the name. Either way it is turned into lower case
+	// words joined by dashes.
+	Slug string `json:"slug" validate:"omitempty,max=255"`
+}
+
+type TagResponse struct {
+	Id        string    `json:"id,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	Slug      string    `json:"slug,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+}
@@ -8,3 +8,30 @@ type UserResponse struct {
 	CreatedAt time.Time `json:"created_at,omitempty"`
 	UpdatedAt time.Time `json:"updated_at,omitempty"`
 }
+
+type ProfileResponse struct {
+	Id               string    `json:"id,omitempty"`
+	Name             string    `json:"name,omitempty"`
+	Email            string    `json:"email,omitempty"`
+	EmailVerified    bool      `json:"email_verified"`
+	PendingEmail     string    `json:"pending_email,omitempty"`
+	TwoFactorEnabled bool      `json:"two_factor_enabled"`
+	CreatedAt        time.Time `json:"created_at,omitempty"`
+	UpdatedAt        time.Time `json:"updated_at,omitempty"`
+}
+
+type UpdateProfileRequest struct {
+	Name            *string `json:"name" validate:"omitempty,min=1,max=16777215"`
+	Email           *string `json:"email" validate:"omitempty,max=255,email"`
+	CurrentPassword string  `json:"current_password"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string     `json:"current_password" validate:"required"`
+	NewPassword     string     `json:"new_password" validate:"required,max=255,min=8"`
+	Client          ClientInfo `json:"-"`
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password" validate:"required"`
+}
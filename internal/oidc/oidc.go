@@ -0,0 +1,372 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"go-crud/internal/keyset"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxResponseSize bounds what is read from the provider, so a misbehaving
+// provider can't exhaust memory.
+const maxResponseSize = 1 << 20
+
+// keyRefreshInterval is the least time between two fetches of the JWKS. An ID
+// token signed with an unknown key triggers a fetch, which would otherwise let
+// anyone make us hammer the provider.
+const keyRefreshInterval = time.Minute
+
+var (
+	// ErrUnavailable means the provider couldn't be reached or answered with
+	// something that isn't OpenID Connect.
+	ErrUnavailable = errors.New("identity provider is unavailable")
+
+	ErrInvalidIDToken = errors.New("invalid id token")
+)
+
+// TokenError is an error the token endpoint answered with, such as
+// invalid_grant for an expired or replayed code.
+type TokenError struct {
+	Code        string
+	Description string
+}
+
+func (e *TokenError) Error() string {
+	if e.Description == "" {
+		return fmt.Sprintf("token endpoint: %s", e.Code)
+	}
+	return fmt.Sprintf("token endpoint: %s: %s", e.Code, e.Description)
+}
+
+type Config struct {
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+	RedirectUrl  string
+	Scopes       []string
+}
+
+// Metadata is the part of the discovery document this package relies on
+// (OpenID Connect Discovery 1.0 section 3).
+type Metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksUri               string `json:"jwks_uri"`
+}
+
+// IDToken holds the verified claims of an ID token.
+type IDToken struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Methods       []string
+}
+
+// Provider signs users in with an external OpenID Connect provider using the
+// authorization code flow with PKCE. Discovery happens on first use and the
+// provider's keys are cached until an ID token names a key we don't know.
+type Provider struct {
+	config Config
+	client *http.Client
+	now    func() time.Time
+
+	mu            sync.Mutex
+	metadata      *Metadata
+	keys          map[string]crypto.PublicKey
+	keysFetchedAt time.Time
+}
+
+func New(config Config, client *http.Client) *Provider {
+	return &Provider{
+		config: config,
+		client: client,
+		now:    time.Now,
+	}
+}
+
+func (p *Provider) Issuer() string {
+	return p.config.Issuer
+}
+
+// AuthCodeURL returns where to send the browser to sign in. The code
+// challenge is derived from codeVerifier with S256.
+func (p *Provider) AuthCodeURL(state string, nonce string, codeVerifier string) (string, error) {
+	metadata, err := p.discover()
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := url.Parse(metadata.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid authorization endpoint", ErrUnavailable)
+	}
+
+	sum := sha256.Sum256([]byte(codeVerifier))
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.config.ClientId)
+	query.Set("redirect_uri", p.config.RedirectUrl)
+	query.Set("scope", strings.Join(p.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(sum[:]))
+	query.Set("code_challenge_method", "S256")
+	authURL.RawQuery = query.Encode()
+
+	return authURL.String(), nil
+}
+
+// Exchange trades the authorization code for tokens and returns the raw ID
+// token, which still has to go through VerifyIDToken.
+func (p *Provider) Exchange(code string, codeVerifier string) (string, error) {
+	metadata, err := p.discover()
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectUrl)
+	form.Set("code_verifier", codeVerifier)
+	if p.config.ClientSecret == "" {
+		form.Set("client_id", p.config.ClientId)
+	}
+
+	request, err := http.NewRequest(http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		request.SetBasicAuth(url.QueryEscape(p.config.ClientId), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	var body struct {
+		IdToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := p.do(request, &body)
+	if err != nil {
+		return "", err
+	}
+	if body.Error != "" && status >= 400 && status < 500 {
+		return "", &TokenError{Code: body.Error, Description: body.ErrorDescription}
+	}
+	if status != http.StatusOK {
+		return "", fmt.Errorf("%w: token endpoint answered %d", ErrUnavailable, status)
+	}
+	if body.IdToken == "" {
+		return "", fmt.Errorf("%w: token response has no id_token", ErrUnavailable)
+	}
+
+	return body.IdToken, nil
+}
+
+// VerifyIDToken checks the signature against the provider's keys and the
+// claims required by OpenID Connect Core section 3.1.3.7, including that the
+// nonce matches the one sent with the authorization request.
+func (p *Provider) VerifyIDToken(rawIDToken string, nonce string) (*IDToken, error) {
+	token, err := jwt.Parse(rawIDToken, p.keyfunc,
+		jwt.WithValidMethods([]string{"RS256", "ES256", keyset.AlgorithmEdDSA}),
+		jwt.WithIssuer(p.config.Issuer),
+		jwt.WithAudience(p.config.ClientId),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+		jwt.WithTimeFunc(p.now),
+	)
+	if err != nil {
+		if errors.Is(err, ErrUnavailable) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	mapClaims, _ := token.Claims.(jwt.MapClaims)
+	audience, _ := mapClaims.GetAudience()
+	if len(audience) > 1 && mapClaims["azp"] != p.config.ClientId {
+		return nil, fmt.Errorf("%w: azp is not the client", ErrInvalidIDToken)
+	}
+
+	tokenNonce, _ := mapClaims["nonce"].(string)
+	if nonce == "" || subtle.ConstantTimeCompare([]byte(tokenNonce), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	subject, _ := mapClaims.GetSubject()
+	if subject == "" {
+		return nil, fmt.Errorf("%w: sub is missing", ErrInvalidIDToken)
+	}
+
+	idToken := &IDToken{
+		Issuer:  p.config.Issuer,
+		Subject: subject,
+	}
+	idToken.Email, _ = mapClaims["email"].(string)
+	idToken.Name, _ = mapClaims["name"].(string)
+	// Some providers send email_verified as a string.
+	switch verified := mapClaims["email_verified"].(type) {
+	case bool:
+		idToken.EmailVerified = verified
+	case string:
+		idToken.EmailVerified = verified == "true"
+	}
+	if methods, ok := mapClaims["amr"].([]any); ok {
+		for _, method := range methods {
+			if value, ok := method.(string); ok {
+				idToken.Methods = append(idToken.Methods, value)
+			}
+		}
+	}
+
+	return idToken, nil
+}
+
+func (p *Provider) discover() (*Metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	request, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(p.config.Issuer, "/")+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	metadata := new(Metadata)
+	status, err := p.do(request, metadata)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("%w: discovery answered %d", ErrUnavailable, status)
+	}
+	// The issuer must be exactly the configured one, or tokens from another
+	// issuer could be accepted (Discovery section 4.3).
+	if metadata.Issuer != p.config.Issuer {
+		return nil, fmt.Errorf("%w: discovery issuer %q doesn't match", ErrUnavailable, metadata.Issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JwksUri == "" {
+		return nil, fmt.Errorf("%w: discovery document is incomplete", ErrUnavailable)
+	}
+
+	p.metadata = metadata
+	return metadata, nil
+}
+
+func (p *Provider) keyfunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	p.mu.Lock()
+	key, ok := p.lookupKey(kid)
+	stale := p.now().Sub(p.keysFetchedAt) >= keyRefreshInterval
+	p.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+	if !stale {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	err := p.fetchKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key, ok = p.lookupKey(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// lookupKey finds the key named by kid. A token without kid is only accepted
+// when the provider publishes a single key. The caller holds p.mu.
+func (p *Provider) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+func (p *Provider) fetchKeys() error {
+	metadata, err := p.discover()
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest(http.MethodGet, metadata.JwksUri, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	jwks := new(keyset.JWKS)
+	status, err := p.do(request, jwks)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("%w: jwks answered %d", ErrUnavailable, status)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		// Keys of a type we don't understand are skipped rather than
+		// failing the whole set.
+		key, err := jwk.PublicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.keysFetchedAt = p.now()
+	p.mu.Unlock()
+
+	return nil
+}
+
+// do sends the request and decodes the JSON body into target, whatever the
+// status code, so error bodies can be inspected by the caller.
+func (p *Provider) do(request *http.Request, target any) (int, error) {
+	response, err := p.client.Do(request)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer response.Body.Close()
+
+	err = json.NewDecoder(io.LimitReader(response.Body, maxResponseSize)).Decode(target)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid json from %s: %v", ErrUnavailable, request.URL.Path, err)
+	}
+
+	return response.StatusCode, nil
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"go-crud/internal/entity"
+	"gorm.io/gorm"
+	"time"
+)
+
+type ApiKeyRepositoryInterface interface {
+	Save(apiKey *entity.ApiKey) error
+	FindOneByHash(hash string) (*entity.ApiKey, error)
+	FindManyActiveByUserId(userID string) ([]entity.ApiKey, error)
+	Revoke(id string, userID string) (bool, error)
+	Touch(id string, lastUsedAt time.Time) error
+}
+
+type ApiKeyRepository struct {
+	Database *gorm.DB
+}
+
+func NewApiKeyRepository(database *gorm.DB) *ApiKeyRepository {
+	return &ApiKeyRepository{
+		Database: database,
+	}
+}
+
+func (r *ApiKeyRepository) Save(apiKey *entity.ApiKey) error {
+	err := r.Database.Create(apiKey).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *ApiKeyRepository) FindOneByHash(hash string) (*entity.ApiKey, error) {
+	var apiKey entity.ApiKey
+	err := r.Database.First(&apiKey, "key_hash = ?", hash).Error
+	if err != nil {
+		return nil, err
+	}
+	return &apiKey, nil
+}
+
+func (r *ApiKeyRepository) FindManyActiveByUserId(userID string) ([]entity.ApiKey, error) {
+	var apiKeys []entity.ApiKey
+	err := r.Database.Where("user_id = ? AND revoked_at IS NULL", userID).Order("created_at DESC").Find(&apiKeys).Error
+	if err != nil {
+		return nil, err
+	}
+	return apiKeys, nil
+}
+
+// Revoke only touches keys owned by userID and reports whether one was
+// revoked.
+func (r *ApiKeyRepository) Revoke(id string, userID string) (bool, error) {
+	result := r.Database.Model(&entity.ApiKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func (r *ApiKeyRepository) Touch(id string, lastUsedAt time.Time) error {
+	err := r.Database.Model(&entity.ApiKey{}).Where("id = ?", id).Update("last_used_at", lastUsedAt).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"go-crud/internal/entity"
+	"go-crud/internal/models"
+	"gorm.io/gorm"
+)
+
+// AuditLogRepositoryInterface has no way to update or delete entries, the
+// audit trail is append-only.
+type AuditLogRepositoryInterface interface {
+	Save(entry *entity.AuditLog) error
+	FindMany(entries *[]entity.AuditLog, filter *models.AuditLogFilter, offset int, limit int) error
+	Count(filter *models.AuditLogFilter) (int64, error)
+}
+
+type AuditLogRepository struct {
+	Database *gorm.DB
+}
+
+func NewAuditLogRepository(database *gorm.DB) *AuditLogRepository {
+	return &AuditLogRepository{
+		Database: database,
+	}
+}
+
+func (r *AuditLogRepository) Save(entry *entity.AuditLog) error {
+	err := r.Database.Create(entry).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *AuditLogRepository) FindMany(entries *[]entity.AuditLog, filter *models.AuditLogFilter, offset int, limit int) error {
+	err := r.filterEntries(filter).Order("created_at DESC").Order("id").Offset(offset).Limit(limit).Find(entries).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *AuditLogRepository) Count(filter *models.AuditLogFilter) (int64, error) {
+	var count int64
+	err := r.filterEntries(filter).Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *AuditLogRepository) filterEntries(filter *models.AuditLogFilter) *gorm.DB {
+	query := r.Database.Model(&entity.AuditLog{})
+	if filter.ActorId != "" {
+		query = query.Where("actor_id = ?", filter.ActorId)
+	}
+	if filter.TargetId != "" {
+		query = query.Where("target_id = ?", filter.TargetId)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.Outcome != "" {
+		query = query.Where("outcome = ?", filter.Outcome)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	return query
+}
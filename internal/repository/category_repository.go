@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"go-crud/internal/entity"
+	"gorm.io/gorm"
+	"time"
+	"unicode/utf8"
+)
+
+type CategoryRepositoryInterface interface {
+	Save(category *entity.Category) error
+	FindOneById(id string) (*entity.Category, error)
+	FindOneBySlug(slug string) (*entity.Category, error)
+	// FindAll lists every category ordered by path.
+	FindAll() ([]entity.Category, error)
+	// FindSubtree lists the category at path and everything below it,
+	// ordered by path.
+	FindSubtree(path string) ([]entity.Category, error)
+	FindManyByPaths(paths []string) ([]entity.Category, error)
+	// Update saves the category and moves its descendants along when its
+	// path changed from oldPath.
+	Update(category *entity.Category, oldPath string) error
+	DeleteById(id string) error
+	CountChildren(id string) (int64, error)
+}
+
+type CategoryRepository struct {
+	Database *gorm.DB
+}
+
+func NewCategoryRepository(database *gorm.DB) *CategoryRepository {
+	return &CategoryRepository{
+		Database: database,
+	}
+}
+
+func (r *CategoryRepository) Save(category *entity.Category) error {
+	err := r.Database.Create(category).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *CategoryRepository) FindOneById(id string) (*entity.Category, error) {
+	var category entity.Category
+	err := r.Database.First(&category, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+func (r *CategoryRepository) FindOneBySlug(slug string) (*entity.Category, error) {
+	var category entity.Category
+	err := r.Database.First(&category, "slug = ?", slug).Error
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+func (r *CategoryRepository) FindAll() ([]entity.Category, error) {
+	var categories []entity.Category
+	err := r.Database.Order("path").Find(&categories).Error
+	if err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+// FindSubtree needs no escaping in its LIKE pattern, slugs are only made of
+// letters, digits and dashes.
+func (r *CategoryRepository) FindSubtree(path string) ([]entity.Category, error) {
+	var categories []entity.Category
+	err := r.Database.Where("path = ? OR path LIKE ?", path, path+"/%").Order("path").Find(&categories).Error
+	if err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+func (r *CategoryRepository) FindManyByPaths(paths []string) ([]entity.Category, error) {
+	categories := []entity.Category{}
+	if len(paths) == 0 {
+		return categories, nil
+	}
+	err := r.Database.Where("path IN ?", paths).Order("path").Find(&categories).Error
+	if err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+func (r *CategoryRepository) Update(category *entity.Category, oldPath string) error {
+	return r.Database.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&entity.Category{}).Where("id = ?", category.Id).Updates(map[string]any{
+			"parent_id":  category.ParentId,
+			"name":       category.Name,
+			"slug":       category.Slug,
+			"path":       category.Path,
+			"updated_at": time.Now(),
+		}).Error
+		if err != nil {
+			return err
+		}
+		if category.Path == oldPath {
+			return nil
+		}
+
+		// SUBSTRING counts characters from 1, so this keeps what follows
+		// the old path, starting with its slash.
+		return tx.Model(&entity.Category{}).Where("path LIKE ?", oldPath+"/%").
+			Update("path", gorm.Expr("CONCAT(?, SUBSTRING(path, ?))", category.Path, utf8.RuneCountInString(oldPath)+1)).Error
+	})
+}
+
+// DeleteById removes the category. Its products stay, without a category,
+// through ON DELETE SET NULL.
+func (r *CategoryRepository) DeleteById(id string) error {
+	err := r.Database.Delete(&entity.Category{}, "id = ?", id).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *CategoryRepository) CountChildren(id string) (int64, error) {
+	var count int64
+	err := r.Database.Model(&entity.Category{}).Where("parent_id = ?", id).Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
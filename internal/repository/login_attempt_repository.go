@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"errors"
+	"go-crud/internal/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sync"
+	"time"
+)
+
+type LoginAttemptRepositoryInterface interface {
+	// FindOneByKey returns nil without an error when the key has no failures.
+	FindOneByKey(key string) (*entity.LoginAttempt, error)
+	// Increment records a failure and returns the updated counter. Failures
+	// older than window are forgotten.
+	Increment(key string, now time.Time, window time.Duration) (*entity.LoginAttempt, error)
+	Lock(key string, until time.Time) error
+	Reset(key string) error
+}
+
+type LoginAttemptRepository struct {
+	Database *gorm.DB
+}
+
+func NewLoginAttemptRepository(database *gorm.DB) *LoginAttemptRepository {
+	return &LoginAttemptRepository{
+		Database: database,
+	}
+}
+
+func (r *LoginAttemptRepository) FindOneByKey(key string) (*entity.LoginAttempt, error) {
+	var attempt entity.LoginAttempt
+	err := r.Database.First(&attempt, "attempt_key = ?", key).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// Increment upserts in one statement so concurrent failures can't be lost.
+func (r *LoginAttemptRepository) Increment(key string, now time.Time, window time.Duration) (*entity.LoginAttempt, error) {
+	attempt := &entity.LoginAttempt{Key: key, Failures: 1, LastFailedAt: now}
+	err := r.Database.Clauses(clause.OnConflict{
+		DoUpdates: clause.Set{
+			{Column: clause.Column{Name: "failures"}, Value: gorm.Expr("IF(last_failed_at < ?, 1, failures + 1)", now.Add(-window))},
+			{Column: clause.Column{Name: "last_failed_at"}, Value: now},
+		},
+	}).Create(attempt).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindOneByKey(key)
+}
+
+func (r *LoginAttemptRepository) Lock(key string, until time.Time) error {
+	err := r.Database.Model(&entity.LoginAttempt{}).Where("attempt_key = ?", key).Update("locked_until", until).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *LoginAttemptRepository) Reset(key string) error {
+	err := r.Database.Delete(&entity.LoginAttempt{}, "attempt_key = ?", key).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// MemoryLoginAttemptRepository keeps the counters in the process. It suits
+// tests and single instance deployments, counters are lost on restart.
+type MemoryLoginAttemptRepository struct {
+	mutex    sync.Mutex
+	attempts map[string]entity.LoginAttempt
+}
+
+func NewMemoryLoginAttemptRepository() *MemoryLoginAttemptRepository {
+	return &MemoryLoginAttemptRepository{
+		attempts: make(map[string]entity.LoginAttempt),
+	}
+}
+
+func (r *MemoryLoginAttemptRepository) FindOneByKey(key string) (*entity.LoginAttempt, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	attempt, ok := r.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+	return &attempt, nil
+}
+
+func (r *MemoryLoginAttemptRepository) Increment(key string, now time.Time, window time.Duration) (*entity.LoginAttempt, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	attempt, ok := r.attempts[key]
+	if !ok || attempt.LastFailedAt.Before(now.Add(-window)) {
+		attempt = entity.LoginAttempt{Key: key, LockedUntil: attempt.LockedUntil}
+	}
+	attempt.Failures++
+	attempt.LastFailedAt = now
+	r.attempts[key] = attempt
+
+	return &attempt, nil
+}
+
+func (r *MemoryLoginAttemptRepository) Lock(key string, until time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	attempt := r.attempts[key]
+	attempt.Key = key
+	attempt.LockedUntil = &until
+	r.attempts[key] = attempt
+	return nil
+}
+
+func (r *MemoryLoginAttemptRepository) Reset(key string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.attempts, key)
+	return nil
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"go-crud/internal/entity"
+	"gorm.io/gorm"
+	"time"
+)
+
+type OAuthAuthorizationCodeRepositoryInterface interface {
+	Save(code *entity.OAuthAuthorizationCode) error
+	FindOneByHash(hash string) (*entity.OAuthAuthorizationCode, error)
+	MarkAsUsed(id string) (bool, error)
+}
+
+type OAuthAuthorizationCodeRepository struct {
+	Database *gorm.DB
+}
+
+func NewOAuthAuthorizationCodeRepository(database *gorm.DB) *OAuthAuthorizationCodeRepository {
+	return &OAuthAuthorizationCodeRepository{
+		Database: database,
+	}
+}
+
+func (r *OAuthAuthorizationCodeRepository) Save(code *entity.OAuthAuthorizationCode) error {
+	err := r.Database.Create(code).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *OAuthAuthorizationCodeRepository) FindOneByHash(hash string) (*entity.OAuthAuthorizationCode, error) {
+	var code entity.OAuthAuthorizationCode
+	err := r.Database.First(&code, "code_hash = ?", hash).Error
+	if err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
+
+// MarkAsUsed consumes the code. It reports false when the code was already
+// exchanged, so two concurrent requests can't both get tokens for it.
+func (r *OAuthAuthorizationCodeRepository) MarkAsUsed(id string) (bool, error) {
+	result := r.Database.Model(&entity.OAuthAuthorizationCode{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"go-crud/internal/entity"
+	"gorm.io/gorm"
+	"time"
+)
+
+type OAuthClientRepositoryInterface interface {
+	Save(client *entity.OAuthClient) error
+	FindOneById(id string) (*entity.OAuthClient, error)
+	FindManyActiveByUserId(userID string) ([]entity.OAuthClient, error)
+	Revoke(id string, userID string) (bool, error)
+}
+
+type OAuthClientRepository struct {
+	Database *gorm.DB
+}
+
+func NewOAuthClientRepository(database *gorm.DB) *OAuthClientRepository {
+	return &OAuthClientRepository{
+		Database: database,
+	}
+}
+
+func (r *OAuthClientRepository) Save(client *entity.OAuthClient) error {
+	err := r.Database.Create(client).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *OAuthClientRepository) FindOneById(id string) (*entity.OAuthClient, error) {
+	var client entity.OAuthClient
+	err := r.Database.First(&client, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+func (r *OAuthClientRepository) FindManyActiveByUserId(userID string) ([]entity.OAuthClient, error) {
+	var clients []entity.OAuthClient
+	err := r.Database.Where("user_id = ? AND revoked_at IS NULL", userID).Order("created_at DESC").Find(&clients).Error
+	if err != nil {
+		return nil, err
+	}
+	return clients, nil
+}
+
+// Revoke only touches clients owned by userID and reports whether one was
+// revoked.
+func (r *OAuthClientRepository) Revoke(id string, userID string) (bool, error) {
+	result := r.Database.Model(&entity.OAuthClient{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"go-crud/internal/entity"
+	"gorm.io/gorm"
+	"time"
+)
+
+type OrganizationInvitationRepositoryInterface interface {
+	Save(invitation *entity.OrganizationInvitation) error
+	FindOneByHash(hash string) (*entity.OrganizationInvitation, error)
+	FindManyPendingByOrganizationId(organizationID string, now time.Time) ([]entity.OrganizationInvitation, error)
+	MarkAsAccepted(id string) (bool, error)
+	Delete(id string, organizationID string) (bool, error)
+}
+
+type OrganizationInvitationRepository struct {
+	Database *gorm.DB
+}
+
+func NewOrganizationInvitationRepository(database *gorm.DB) *OrganizationInvitationRepository {
+	return &OrganizationInvitationRepository{
+		Database: database,
+	}
+}
+
+func (r *OrganizationInvitationRepository) Save(invitation *entity.OrganizationInvitation) error {
+	err := r.Database.Create(invitation).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *OrganizationInvitationRepository) FindOneByHash(hash string) (*entity.OrganizationInvitation, error) {
+	var invitation entity.OrganizationInvitation
+	err := r.Database.First(&invitation, "token_hash = ?", hash).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+func (r *OrganizationInvitationRepository) FindManyPendingByOrganizationId(organizationID string, now time.Time) ([]entity.OrganizationInvitation, error) {
+	var invitations []entity.OrganizationInvitation
+	err := r.Database.
+		Where("organization_id = ? AND accepted_at IS NULL AND expires_at > ?", organizationID, now).
+		Order("created_at DESC").
+		Find(&invitations).Error
+	if err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+// MarkAsAccepted consumes the invitation. It reports false when it was
+// already accepted, so an invitation can't be redeemed twice.
+func (r *OrganizationInvitationRepository) MarkAsAccepted(id string) (bool, error) {
+	result := r.Database.Model(&entity.OrganizationInvitation{}).
+		Where("id = ? AND accepted_at IS NULL", id).
+		Update("accepted_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// Delete revokes a pending invitation of the organization. It reports false
+// when there is none with this id.
+func (r *OrganizationInvitationRepository) Delete(id string, organizationID string) (bool, error) {
+	result := r.Database.Delete(&entity.OrganizationInvitation{}, "id = ? AND organization_id = ? AND accepted_at IS NULL", id, organizationID)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
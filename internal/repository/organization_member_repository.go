@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"go-crud/internal/entity"
+	"gorm.io/gorm"
+)
+
+type OrganizationMemberRepositoryInterface interface {
+	Save(member *entity.OrganizationMember) error
+	FindOne(organizationID string, userID string) (*entity.OrganizationMember, error)
+	FindManyByUserId(userID string) ([]entity.OrganizationMember, error)
+	FindManyByOrganizationId(organizationID string) ([]entity.OrganizationMember, error)
+	UpdateRole(organizationID string, userID string, role string) error
+	Delete(organizationID string, userID string) error
+	CountByRole(organizationID string, role string) (int64, error)
+	CountSoleByRole(userID string, role string) (int64, error)
+}
+
+type OrganizationMemberRepository struct {
+	Database *gorm.DB
+}
+
+func NewOrganizationMemberRepository(database *gorm.DB) *OrganizationMemberRepository {
+	return &OrganizationMemberRepository{
+		Database: database,
+	}
+}
+
+func (r *OrganizationMemberRepository) Save(member *entity.OrganizationMember) error {
+	err := r.Database.Omit("Organization", "User").Create(member).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *OrganizationMemberRepository) FindOne(organizationID string, userID string) (*entity.OrganizationMember, error) {
+	var member entity.OrganizationMember
+	err := r.Database.InnerJoins("Organization").
+		First(&member, "organization_members.organization_id = ? AND organization_members.user_id = ?", organizationID, userID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// FindManyByUserId lists the organizations the user belongs to.
+func (r *OrganizationMemberRepository) FindManyByUserId(userID string) ([]entity.OrganizationMember, error) {
+	var members []entity.OrganizationMember
+	err := r.Database.InnerJoins("Organization").
+		Where("organization_members.user_id = ?", userID).
+		Order("organization_members.created_at").
+		Find(&members).Error
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (r *OrganizationMemberRepository) FindManyByOrganizationId(organizationID string) ([]entity.OrganizationMember, error) {
+	var members []entity.OrganizationMember
+	err := r.Database.InnerJoins("User").
+		Where("organization_members.organization_id = ?", organizationID).
+		Order("organization_members.created_at").
+		Find(&members).Error
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (r *OrganizationMemberRepository) UpdateRole(organizationID string, userID string, role string) error {
+	err := r.Database.Model(&entity.OrganizationMember{}).
+		Where("organization_id = ? AND user_id = ?", organizationID, userID).
+		Update("role", role).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *OrganizationMemberRepository) Delete(organizationID string, userID string) error {
+	err := r.Database.Delete(&entity.OrganizationMember{}, "organization_id = ? AND user_id = ?", organizationID, userID).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *OrganizationMemberRepository) CountByRole(organizationID string, role string) (int64, error) {
+	var count int64
+	err := r.Database.Model(&entity.OrganizationMember{}).
+		Where("organization_id = ? AND role = ?", organizationID, role).
+		Count(&count).Error
+	if err != nil {
+		return -1, err
+	}
+	return count, nil
+}
+
+// CountSoleByRole counts the organizations where the user is the only member
+// with the role.
+func (r *OrganizationMemberRepository) CountSoleByRole(userID string, role string) (int64, error) {
+	var count int64
+	err := r.Database.Model(&entity.OrganizationMember{}).
+		Where("user_id = ? AND role = ?", userID, role).
+		Where("NOT EXISTS (SELECT 1 FROM organization_members others WHERE others.organization_id = organization_members.organization_id AND others.role = ? AND others.user_id <> organization_members.user_id)", role).
+		Count(&count).Error
+	if err != nil {
+		return -1, err
+	}
+	return count, nil
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"go-crud/internal/entity"
+	"gorm.io/gorm"
+)
+
+type OrganizationRepositoryInterface interface {
+	Create(organization *entity.Organization, owner *entity.OrganizationMember) error
+	FindOneById(id string) (*entity.Organization, error)
+	DeleteById(id string) error
+}
+
+type OrganizationRepository struct {
+	Database *gorm.DB
+}
+
+func NewOrganizationRepository(database *gorm.DB) *OrganizationRepository {
+	return &OrganizationRepository{
+		Database: database,
+	}
+}
+
+// Create saves the organization together with its first owner, so there is
+// never an organization nobody can manage.
+func (r *OrganizationRepository) Create(organization *entity.Organization, owner *entity.OrganizationMember) error {
+	return r.Database.Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(organization).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Omit("Organization", "User").Create(owner).Error
+	})
+}
+
+func (r *OrganizationRepository) FindOneById(id string) (*entity.Organization, error) {
+	var organization entity.Organization
+	err := r.Database.First(&organization, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &organization, nil
+}
+
+// DeleteById removes the organization. Its members, invitations and products
+// go with it through ON DELETE CASCADE.
+func (r *OrganizationRepository) DeleteById(id string) error {
+	err := r.Database.Delete(&entity.Organization{}, "id = ?", id).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
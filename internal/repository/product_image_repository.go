@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"go-crud/internal/entity"
+	"gorm.io/gorm"
+)
+
+type ProductImageRepositoryInterface interface {
+	Save(image *entity.ProductImage) error
+	FindOne(productID string, imageID string) (*entity.ProductImage, error)
+	// FindManyByProductId lists the images of a product, primary first.
+	FindManyByProductId(productID string) ([]entity.ProductImage, error)
+	FindManyByProductIds(productIDs []string) ([]entity.ProductImage, error)
+	// UpdatePositions orders the images of a product as imageIDs.
+	UpdatePositions(productID string, imageIDs []string) error
+	DeleteById(imageID string) error
+}
+
+type ProductImageRepository struct {
+	Database *gorm.DB
+}
+
+func NewProductImageRepository(database *gorm.DB) *ProductImageRepository {
+	return &ProductImageRepository{
+		Database: database,
+	}
+}
+
+func (r *ProductImageRepository) Save(image *entity.ProductImage) error {
+	err := r.Database.Create(image).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *ProductImageRepository) FindOne(productID string, imageID string) (*entity.ProductImage, error) {
+	var image entity.ProductImage
+	err := r.Database.First(&image, "product_id = ? AND id = ?", productID, imageID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &image, nil
+}
+
+func (r *ProductImageRepository) FindManyByProductId(productID string) ([]entity.ProductImage, error) {
+	images := []entity.ProductImage{}
+	err := r.Database.Where("product_id = ?", productID).Order("position").Order("created_at").Find(&images).Error
+	if err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+func (r *ProductImageRepository) FindManyByProductIds(productIDs []string) ([]entity.ProductImage, error) {
+	images := []entity.ProductImage{}
+	err := r.Database.Where("product_id IN ?", productIDs).Find(&images).Error
+	if err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+func (r *ProductImageRepository) UpdatePositions(productID string, imageIDs []string) error {
+	return r.Database.Transaction(func(tx *gorm.DB) error {
+		for position, imageID := range imageIDs {
+			err := tx.Model(&entity.ProductImage{}).Where("product_id = ? AND id = ?", productID, imageID).Update("position", position).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *ProductImageRepository) DeleteById(imageID string) error {
+	err := r.Database.Delete(&entity.ProductImage{}, "id = ?", imageID).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -1,17 +1,30 @@
 package repository
 
 import (
+	"fmt"
 	"go-crud/internal/entity"
+	"go-crud/internal/models"
 	"gorm.io/gorm"
+	"strings"
 )
 
 type ProductRepositoryInterface interface {
 	Save(product *entity.Product) error
 	FindOneById(product *entity.Product, id string) error
-	FindMany(products *[]entity.Product, offset int, limit int) error
+	FindMany(products *[]entity.Product, filter *models.ProductFilter, offset int, limit int) error
+	FindManyByIds(products *[]entity.Product, ids []string) error
 	UpdateById(product entity.Product, productID string) (*entity.Product, error)
+	// UpdateCategoryById moves the product to a category, or out of any when
+	// categoryID is nil.
+	UpdateCategoryById(productID string, categoryID *string) error
+	ReplaceTags(productID string, tags []entity.Tag) error
 	DeleteById(productID string) error
-	Count() (int64, error)
+	Count(filter *models.ProductFilter) (int64, error)
+	CountByUserId(userID string) (int64, error)
+	// FindPersonalIds lists the products of the user that don't belong to an
+	// organization.
+	FindPersonalIds(userID string) ([]string, error)
+	FindIdsByOrganizationId(organizationID string) ([]string, error)
 }
 
 type ProductRepository struct {
@@ -24,30 +37,112 @@ func NewProductRepository(database *gorm.DB) *ProductRepository {
 	}
 }
 
+// Save links the product to its tags, the tags themselves are left as they
+// are.
 func (r *ProductRepository) Save(product *entity.Product) error {
-	err := r.Database.Create(product).Error
+	err := r.Database.Omit("Tags.*").Create(product).Error
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// FindOneById loads the product with its creator, category, tags and
+// images. Products of an organization outlive their creator, so the user is
+// left joined.
 func (r *ProductRepository) FindOneById(product *entity.Product, id string) error {
-	err := r.Database.InnerJoins("User").First(product, r.Database.Where("product.id = ?", id)).Error
+	err := r.withRelations(r.Database).First(product, r.Database.Where("product.id = ?", id)).Error
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (r *ProductRepository) FindMany(products *[]entity.Product, offset int, limit int) error {
-	err := r.Database.InnerJoins("User").Limit(limit).Offset(offset).Find(products).Order("created_at DESC").Error
+// productColumns maps the fields products are filtered and sorted by to their
+// columns. Only these names ever reach the query, so they are safe to format
+// into it.
+var productColumns = map[string]string{
+	"name":       "product.name",
+	"price":      "product.price",
+	"stock":      "product.stock",
+	"owner":      "product.user_id",
+	"created_at": "product.created_at",
+	"id":         "product.id",
+}
+
+var productOperators = map[string]string{
+	"eq":  "=",
+	"gt":  ">",
+	"gte": ">=",
+	"lt":  "<",
+	"lte": "<=",
+}
+
+// FindMany lists the products matching filter in its order, with the id
+// breaking ties so pages never overlap. With a cursor, the products next to it
+// are listed, still in the order of the list.
+func (r *ProductRepository) FindMany(products *[]entity.Product, filter *models.ProductFilter, offset int, limit int) error {
+	order := append(append([]models.ProductSort{}, filter.SortOrder()...), models.ProductSort{Field: "id"})
+	backward := filter.Cursor != nil && filter.Cursor.Backward
+
+	query := r.withRelations(r.filterProducts(filter))
+	if filter.Cursor != nil {
+		condition, args := keysetCondition(order, filter.Cursor)
+		query = query.Where(condition, args...)
+	}
+	for _, sort := range order {
+		direction := "ASC"
+		if sort.Descending != backward {
+			direction = "DESC"
+		}
+		query = query.Order(fmt.Sprintf("%s %s", productColumns[sort.Field], direction))
+	}
+
+	err := query.Offset(offset).Limit(limit).Find(products).Error
+	if err != nil {
+		return err
+	}
+	if backward {
+		for i, j := 0, len(*products)-1; i < j; i, j = i+1, j-1 {
+			(*products)[i], (*products)[j] = (*products)[j], (*products)[i]
+		}
+	}
+	return nil
+}
+
+// FindManyByIds loads the products with their relations, in no particular
+// order.
+func (r *ProductRepository) FindManyByIds(products *[]entity.Product, ids []string) error {
+	err := r.withRelations(r.Database).Where("product.id IN ?", ids).Find(products).Error
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// keysetCondition matches the products past cursor in order, such as
+// (price < ?) OR (price = ? AND id > ?) for -price.
+func keysetCondition(order []models.ProductSort, cursor *models.ProductCursor) (string, []any) {
+	values := append(append([]any{}, cursor.Values...), cursor.Id)
+	clauses := make([]string, len(order))
+	var args []any
+	for index, sort := range order {
+		var parts []string
+		for _, previous := range order[:index] {
+			parts = append(parts, fmt.Sprintf("%s = ?", productColumns[previous.Field]))
+		}
+		operator := ">"
+		if sort.Descending != cursor.Backward {
+			operator = "<"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s ?", productColumns[sort.Field], operator))
+		clauses[index] = "(" + strings.Join(parts, " AND ") + ")"
+		args = append(args, values[:index+1]...)
+	}
+
+	return strings.Join(clauses, " OR "), args
+}
+
 func (r *ProductRepository) UpdateById(product entity.Product, productID string) (*entity.Product, error) {
 	model := new(entity.Product)
 	err := r.FindOneById(model, productID)
@@ -62,6 +157,22 @@ func (r *ProductRepository) UpdateById(product entity.Product, productID string)
 	return model, nil
 }
 
+func (r *ProductRepository) UpdateCategoryById(productID string, categoryID *string) error {
+	err := r.Database.Model(&entity.Product{}).Where("id = ?", productID).Update("category_id", categoryID).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *ProductRepository) ReplaceTags(productID string, tags []entity.Tag) error {
+	err := r.Database.Model(&entity.Product{Id: productID}).Omit("Tags.*").Association("Tags").Replace(tags)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
 func (r *ProductRepository) DeleteById(productID string) error {
 	err := r.Database.Delete(&entity.Product{}, "id = ?", productID).Error
 	if err != nil {
@@ -71,12 +182,72 @@ func (r *ProductRepository) DeleteById(productID string) error {
 	return nil
 }
 
-func (r *ProductRepository) Count() (int64, error) {
+func (r *ProductRepository) Count(filter *models.ProductFilter) (int64, error) {
+	var count int64
+	err := r.filterProducts(filter).Count(&count).Error
+	if err != nil {
+		return -1, err
+	}
+
+	return count, nil
+}
+
+func (r *ProductRepository) CountByUserId(userID string) (int64, error) {
 	var count int64
-	err := r.Database.Model(&entity.Product{}).Count(&count).Error
+	err := r.Database.Model(&entity.Product{}).Where("user_id = ?", userID).Count(&count).Error
 	if err != nil {
 		return -1, err
 	}
 
 	return count, nil
 }
+
+func (r *ProductRepository) FindPersonalIds(userID string) ([]string, error) {
+	var ids []string
+	err := r.Database.Model(&entity.Product{}).Where("user_id = ? AND organization_id IS NULL", userID).Pluck("id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (r *ProductRepository) FindIdsByOrganizationId(organizationID string) ([]string, error) {
+	var ids []string
+	err := r.Database.Model(&entity.Product{}).Where("organization_id = ?", organizationID).Pluck("id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (r *ProductRepository) withRelations(query *gorm.DB) *gorm.DB {
+	return query.Joins("User").Joins("Category").Preload("Tags", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("tags.name")
+	}).Preload("Images", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("product_images.position").Order("product_images.created_at")
+	})
+}
+
+// filterProducts needs no escaping in the category path pattern, slugs are
+// only made of letters, digits and dashes.
+func (r *ProductRepository) filterProducts(filter *models.ProductFilter) *gorm.DB {
+	query := r.Database.Model(&entity.Product{})
+	for _, condition := range filter.Conditions {
+		switch {
+		case condition.Field == "category" && condition.Operator == "descendants":
+			query = query.Where("product.category_id IN (SELECT descendant.id FROM categories descendant JOIN categories root ON root.slug = ? WHERE descendant.path = root.path OR descendant.path LIKE CONCAT(root.path, '/%'))", condition.Value)
+		case condition.Field == "category":
+			query = query.Where("product.category_id IN (SELECT id FROM categories WHERE slug = ?)", condition.Value)
+		case condition.Field == "tag":
+			query = query.Where("product.id IN (SELECT product_tags.product_id FROM product_tags JOIN tags ON tags.id = product_tags.tag_id WHERE tags.slug = ?)", condition.Value)
+		case condition.Operator == "contains":
+			query = query.Where(fmt.Sprintf("%s LIKE ?", productColumns[condition.Field]), "%"+escapeLike(fmt.Sprint(condition.Value))+"%")
+		default:
+			query = query.Where(fmt.Sprintf("%s %s ?", productColumns[condition.Field], productOperators[condition.Operator]), condition.Value)
+		}
+	}
+
+	return query
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"go-crud/internal/entity"
+	"go-crud/internal/helper"
+	"go-crud/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ProductSearchRepositoryInterface finds products by the words of their name.
+// Terms match words exactly, as a prefix or with a typo, and every term has to
+// match. Hits are ranked best first, with the id breaking ties.
+type ProductSearchRepositoryInterface interface {
+	// Index adds or replaces a product in the index.
+	Index(product *entity.Product) error
+	Remove(productID string) error
+	Search(terms []string, offset int, limit int) ([]models.ProductSearchHit, int64, error)
+}
+
+// productSearchCandidates is how many products the database hands over for
+// ranking, more are only fetched to reach a later page.
+const productSearchCandidates = 1000
+
+// ProductSearchRepository searches the FULLTEXT index of the product table.
+// The index is built with the ngram parser, so prefixes and misspelled words
+// still share most of their ngrams with the name. Candidates are then ranked
+// like MemoryProductSearchRepository does.
+type ProductSearchRepository struct {
+	Database *gorm.DB
+}
+
+func NewProductSearchRepository(database *gorm.DB) *ProductSearchRepository {
+	return &ProductSearchRepository{
+		Database: database,
+	}
+}
+
+// Index does nothing, MySQL keeps the FULLTEXT index up to date.
+func (r *ProductSearchRepository) Index(product *entity.Product) error {
+	return nil
+}
+
+// Remove does nothing, MySQL keeps the FULLTEXT index up to date.
+func (r *ProductSearchRepository) Remove(productID string) error {
+	return nil
+}
+
+// Search ranks the best candidates of the index. When there are more than
+// it fetched, the total adds the unranked ones MySQL counts as matching.
+func (r *ProductSearchRepository) Search(terms []string, offset int, limit int) ([]models.ProductSearchHit, int64, error) {
+	query := strings.Join(terms, " ")
+	window := productSearchCandidates
+	if offset+limit > window {
+		window = offset + limit
+	}
+
+	var candidates []entity.Product
+	err := r.Database.Model(&entity.Product{}).Select("id", "name").
+		Where("MATCH(name) AGAINST(? IN NATURAL LANGUAGE MODE)", query).
+		Clauses(clause.OrderBy{Expression: clause.Expr{SQL: "MATCH(name) AGAINST(? IN NATURAL LANGUAGE MODE) DESC", Vars: []any{query}, WithoutParentheses: true}}).
+		Limit(window).Find(&candidates).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	names := make(map[string]string, len(candidates))
+	for _, candidate := range candidates {
+		names[candidate.Id] = candidate.Name
+	}
+	hits := rankProducts(terms, names)
+	total := int64(len(hits))
+	if len(candidates) == window {
+		var matched int64
+		err = r.Database.Model(&entity.Product{}).
+			Where("MATCH(name) AGAINST(? IN NATURAL LANGUAGE MODE)", query).
+			Count(&matched).Error
+		if err != nil {
+			return nil, 0, err
+		}
+		total += matched - int64(len(candidates))
+	}
+	return pageHits(hits, offset, limit), total, nil
+}
+
+// MemoryProductSearchRepository is an inverted index kept in the process. It
+// suits tests, SQLite and single instance deployments, and has to be filled
+// with Index on start.
+type MemoryProductSearchRepository struct {
+	mutex sync.RWMutex
+	names map[string]string
+	// postings lists the ids of the products having each word.
+	postings map[string]map[string]bool
+}
+
+func NewMemoryProductSearchRepository() *MemoryProductSearchRepository {
+	return &MemoryProductSearchRepository{
+		names:    make(map[string]string),
+		postings: make(map[string]map[string]bool),
+	}
+}
+
+func (r *MemoryProductSearchRepository) Index(product *entity.Product) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.remove(product.Id)
+	r.names[product.Id] = product.Name
+	for _, word := range helper.SearchTerms(product.Name) {
+		if r.postings[word] == nil {
+			r.postings[word] = make(map[string]bool)
+		}
+		r.postings[word][product.Id] = true
+	}
+	return nil
+}
+
+func (r *MemoryProductSearchRepository) Remove(productID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.remove(productID)
+	return nil
+}
+
+func (r *MemoryProductSearchRepository) remove(productID string) {
+	name, ok := r.names[productID]
+	if !ok {
+		return
+	}
+	for _, word := range helper.SearchTerms(name) {
+		delete(r.postings[word], productID)
+		if len(r.postings[word]) == 0 {
+			delete(r.postings, word)
+		}
+	}
+	delete(r.names, productID)
+}
+
+// Search looks the terms up in the vocabulary of the index, and ranks the
+// products having a matching word for every term.
+func (r *MemoryProductSearchRepository) Search(terms []string, offset int, limit int) ([]models.ProductSearchHit, int64, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var candidates map[string]bool
+	for _, term := range terms {
+		matching := make(map[string]bool)
+		for word, products := range r.postings {
+			if helper.MatchSearchTerm(term, word) == 0 {
+				continue
+			}
+			for productID := range products {
+				if candidates == nil || candidates[productID] {
+					matching[productID] = true
+				}
+			}
+		}
+		candidates = matching
+	}
+
+	names := make(map[string]string, len(candidates))
+	for productID := range candidates {
+		names[productID] = r.names[productID]
+	}
+	hits := rankProducts(terms, names)
+	return pageHits(hits, offset, limit), int64(len(hits)), nil
+}
+
+// rankProducts scores the names of products against terms, best first.
+func rankProducts(terms []string, names map[string]string) []models.ProductSearchHit {
+	hits := make([]models.ProductSearchHit, 0, len(names))
+	for productID, name := range names {
+		score := helper.ScoreSearch(terms, name)
+		if score > 0 {
+			hits = append(hits, models.ProductSearchHit{ProductId: productID, Score: score})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ProductId < hits[j].ProductId
+	})
+	return hits
+}
+
+func pageHits(hits []models.ProductSearchHit, offset int, limit int) []models.ProductSearchHit {
+	if offset >= len(hits) {
+		return []models.ProductSearchHit{}
+	}
+	end := offset + limit
+	if end > len(hits) {
+		end = len(hits)
+	}
+	return hits[offset:end]
+}
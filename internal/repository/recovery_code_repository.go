@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"go-crud/internal/entity"
+	"gorm.io/gorm"
+	"time"
+)
+
+type RecoveryCodeRepositoryInterface interface {
+	ReplaceAll(userID string, codes []entity.RecoveryCode) error
+	FindOneUnused(userID string, hash string) (*entity.RecoveryCode, error)
+	MarkAsUsed(id string) (bool, error)
+	DeleteByUserId(userID string) error
+}
+
+type RecoveryCodeRepository struct {
+	Database *gorm.DB
+}
+
+func NewRecoveryCodeRepository(database *gorm.DB) *RecoveryCodeRepository {
+	return &RecoveryCodeRepository{
+		Database: database,
+	}
+}
+
+// ReplaceAll swaps the user's recovery codes for a new set in one
+// transaction, so the old codes never outlive the new ones.
+func (r *RecoveryCodeRepository) ReplaceAll(userID string, codes []entity.RecoveryCode) error {
+	return r.Database.Transaction(func(tx *gorm.DB) error {
+		err := tx.Delete(&entity.RecoveryCode{}, "user_id = ?", userID).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *RecoveryCodeRepository) FindOneUnused(userID string, hash string) (*entity.RecoveryCode, error) {
+	var code entity.RecoveryCode
+	err := r.Database.First(&code, "user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).Error
+	if err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
+
+func (r *RecoveryCodeRepository) MarkAsUsed(id string) (bool, error) {
+	result := r.Database.Model(&entity.RecoveryCode{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func (r *RecoveryCodeRepository) DeleteByUserId(userID string) error {
+	err := r.Database.Delete(&entity.RecoveryCode{}, "user_id = ?", userID).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
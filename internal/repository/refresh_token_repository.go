@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"go-crud/internal/entity"
+	"gorm.io/gorm"
+	"time"
+)
+
+type RefreshTokenRepositoryInterface interface {
+	Save(token *entity.RefreshToken) error
+	FindOneByHash(hash string) (*entity.RefreshToken, error)
+	MarkAsUsed(id string) (bool, error)
+	RevokeFamily(familyID string) error
+	RevokeAllByUserId(userID string) error
+	RevokeAllByUserIdExcept(userID string, familyID string) error
+}
+
+type RefreshTokenRepository struct {
+	Database *gorm.DB
+}
+
+func NewRefreshTokenRepository(database *gorm.DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{
+		Database: database,
+	}
+}
+
+func (r *RefreshTokenRepository) Save(token *entity.RefreshToken) error {
+	err := r.Database.Create(token).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *RefreshTokenRepository) FindOneByHash(hash string) (*entity.RefreshToken, error) {
+	var token entity.RefreshToken
+	err := r.Database.First(&token, "token_hash = ?", hash).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkAsUsed flags the token as consumed. It reports false when the token was
+// already used, so two concurrent rotations of the same token can't both win.
+func (r *RefreshTokenRepository) MarkAsUsed(id string) (bool, error) {
+	result := r.Database.Model(&entity.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func (r *RefreshTokenRepository) RevokeFamily(familyID string) error {
+	err := r.Database.Model(&entity.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *RefreshTokenRepository) RevokeAllByUserId(userID string) error {
+	err := r.Database.Model(&entity.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *RefreshTokenRepository) RevokeAllByUserIdExcept(userID string, familyID string) error {
+	err := r.Database.Model(&entity.RefreshToken{}).
+		Where("user_id = ? AND family_id <> ? AND revoked_at IS NULL", userID, familyID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"go-crud/internal/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type RevokedAccessTokenRepositoryInterface interface {
+	Save(token *entity.RevokedAccessToken) error
+	Exists(jti string) (bool, error)
+	DeleteExpired(now time.Time) error
+}
+
+type RevokedAccessTokenRepository struct {
+	Database *gorm.DB
+}
+
+func NewRevokedAccessTokenRepository(database *gorm.DB) *RevokedAccessTokenRepository {
+	return &RevokedAccessTokenRepository{
+		Database: database,
+	}
+}
+
+// Save ignores a token that is already on the list, revoking is idempotent.
+func (r *RevokedAccessTokenRepository) Save(token *entity.RevokedAccessToken) error {
+	err := r.Database.Clauses(clause.OnConflict{DoNothing: true}).Create(token).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *RevokedAccessTokenRepository) Exists(jti string) (bool, error) {
+	var count int64
+	err := r.Database.Model(&entity.RevokedAccessToken{}).Where("jti = ?", jti).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *RevokedAccessTokenRepository) DeleteExpired(now time.Time) error {
+	err := r.Database.Where("expires_at < ?", now).Delete(&entity.RevokedAccessToken{}).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
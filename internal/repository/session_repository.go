@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"go-crud/internal/entity"
+	"gorm.io/gorm"
+	"time"
+)
+
+type SessionRepositoryInterface interface {
+	Save(session *entity.Session) error
+	FindOneById(id string) (*entity.Session, error)
+	FindManyActiveByUserId(userID string) ([]entity.Session, error)
+	Touch(id string, lastSeenAt time.Time) error
+	Revoke(id string) error
+	RevokeAllByUserId(userID string) error
+	RevokeAllByUserIdExcept(userID string, sessionID string) error
+	RevokeAllByClientId(clientID string) error
+}
+
+type SessionRepository struct {
+	Database *gorm.DB
+}
+
+func NewSessionRepository(database *gorm.DB) *SessionRepository {
+	return &SessionRepository{
+		Database: database,
+	}
+}
+
+func (r *SessionRepository) Save(session *entity.Session) error {
+	err := r.Database.Create(session).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *SessionRepository) FindOneById(id string) (*entity.Session, error) {
+	var session entity.Session
+	err := r.Database.First(&session, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *SessionRepository) FindManyActiveByUserId(userID string) ([]entity.Session, error) {
+	var sessions []entity.Session
+	err := r.Database.Where("user_id = ? AND revoked_at IS NULL", userID).Order("last_seen_at DESC").Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (r *SessionRepository) Touch(id string, lastSeenAt time.Time) error {
+	err := r.Database.Model(&entity.Session{}).Where("id = ?", id).Update("last_seen_at", lastSeenAt).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *SessionRepository) Revoke(id string) error {
+	err := r.Database.Model(&entity.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *SessionRepository) RevokeAllByUserId(userID string) error {
+	err := r.Database.Model(&entity.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *SessionRepository) RevokeAllByUserIdExcept(userID string, sessionID string) error {
+	err := r.Database.Model(&entity.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, sessionID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *SessionRepository) RevokeAllByClientId(clientID string) error {
+	err := r.Database.Model(&entity.Session{}).
+		Where("client_id = ? AND revoked_at IS NULL", clientID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"go-crud/internal/entity"
+	"gorm.io/gorm"
+)
+
+type TagRepositoryInterface interface {
+	Save(tag *entity.Tag) error
+	FindOneById(id string) (*entity.Tag, error)
+	FindOneBySlug(slug string) (*entity.Tag, error)
+	// FindAll lists every tag ordered by name.
+	FindAll() ([]entity.Tag, error)
+	FindManyByIds(ids []string) ([]entity.Tag, error)
+	UpdateById(tag *entity.Tag) error
+	DeleteById(id string) error
+}
+
+type TagRepository struct {
+	Database *gorm.DB
+}
+
+func NewTagRepository(database *gorm.DB) *TagRepository {
+	return &TagRepository{
+		Database: database,
+	}
+}
+
+func (r *TagRepository) Save(tag *entity.Tag) error {
+	err := r.Database.Create(tag).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *TagRepository) FindOneById(id string) (*entity.Tag, error) {
+	var tag entity.Tag
+	err := r.Database.First(&tag, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (r *TagRepository) FindOneBySlug(slug string) (*entity.Tag, error) {
+	var tag entity.Tag
+	err := r.Database.First(&tag, "slug = ?", slug).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (r *TagRepository) FindAll() ([]entity.Tag, error) {
+	var tags []entity.Tag
+	err := r.Database.Order("name").Order("id").Find(&tags).Error
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (r *TagRepository) FindManyByIds(ids []string) ([]entity.Tag, error) {
+	tags := []entity.Tag{}
+	if len(ids) == 0 {
+		return tags, nil
+	}
+	err := r.Database.Where("id IN ?", ids).Order("name").Find(&tags).Error
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (r *TagRepository) UpdateById(tag *entity.Tag) error {
+	err := r.Database.Model(&entity.Tag{}).Where("id = ?", tag.Id).Updates(map[string]any{
+		"name": tag.Name,
+		"slug": tag.Slug,
+	}).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteById removes the tag from every product through ON DELETE CASCADE.
+func (r *TagRepository) DeleteById(id string) error {
+	err := r.Database.Delete(&entity.Tag{}, "id = ?", id).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"go-crud/internal/entity"
+	"gorm.io/gorm"
+)
+
+type UserIdentityRepositoryInterface interface {
+	Save(identity *entity.UserIdentity) error
+	FindOneBySubject(issuer string, subject string) (*entity.UserIdentity, error)
+}
+
+type UserIdentityRepository struct {
+	Database *gorm.DB
+}
+
+func NewUserIdentityRepository(database *gorm.DB) *UserIdentityRepository {
+	return &UserIdentityRepository{
+		Database: database,
+	}
+}
+
+func (r *UserIdentityRepository) Save(identity *entity.UserIdentity) error {
+	err := r.Database.Create(identity).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *UserIdentityRepository) FindOneBySubject(issuer string, subject string) (*entity.UserIdentity, error) {
+	var identity entity.UserIdentity
+	err := r.Database.First(&identity, "issuer = ? AND subject = ?", issuer, subject).Error
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
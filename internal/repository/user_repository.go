@@ -3,6 +3,8 @@ package repository
 import (
 	"go-crud/internal/entity"
 	"gorm.io/gorm"
+	"strings"
+	"time"
 )
 
 type UserRepositoryInterface interface {
@@ -10,6 +12,17 @@ type UserRepositoryInterface interface {
 	FindOneByEmail(email string) (*entity.User, error)
 	FindOneById(user *entity.User, id string) error
 	DeleteOneById(id string) error
+	UpdatePassword(id string, password string) error
+	MarkEmailVerified(id string, verifiedAt time.Time) error
+	UpdateTotp(id string, secret string, enabledAt *time.Time) error
+	UseTotpStep(id string, step int64) (bool, error)
+	FindRolesByUserId(id string) ([]entity.Role, error)
+	UpdateProfile(user *entity.User) error
+	ConfirmPendingEmail(id string, email string, verifiedAt time.Time) (bool, error)
+	FindMany(users *[]entity.User, search string, offset int, limit int) error
+	Count(search string) (int64, error)
+	UpdateDisabledAt(id string, disabledAt *time.Time) error
+	IsDisabled(id string) (bool, error)
 }
 type UserRepository struct {
 	Database *gorm.DB
@@ -40,7 +53,7 @@ func (r *UserRepository) FindOneByEmail(email string) (*entity.User, error) {
 }
 
 func (r *UserRepository) FindOneById(user *entity.User, id string) error {
-	err := r.Database.First(user, id).Error
+	err := r.Database.First(user, "id = ?", id).Error
 	if err != nil {
 		return err
 	}
@@ -49,11 +62,157 @@ func (r *UserRepository) FindOneById(user *entity.User, id string) error {
 
 }
 
+// DeleteOneById removes the user with their own products. Products they
+// created for an organization stay with the organization.
 func (r *UserRepository) DeleteOneById(id string) error {
-	err := r.Database.Delete(&entity.User{}, "id = ?", id).Error
+	return r.Database.Transaction(func(tx *gorm.DB) error {
+		err := tx.Delete(&entity.Product{}, "user_id = ? AND organization_id IS NULL", id).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Delete(&entity.User{}, "id = ?", id).Error
+	})
+}
+
+func (r *UserRepository) UpdatePassword(id string, password string) error {
+	err := r.Database.Model(&entity.User{}).Where("id = ?", id).Update("password", password).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *UserRepository) MarkEmailVerified(id string, verifiedAt time.Time) error {
+	err := r.Database.Model(&entity.User{}).Where("id = ?", id).Update("email_verified_at", verifiedAt).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *UserRepository) UpdateTotp(id string, secret string, enabledAt *time.Time) error {
+	err := r.Database.Model(&entity.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"totp_secret":     secret,
+		"totp_enabled_at": enabledAt,
+		"totp_last_step":  0,
+	}).Error
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// UseTotpStep records the time step of an accepted code. It reports false
+// when that step or a later one was already used, which stops a code from
+// being replayed within its validity window.
+func (r *UserRepository) UseTotpStep(id string, step int64) (bool, error) {
+	result := r.Database.Model(&entity.User{}).
+		Where("id = ? AND totp_last_step < ?", id, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// FindRolesByUserId returns the roles of the user with their permissions.
+func (r *UserRepository) FindRolesByUserId(id string) ([]entity.Role, error) {
+	var roles []entity.Role
+	err := r.Database.Preload("Permissions").
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", id).
+		Find(&roles).Error
+	if err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// UpdateProfile stores the name and the pending email. The email itself only
+// changes through ConfirmPendingEmail.
+func (r *UserRepository) UpdateProfile(user *entity.User) error {
+	err := r.Database.Model(user).Select("name", "pending_email").Updates(user).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ConfirmPendingEmail makes the pending email the verified email of the user.
+// It reports false when the pending email isn't email anymore, which happens
+// when the user asked for another change in the meantime.
+func (r *UserRepository) ConfirmPendingEmail(id string, email string, verifiedAt time.Time) (bool, error) {
+	result := r.Database.Model(&entity.User{}).
+		Where("id = ? AND pending_email = ?", id, email).
+		Updates(map[string]interface{}{
+			"email":             email,
+			"pending_email":     nil,
+			"email_verified_at": verifiedAt,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// FindMany lists users, newest first. A non-empty search matches a part of
+// the name or the email.
+func (r *UserRepository) FindMany(users *[]entity.User, search string, offset int, limit int) error {
+	err := r.searchUsers(search).Order("created_at DESC").Order("id").Offset(offset).Limit(limit).Find(users).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *UserRepository) Count(search string) (int64, error) {
+	var count int64
+	err := r.searchUsers(search).Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (r *UserRepository) searchUsers(search string) *gorm.DB {
+	query := r.Database.Model(&entity.User{})
+	if search == "" {
+		return query
+	}
+
+	pattern := "%" + escapeLike(search) + "%"
+	return query.Where("name LIKE ? OR email LIKE ?", pattern, pattern)
+}
+
+// escapeLike makes the wildcards of a LIKE pattern match themselves.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+func (r *UserRepository) UpdateDisabledAt(id string, disabledAt *time.Time) error {
+	err := r.Database.Model(&entity.User{}).Where("id = ?", id).Update("disabled_at", disabledAt).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *UserRepository) IsDisabled(id string) (bool, error) {
+	var count int64
+	err := r.Database.Model(&entity.User{}).Where("id = ? AND disabled_at IS NOT NULL", id).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
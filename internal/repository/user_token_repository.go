@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"go-crud/internal/entity"
+	"gorm.io/gorm"
+	"time"
+)
+
+type UserTokenRepositoryInterface interface {
+	Save(token *entity.UserToken) error
+	FindOneByHash(hash string, purpose string) (*entity.UserToken, error)
+	MarkAsUsed(id string) (bool, error)
+	InvalidateByUserId(userID string, purpose string) error
+	FindLatestByUserId(userID string, purpose string) (*entity.UserToken, error)
+}
+
+type UserTokenRepository struct {
+	Database *gorm.DB
+}
+
+func NewUserTokenRepository(database *gorm.DB) *UserTokenRepository {
+	return &UserTokenRepository{
+		Database: database,
+	}
+}
+
+func (r *UserTokenRepository) Save(token *entity.UserToken) error {
+	err := r.Database.Create(token).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *UserTokenRepository) FindOneByHash(hash string, purpose string) (*entity.UserToken, error) {
+	var token entity.UserToken
+	err := r.Database.First(&token, "token_hash = ? AND purpose = ?", hash, purpose).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkAsUsed consumes the token. It reports false when the token was already
+// used, so a token can't be redeemed twice by concurrent requests.
+func (r *UserTokenRepository) MarkAsUsed(id string) (bool, error) {
+	result := r.Database.Model(&entity.UserToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func (r *UserTokenRepository) InvalidateByUserId(userID string, purpose string) error {
+	err := r.Database.Model(&entity.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", time.Now()).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *UserTokenRepository) FindLatestByUserId(userID string, purpose string) (*entity.UserToken, error) {
+	var token entity.UserToken
+	err := r.Database.Where("user_id = ? AND purpose = ?", userID, purpose).Order("created_at DESC").First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
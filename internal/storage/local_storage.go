@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStorage keeps files in Directory, and serves them under BaseURL.
+type LocalStorage struct {
+	Directory string
+	BaseURL   string
+}
+
+func NewLocalStorage(directory string, baseURL string) *LocalStorage {
+	return &LocalStorage{
+		Directory: directory,
+		BaseURL:   strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// Put writes to a temporary file first, so a file is never served half
+// written.
+func (s *LocalStorage) Put(key string, contentType string, content []byte) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+	path := filepath.Join(s.Directory, filepath.FromSlash(key))
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	_, err = file.Write(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	err = os.Chmod(file.Name(), 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+func (s *LocalStorage) Delete(key string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+	err := os.Remove(filepath.Join(s.Directory, filepath.FromSlash(key)))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStorage) URL(key string) string {
+	return s.BaseURL + "/" + key
+}
@@ -0,0 +1,181 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Storage keeps files in a bucket of an S3 compatible service, such as
+// AWS S3, MinIO or R2. Requests use path style URLs, Endpoint/Bucket/key,
+// which every such service supports.
+type S3Storage struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// BaseURL is where the files are downloaded from, such as a CDN. Files
+	// are downloaded from the bucket when it is empty.
+	BaseURL string
+	Client  *http.Client
+	Now     func() time.Time
+}
+
+func NewS3Storage(endpoint string, region string, bucket string, accessKey string, secretKey string, baseURL string, timeout time.Duration) *S3Storage {
+	return &S3Storage{
+		Endpoint:  strings.TrimSuffix(endpoint, "/"),
+		Region:    region,
+		Bucket:    bucket,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		BaseURL:   strings.TrimSuffix(baseURL, "/"),
+		Client:    &http.Client{Timeout: timeout},
+		Now:       time.Now,
+	}
+}
+
+func (s *S3Storage) Put(key string, contentType string, content []byte) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+	request, err := http.NewRequest(http.MethodPut, s.objectURL(key), bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", contentType)
+	return s.do(request, content)
+}
+
+func (s *S3Storage) Delete(key string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+	request, err := http.NewRequest(http.MethodDelete, s.objectURL(key), nil)
+	if err != nil {
+		return err
+	}
+	// S3 answers 204 whether the object existed or not.
+	return s.do(request, nil)
+}
+
+func (s *S3Storage) URL(key string) string {
+	if s.BaseURL != "" {
+		return s.BaseURL + "/" + key
+	}
+	return s.objectURL(key)
+}
+
+func (s *S3Storage) objectURL(key string) string {
+	return s.Endpoint + "/" + escapeURI(s.Bucket, false) + "/" + escapeURI(key, true)
+}
+
+func (s *S3Storage) do(request *http.Request, payload []byte) error {
+	SignV4(request, payload, s.AccessKey, s.SecretKey, s.Region, "s3", s.Now())
+	response, err := s.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("%s %s: %s: %s", request.Method, request.URL.Path, response.Status, body)
+	}
+	return nil
+}
+
+// SignV4 adds the AWS Signature Version 4 headers to request, signing its
+// payload and every header it already has.
+func SignV4(request *http.Request, payload []byte, accessKey string, secretKey string, region string, service string, now time.Time) {
+	now = now.UTC()
+	payloadHash := sha256.Sum256(payload)
+	request.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	request.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+
+	headers := map[string]string{"host": request.URL.Host}
+	for name, values := range request.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		escapeURI(request.URL.Path, true),
+		canonicalQuery(request.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+
+	date := now.Format("20060102")
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + now.Format("20060102T150405Z") + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// escapeURI escapes every byte of value but unreserved characters, and
+// slashes when keepSlash is set, as signing asks.
+func escapeURI(value string, keepSlash bool) string {
+	var builder strings.Builder
+	for index := 0; index < len(value); index++ {
+		c := value[index]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			builder.WriteByte(c)
+		case c == '/' && keepSlash:
+			builder.WriteByte(c)
+		default:
+			fmt.Fprintf(&builder, "%%%02X", c)
+		}
+	}
+	return builder.String()
+}
+
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, key := range keys {
+		values := append([]string{}, query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, escapeURI(key, false)+"="+escapeURI(value, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
@@ -0,0 +1,35 @@
+package storage
+
+import "errors"
+
+// ErrInvalidKey is returned for keys that could escape the storage, such as
+// ones with .. segments.
+var ErrInvalidKey = errors.New("invalid storage key")
+
+// Storage keeps files under slash separated keys, like
+// products/<id>/original.jpg, and tells where they can be downloaded.
+type Storage interface {
+	Put(key string, contentType string, content []byte) error
+	// Delete removes the file of key. Deleting a missing file is not an
+	// error.
+	Delete(key string) error
+	URL(key string) string
+}
+
+func validKey(key string) bool {
+	if key == "" || key[0] == '/' {
+		return false
+	}
+	start := 0
+	for index := 0; index <= len(key); index++ {
+		if index < len(key) && key[index] != '/' {
+			continue
+		}
+		segment := key[start:index]
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+		start = index + 1
+	}
+	return true
+}
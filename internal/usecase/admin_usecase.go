@@ -0,0 +1,291 @@
+package usecase
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"go-crud/internal/entity"
+	"go-crud/internal/helper"
+	"go-crud/internal/models"
+	"go-crud/internal/repository"
+	"gorm.io/gorm"
+	"math"
+	"net/url"
+	"time"
+)
+
+// AdminUsecase lets support staff manage the accounts of other users. Every
+// action is recorded in the audit log, whether it succeeded or not.
+type AdminUsecase struct {
+	UserRepository       repository.UserRepositoryInterface
+	ProductRepository    repository.ProductRepositoryInterface
+	AuthUsecase          *AuthUsecase
+	SessionUsecase       *SessionUsecase
+	PasswordResetUsecase *PasswordResetUsecase
+	AuditLog             *AuditLogUsecase
+	Viper                *viper.Viper
+	Log                  *logrus.Logger
+}
+
+func NewAdminUsecase(userRepository repository.UserRepositoryInterface, productRepository repository.ProductRepositoryInterface, authUsecase *AuthUsecase, sessionUsecase *SessionUsecase, passwordResetUsecase *PasswordResetUsecase, auditLog *AuditLogUsecase, viper *viper.Viper, log *logrus.Logger) *AdminUsecase {
+	return &AdminUsecase{
+		UserRepository:       userRepository,
+		ProductRepository:    productRepository,
+		AuthUsecase:          authUsecase,
+		SessionUsecase:       sessionUsecase,
+		PasswordResetUsecase: passwordResetUsecase,
+		AuditLog:             auditLog,
+		Viper:                viper,
+		Log:                  log,
+	}
+}
+
+// GetUsers lists users, newest first, optionally searching their name and
+// email.
+func (c *AdminUsecase) GetUsers(actor models.Actor, search string, offset int, limit int) (*[]models.AdminUserResponse, error) {
+	users := new([]entity.User)
+	err := c.UserRepository.FindMany(users, search, offset, limit)
+	c.AuditLog.Record(actor, entity.AuditActionAdminUserList, "", auditOutcome(err))
+	if err != nil {
+		return nil, c.serverError(err, "Error while getting users")
+	}
+
+	result := make([]models.AdminUserResponse, 0, len(*users))
+	for i := range *users {
+		result = append(result, *toAdminUserResponse(&(*users)[i]))
+	}
+
+	return &result, nil
+}
+
+func (c *AdminUsecase) GetMetadataPagination(search string, pageNumber int, limit int) (*models.Metadata, error) {
+	count, err := c.UserRepository.Count(search)
+	if err != nil {
+		return nil, c.serverError(err, "Error while counting users")
+	}
+	pageSize := int64(math.Ceil(float64(count) / float64(limit)))
+
+	var query url.Values
+	if search != "" {
+		query = url.Values{"q": {search}}
+	}
+
+	metadata := new(models.Metadata)
+	metadata.PageSize = pageSize
+	metadata.TotalItemCount = count
+	metadata.PageNumber = pageNumber
+	metadata.Next = helper.AppendQuery(helper.FormatNextURLPagination("admin/users", pageNumber, limit, pageSize), query)
+	metadata.Prev = helper.AppendQuery(helper.FormatPrevURLPagination("admin/users", pageNumber, limit), query)
+
+	return metadata, nil
+}
+
+// GetUser returns the user with the number of products they created.
+func (c *AdminUsecase) GetUser(actor models.Actor, userID string) (*models.AdminUserResponse, error) {
+	result, err := c.getUser(userID)
+	c.AuditLog.Record(actor, entity.AuditActionAdminUserView, userID, auditOutcome(err))
+	return result, err
+}
+
+func (c *AdminUsecase) getUser(userID string) (*models.AdminUserResponse, error) {
+	user, err := c.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := c.ProductRepository.CountByUserId(userID)
+	if err != nil {
+		return nil, c.serverError(err, "Error while counting products of user")
+	}
+
+	result := toAdminUserResponse(user)
+	result.ProductCount = &count
+	return result, nil
+}
+
+// DisableUser stops the user from signing in and signs them out of every
+// session. Their API keys stay but are refused while the account is
+// disabled.
+func (c *AdminUsecase) DisableUser(actor models.Actor, userID string) error {
+	err := c.disableUser(actor, userID)
+	c.AuditLog.Record(actor, entity.AuditActionAdminUserDisable, userID, auditOutcome(err))
+	return err
+}
+
+func (c *AdminUsecase) disableUser(actor models.Actor, userID string) error {
+	if userID == actor.UserId {
+		return &models.ErrorResponse{
+			Code:    400,
+			Message: "You can't disable your own account",
+			Status:  "Bad Request",
+		}
+	}
+
+	user, err := c.findUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if user.DisabledAt == nil {
+		now := time.Now()
+		err = c.UserRepository.UpdateDisabledAt(userID, &now)
+		if err != nil {
+			return c.serverError(err, "Error while disabling user")
+		}
+	}
+
+	return c.SessionUsecase.RevokeAllSessions(userID)
+}
+
+// EnableUser lets a disabled user sign in again. Sessions revoked when the
+// account was disabled stay revoked.
+func (c *AdminUsecase) EnableUser(actor models.Actor, userID string) error {
+	err := c.enableUser(userID)
+	c.AuditLog.Record(actor, entity.AuditActionAdminUserEnable, userID, auditOutcome(err))
+	return err
+}
+
+func (c *AdminUsecase) enableUser(userID string) error {
+	user, err := c.findUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if user.DisabledAt == nil {
+		return nil
+	}
+
+	err = c.UserRepository.UpdateDisabledAt(userID, nil)
+	if err != nil {
+		return c.serverError(err, "Error while enabling user")
+	}
+
+	return nil
+}
+
+// LogoutUser revokes every session of the user, their access tokens stop
+// working right away.
+func (c *AdminUsecase) LogoutUser(actor models.Actor, userID string) error {
+	err := c.logoutUser(userID)
+	c.AuditLog.Record(actor, entity.AuditActionAdminUserLogout, userID, auditOutcome(err))
+	return err
+}
+
+func (c *AdminUsecase) logoutUser(userID string) error {
+	_, err := c.findUser(userID)
+	if err != nil {
+		return err
+	}
+
+	return c.SessionUsecase.RevokeAllSessions(userID)
+}
+
+// SendPasswordReset mails the user a reset link, as if they had asked for it
+// themselves. Support never gets to see or choose the password.
+func (c *AdminUsecase) SendPasswordReset(actor models.Actor, userID string) error {
+	err := c.sendPasswordReset(userID)
+	c.AuditLog.Record(actor, entity.AuditActionAdminUserPasswordReset, userID, auditOutcome(err))
+	return err
+}
+
+func (c *AdminUsecase) sendPasswordReset(userID string) error {
+	user, err := c.findUser(userID)
+	if err != nil {
+		return err
+	}
+
+	return c.PasswordResetUsecase.SendResetLink(user)
+}
+
+// Impersonate issues a short-lived access token to act as the user. The
+// token names the admin in its act claim and carries neither roles nor
+// permissions, the admin sees what the user sees without borrowing their
+// rights. It is bound to the session of the admin, signing the admin out
+// ends it too.
+func (c *AdminUsecase) Impersonate(actor models.Actor, request *models.ImpersonateRequest) (*models.ImpersonationResponse, error) {
+	result, err := c.impersonate(actor, request)
+	c.AuditLog.Record(actor, entity.AuditActionAdminUserImpersonate, request.UserId, auditOutcome(err))
+	return result, err
+}
+
+func (c *AdminUsecase) impersonate(actor models.Actor, request *models.ImpersonateRequest) (*models.ImpersonationResponse, error) {
+	if request.UserId == actor.UserId {
+		return nil, &models.ErrorResponse{
+			Code:    400,
+			Message: "You can't impersonate yourself",
+			Status:  "Bad Request",
+		}
+	}
+
+	user, err := c.findUser(request.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.DisabledAt != nil {
+		return nil, &models.ErrorResponse{
+			Code:    400,
+			Message: "Disabled users can't be impersonated",
+			Status:  "Bad Request",
+		}
+	}
+
+	expiresAt := time.Now().Add(time.Duration(c.Viper.GetInt("auth.impersonation.expiration")) * time.Second)
+	accessToken, err := c.AuthUsecase.GenerateAccessToken(&models.AccessTokenClaims{
+		Subject:   user.Id,
+		SessionId: request.SessionId,
+		Methods:   request.Methods,
+		Scopes:    models.AllScopes,
+		Actor:     actor.UserId,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ImpersonationResponse{
+		AccessToken: accessToken,
+		ExpiresAt:   expiresAt,
+	}, nil
+}
+
+func (c *AdminUsecase) findUser(userID string) (*entity.User, error) {
+	user := new(entity.User)
+	err := c.UserRepository.FindOneById(user, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &models.ErrorResponse{
+				Code:    404,
+				Message: "User not found",
+				Status:  "Not Found",
+			}
+		}
+
+		return nil, c.serverError(err, "Error while finding user")
+	}
+
+	return user, nil
+}
+
+func (c *AdminUsecase) serverError(err error, message string) error {
+	c.Log.WithError(err).Error(message)
+	return &models.ErrorResponse{
+		Code:    500,
+		Message: "Something Error",
+		Status:  "Internal Server Error",
+	}
+}
+
+func toAdminUserResponse(user *entity.User) *models.AdminUserResponse {
+	return &models.AdminUserResponse{
+		Id:               user.Id,
+		Name:             user.Name,
+		Email:            user.Email,
+		EmailVerified:    user.EmailVerifiedAt != nil,
+		TwoFactorEnabled: user.TotpEnabledAt != nil,
+		Disabled:         user.DisabledAt != nil,
+		DisabledAt:       user.DisabledAt,
+		CreatedAt:        user.CreatedAt,
+		UpdatedAt:        user.UpdatedAt,
+	}
+}
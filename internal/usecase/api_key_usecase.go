@@ -0,0 +1,205 @@
+package usecase
+
+import (
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go-crud/internal/entity"
+	"go-crud/internal/helper"
+	"go-crud/internal/models"
+	"go-crud/internal/repository"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+// ApiKeyPrefix starts every API key so they can be told apart from access
+// tokens in the Authorization header, and found by secret scanners.
+const ApiKeyPrefix = "gck_"
+
+// apiKeyDisplayLength is how much of a key is kept in clear to let users
+// recognise it in the list.
+const apiKeyDisplayLength = 12
+
+type ApiKeyUsecase struct {
+	Repository repository.ApiKeyRepositoryInterface
+	Validate   *validator.Validate
+	Log        *logrus.Logger
+}
+
+func NewApiKeyUsecase(repository repository.ApiKeyRepositoryInterface, validate *validator.Validate, log *logrus.Logger) *ApiKeyUsecase {
+	return &ApiKeyUsecase{
+		Repository: repository,
+		Validate:   validate,
+		Log:        log,
+	}
+}
+
+func (c *ApiKeyUsecase) ValidateRequest(request any) error {
+	err := c.Validate.Struct(request)
+	if err != nil {
+		c.Log.WithError(err).Warn("Error validating request")
+		message := helper.GetFirstValidationErrorAndConvert(err)
+		return &models.ErrorResponse{
+			Code:    400,
+			Status:  "Bad Request",
+			Message: message,
+		}
+	}
+	return nil
+}
+
+func IsApiKey(token string) bool {
+	return strings.HasPrefix(token, ApiKeyPrefix)
+}
+
+// CreateApiKey returns the plain key once. Only its hash is stored.
+func (c *ApiKeyUsecase) CreateApiKey(userID string, request *models.CreateApiKeyRequest) (*models.ApiKeyCreatedResponse, error) {
+	err := c.ValidateRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		return nil, &models.ErrorResponse{
+			Code:    400,
+			Status:  "Bad Request",
+			Message: "Expiration must be in the future",
+		}
+	}
+
+	secret, err := helper.GenerateRandomToken(32)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while generating api key")
+		return nil, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	key := ApiKeyPrefix + secret
+	apiKey := &entity.ApiKey{
+		Id:        uuid.New().String(),
+		UserId:    userID,
+		Name:      request.Name,
+		Prefix:    key[:apiKeyDisplayLength],
+		KeyHash:   helper.HashToken(key),
+		Scopes:    strings.Join(uniqueScopes(request.Scopes), " "),
+		ExpiresAt: request.ExpiresAt,
+		CreatedAt: time.Now(),
+	}
+
+	err = c.Repository.Save(apiKey)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while saving api key")
+		return nil, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	return &models.ApiKeyCreatedResponse{
+		ApiKeyResponse: toApiKeyResponse(apiKey),
+		Key:            key,
+	}, nil
+}
+
+func (c *ApiKeyUsecase) GetApiKeys(userID string) (*[]models.ApiKeyResponse, error) {
+	apiKeys, err := c.Repository.FindManyActiveByUserId(userID)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while getting api keys")
+		return nil, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	apiKeyResponse := make([]models.ApiKeyResponse, len(apiKeys))
+	for index := range apiKeys {
+		apiKeyResponse[index] = toApiKeyResponse(&apiKeys[index])
+	}
+
+	return &apiKeyResponse, nil
+}
+
+func (c *ApiKeyUsecase) RevokeApiKey(userID string, id string) error {
+	revoked, err := c.Repository.Revoke(id, userID)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while revoking api key")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	if !revoked {
+		return &models.ErrorResponse{
+			Code:    404,
+			Message: "API key not found",
+			Status:  "Not Found",
+		}
+	}
+
+	return nil
+}
+
+// VerifyApiKey returns the stored key when it exists, isn't revoked and
+// hasn't expired.
+func (c *ApiKeyUsecase) VerifyApiKey(key string) (*entity.ApiKey, error) {
+	apiKey, err := c.Repository.FindOneByHash(helper.HashToken(key))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.Log.WithError(err).Error("Error while finding api key")
+		return nil, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	now := time.Now()
+	if apiKey == nil || apiKey.RevokedAt != nil || (apiKey.ExpiresAt != nil && !apiKey.ExpiresAt.After(now)) {
+		return nil, &models.ErrorResponse{
+			Code:    401,
+			Message: "Invalid API key",
+			Status:  "Unauthorized",
+		}
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > time.Minute {
+		err = c.Repository.Touch(apiKey.Id, now)
+		if err != nil {
+			c.Log.WithError(err).Warn("Error while updating api key last used time")
+		}
+	}
+
+	return apiKey, nil
+}
+
+func toApiKeyResponse(apiKey *entity.ApiKey) models.ApiKeyResponse {
+	return models.ApiKeyResponse{
+		Id:         apiKey.Id,
+		Name:       apiKey.Name,
+		Prefix:     apiKey.Prefix,
+		Scopes:     strings.Fields(apiKey.Scopes),
+		ExpiresAt:  apiKey.ExpiresAt,
+		LastUsedAt: apiKey.LastUsedAt,
+		CreatedAt:  apiKey.CreatedAt,
+	}
+}
+
+func uniqueScopes(scopes []string) []string {
+	seen := make(map[string]bool, len(scopes))
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	return result
+}
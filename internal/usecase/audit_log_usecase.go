@@ -0,0 +1,157 @@
+package usecase
+
+import (
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go-crud/internal/entity"
+	"go-crud/internal/helper"
+	"go-crud/internal/models"
+	"go-crud/internal/repository"
+	"math"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const maxAuditDetailsLength = 512
+
+type AuditLogUsecase struct {
+	Repository repository.AuditLogRepositoryInterface
+	Log        *logrus.Logger
+}
+
+func NewAuditLogUsecase(repository repository.AuditLogRepositoryInterface, log *logrus.Logger) *AuditLogUsecase {
+	return &AuditLogUsecase{
+		Repository: repository,
+		Log:        log,
+	}
+}
+
+// Record appends an entry for an action of the actor on targetID, which may
+// be empty. The action already happened, so failing to store the entry is
+// logged with all its fields instead of failing the request.
+func (c *AuditLogUsecase) Record(actor models.Actor, action string, targetID string, outcome string) {
+	c.RecordWithDetails(actor, action, targetID, outcome, "")
+}
+
+// RecordWithDetails is Record with a short free-form description, cut to
+// the size of the column.
+func (c *AuditLogUsecase) RecordWithDetails(actor models.Actor, action string, targetID string, outcome string, details string) {
+	if len(details) > maxAuditDetailsLength {
+		details = strings.ToValidUTF8(details[:maxAuditDetailsLength], "")
+	}
+
+	entry := &entity.AuditLog{
+		Id:        uuid.New().String(),
+		Action:    action,
+		Outcome:   outcome,
+		Details:   details,
+		IpAddress: actor.Client.IpAddress,
+		UserAgent: actor.Client.UserAgent,
+		CreatedAt: time.Now(),
+	}
+	if actor.UserId != "" {
+		entry.ActorId = &actor.UserId
+	}
+	if targetID != "" {
+		entry.TargetId = &targetID
+	}
+
+	err := c.Repository.Save(entry)
+	if err != nil {
+		c.Log.WithError(err).WithFields(logrus.Fields{
+			"actor_id":   actor.UserId,
+			"action":     action,
+			"target_id":  targetID,
+			"outcome":    outcome,
+			"details":    details,
+			"ip_address": actor.Client.IpAddress,
+		}).Error("Error while saving audit log")
+	}
+}
+
+// GetAuditLogs lists the entries matching the filter, newest first. Reading
+// the audit log is itself recorded.
+func (c *AuditLogUsecase) GetAuditLogs(actor models.Actor, filter *models.AuditLogFilter, offset int, limit int) (*[]models.AuditLogResponse, error) {
+	entries := new([]entity.AuditLog)
+	err := c.Repository.FindMany(entries, filter, offset, limit)
+	c.Record(actor, entity.AuditActionAuditLogList, "", auditOutcome(err))
+	if err != nil {
+		return nil, c.serverError(err, "Error while getting audit logs")
+	}
+
+	result := make([]models.AuditLogResponse, 0, len(*entries))
+	for _, entry := range *entries {
+		result = append(result, models.AuditLogResponse{
+			Id:        entry.Id,
+			ActorId:   entry.ActorId,
+			Action:    entry.Action,
+			TargetId:  entry.TargetId,
+			Outcome:   entry.Outcome,
+			Details:   entry.Details,
+			IpAddress: entry.IpAddress,
+			UserAgent: entry.UserAgent,
+			CreatedAt: entry.CreatedAt,
+		})
+	}
+
+	return &result, nil
+}
+
+func (c *AuditLogUsecase) GetMetadataPagination(filter *models.AuditLogFilter, pageNumber int, limit int) (*models.Metadata, error) {
+	count, err := c.Repository.Count(filter)
+	if err != nil {
+		return nil, c.serverError(err, "Error while counting audit logs")
+	}
+	pageSize := int64(math.Ceil(float64(count) / float64(limit)))
+
+	query := auditLogFilterQuery(filter)
+	metadata := new(models.Metadata)
+	metadata.PageSize = pageSize
+	metadata.TotalItemCount = count
+	metadata.PageNumber = pageNumber
+	metadata.Next = helper.AppendQuery(helper.FormatNextURLPagination("admin/audit-logs", pageNumber, limit, pageSize), query)
+	metadata.Prev = helper.AppendQuery(helper.FormatPrevURLPagination("admin/audit-logs", pageNumber, limit), query)
+
+	return metadata, nil
+}
+
+// auditLogFilterQuery keeps the filter in the pagination links.
+func auditLogFilterQuery(filter *models.AuditLogFilter) url.Values {
+	query := url.Values{}
+	for key, value := range map[string]string{
+		"actor_id":  filter.ActorId,
+		"target_id": filter.TargetId,
+		"action":    filter.Action,
+		"outcome":   filter.Outcome,
+	} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	if filter.From != nil {
+		query.Set("from", filter.From.Format(time.RFC3339))
+	}
+	if filter.To != nil {
+		query.Set("to", filter.To.Format(time.RFC3339))
+	}
+
+	return query
+}
+
+func (c *AuditLogUsecase) serverError(err error, message string) error {
+	c.Log.WithError(err).Error(message)
+	return &models.ErrorResponse{
+		Code:    500,
+		Message: "Something Error",
+		Status:  "Internal Server Error",
+	}
+}
+
+// auditOutcome tells how an action that returned err ended.
+func auditOutcome(err error) string {
+	if err != nil {
+		return entity.AuditOutcomeFailure
+	}
+	return entity.AuditOutcomeSuccess
+}
@@ -4,37 +4,59 @@ import (
 	"errors"
 	"github.com/go-playground/validator/v10"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
+	"go-crud/internal/entity"
+	"go-crud/internal/hasher"
 	"go-crud/internal/helper"
+	"go-crud/internal/keyset"
 	"go-crud/internal/models"
 	"go-crud/internal/repository"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 	"strings"
 	"sync"
 	"time"
 )
 
+const mfaPendingTokenType = "mfa_pending"
+
+// AccessTokenLifetime is how long an access token is valid.
+const AccessTokenLifetime = time.Hour
+
 type AuthUsecase struct {
-	Repository repository.UserRepositoryInterface
-	Validate   *validator.Validate
-	Viper      *viper.Viper
-	Log        *logrus.Logger
+	Repository                   repository.UserRepositoryInterface
+	RefreshTokenRepository       repository.RefreshTokenRepositoryInterface
+	SessionRepository            repository.SessionRepositoryInterface
+	RevokedAccessTokenRepository repository.RevokedAccessTokenRepositoryInterface
+	LoginAttempts                *LoginAttemptUsecase
+	AuditLog                     *AuditLogUsecase
+	Hasher                       *hasher.Hasher
+	KeySet                       *keyset.KeySet
+	Validate                     *validator.Validate
+	Viper                        *viper.Viper
+	Log                          *logrus.Logger
 }
 
-func NewAuthUsecase(repository repository.UserRepositoryInterface, validator *validator.Validate, viper *viper.Viper, log *logrus.Logger) *AuthUsecase {
+func NewAuthUsecase(repository repository.UserRepositoryInterface, refreshTokenRepository repository.RefreshTokenRepositoryInterface, sessionRepository repository.SessionRepositoryInterface, revokedAccessTokenRepository repository.RevokedAccessTokenRepositoryInterface, loginAttempts *LoginAttemptUsecase, auditLog *AuditLogUsecase, passwordHasher *hasher.Hasher, keySet *keyset.KeySet, validator *validator.Validate, viper *viper.Viper, log *logrus.Logger) *AuthUsecase {
 	return &AuthUsecase{
-		Repository: repository,
-		Validate:   validator,
-		Viper:      viper,
-		Log:        log,
+		Repository:                   repository,
+		RefreshTokenRepository:       refreshTokenRepository,
+		SessionRepository:            sessionRepository,
+		RevokedAccessTokenRepository: revokedAccessTokenRepository,
+		LoginAttempts:                loginAttempts,
+		AuditLog:                     auditLog,
+		Hasher:                       passwordHasher,
+		KeySet:                       keySet,
+		Validate:                     validator,
+		Viper:                        viper,
+		Log:                          log,
 	}
 }
 
-func (c *AuthUsecase) VerifyRefreshToken(refreshToken string) (string, error) {
+func (c *AuthUsecase) VerifyRefreshToken(refreshToken string) (*entity.RefreshToken, error) {
 	if refreshToken == "" {
-		return "", &models.ErrorResponse{
+		return nil, &models.ErrorResponse{
 			Code:    401,
 			Status:  "Unauthorized",
 			Message: "You're unauthorized",
@@ -42,14 +64,14 @@ func (c *AuthUsecase) VerifyRefreshToken(refreshToken string) (string, error) {
 	}
 	refreshTokenKey := c.Viper.GetString("token.key.refresh")
 
-	token, err := jwt.Parse(refreshToken, func(token *jwt.Token) (interface{}, error) {
+	_, err := jwt.Parse(refreshToken, func(token *jwt.Token) (interface{}, error) {
 		return []byte(refreshTokenKey), nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 
 	if err != nil {
 		c.Log.WithError(err).Error("Error while parsing refresh token")
 		if errors.Is(err, jwt.ErrTokenExpired) {
-			return "", &models.ErrorResponse{
+			return nil, &models.ErrorResponse{
 				Code:    401,
 				Status:  "Unauthorized",
 				Message: "Token is expired",
@@ -57,45 +79,203 @@ func (c *AuthUsecase) VerifyRefreshToken(refreshToken string) (string, error) {
 		}
 
 		if errors.Is(err, jwt.ErrInvalidKey) {
-			return "", &models.ErrorResponse{
+			return nil, &models.ErrorResponse{
 				Code:    401,
 				Status:  "Unauthorized",
 				Message: "Refresh token key is invalid",
 			}
 		}
 
-		return "", &models.ErrorResponse{
+		return nil, &models.ErrorResponse{
 			Code:    401,
 			Status:  "Unauthorized",
 			Message: "Invalid token",
 		}
 	}
 
-	var sub string
-	if claims, ok := token.Claims.(jwt.MapClaims); ok {
-		sub = claims["sub"].(string)
+	stored, err := c.RefreshTokenRepository.FindOneByHash(helper.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &models.ErrorResponse{
+				Code:    401,
+				Status:  "Unauthorized",
+				Message: "Invalid token",
+			}
+		}
+
+		c.Log.WithError(err).Error("Error while finding refresh token")
+		return nil, &models.ErrorResponse{
+			Code:    500,
+			Status:  "Internal Server Error",
+			Message: "Something error",
+		}
+	}
+
+	if stored.RevokedAt != nil {
+		return nil, &models.ErrorResponse{
+			Code:    401,
+			Status:  "Unauthorized",
+			Message: "Token has been revoked",
+		}
+	}
+
+	if stored.UsedAt != nil {
+		return nil, c.revokeReusedFamily(stored)
 	}
 
-	return sub, nil
+	return stored, nil
 
 }
 
-func (c *AuthUsecase) RefreshToken(refreshToken string) (*models.AuthResponse, error) {
-	sub, err := c.VerifyRefreshToken(refreshToken)
+// revokeReusedFamily is called when a refresh token that was already rotated
+// is presented again. Either the legitimate client or an attacker holds a
+// stale copy, and we can't tell which, so every token of the family dies.
+func (c *AuthUsecase) revokeReusedFamily(token *entity.RefreshToken) error {
+	c.Log.WithFields(logrus.Fields{
+		"user_id":   token.UserId,
+		"family_id": token.FamilyId,
+	}).Warn("Refresh token reuse detected")
+	c.AuditLog.RecordWithDetails(models.Actor{UserId: token.UserId}, entity.AuditActionRefreshReuse, "", entity.AuditOutcomeFailure, "session "+token.FamilyId)
+
+	err := c.RefreshTokenRepository.RevokeFamily(token.FamilyId)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while revoking refresh token family")
+		return &models.ErrorResponse{
+			Code:    500,
+			Status:  "Internal Server Error",
+			Message: "Something error",
+		}
+	}
+
+	return &models.ErrorResponse{
+		Code:    401,
+		Status:  "Unauthorized",
+		Message: "Token has been revoked",
+	}
+}
+
+// RefreshToken rotates a refresh token of a first-party session. Every
+// attempt is recorded in the audit log, under the user when the token is
+// known.
+func (c *AuthUsecase) RefreshToken(refreshToken string, client models.ClientInfo) (*models.AuthResponse, error) {
+	result, userID, err := c.rotateRefreshToken(refreshToken, "")
+	c.AuditLog.Record(models.Actor{UserId: userID, Client: client}, entity.AuditActionRefresh, "", auditOutcome(err))
+	return result, err
+}
+
+// RefreshClientToken rotates a refresh token issued to the third-party
+// application clientID.
+func (c *AuthUsecase) RefreshClientToken(refreshToken string, clientID string, client models.ClientInfo) (*models.AuthResponse, error) {
+	result, userID, err := c.rotateRefreshToken(refreshToken, clientID)
+	c.AuditLog.RecordWithDetails(models.Actor{UserId: userID, Client: client}, entity.AuditActionRefresh, "", auditOutcome(err), "client "+clientID)
+	return result, err
+}
+
+// rotateRefreshToken exchanges a refresh token for a new pair and returns
+// the user it belongs to, empty when the token isn't valid. clientID is
+// empty for first-party sessions. A token presented by another client than
+// the one it was issued to is treated as leaked and its family revoked.
+func (c *AuthUsecase) rotateRefreshToken(refreshToken string, clientID string) (*models.AuthResponse, string, error) {
+	stored, err := c.VerifyRefreshToken(refreshToken)
+	if err != nil {
+		return nil, "", err
+	}
+	result, err := c.rotateVerifiedRefreshToken(stored, clientID)
+	return result, stored.UserId, err
+}
+
+func (c *AuthUsecase) rotateVerifiedRefreshToken(stored *entity.RefreshToken, clientID string) (*models.AuthResponse, error) {
+
+	marked, err := c.RefreshTokenRepository.MarkAsUsed(stored.Id)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while marking refresh token as used")
+		return nil, &models.ErrorResponse{
+			Code:    500,
+			Status:  "Internal Server Error",
+			Message: "Something error",
+		}
+	}
+
+	if !marked {
+		return nil, c.revokeReusedFamily(stored)
+	}
+
+	session, err := c.SessionRepository.FindOneById(stored.FamilyId)
+	if err != nil || session.RevokedAt != nil {
+		c.Log.WithError(err).Error("Error while finding session of refresh token")
+		return nil, &models.ErrorResponse{
+			Code:    401,
+			Status:  "Unauthorized",
+			Message: "Session has been revoked",
+		}
+	}
+
+	if sessionClientId(session) != clientID {
+		return nil, c.revokeReusedFamily(stored)
+	}
+
+	err = c.SessionRepository.Touch(session.Id, time.Now())
+	if err != nil {
+		c.Log.WithError(err).Warn("Error while updating session last seen time")
+	}
+
+	claims, err := c.accessTokenClaims(session)
 	if err != nil {
 		return nil, err
 	}
 
-	accessToken, err := c.GenerateAccessToken(sub)
+	accessToken, err := c.GenerateAccessToken(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	newRefreshToken, err := c.GenerateRefreshToken(stored.UserId, stored.FamilyId)
 	if err != nil {
 		return nil, err
 	}
 
 	return &models.AuthResponse{
-		AccessToken: accessToken,
+		AccessToken:  accessToken,
+		RefreshToken: newRefreshToken,
 	}, nil
 }
 
+// SignOut revokes the whole family of the given refresh token. Unknown or
+// empty tokens are ignored so signing out never fails for the client, only
+// known ones are recorded in the audit log.
+func (c *AuthUsecase) SignOut(refreshToken string, client models.ClientInfo) error {
+	if refreshToken == "" {
+		return nil
+	}
+
+	stored, err := c.RefreshTokenRepository.FindOneByHash(helper.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+
+		c.Log.WithError(err).Error("Error while finding refresh token")
+		return &models.ErrorResponse{
+			Code:    500,
+			Status:  "Internal Server Error",
+			Message: "Something error",
+		}
+	}
+
+	err = c.RefreshTokenRepository.RevokeFamily(stored.FamilyId)
+	c.AuditLog.Record(models.Actor{UserId: stored.UserId, Client: client}, entity.AuditActionSignOut, "", auditOutcome(err))
+	if err != nil {
+		c.Log.WithError(err).Error("Error while revoking refresh token family")
+		return &models.ErrorResponse{
+			Code:    500,
+			Status:  "Internal Server Error",
+			Message: "Something error",
+		}
+	}
+
+	return nil
+}
+
 func (c *AuthUsecase) ValidateRequest(request *models.SignInRequest) error {
 	err := c.Validate.Struct(request)
 
@@ -121,13 +301,40 @@ func (c *AuthUsecase) ValidateRequest(request *models.SignInRequest) error {
 	return nil
 }
 
-func (c *AuthUsecase) GenerateAccessToken(userID string) (string, error) {
-	accessTokenKey := c.Viper.GetString("token.key.access")
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"exp": time.Now().Add(1 * time.Hour).Unix(),
-		"sub": userID,
-	})
-	token, err := jwtToken.SignedString([]byte(accessTokenKey))
+func (c *AuthUsecase) GenerateAccessToken(claims *models.AccessTokenClaims) (string, error) {
+	now := time.Now()
+	expiresAt := now.Add(AccessTokenLifetime)
+	if !claims.ExpiresAt.IsZero() && claims.ExpiresAt.Before(expiresAt) {
+		expiresAt = claims.ExpiresAt
+	}
+	mapClaims := jwt.MapClaims{
+		"jti": uuid.New().String(),
+		"iat": now.Unix(),
+		"exp": expiresAt.Unix(),
+		"sub": claims.Subject,
+	}
+	if claims.SessionId != "" {
+		mapClaims["sid"] = claims.SessionId
+	}
+	if len(claims.Methods) > 0 {
+		mapClaims["amr"] = claims.Methods
+	}
+	if len(claims.Roles) > 0 {
+		mapClaims["roles"] = claims.Roles
+	}
+	if len(claims.Permissions) > 0 {
+		mapClaims["permissions"] = claims.Permissions
+	}
+	if claims.ClientId != "" {
+		mapClaims["client_id"] = claims.ClientId
+	}
+	if claims.ClientId != "" || len(claims.Scopes) > 0 {
+		mapClaims["scope"] = strings.Join(claims.Scopes, " ")
+	}
+	if claims.Actor != "" {
+		mapClaims["act"] = map[string]string{"sub": claims.Actor}
+	}
+	token, err := c.KeySet.Sign(mapClaims)
 	if err != nil {
 		c.Log.Errorf("%v", err)
 		return "", &models.ErrorResponse{
@@ -141,11 +348,61 @@ func (c *AuthUsecase) GenerateAccessToken(userID string) (string, error) {
 
 }
 
-func (c *AuthUsecase) GenerateRefreshToken(userID string) (string, error) {
+// accessTokenClaims builds the claims of a new access token for the session,
+// loading the current roles and permissions of the user. Third-party
+// applications never get the roles of the user. Every token carries the
+// scopes of its session, sessions of the user that didn't narrow them down
+// get them all.
+func (c *AuthUsecase) accessTokenClaims(session *entity.Session) (*models.AccessTokenClaims, error) {
+	claims := &models.AccessTokenClaims{
+		Subject:   session.UserId,
+		SessionId: session.Id,
+		Methods:   strings.Fields(session.Methods),
+	}
+	if session.ClientId != nil {
+		claims.ClientId = *session.ClientId
+		claims.Scopes = strings.Fields(session.Scopes)
+		return claims, nil
+	}
+
+	roles, err := c.Repository.FindRolesByUserId(session.UserId)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while finding roles of user")
+		return nil, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	claims.Scopes = strings.Fields(session.Scopes)
+	if len(claims.Scopes) == 0 {
+		claims.Scopes = models.AllScopes
+	}
+
+	seen := make(map[string]bool)
+	for _, role := range roles {
+		claims.Roles = append(claims.Roles, role.Name)
+		for _, permission := range role.Permissions {
+			if !seen[permission.Name] {
+				seen[permission.Name] = true
+				claims.Permissions = append(claims.Permissions, permission.Name)
+			}
+		}
+	}
+
+	return claims, nil
+}
+
+func (c *AuthUsecase) GenerateRefreshToken(userID string, familyID string) (string, error) {
 	refreshTokenKey := c.Viper.GetString("token.key.refresh")
+	expiresAt := time.Now().Add(time.Duration(c.Viper.GetInt("token.expiration.refresh")) * time.Second)
+	tokenID := uuid.New().String()
 	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"exp": time.Now().Add(1 * time.Hour).Unix(),
+		"exp": expiresAt.Unix(),
 		"sub": userID,
+		"jti": tokenID,
+		"fid": familyID,
 	})
 	token, err := jwtToken.SignedString([]byte(refreshTokenKey))
 	if err != nil {
@@ -157,14 +414,40 @@ func (c *AuthUsecase) GenerateRefreshToken(userID string) (string, error) {
 		}
 	}
 
+	err = c.RefreshTokenRepository.Save(&entity.RefreshToken{
+		Id:        tokenID,
+		UserId:    userID,
+		FamilyId:  familyID,
+		TokenHash: helper.HashToken(token),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		c.Log.WithError(err).Error("Error while saving refresh token")
+		return "", &models.ErrorResponse{
+			Code:    500,
+			Status:  "Internal Server Error",
+			Message: "Something error",
+		}
+	}
+
 	return token, nil
 
 }
+
+// SignIn checks the email and password of the user. Every attempt past
+// validation is recorded in the audit log, failures with their reason.
 func (c *AuthUsecase) SignIn(request *models.SignInRequest) (*models.AuthResponse, error) {
 	err := c.ValidateRequest(request)
 	if err != nil {
 		return nil, err
 	}
+
+	err = c.LoginAttempts.Check(request.Email, request.Client.IpAddress)
+	if err != nil {
+		c.RecordSignInFailure("", request.Client, "too many attempts for "+request.Email)
+		return nil, err
+	}
+
 	user, err := c.Repository.FindOneByEmail(request.Email)
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		c.Log.Errorf("%v", err)
@@ -179,6 +462,8 @@ func (c *AuthUsecase) SignIn(request *models.SignInRequest) (*models.AuthRespons
 		c.Log.WithFields(logrus.Fields{
 			"email": request.Email,
 		}).Warn("User not found")
+		c.LoginAttempts.RegisterFailure(request.Email, request.Client.IpAddress)
+		c.RecordSignInFailure("", request.Client, "unknown email "+request.Email)
 		return nil, &models.ErrorResponse{
 			Code:    401,
 			Message: "Email or password is incorrect",
@@ -186,17 +471,216 @@ func (c *AuthUsecase) SignIn(request *models.SignInRequest) (*models.AuthRespons
 		}
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(request.Password))
-	if err != nil {
+	if !verifyPassword(c.Hasher, user.Password, request.Password) {
 		c.Log.WithFields(logrus.Fields{
-			"password": request.Password,
+			"email": request.Email,
 		}).Warn("Password not match")
+		c.LoginAttempts.RegisterFailure(request.Email, request.Client.IpAddress)
+		c.RecordSignInFailure(user.Id, request.Client, "wrong password")
 		return nil, &models.ErrorResponse{
 			Code:    401,
 			Message: "Email or password is incorrect",
 			Status:  "Unauthorized",
 		}
 	}
+	c.LoginAttempts.RegisterSuccess(request.Email)
+	c.rehashPassword(user, request.Password)
+
+	if user.DisabledAt != nil {
+		c.RecordSignInFailure(user.Id, request.Client, "account disabled")
+		return nil, accountDisabled()
+	}
+
+	if user.EmailVerifiedAt == nil && c.Viper.GetString("auth.email_verification.unverified_sign_in") == "refuse" {
+		c.RecordSignInFailure(user.Id, request.Client, "email not verified")
+		return nil, &models.ErrorResponse{
+			Code:    403,
+			Message: "Email is not verified",
+			Status:  "Forbidden",
+		}
+	}
+
+	scopes := uniqueScopes(request.Scopes)
+	if user.TotpEnabledAt != nil {
+		mfaToken, err := c.GenerateMfaToken(user.Id, scopes, "pwd")
+		if err != nil {
+			return nil, err
+		}
+		c.AuditLog.RecordWithDetails(models.Actor{UserId: user.Id, Client: request.Client}, entity.AuditActionSignIn, "", entity.AuditOutcomeSuccess, "second factor required")
+
+		return &models.AuthResponse{
+			MfaRequired: true,
+			MfaToken:    mfaToken,
+		}, nil
+	}
+
+	return c.StartScopedSession(user.Id, request.Client, scopes, "pwd")
+}
+
+// RecordSignInFailure adds a failed sign in to the audit log. userID is
+// empty when no account matches, the reason then names the email tried.
+func (c *AuthUsecase) RecordSignInFailure(userID string, client models.ClientInfo, reason string) {
+	c.AuditLog.RecordWithDetails(models.Actor{UserId: userID, Client: client}, entity.AuditActionSignIn, "", entity.AuditOutcomeFailure, reason)
+}
+
+// CheckUserEnabled refuses users whose account was disabled by an admin. It
+// runs on every authenticated request, so disabling takes effect before the
+// access tokens and API keys of the user expire.
+func (c *AuthUsecase) CheckUserEnabled(userID string) error {
+	disabled, err := c.Repository.IsDisabled(userID)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while checking whether user is disabled")
+		return &models.ErrorResponse{
+			Code:    500,
+			Status:  "Internal Server Error",
+			Message: "Something error",
+		}
+	}
+
+	if disabled {
+		return accountDisabled()
+	}
+
+	return nil
+}
+
+func accountDisabled() error {
+	return &models.ErrorResponse{
+		Code:    403,
+		Message: "Account is disabled",
+		Status:  "Forbidden",
+	}
+}
+
+// rehashPassword upgrades a hash made with another algorithm or outdated
+// parameters while the plaintext password is at hand. Failing to do so only
+// delays the upgrade to the next sign in.
+func (c *AuthUsecase) rehashPassword(user *entity.User, password string) {
+	if !c.Hasher.NeedsRehash(user.Password) {
+		return
+	}
+
+	hashedPassword, err := c.Hasher.Hash(password)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while rehashing password")
+		return
+	}
+
+	err = c.Repository.UpdatePassword(user.Id, hashedPassword)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while storing rehashed password")
+		return
+	}
+	user.Password = hashedPassword
+}
+
+// GenerateMfaToken issues the short-lived token handed out after a correct
+// password when the user still has to pass the second factor. It is signed
+// like an access token but its typ claim keeps it from being used as one.
+// methods are the first factors already passed and scopes those asked for
+// at sign in, both carried over to the session.
+func (c *AuthUsecase) GenerateMfaToken(userID string, scopes []string, methods ...string) (string, error) {
+	mapClaims := jwt.MapClaims{
+		"exp": time.Now().Add(time.Duration(c.Viper.GetInt("auth.mfa.pending_expiration")) * time.Second).Unix(),
+		"sub": userID,
+		"amr": methods,
+		"typ": mfaPendingTokenType,
+	}
+	if len(scopes) > 0 {
+		mapClaims["scope"] = strings.Join(scopes, " ")
+	}
+	token, err := c.KeySet.Sign(mapClaims)
+	if err != nil {
+		c.Log.Errorf("%v", err)
+		return "", &models.ErrorResponse{
+			Code:    500,
+			Status:  "Internal Server Error",
+			Message: "Something error",
+		}
+	}
+
+	return token, nil
+}
+
+// VerifyMfaToken returns the user, the first factors they passed and the
+// scopes they asked for.
+func (c *AuthUsecase) VerifyMfaToken(mfaToken string) (string, []string, []string, error) {
+	token, err := jwt.Parse(mfaToken, c.KeySet.Keyfunc, jwt.WithValidMethods(c.KeySet.Algorithms()))
+	if err != nil {
+		c.Log.WithError(err).Warn("Error parsing mfa token")
+		return "", nil, nil, &models.ErrorResponse{
+			Code:    401,
+			Status:  "Unauthorized",
+			Message: "Two-factor authentication token is invalid or expired",
+		}
+	}
+
+	mapClaims, _ := token.Claims.(jwt.MapClaims)
+	if mapClaims["typ"] != mfaPendingTokenType {
+		return "", nil, nil, &models.ErrorResponse{
+			Code:    401,
+			Status:  "Unauthorized",
+			Message: "Two-factor authentication token is invalid or expired",
+		}
+	}
+
+	sub, _ := mapClaims["sub"].(string)
+	methods := stringsClaim(mapClaims, "amr")
+	if len(methods) == 0 {
+		methods = []string{"pwd"}
+	}
+	scope, _ := mapClaims["scope"].(string)
+	return sub, methods, strings.Fields(scope), nil
+}
+
+// StartSession records a new session for the user and issues the access and
+// refresh token pair bound to it. The session id doubles as the refresh token
+// family id, so revoking one revokes the other. It is only reached once a
+// sign in succeeded, which is recorded in the audit log with the methods.
+func (c *AuthUsecase) StartSession(userID string, client models.ClientInfo, methods ...string) (*models.AuthResponse, error) {
+	return c.StartScopedSession(userID, client, nil, methods...)
+}
+
+// StartScopedSession is StartSession for a session limited to scopes, or
+// granted them all when scopes is empty.
+func (c *AuthUsecase) StartScopedSession(userID string, client models.ClientInfo, scopes []string, methods ...string) (*models.AuthResponse, error) {
+	result, err := c.StartClientSession(&entity.Session{
+		UserId:    userID,
+		UserAgent: client.UserAgent,
+		IpAddress: client.IpAddress,
+		Methods:   strings.Join(methods, " "),
+		Scopes:    strings.Join(scopes, " "),
+	}, true)
+	if err != nil {
+		return nil, err
+	}
+
+	c.AuditLog.RecordWithDetails(models.Actor{UserId: userID, Client: client}, entity.AuditActionSignIn, "", entity.AuditOutcomeSuccess, "methods "+strings.Join(methods, " "))
+	return result, nil
+}
+
+// StartClientSession records the session, which may be a grant to a
+// third-party application, and issues its access token, along with a refresh
+// token when withRefreshToken is set.
+func (c *AuthUsecase) StartClientSession(session *entity.Session, withRefreshToken bool) (*models.AuthResponse, error) {
+	now := time.Now()
+	session.Id = uuid.New().String()
+	session.CreatedAt = now
+	session.LastSeenAt = now
+	claims, err := c.accessTokenClaims(session)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.SessionRepository.Save(session)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while saving session")
+		return nil, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
 
 	var wg sync.WaitGroup
 	wg.Add(2)
@@ -205,40 +689,52 @@ func (c *AuthUsecase) SignIn(request *models.SignInRequest) (*models.AuthRespons
 	var errorChannel = make(chan error)
 	go func() {
 		defer wg.Done()
-		accessToken, err = c.GenerateAccessToken(user.Id)
+		token, err := c.GenerateAccessToken(claims)
 		if err != nil {
 			errorChannel <- err
 			return
 		}
 
+		accessToken = token
 		errorChannel <- nil
 
 	}()
 
 	go func() {
 		defer wg.Done()
-		refreshToken, err = c.GenerateRefreshToken(user.Id)
+		if !withRefreshToken {
+			errorChannel <- nil
+			return
+		}
+
+		token, err := c.GenerateRefreshToken(session.UserId, session.Id)
 		if err != nil {
 			errorChannel <- err
 			return
 		}
 
+		refreshToken = token
 		errorChannel <- nil
 
 	}()
 
+	var failed error
 	for i := 0; i < 2; i++ {
 		if e := <-errorChannel; e != nil {
-			c.Log.Errorf("%v", e)
-			return nil, &models.ErrorResponse{
-				Code:    500,
-				Message: "Something Error",
-				Status:  "Internal Server Error",
-			}
+			failed = e
 		}
 	}
 	wg.Wait()
 
+	if failed != nil {
+		c.Log.Errorf("%v", failed)
+		return nil, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
 	return &models.AuthResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
@@ -266,23 +762,20 @@ func (c *AuthUsecase) ParseTokenFromHeader(authorization string) (string, error)
 
 }
 
-func (c *AuthUsecase) VerifyAccessToken(accessToken string) (string, error) {
+func (c *AuthUsecase) VerifyAccessToken(accessToken string) (*models.AccessTokenClaims, error) {
 	if accessToken == "" {
-		return "", &models.ErrorResponse{
+		return nil, &models.ErrorResponse{
 			Code:    401,
 			Status:  "Unauthorized",
 			Message: "You're unauthorized",
 		}
 	}
-	accessTokenKey := c.Viper.GetString("token.key.access")
-	token, err := jwt.Parse(accessToken, func(token *jwt.Token) (interface{}, error) {
-		return []byte(accessTokenKey), nil
-	})
+	token, err := jwt.Parse(accessToken, c.KeySet.Keyfunc, jwt.WithValidMethods(c.KeySet.Algorithms()))
 
 	if err != nil {
 		c.Log.WithError(err).Error("Error parsing token")
 		if errors.Is(err, jwt.ErrTokenExpired) {
-			return "", &models.ErrorResponse{
+			return nil, &models.ErrorResponse{
 				Code:    401,
 				Status:  "Unauthorized",
 				Message: "Token expired",
@@ -290,34 +783,290 @@ func (c *AuthUsecase) VerifyAccessToken(accessToken string) (string, error) {
 
 		}
 
-		if errors.Is(err, jwt.ErrInvalidKey) {
-			return "", &models.ErrorResponse{
+		if errors.Is(err, jwt.ErrInvalidKey) || errors.Is(err, keyset.ErrUnknownKey) || errors.Is(err, keyset.ErrRetiredKey) {
+			return nil, &models.ErrorResponse{
 				Code:    401,
 				Status:  "Unauthorized",
 				Message: "Invalid key",
 			}
 		}
 
-		if !token.Valid {
-			return "", &models.ErrorResponse{
+		if token == nil || !token.Valid {
+			return nil, &models.ErrorResponse{
 				Code:    401,
 				Status:  "Unauthorized",
 				Message: "Invalid token",
 			}
 		}
 
-		return "", &models.ErrorResponse{
+		return nil, &models.ErrorResponse{
 			Code:    401,
 			Status:  "Unauthorized",
 			Message: err.Error(),
 		}
 	}
 
-	var sub string
-	if claims, ok := token.Claims.(jwt.MapClaims); ok {
-		sub = claims["sub"].(string)
+	claims := new(models.AccessTokenClaims)
+	if mapClaims, ok := token.Claims.(jwt.MapClaims); ok {
+		if _, ok := mapClaims["typ"]; ok {
+			return nil, &models.ErrorResponse{
+				Code:    401,
+				Status:  "Unauthorized",
+				Message: "Invalid token",
+			}
+		}
+
+		claims.TokenId, _ = mapClaims["jti"].(string)
+		claims.Subject, _ = mapClaims["sub"].(string)
+		claims.SessionId, _ = mapClaims["sid"].(string)
+		claims.Methods = stringsClaim(mapClaims, "amr")
+		claims.Roles = stringsClaim(mapClaims, "roles")
+		claims.Permissions = stringsClaim(mapClaims, "permissions")
+		claims.ClientId, _ = mapClaims["client_id"].(string)
+		if scope, ok := mapClaims["scope"].(string); ok {
+			claims.Scopes = strings.Fields(scope)
+		}
+		if act, ok := mapClaims["act"].(map[string]interface{}); ok {
+			claims.Actor, _ = act["sub"].(string)
+		}
+		if issuedAt, err := mapClaims.GetIssuedAt(); err == nil && issuedAt != nil {
+			claims.IssuedAt = issuedAt.Time
+		}
+		if expiresAt, err := mapClaims.GetExpirationTime(); err == nil && expiresAt != nil {
+			claims.ExpiresAt = expiresAt.Time
+		}
 	}
 
-	return sub, nil
+	if claims.TokenId != "" {
+		err = c.checkDenyList(claims.TokenId)
+		if err != nil {
+			return nil, err
+		}
+	}
 
+	if claims.SessionId != "" {
+		err = c.verifySession(claims.SessionId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return claims, nil
+
+}
+
+// checkDenyList rejects access tokens revoked on their own, through the
+// revocation endpoint, before their exp.
+func (c *AuthUsecase) checkDenyList(tokenID string) error {
+	revoked, err := c.RevokedAccessTokenRepository.Exists(tokenID)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while checking revoked access tokens")
+		return &models.ErrorResponse{
+			Code:    500,
+			Status:  "Internal Server Error",
+			Message: "Something error",
+		}
+	}
+
+	if revoked {
+		return &models.ErrorResponse{
+			Code:    401,
+			Status:  "Unauthorized",
+			Message: "Token has been revoked",
+		}
+	}
+
+	return nil
+}
+
+// RevokeAccessToken puts an access token on the deny-list until it expires.
+// It reports false when accessToken isn't a valid access token, which leaves
+// nothing to revoke. clientID must be the client the token was issued to,
+// empty for first-party tokens.
+func (c *AuthUsecase) RevokeAccessToken(accessToken string, clientID string) (bool, error) {
+	token, err := jwt.Parse(accessToken, c.KeySet.Keyfunc, jwt.WithValidMethods(c.KeySet.Algorithms()))
+	if err != nil {
+		return false, nil
+	}
+
+	mapClaims, _ := token.Claims.(jwt.MapClaims)
+	if _, ok := mapClaims["typ"]; ok {
+		return false, nil
+	}
+
+	tokenClientID, _ := mapClaims["client_id"].(string)
+	if tokenClientID != clientID {
+		return false, tokenOfAnotherClient()
+	}
+
+	tokenID, _ := mapClaims["jti"].(string)
+	if tokenID == "" {
+		// Tokens issued before the deny-list have no jti, only their whole
+		// session can be revoked.
+		sessionID, _ := mapClaims["sid"].(string)
+		if sessionID == "" {
+			return false, nil
+		}
+		return true, c.revokeSession(sessionID)
+	}
+
+	expiresAt, err := mapClaims.GetExpirationTime()
+	if err != nil || expiresAt == nil {
+		return false, nil
+	}
+
+	now := time.Now()
+	err = c.RevokedAccessTokenRepository.Save(&entity.RevokedAccessToken{
+		Jti:       tokenID,
+		ExpiresAt: expiresAt.Time,
+		CreatedAt: now,
+	})
+	if err != nil {
+		c.Log.WithError(err).Error("Error while revoking access token")
+		return false, &models.ErrorResponse{
+			Code:    500,
+			Status:  "Internal Server Error",
+			Message: "Something error",
+		}
+	}
+
+	// Expired entries are useless, dropping them here keeps the list as
+	// short as the number of live revoked tokens.
+	err = c.RevokedAccessTokenRepository.DeleteExpired(now)
+	if err != nil {
+		c.Log.WithError(err).Warn("Error while deleting expired revoked access tokens")
+	}
+
+	return true, nil
+}
+
+// RevokeRefreshToken revokes the refresh token along with its session, so the
+// access tokens issued from it stop working too. It reports false for an
+// unknown token. clientID works as for RevokeAccessToken.
+func (c *AuthUsecase) RevokeRefreshToken(refreshToken string, clientID string) (bool, error) {
+	stored, err := c.RefreshTokenRepository.FindOneByHash(helper.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+
+		c.Log.WithError(err).Error("Error while finding refresh token")
+		return false, &models.ErrorResponse{
+			Code:    500,
+			Status:  "Internal Server Error",
+			Message: "Something error",
+		}
+	}
+
+	session, err := c.SessionRepository.FindOneById(stored.FamilyId)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.Log.WithError(err).Error("Error while finding session of refresh token")
+		return false, &models.ErrorResponse{
+			Code:    500,
+			Status:  "Internal Server Error",
+			Message: "Something error",
+		}
+	}
+	tokenClientID := ""
+	if session != nil {
+		tokenClientID = sessionClientId(session)
+	}
+	if tokenClientID != clientID {
+		return false, tokenOfAnotherClient()
+	}
+
+	return true, c.revokeSession(stored.FamilyId)
+}
+
+// revokeSession revokes a session and its refresh token family, which share
+// their id.
+func (c *AuthUsecase) revokeSession(sessionID string) error {
+	err := c.SessionRepository.Revoke(sessionID)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while revoking session")
+		return &models.ErrorResponse{
+			Code:    500,
+			Status:  "Internal Server Error",
+			Message: "Something error",
+		}
+	}
+
+	err = c.RefreshTokenRepository.RevokeFamily(sessionID)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while revoking refresh token family")
+		return &models.ErrorResponse{
+			Code:    500,
+			Status:  "Internal Server Error",
+			Message: "Something error",
+		}
+	}
+
+	return nil
+}
+
+func tokenOfAnotherClient() error {
+	return &models.ErrorResponse{
+		Code:    403,
+		Status:  "Forbidden",
+		Message: "Token was issued to another client",
+	}
+}
+
+// verifySession rejects access tokens whose session has been revoked, so
+// signing a device out takes effect before the token's own expiry.
+func (c *AuthUsecase) verifySession(sessionID string) error {
+	session, err := c.SessionRepository.FindOneById(sessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &models.ErrorResponse{
+				Code:    401,
+				Status:  "Unauthorized",
+				Message: "Session has been revoked",
+			}
+		}
+
+		c.Log.WithError(err).Error("Error while finding session")
+		return &models.ErrorResponse{
+			Code:    500,
+			Status:  "Internal Server Error",
+			Message: "Something error",
+		}
+	}
+
+	if session.RevokedAt != nil {
+		return &models.ErrorResponse{
+			Code:    401,
+			Status:  "Unauthorized",
+			Message: "Session has been revoked",
+		}
+	}
+
+	now := time.Now()
+	if now.Sub(session.LastSeenAt) > time.Minute {
+		err = c.SessionRepository.Touch(session.Id, now)
+		if err != nil {
+			c.Log.WithError(err).Warn("Error while updating session last seen time")
+		}
+	}
+
+	return nil
+}
+
+func stringsClaim(mapClaims jwt.MapClaims, key string) []string {
+	var values []string
+	if items, ok := mapClaims[key].([]interface{}); ok {
+		for _, item := range items {
+			if value, ok := item.(string); ok {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+func sessionClientId(session *entity.Session) string {
+	if session.ClientId == nil {
+		return ""
+	}
+	return *session.ClientId
 }
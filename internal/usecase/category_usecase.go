@@ -0,0 +1,301 @@
+package usecase
+
+import (
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go-crud/internal/entity"
+	"go-crud/internal/helper"
+	"go-crud/internal/models"
+	"go-crud/internal/repository"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+type CategoryUsecase struct {
+	Repository repository.CategoryRepositoryInterface
+	Validate   *validator.Validate
+	Log        *logrus.Logger
+}
+
+func NewCategoryUsecase(repository repository.CategoryRepositoryInterface, validate *validator.Validate, log *logrus.Logger) *CategoryUsecase {
+	return &CategoryUsecase{
+		Repository: repository,
+		Validate:   validate,
+		Log:        log,
+	}
+}
+
+func (c *CategoryUsecase) ValidateRequest(request any) error {
+	err := c.Validate.Struct(request)
+	if err != nil {
+		c.Log.WithError(err).Warn("Error validating request")
+		message := helper.GetFirstValidationErrorAndConvert(err)
+		return &models.ErrorResponse{
+			Code:    400,
+			Status:  "Bad Request",
+			Message: message,
+		}
+	}
+	return nil
+}
+
+func (c *CategoryUsecase) CreateCategory(request *models.CategoryRequest) (*models.CategoryResponse, error) {
+	err := c.ValidateRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	slug, err := c.availableSlug(request, "")
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	category := &entity.Category{
+		Id:        uuid.New().String(),
+		Name:      request.Name,
+		Slug:      slug,
+		Path:      slug,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if request.ParentId != "" {
+		parent, err := c.findParent(request.ParentId)
+		if err != nil {
+			return nil, err
+		}
+		category.ParentId = &parent.Id
+		category.Path = parent.Path + "/" + slug
+	}
+
+	err = c.Repository.Save(category)
+	if err != nil {
+		return nil, c.serverError(err, "Error while creating category")
+	}
+
+	response := toCategoryResponse(category)
+	return &response, nil
+}
+
+// GetCategoryTree lists the root categories with their subcategories.
+func (c *CategoryUsecase) GetCategoryTree() (*[]models.CategoryResponse, error) {
+	categories, err := c.Repository.FindAll()
+	if err != nil {
+		return nil, c.serverError(err, "Error while getting categories")
+	}
+
+	tree := buildCategoryTree(categories)
+	return &tree, nil
+}
+
+// GetCategory returns the category with its subcategories and the categories
+// above it, for browsing the tree from there.
+func (c *CategoryUsecase) GetCategory(slug string) (*models.CategoryResponse, error) {
+	category, err := c.Repository.FindOneBySlug(slug)
+	if err != nil {
+		return nil, c.notFound(err, "Error while getting category")
+	}
+
+	subtree, err := c.Repository.FindSubtree(category.Path)
+	if err != nil {
+		return nil, c.serverError(err, "Error while getting subcategories")
+	}
+	segments := strings.Split(category.Path, "/")
+	ancestorPaths := make([]string, len(segments)-1)
+	for index := range ancestorPaths {
+		ancestorPaths[index] = strings.Join(segments[:index+1], "/")
+	}
+	ancestors, err := c.Repository.FindManyByPaths(ancestorPaths)
+	if err != nil {
+		return nil, c.serverError(err, "Error while getting parent categories")
+	}
+
+	response := buildCategoryTree(subtree)[0]
+	response.Ancestors = make([]models.CategoryResponse, len(ancestors))
+	for index := range ancestors {
+		response.Ancestors[index] = toCategoryResponse(&ancestors[index])
+	}
+	return &response, nil
+}
+
+// UpdateCategory renames or moves the category, its subcategories move along.
+func (c *CategoryUsecase) UpdateCategory(categoryID string, request *models.CategoryRequest) (*models.CategoryResponse, error) {
+	err := c.ValidateRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	category, err := c.Repository.FindOneById(categoryID)
+	if err != nil {
+		return nil, c.notFound(err, "Error while getting category")
+	}
+	slug, err := c.availableSlug(request, category.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	oldPath := category.Path
+	category.Name = request.Name
+	category.Slug = slug
+	category.ParentId = nil
+	category.Path = slug
+	if request.ParentId != "" {
+		parent, err := c.findParent(request.ParentId)
+		if err != nil {
+			return nil, err
+		}
+		if parent.Id == category.Id || strings.HasPrefix(parent.Path, oldPath+"/") {
+			return nil, &models.ErrorResponse{
+				Code:    400,
+				Message: "A category can't be moved under itself",
+				Status:  "Bad Request",
+			}
+		}
+		category.ParentId = &parent.Id
+		category.Path = parent.Path + "/" + slug
+	}
+
+	err = c.Repository.Update(category, oldPath)
+	if err != nil {
+		return nil, c.serverError(err, "Error while updating category")
+	}
+
+	response := toCategoryResponse(category)
+	return &response, nil
+}
+
+// DeleteCategory removes a category without subcategories. Its products stay,
+// without a category.
+func (c *CategoryUsecase) DeleteCategory(categoryID string) error {
+	_, err := c.Repository.FindOneById(categoryID)
+	if err != nil {
+		return c.notFound(err, "Error while getting category")
+	}
+
+	children, err := c.Repository.CountChildren(categoryID)
+	if err != nil {
+		return c.serverError(err, "Error while counting subcategories")
+	}
+	if children > 0 {
+		return &models.ErrorResponse{
+			Code:    409,
+			Message: "Move or delete the subcategories first",
+			Status:  "Conflict",
+		}
+	}
+
+	err = c.Repository.DeleteById(categoryID)
+	if err != nil {
+		return c.serverError(err, "Error while deleting category")
+	}
+	return nil
+}
+
+// availableSlug returns the slug of request, unless another category than
+// categoryID already has it.
+func (c *CategoryUsecase) availableSlug(request *models.CategoryRequest, categoryID string) (string, error) {
+	slug := request.Slug
+	if slug == "" {
+		slug = request.Name
+	}
+	slug = helper.Slugify(slug)
+	if slug == "" {
+		return "", &models.ErrorResponse{
+			Code:    400,
+			Message: "Slug needs a letter or a digit",
+			Status:  "Bad Request",
+		}
+	}
+
+	existing, err := c.Repository.FindOneBySlug(slug)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", c.serverError(err, "Error while checking category slug")
+	}
+	if err == nil && existing.Id != categoryID {
+		return "", &models.ErrorResponse{
+			Code:    409,
+			Message: "Slug is already taken",
+			Status:  "Conflict",
+		}
+	}
+	return slug, nil
+}
+
+func (c *CategoryUsecase) findParent(parentID string) (*entity.Category, error) {
+	parent, err := c.Repository.FindOneById(parentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &models.ErrorResponse{
+				Code:    400,
+				Message: "Parent category not found",
+				Status:  "Bad Request",
+			}
+		}
+		return nil, c.serverError(err, "Error while getting parent category")
+	}
+	return parent, nil
+}
+
+func (c *CategoryUsecase) notFound(err error, message string) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.ErrorResponse{
+			Code:    404,
+			Message: "Category not found",
+			Status:  "Not Found",
+		}
+	}
+	return c.serverError(err, message)
+}
+
+func (c *CategoryUsecase) serverError(err error, message string) error {
+	c.Log.WithError(err).Error(message)
+	return &models.ErrorResponse{
+		Code:    500,
+		Message: "Something Wrong",
+		Status:  "Internal Server Error",
+	}
+}
+
+// buildCategoryTree nests categories under their parent. Categories whose
+// parent isn't in the list are the roots.
+func buildCategoryTree(categories []entity.Category) []models.CategoryResponse {
+	known := make(map[string]bool, len(categories))
+	for _, category := range categories {
+		known[category.Id] = true
+	}
+	children := make(map[string][]entity.Category)
+	var roots []entity.Category
+	for _, category := range categories {
+		if category.ParentId == nil || !known[*category.ParentId] {
+			roots = append(roots, category)
+			continue
+		}
+		children[*category.ParentId] = append(children[*category.ParentId], category)
+	}
+
+	var build func(categories []entity.Category) []models.CategoryResponse
+	build = func(categories []entity.Category) []models.CategoryResponse {
+		responses := make([]models.CategoryResponse, len(categories))
+		for index := range categories {
+			responses[index] = toCategoryResponse(&categories[index])
+			responses[index].Children = build(children[categories[index].Id])
+		}
+		return responses
+	}
+	return build(roots)
+}
+
+func toCategoryResponse(category *entity.Category) models.CategoryResponse {
+	response := models.CategoryResponse{
+		Id:        category.Id,
+		Name:      category.Name,
+		Slug:      category.Slug,
+		Path:      category.Path,
+		CreatedAt: category.CreatedAt,
+	}
+	if category.ParentId != nil {
+		response.ParentId = *category.ParentId
+	}
+	return response
+}
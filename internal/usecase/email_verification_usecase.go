@@ -0,0 +1,368 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"go-crud/internal/entity"
+	"go-crud/internal/helper"
+	"go-crud/internal/mail"
+	"go-crud/internal/models"
+	"go-crud/internal/repository"
+	"gorm.io/gorm"
+	"net/url"
+	"time"
+)
+
+type EmailVerificationUsecase struct {
+	UserRepository      repository.UserRepositoryInterface
+	UserTokenRepository repository.UserTokenRepositoryInterface
+	MailSender          mail.Sender
+	Validate            *validator.Validate
+	Viper               *viper.Viper
+	Log                 *logrus.Logger
+}
+
+func NewEmailVerificationUsecase(userRepository repository.UserRepositoryInterface, userTokenRepository repository.UserTokenRepositoryInterface, mailSender mail.Sender, validate *validator.Validate, viper *viper.Viper, log *logrus.Logger) *EmailVerificationUsecase {
+	return &EmailVerificationUsecase{
+		UserRepository:      userRepository,
+		UserTokenRepository: userTokenRepository,
+		MailSender:          mailSender,
+		Validate:            validate,
+		Viper:               viper,
+		Log:                 log,
+	}
+}
+
+func (c *EmailVerificationUsecase) ValidateRequest(request any) error {
+	err := c.Validate.Struct(request)
+	if err != nil {
+		c.Log.WithError(err).Warn("Error validating request")
+		message := helper.GetFirstValidationErrorAndConvert(err)
+		return &models.ErrorResponse{
+			Code:    400,
+			Status:  "Bad Request",
+			Message: message,
+		}
+	}
+	return nil
+}
+
+// SendVerification mails a new verification link to the user. Links sent
+// before stop working.
+func (c *EmailVerificationUsecase) SendVerification(user *entity.User) error {
+	expiration := time.Duration(c.Viper.GetInt("auth.email_verification.expiration")) * time.Second
+	token, err := c.issueToken(user.Id, entity.UserTokenEmailVerification, expiration)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/auth/verify-email?token=%s", c.Viper.GetString("web.url"), url.QueryEscape(token))
+	err = c.MailSender.Send(&mail.Message{
+		From:    c.Viper.GetString("mail.from"),
+		To:      user.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Hi %s,\n\nConfirm this is your email address by opening the link below. It expires in %d hours.\n\n%s\n\nVerification token: %s",
+			user.Name, int(expiration.Hours()), link, token),
+	})
+	if err != nil {
+		c.Log.WithError(err).Error("Error while sending verification email")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	return nil
+}
+
+// SendEmailChange mails a confirmation link to the pending email of the user,
+// and tells the current email about the change. Links sent before stop
+// working.
+func (c *EmailVerificationUsecase) SendEmailChange(user *entity.User) error {
+	expiration := time.Duration(c.Viper.GetInt("auth.email_verification.expiration")) * time.Second
+	token, err := c.issueToken(user.Id, entity.UserTokenEmailChange, expiration)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/auth/verify-email/change?token=%s", c.Viper.GetString("web.url"), url.QueryEscape(token))
+	err = c.MailSender.Send(&mail.Message{
+		From:    c.Viper.GetString("mail.from"),
+		To:      *user.PendingEmail,
+		Subject: "Confirm your new email",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen the link below to make this your email address. It expires in %d hours.\n\n%s\n\nConfirmation token: %s",
+			user.Name, int(expiration.Hours()), link, token),
+	})
+	if err != nil {
+		c.Log.WithError(err).Error("Error while sending email change confirmation")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	err = c.MailSender.Send(&mail.Message{
+		From:    c.Viper.GetString("mail.from"),
+		To:      user.Email,
+		Subject: "Your email is being changed",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to change the email of your account to %s. It only changes once the new address is confirmed.\n\nIf this wasn't you, change your password and sign out your other sessions.",
+			user.Name, *user.PendingEmail),
+	})
+	if err != nil {
+		c.Log.WithError(err).Error("Error while sending email change notice")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	return nil
+}
+
+// issueToken saves a new token of the purpose for the user and returns the
+// secret to mail. Tokens of the same purpose sent before stop working.
+func (c *EmailVerificationUsecase) issueToken(userID string, purpose string, expiration time.Duration) (string, error) {
+	err := c.UserTokenRepository.InvalidateByUserId(userID, purpose)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while invalidating previous verification tokens")
+		return "", &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	token, err := helper.GenerateRandomToken(32)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while generating verification token")
+		return "", &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	err = c.UserTokenRepository.Save(&entity.UserToken{
+		Id:        uuid.New().String(),
+		UserId:    userID,
+		Purpose:   purpose,
+		TokenHash: helper.HashToken(token),
+		ExpiresAt: time.Now().Add(expiration),
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		c.Log.WithError(err).Error("Error while saving verification token")
+		return "", &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	return token, nil
+}
+
+func (c *EmailVerificationUsecase) VerifyEmail(request *models.VerifyEmailRequest) error {
+	err := c.ValidateRequest(request)
+	if err != nil {
+		return err
+	}
+
+	invalidToken := &models.ErrorResponse{
+		Code:    400,
+		Message: "Verification token is invalid or expired",
+		Status:  "Bad Request",
+	}
+
+	token, err := c.UserTokenRepository.FindOneByHash(helper.HashToken(request.Token), entity.UserTokenEmailVerification)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return invalidToken
+		}
+
+		c.Log.WithError(err).Error("Error while finding verification token")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	if token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+		return invalidToken
+	}
+
+	marked, err := c.UserTokenRepository.MarkAsUsed(token.Id)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while marking verification token as used")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+	if !marked {
+		return invalidToken
+	}
+
+	err = c.UserRepository.MarkEmailVerified(token.UserId, time.Now())
+	if err != nil {
+		c.Log.WithError(err).Error("Error while marking email as verified")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	return nil
+}
+
+// ConfirmEmailChange redeems an email change token and makes the pending
+// email the email of the user. Reset and magic links mailed to the old
+// address stop working.
+func (c *EmailVerificationUsecase) ConfirmEmailChange(request *models.VerifyEmailRequest) error {
+	err := c.ValidateRequest(request)
+	if err != nil {
+		return err
+	}
+
+	invalidToken := &models.ErrorResponse{
+		Code:    400,
+		Message: "Confirmation token is invalid or expired",
+		Status:  "Bad Request",
+	}
+
+	token, err := c.UserTokenRepository.FindOneByHash(helper.HashToken(request.Token), entity.UserTokenEmailChange)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return invalidToken
+		}
+
+		c.Log.WithError(err).Error("Error while finding email change token")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	if token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+		return invalidToken
+	}
+
+	marked, err := c.UserTokenRepository.MarkAsUsed(token.Id)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while marking email change token as used")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+	if !marked {
+		return invalidToken
+	}
+
+	user := new(entity.User)
+	err = c.UserRepository.FindOneById(user, token.UserId)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while finding user to change email")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+	if user.PendingEmail == nil {
+		return invalidToken
+	}
+
+	existing, err := c.UserRepository.FindOneByEmail(*user.PendingEmail)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.Log.WithError(err).Error("Error while finding user by email")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+	if existing != nil && existing.Id != user.Id {
+		return &models.ErrorResponse{
+			Code:    400,
+			Message: "Email already exists",
+			Status:  "Bad Request",
+		}
+	}
+
+	changed, err := c.UserRepository.ConfirmPendingEmail(user.Id, *user.PendingEmail, time.Now())
+	if err != nil {
+		c.Log.WithError(err).Error("Error while confirming pending email")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+	if !changed {
+		return invalidToken
+	}
+
+	for _, purpose := range []string{entity.UserTokenPasswordReset, entity.UserTokenMagicLink} {
+		err = c.UserTokenRepository.InvalidateByUserId(user.Id, purpose)
+		if err != nil {
+			c.Log.WithError(err).WithField("purpose", purpose).Error("Error while invalidating tokens after email change")
+		}
+	}
+
+	return nil
+}
+
+// ResendVerification mails a fresh link, at most once per
+// auth.email_verification.resend_interval seconds. Unknown and already
+// verified emails and throttled requests are all silently ignored, so the
+// answer never tells whether an email is registered.
+func (c *EmailVerificationUsecase) ResendVerification(request *models.ResendVerificationRequest) error {
+	err := c.ValidateRequest(request)
+	if err != nil {
+		return err
+	}
+
+	user, err := c.UserRepository.FindOneByEmail(request.Email)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.Log.WithError(err).Error("Error while getting user by email")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	if user == nil || user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	latest, err := c.UserTokenRepository.FindLatestByUserId(user.Id, entity.UserTokenEmailVerification)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.Log.WithError(err).Error("Error while finding latest verification token")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	interval := time.Duration(c.Viper.GetInt("auth.email_verification.resend_interval")) * time.Second
+	if latest != nil && time.Since(latest.CreatedAt) < interval {
+		return nil
+	}
+
+	return c.SendVerification(user)
+}
@@ -0,0 +1,188 @@
+package usecase
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"go-crud/internal/entity"
+	"go-crud/internal/models"
+	"go-crud/internal/repository"
+	"math"
+	"strings"
+	"time"
+)
+
+// LoginAttemptUsecase slows down password guessing. Failures are counted per
+// account and per IP address: an account is first delayed, growing with each
+// failure, then locked; an address is blocked once it fails too often across
+// any account.
+type LoginAttemptUsecase struct {
+	Repository repository.LoginAttemptRepositoryInterface
+	Viper      *viper.Viper
+	Log        *logrus.Logger
+	// Now is the clock attempts are recorded with, replaced in tests.
+	Now func() time.Time
+}
+
+func NewLoginAttemptUsecase(repository repository.LoginAttemptRepositoryInterface, viper *viper.Viper, log *logrus.Logger) *LoginAttemptUsecase {
+	return &LoginAttemptUsecase{
+		Repository: repository,
+		Viper:      viper,
+		Log:        log,
+		Now:        time.Now,
+	}
+}
+
+func accountAttemptKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func addressAttemptKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Check returns a 423 error while the account is locked and a 429 error
+// while the address is blocked or the account has to wait before trying
+// again.
+func (c *LoginAttemptUsecase) Check(email string, ip string) error {
+	now := c.Now()
+
+	if ip != "" {
+		address, err := c.find(addressAttemptKey(ip), now)
+		if err != nil {
+			return err
+		}
+		if address != nil && address.LockedUntil != nil {
+			return &models.ErrorResponse{
+				Code:    429,
+				Message: fmt.Sprintf("Too many failed sign in attempts from this address, try again in %d seconds", secondsUntil(now, *address.LockedUntil)),
+				Status:  "Too Many Requests",
+			}
+		}
+	}
+
+	account, err := c.find(accountAttemptKey(email), now)
+	if err != nil || account == nil {
+		return err
+	}
+
+	if account.LockedUntil != nil {
+		return &models.ErrorResponse{
+			Code:    423,
+			Message: fmt.Sprintf("Account is temporarily locked, try again in %d seconds or reset your password", secondsUntil(now, *account.LockedUntil)),
+			Status:  "Locked",
+		}
+	}
+
+	retryAt := account.LastFailedAt.Add(c.delay(account.Failures))
+	if now.Before(retryAt) {
+		return &models.ErrorResponse{
+			Code:    429,
+			Message: fmt.Sprintf("Too many sign in attempts, try again in %d seconds", secondsUntil(now, retryAt)),
+			Status:  "Too Many Requests",
+		}
+	}
+
+	return nil
+}
+
+// RegisterFailure counts a failed attempt and locks the account or the
+// address once they reach their threshold. Storage errors are only logged so
+// they never turn a wrong password into a 500.
+func (c *LoginAttemptUsecase) RegisterFailure(email string, ip string) {
+	now := c.Now()
+	window := time.Duration(c.Viper.GetInt("auth.lockout.window")) * time.Second
+	lockout := time.Duration(c.Viper.GetInt("auth.lockout.duration")) * time.Second
+
+	account, err := c.Repository.Increment(accountAttemptKey(email), now, window)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while counting failed sign in of account")
+	} else if account.Failures >= c.Viper.GetInt("auth.lockout.max_attempts") {
+		c.Log.WithField("email", email).Warn("Account locked after too many failed sign in attempts")
+		err = c.Repository.Lock(account.Key, now.Add(lockout))
+		if err != nil {
+			c.Log.WithError(err).Error("Error while locking account")
+		}
+	}
+
+	if ip == "" {
+		return
+	}
+
+	address, err := c.Repository.Increment(addressAttemptKey(ip), now, window)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while counting failed sign in of address")
+	} else if address.Failures >= c.Viper.GetInt("auth.lockout.ip_max_attempts") {
+		c.Log.WithField("ip", ip).Warn("Address blocked after too many failed sign in attempts")
+		err = c.Repository.Lock(address.Key, now.Add(lockout))
+		if err != nil {
+			c.Log.WithError(err).Error("Error while blocking address")
+		}
+	}
+}
+
+// RegisterSuccess clears the failures of the account. Those of the address
+// are kept so an attacker can't reset them by signing in to their own
+// account.
+func (c *LoginAttemptUsecase) RegisterSuccess(email string) {
+	err := c.Repository.Reset(accountAttemptKey(email))
+	if err != nil {
+		c.Log.WithError(err).Error("Error while clearing failed sign ins of account")
+	}
+}
+
+// Unlock lifts the lock of an account, e.g. after its password is reset.
+func (c *LoginAttemptUsecase) Unlock(email string) error {
+	err := c.Repository.Reset(accountAttemptKey(email))
+	if err != nil {
+		c.Log.WithError(err).Error("Error while unlocking account")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+	return nil
+}
+
+// find returns the counter of key, dropping it once its lock has expired so
+// the next failure starts from zero.
+func (c *LoginAttemptUsecase) find(key string, now time.Time) (*entity.LoginAttempt, error) {
+	attempt, err := c.Repository.FindOneByKey(key)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while finding failed sign ins")
+		return nil, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	if attempt != nil && attempt.LockedUntil != nil && !now.Before(*attempt.LockedUntil) {
+		err = c.Repository.Reset(key)
+		if err != nil {
+			c.Log.WithError(err).Error("Error while clearing expired lock")
+		}
+		return nil, nil
+	}
+
+	return attempt, nil
+}
+
+// delay is how long an account waits after its last failure: nothing for the
+// first auth.lockout.delay_after failures, then delay_base seconds doubling
+// with each failure up to delay_max.
+func (c *LoginAttemptUsecase) delay(failures int) time.Duration {
+	over := failures - c.Viper.GetInt("auth.lockout.delay_after")
+	if over < 0 {
+		return 0
+	}
+
+	seconds := float64(c.Viper.GetInt("auth.lockout.delay_base")) * math.Pow(2, float64(over))
+	seconds = math.Min(seconds, float64(c.Viper.GetInt("auth.lockout.delay_max")))
+	return time.Duration(seconds) * time.Second
+}
+
+func secondsUntil(now time.Time, until time.Time) int {
+	return int(math.Ceil(until.Sub(now).Seconds()))
+}
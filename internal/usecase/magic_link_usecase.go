@@ -0,0 +1,203 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"go-crud/internal/entity"
+	"go-crud/internal/helper"
+	"go-crud/internal/mail"
+	"go-crud/internal/models"
+	"go-crud/internal/repository"
+	"gorm.io/gorm"
+	"net/url"
+	"time"
+)
+
+// MagicLinkMethod is the amr value of sessions started with a link mailed to
+// the user. RFC 8176 has no value for proof of a mailbox.
+const MagicLinkMethod = "email"
+
+type MagicLinkUsecase struct {
+	UserRepository      repository.UserRepositoryInterface
+	UserTokenRepository repository.UserTokenRepositoryInterface
+	AuthUsecase         *AuthUsecase
+	MailSender          mail.Sender
+	Validate            *validator.Validate
+	Viper               *viper.Viper
+	Log                 *logrus.Logger
+}
+
+func NewMagicLinkUsecase(userRepository repository.UserRepositoryInterface, userTokenRepository repository.UserTokenRepositoryInterface, authUsecase *AuthUsecase, mailSender mail.Sender, validate *validator.Validate, viper *viper.Viper, log *logrus.Logger) *MagicLinkUsecase {
+	return &MagicLinkUsecase{
+		UserRepository:      userRepository,
+		UserTokenRepository: userTokenRepository,
+		AuthUsecase:         authUsecase,
+		MailSender:          mailSender,
+		Validate:            validate,
+		Viper:               viper,
+		Log:                 log,
+	}
+}
+
+func (c *MagicLinkUsecase) ValidateRequest(request any) error {
+	err := c.Validate.Struct(request)
+	if err != nil {
+		c.Log.WithError(err).Warn("Error validating request")
+		message := helper.GetFirstValidationErrorAndConvert(err)
+		return &models.ErrorResponse{
+			Code:    400,
+			Status:  "Bad Request",
+			Message: message,
+		}
+	}
+	return nil
+}
+
+// RequestLink mails a sign in link when the email belongs to a user. Like
+// ForgotPassword it succeeds for unknown emails too.
+func (c *MagicLinkUsecase) RequestLink(request *models.MagicLinkRequest) error {
+	err := c.ValidateRequest(request)
+	if err != nil {
+		return err
+	}
+
+	user, err := c.UserRepository.FindOneByEmail(request.Email)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.serverError(err, "Error while getting user by email")
+	}
+
+	if user == nil {
+		c.Log.WithField("email", request.Email).Warn("Magic link requested for unknown email")
+		return nil
+	}
+
+	return c.SendLink(user)
+}
+
+// SendLink issues a new sign in token for the user and mails it. Links sent
+// before stop working.
+func (c *MagicLinkUsecase) SendLink(user *entity.User) error {
+	err := c.UserTokenRepository.InvalidateByUserId(user.Id, entity.UserTokenMagicLink)
+	if err != nil {
+		return c.serverError(err, "Error while invalidating previous magic links")
+	}
+
+	token, err := helper.GenerateRandomToken(32)
+	if err != nil {
+		return c.serverError(err, "Error while generating magic link token")
+	}
+
+	expiration := time.Duration(c.Viper.GetInt("auth.magic_link.expiration")) * time.Second
+	err = c.UserTokenRepository.Save(&entity.UserToken{
+		Id:        uuid.New().String(),
+		UserId:    user.Id,
+		Purpose:   entity.UserTokenMagicLink,
+		TokenHash: helper.HashToken(token),
+		ExpiresAt: time.Now().Add(expiration),
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return c.serverError(err, "Error while saving magic link token")
+	}
+
+	link := fmt.Sprintf("%s/auth/magic-link?token=%s", c.Viper.GetString("web.url"), url.QueryEscape(token))
+	err = c.MailSender.Send(&mail.Message{
+		From:    c.Viper.GetString("mail.from"),
+		To:      user.Email,
+		Subject: "Your sign in link",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to sign in. It expires in %d minutes and can only be used once.\n\n%s\n\nSign in token: %s\n\nIf you didn't ask for this, you can ignore this email.",
+			user.Name, int(expiration.Minutes()), link, token),
+	})
+	if err != nil {
+		return c.serverError(err, "Error while sending magic link email")
+	}
+
+	return nil
+}
+
+// SignIn redeems a sign in token and answers like AuthUsecase.SignIn. Opening
+// the link proves the user owns the email, so it verifies the email and lifts
+// a lockout like a password reset does.
+func (c *MagicLinkUsecase) SignIn(request *models.MagicLinkSignInRequest) (*models.AuthResponse, error) {
+	err := c.ValidateRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	invalidToken := &models.ErrorResponse{
+		Code:    401,
+		Message: "Sign in link is invalid or expired",
+		Status:  "Unauthorized",
+	}
+
+	token, err := c.UserTokenRepository.FindOneByHash(helper.HashToken(request.Token), entity.UserTokenMagicLink)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, invalidToken
+		}
+		return nil, c.serverError(err, "Error while finding magic link token")
+	}
+
+	if token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+		return nil, invalidToken
+	}
+
+	marked, err := c.UserTokenRepository.MarkAsUsed(token.Id)
+	if err != nil {
+		return nil, c.serverError(err, "Error while marking magic link token as used")
+	}
+	if !marked {
+		return nil, invalidToken
+	}
+
+	user := new(entity.User)
+	err = c.UserRepository.FindOneById(user, token.UserId)
+	if err != nil {
+		return nil, c.serverError(err, "Error while finding user of magic link")
+	}
+
+	if user.DisabledAt != nil {
+		return nil, accountDisabled()
+	}
+
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		err = c.UserRepository.MarkEmailVerified(user.Id, now)
+		if err != nil {
+			return nil, c.serverError(err, "Error while marking email verified")
+		}
+		user.EmailVerifiedAt = &now
+	}
+
+	err = c.AuthUsecase.LoginAttempts.Unlock(user.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TotpEnabledAt != nil {
+		mfaToken, err := c.AuthUsecase.GenerateMfaToken(user.Id, nil, MagicLinkMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		return &models.AuthResponse{
+			MfaRequired: true,
+			MfaToken:    mfaToken,
+		}, nil
+	}
+
+	return c.AuthUsecase.StartSession(user.Id, request.Client, MagicLinkMethod)
+}
+
+func (c *MagicLinkUsecase) serverError(err error, message string) error {
+	c.Log.WithError(err).Error(message)
+	return &models.ErrorResponse{
+		Code:    500,
+		Message: "Something Error",
+		Status:  "Internal Server Error",
+	}
+}
@@ -0,0 +1,391 @@
+package usecase
+
+import (
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"go-crud/internal/entity"
+	"go-crud/internal/helper"
+	"go-crud/internal/models"
+	"go-crud/internal/repository"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+const recoveryCodeCount = 10
+
+type MfaUsecase struct {
+	UserRepository         repository.UserRepositoryInterface
+	RecoveryCodeRepository repository.RecoveryCodeRepositoryInterface
+	AuthUsecase            *AuthUsecase
+	Validate               *validator.Validate
+	Viper                  *viper.Viper
+	Log                    *logrus.Logger
+	// Now is the clock TOTP codes are checked against, replaced in tests.
+	Now func() time.Time
+}
+
+func NewMfaUsecase(userRepository repository.UserRepositoryInterface, recoveryCodeRepository repository.RecoveryCodeRepositoryInterface, authUsecase *AuthUsecase, validate *validator.Validate, viper *viper.Viper, log *logrus.Logger) *MfaUsecase {
+	return &MfaUsecase{
+		UserRepository:         userRepository,
+		RecoveryCodeRepository: recoveryCodeRepository,
+		AuthUsecase:            authUsecase,
+		Validate:               validate,
+		Viper:                  viper,
+		Log:                    log,
+		Now:                    time.Now,
+	}
+}
+
+func (c *MfaUsecase) ValidateRequest(request any) error {
+	err := c.Validate.Struct(request)
+	if err != nil {
+		c.Log.WithError(err).Warn("Error validating request")
+		message := helper.GetFirstValidationErrorAndConvert(err)
+		return &models.ErrorResponse{
+			Code:    400,
+			Status:  "Bad Request",
+			Message: message,
+		}
+	}
+	return nil
+}
+
+func (c *MfaUsecase) findUser(userID string) (*entity.User, error) {
+	user := new(entity.User)
+	err := c.UserRepository.FindOneById(user, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &models.ErrorResponse{
+				Code:    401,
+				Message: "You're unauthorized",
+				Status:  "Unauthorized",
+			}
+		}
+
+		c.Log.WithError(err).Error("Error while finding user")
+		return nil, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	return user, nil
+}
+
+// Enroll generates a new TOTP secret for the user. It isn't enforced until
+// the user proves their authenticator works through Confirm.
+func (c *MfaUsecase) Enroll(userID string) (*models.TotpEnrollResponse, error) {
+	user, err := c.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TotpEnabledAt != nil {
+		return nil, &models.ErrorResponse{
+			Code:    400,
+			Message: "Two-factor authentication is already enabled",
+			Status:  "Bad Request",
+		}
+	}
+
+	secret, err := helper.GenerateTOTPSecret()
+	if err != nil {
+		c.Log.WithError(err).Error("Error while generating totp secret")
+		return nil, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	err = c.UserRepository.UpdateTotp(user.Id, secret, nil)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while saving totp secret")
+		return nil, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	return &models.TotpEnrollResponse{
+		Secret:     secret,
+		OtpauthURI: helper.TOTPURI(c.Viper.GetString("auth.mfa.issuer"), user.Email, secret),
+	}, nil
+}
+
+// Confirm enables TOTP once the user sends a valid code for the enrolled
+// secret and returns the first set of recovery codes.
+func (c *MfaUsecase) Confirm(userID string, request *models.TotpCodeRequest) (*models.RecoveryCodesResponse, error) {
+	err := c.ValidateRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := c.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TotpEnabledAt != nil || user.TotpSecret == "" {
+		return nil, &models.ErrorResponse{
+			Code:    400,
+			Message: "No two-factor enrollment is pending",
+			Status:  "Bad Request",
+		}
+	}
+
+	step, ok := helper.ValidateTOTP(user.TotpSecret, request.Code, c.Now(), 1)
+	if !ok {
+		return nil, invalidSecondFactor()
+	}
+
+	now := c.Now()
+	err = c.UserRepository.UpdateTotp(user.Id, user.TotpSecret, &now)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while enabling totp")
+		return nil, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	_, err = c.UserRepository.UseTotpStep(user.Id, step)
+	if err != nil {
+		c.Log.WithError(err).Warn("Error while recording used totp step")
+	}
+
+	return c.replaceRecoveryCodes(user.Id)
+}
+
+func (c *MfaUsecase) Disable(userID string, request *models.SecondFactorRequest) error {
+	err := c.ValidateRequest(request)
+	if err != nil {
+		return err
+	}
+
+	user, err := c.findUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if user.TotpEnabledAt == nil {
+		return &models.ErrorResponse{
+			Code:    400,
+			Message: "Two-factor authentication is not enabled",
+			Status:  "Bad Request",
+		}
+	}
+
+	_, err = c.checkSecondFactor(user, request.Code, request.RecoveryCode)
+	if err != nil {
+		return err
+	}
+
+	err = c.UserRepository.UpdateTotp(user.Id, "", nil)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while disabling totp")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	err = c.RecoveryCodeRepository.DeleteByUserId(user.Id)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while deleting recovery codes")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces every recovery code of the user, used or
+// not, with a new set.
+func (c *MfaUsecase) RegenerateRecoveryCodes(userID string, request *models.TotpCodeRequest) (*models.RecoveryCodesResponse, error) {
+	err := c.ValidateRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := c.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TotpEnabledAt == nil {
+		return nil, &models.ErrorResponse{
+			Code:    400,
+			Message: "Two-factor authentication is not enabled",
+			Status:  "Bad Request",
+		}
+	}
+
+	_, err = c.checkSecondFactor(user, request.Code, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return c.replaceRecoveryCodes(user.Id)
+}
+
+// Verify completes a sign in that stopped at the second factor and starts
+// the session.
+func (c *MfaUsecase) Verify(request *models.MfaVerifyRequest) (*models.AuthResponse, error) {
+	err := c.ValidateRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, firstFactors, scopes, err := c.AuthUsecase.VerifyMfaToken(request.MfaToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := c.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TotpEnabledAt == nil {
+		return nil, invalidSecondFactor()
+	}
+
+	// Second factors are guessed against the same counters as passwords,
+	// otherwise a stolen password leaves only a million codes to try.
+	loginAttempts := c.AuthUsecase.LoginAttempts
+	err = loginAttempts.Check(user.Email, request.Client.IpAddress)
+	if err != nil {
+		c.AuthUsecase.RecordSignInFailure(user.Id, request.Client, "too many attempts")
+		return nil, err
+	}
+
+	methods, err := c.checkSecondFactor(user, request.Code, request.RecoveryCode)
+	if err != nil {
+		if e, ok := err.(*models.ErrorResponse); ok && e.Code == 401 {
+			loginAttempts.RegisterFailure(user.Email, request.Client.IpAddress)
+			c.AuthUsecase.RecordSignInFailure(user.Id, request.Client, "wrong second factor")
+		}
+		return nil, err
+	}
+	loginAttempts.RegisterSuccess(user.Email)
+
+	return c.AuthUsecase.StartScopedSession(user.Id, request.Client, scopes, append(firstFactors, methods...)...)
+}
+
+// checkSecondFactor accepts either a TOTP code or an unused recovery code
+// and returns the amr values describing which one was used.
+func (c *MfaUsecase) checkSecondFactor(user *entity.User, code string, recoveryCode string) ([]string, error) {
+	if code != "" {
+		step, ok := helper.ValidateTOTP(user.TotpSecret, code, c.Now(), 1)
+		if !ok {
+			return nil, invalidSecondFactor()
+		}
+
+		used, err := c.UserRepository.UseTotpStep(user.Id, step)
+		if err != nil {
+			c.Log.WithError(err).Error("Error while recording used totp step")
+			return nil, &models.ErrorResponse{
+				Code:    500,
+				Message: "Something Error",
+				Status:  "Internal Server Error",
+			}
+		}
+		if !used {
+			return nil, invalidSecondFactor()
+		}
+
+		return []string{"otp", "mfa"}, nil
+	}
+
+	stored, err := c.RecoveryCodeRepository.FindOneUnused(user.Id, hashRecoveryCode(recoveryCode))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, invalidSecondFactor()
+		}
+
+		c.Log.WithError(err).Error("Error while finding recovery code")
+		return nil, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	marked, err := c.RecoveryCodeRepository.MarkAsUsed(stored.Id)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while marking recovery code as used")
+		return nil, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+	if !marked {
+		return nil, invalidSecondFactor()
+	}
+
+	return []string{"mfa"}, nil
+}
+
+func (c *MfaUsecase) replaceRecoveryCodes(userID string) (*models.RecoveryCodesResponse, error) {
+	codes := make([]string, recoveryCodeCount)
+	records := make([]entity.RecoveryCode, recoveryCodeCount)
+	for index := range codes {
+		secret, err := helper.GenerateTOTPSecret()
+		if err != nil {
+			c.Log.WithError(err).Error("Error while generating recovery code")
+			return nil, &models.ErrorResponse{
+				Code:    500,
+				Message: "Something Error",
+				Status:  "Internal Server Error",
+			}
+		}
+
+		code := strings.ToLower(secret[:5] + "-" + secret[5:10])
+		codes[index] = code
+		records[index] = entity.RecoveryCode{
+			Id:        uuid.New().String(),
+			UserId:    userID,
+			CodeHash:  hashRecoveryCode(code),
+			CreatedAt: c.Now(),
+		}
+	}
+
+	err := c.RecoveryCodeRepository.ReplaceAll(userID, records)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while saving recovery codes")
+		return nil, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	return &models.RecoveryCodesResponse{Codes: codes}, nil
+}
+
+// hashRecoveryCode ignores case and dashes so codes typed by hand still match.
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	return helper.HashToken(normalized)
+}
+
+func invalidSecondFactor() error {
+	return &models.ErrorResponse{
+		Code:    401,
+		Message: "Invalid two-factor authentication code",
+		Status:  "Unauthorized",
+	}
+}
@@ -0,0 +1,668 @@
+package usecase
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"go-crud/internal/entity"
+	"go-crud/internal/helper"
+	"go-crud/internal/models"
+	"go-crud/internal/repository"
+	"gorm.io/gorm"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// OAuthClientSecretPrefix starts every client secret so secret scanners can
+// find leaked ones.
+const OAuthClientSecretPrefix = "gcs_"
+
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+	GrantTypeClientCredentials = "client_credentials"
+)
+
+// OAuthUsecase lets users register third-party applications and grant them
+// access to their account within a set of scopes. Grants are sessions bound
+// to the client, so they are listed and revoked like any other session.
+type OAuthUsecase struct {
+	ClientRepository  repository.OAuthClientRepositoryInterface
+	CodeRepository    repository.OAuthAuthorizationCodeRepositoryInterface
+	SessionRepository repository.SessionRepositoryInterface
+	AuthUsecase       *AuthUsecase
+	Validate          *validator.Validate
+	Viper             *viper.Viper
+	Log               *logrus.Logger
+}
+
+func NewOAuthUsecase(clientRepository repository.OAuthClientRepositoryInterface, codeRepository repository.OAuthAuthorizationCodeRepositoryInterface, sessionRepository repository.SessionRepositoryInterface, authUsecase *AuthUsecase, validate *validator.Validate, viper *viper.Viper, log *logrus.Logger) *OAuthUsecase {
+	return &OAuthUsecase{
+		ClientRepository:  clientRepository,
+		CodeRepository:    codeRepository,
+		SessionRepository: sessionRepository,
+		AuthUsecase:       authUsecase,
+		Validate:          validate,
+		Viper:             viper,
+		Log:               log,
+	}
+}
+
+func (c *OAuthUsecase) ValidateRequest(request any) error {
+	err := c.Validate.Struct(request)
+	if err != nil {
+		c.Log.WithError(err).Warn("Error validating request")
+		message := helper.GetFirstValidationErrorAndConvert(err)
+		return &models.ErrorResponse{
+			Code:    400,
+			Status:  "Bad Request",
+			Message: message,
+		}
+	}
+	return nil
+}
+
+// CreateClient registers an application. The secret of a confidential
+// client is returned once, only its hash is stored.
+func (c *OAuthUsecase) CreateClient(userID string, request *models.CreateOAuthClientRequest) (*models.OAuthClientCreatedResponse, error) {
+	err := c.ValidateRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, redirectUri := range request.RedirectUris {
+		parsed, err := url.Parse(redirectUri)
+		if err != nil || !parsed.IsAbs() || parsed.Fragment != "" || strings.ContainsAny(redirectUri, " \t\n") {
+			return nil, &models.ErrorResponse{
+				Code:    400,
+				Status:  "Bad Request",
+				Message: "Redirect URIs must be absolute URLs without a fragment",
+			}
+		}
+	}
+
+	client := &entity.OAuthClient{
+		Id:           uuid.New().String(),
+		UserId:       userID,
+		Name:         request.Name,
+		RedirectUris: strings.Join(request.RedirectUris, " "),
+		Scopes:       strings.Join(uniqueScopes(request.Scopes), " "),
+		CreatedAt:    time.Now(),
+	}
+
+	var secret string
+	if !request.Public {
+		random, err := helper.GenerateRandomToken(32)
+		if err != nil {
+			c.Log.WithError(err).Error("Error while generating client secret")
+			return nil, &models.ErrorResponse{
+				Code:    500,
+				Message: "Something Error",
+				Status:  "Internal Server Error",
+			}
+		}
+		secret = OAuthClientSecretPrefix + random
+		client.SecretHash = helper.HashToken(secret)
+	}
+
+	err = c.ClientRepository.Save(client)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while saving oauth client")
+		return nil, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	return &models.OAuthClientCreatedResponse{
+		OAuthClientResponse: toOAuthClientResponse(client),
+		Secret:              secret,
+	}, nil
+}
+
+func (c *OAuthUsecase) GetClients(userID string) (*[]models.OAuthClientResponse, error) {
+	clients, err := c.ClientRepository.FindManyActiveByUserId(userID)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while getting oauth clients")
+		return nil, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	clientResponse := make([]models.OAuthClientResponse, len(clients))
+	for index := range clients {
+		clientResponse[index] = toOAuthClientResponse(&clients[index])
+	}
+
+	return &clientResponse, nil
+}
+
+// RevokeClient deletes an application of the user and signs it out of every
+// account that granted it access.
+func (c *OAuthUsecase) RevokeClient(userID string, clientID string) error {
+	revoked, err := c.ClientRepository.Revoke(clientID, userID)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while revoking oauth client")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	if !revoked {
+		return &models.ErrorResponse{
+			Code:    404,
+			Message: "OAuth client not found",
+			Status:  "Not Found",
+		}
+	}
+
+	err = c.SessionRepository.RevokeAllByClientId(clientID)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while revoking sessions of oauth client")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	return nil
+}
+
+// Authorize checks an authorization request and returns what the user is
+// asked to consent to.
+func (c *OAuthUsecase) Authorize(request *models.AuthorizeRequest) (*models.ConsentResponse, error) {
+	client, scopes, err := c.checkAuthorization(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ConsentResponse{
+		ClientId:    client.Id,
+		ClientName:  client.Name,
+		RedirectUri: request.RedirectUri,
+		Scopes:      scopes,
+	}, nil
+}
+
+// Decide records the answer of the user to an authorization request and
+// returns where to send them back to the client: with an authorization code
+// when they approved, with an access_denied error otherwise. methods are the
+// authentication methods of the session the user consented from, carried
+// over to the grant.
+func (c *OAuthUsecase) Decide(userID string, methods []string, request *models.AuthorizeDecisionRequest) (*models.AuthorizeResponse, error) {
+	client, scopes, err := c.checkAuthorization(&request.AuthorizeRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	if !request.Approved {
+		return &models.AuthorizeResponse{
+			RedirectUri: redirectWith(request.RedirectUri, map[string]string{
+				"error": "access_denied",
+				"state": request.State,
+			}),
+		}, nil
+	}
+
+	code, err := helper.GenerateRandomToken(32)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while generating authorization code")
+		return nil, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	now := time.Now()
+	err = c.CodeRepository.Save(&entity.OAuthAuthorizationCode{
+		Id:            uuid.New().String(),
+		CodeHash:      helper.HashToken(code),
+		ClientId:      client.Id,
+		UserId:        userID,
+		RedirectUri:   request.RedirectUri,
+		Scopes:        strings.Join(scopes, " "),
+		Methods:       strings.Join(methods, " "),
+		CodeChallenge: request.CodeChallenge,
+		ExpiresAt:     now.Add(time.Duration(c.Viper.GetInt("oauth.code_expiration")) * time.Second),
+		CreatedAt:     now,
+	})
+	if err != nil {
+		c.Log.WithError(err).Error("Error while saving authorization code")
+		return nil, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	return &models.AuthorizeResponse{
+		RedirectUri: redirectWith(request.RedirectUri, map[string]string{
+			"code":  code,
+			"state": request.State,
+		}),
+	}, nil
+}
+
+// checkAuthorization returns the client and the scopes of a valid
+// authorization request. PKCE with S256 is required from every client.
+func (c *OAuthUsecase) checkAuthorization(request *models.AuthorizeRequest) (*entity.OAuthClient, []string, error) {
+	err := c.ValidateRequest(request)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client, err := c.ClientRepository.FindOneById(request.ClientId)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.Log.WithError(err).Error("Error while finding oauth client")
+		return nil, nil, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+	if client == nil || client.RevokedAt != nil {
+		return nil, nil, badAuthorizeRequest("Unknown client")
+	}
+
+	if !containsString(strings.Fields(client.RedirectUris), request.RedirectUri) {
+		return nil, nil, badAuthorizeRequest("Redirect URI is not registered for this client")
+	}
+
+	if request.ResponseType != "code" {
+		return nil, nil, badAuthorizeRequest("Only the code response type is supported")
+	}
+
+	if request.CodeChallenge == "" {
+		return nil, nil, badAuthorizeRequest("PKCE code challenge is required")
+	}
+	if request.CodeChallengeMethod != "S256" {
+		return nil, nil, badAuthorizeRequest("Only the S256 code challenge method is supported")
+	}
+	if len(request.CodeChallenge) != base64.RawURLEncoding.EncodedLen(sha256.Size) {
+		return nil, nil, badAuthorizeRequest("Code challenge is invalid")
+	}
+
+	scopes, err := grantedScopes(client, request.Scope)
+	if err != nil {
+		return nil, nil, badAuthorizeRequest(err.Error())
+	}
+
+	return client, scopes, nil
+}
+
+// Token implements the token endpoint. Errors are *models.OAuthError.
+func (c *OAuthUsecase) Token(request *models.TokenRequest) (*models.TokenResponse, error) {
+	client, err := c.authenticateClient(request.ClientId, request.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	switch request.GrantType {
+	case GrantTypeAuthorizationCode:
+		return c.exchangeCode(client, request)
+	case GrantTypeRefreshToken:
+		return c.refresh(client, request)
+	case GrantTypeClientCredentials:
+		return c.clientCredentials(client, request)
+	default:
+		return nil, &models.OAuthError{
+			Code:        400,
+			Err:         "unsupported_grant_type",
+			Description: fmt.Sprintf("Grant type %q is not supported", request.GrantType),
+		}
+	}
+}
+
+// authenticateClient requires the secret of confidential clients. Public
+// clients only identify themselves, PKCE stands in for the secret.
+func (c *OAuthUsecase) authenticateClient(clientID string, clientSecret string) (*entity.OAuthClient, error) {
+	invalidClient := &models.OAuthError{
+		Code:        401,
+		Err:         "invalid_client",
+		Description: "Client authentication failed",
+	}
+	if clientID == "" {
+		return nil, invalidClient
+	}
+
+	client, err := c.ClientRepository.FindOneById(clientID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.Log.WithError(err).Error("Error while finding oauth client")
+		return nil, serverError()
+	}
+	if client == nil || client.RevokedAt != nil {
+		return nil, invalidClient
+	}
+
+	if client.Confidential() && subtle.ConstantTimeCompare([]byte(helper.HashToken(clientSecret)), []byte(client.SecretHash)) != 1 {
+		c.Log.WithField("client_id", client.Id).Warn("Invalid client secret")
+		return nil, invalidClient
+	}
+
+	return client, nil
+}
+
+// Introspect tells a confidential client, typically another of our services,
+// whether an access token is active and what it grants. Tokens that are
+// expired, revoked, malformed or of another kind are simply inactive.
+func (c *OAuthUsecase) Introspect(request *models.TokenHintRequest) (*models.IntrospectionResponse, error) {
+	client, err := c.authenticateClient(request.ClientId, request.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if !client.Confidential() {
+		return nil, &models.OAuthError{
+			Code:        401,
+			Err:         "invalid_client",
+			Description: "Only confidential clients can introspect tokens",
+		}
+	}
+
+	// Only access tokens are described. A refresh token comes out inactive
+	// whatever the hint says.
+	inactive := &models.IntrospectionResponse{Active: false}
+	if request.Token == "" {
+		return inactive, nil
+	}
+
+	claims, err := c.AuthUsecase.VerifyAccessToken(request.Token)
+	if err != nil {
+		if e, ok := err.(*models.ErrorResponse); ok && e.Code == 401 {
+			return inactive, nil
+		}
+		return nil, serverError()
+	}
+
+	return &models.IntrospectionResponse{
+		Active:    true,
+		Scope:     strings.Join(claims.Scopes, " "),
+		ClientId:  claims.ClientId,
+		TokenType: "Bearer",
+		Exp:       unixOrZero(claims.ExpiresAt),
+		Iat:       unixOrZero(claims.IssuedAt),
+		Sub:       claims.Subject,
+		Jti:       claims.TokenId,
+	}, nil
+}
+
+// Revoke revokes an access or refresh token (RFC 7009). A client may only
+// revoke its own tokens; without client_id, only first-party tokens can be
+// revoked, holding the token being enough. Unknown tokens aren't an error,
+// the token is invalid either way.
+func (c *OAuthUsecase) Revoke(request *models.TokenHintRequest) error {
+	clientID := ""
+	if request.ClientId != "" {
+		client, err := c.authenticateClient(request.ClientId, request.ClientSecret)
+		if err != nil {
+			return err
+		}
+		clientID = client.Id
+	}
+
+	if request.Token == "" {
+		return &models.OAuthError{
+			Code:        400,
+			Err:         "invalid_request",
+			Description: "token is required",
+		}
+	}
+
+	// The hint only decides which kind is tried first (section 2.1).
+	revokers := []func(string, string) (bool, error){c.AuthUsecase.RevokeAccessToken, c.AuthUsecase.RevokeRefreshToken}
+	if request.TokenTypeHint == "refresh_token" {
+		revokers[0], revokers[1] = revokers[1], revokers[0]
+	}
+
+	for _, revoke := range revokers {
+		revoked, err := revoke(request.Token, clientID)
+		if err != nil {
+			if e, ok := err.(*models.ErrorResponse); ok && e.Code == 403 {
+				return &models.OAuthError{
+					Code:        400,
+					Err:         "unauthorized_client",
+					Description: e.Message,
+				}
+			}
+			return serverError()
+		}
+		if revoked {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+func (c *OAuthUsecase) exchangeCode(client *entity.OAuthClient, request *models.TokenRequest) (*models.TokenResponse, error) {
+	if request.Code == "" || request.CodeVerifier == "" {
+		return nil, &models.OAuthError{
+			Code:        400,
+			Err:         "invalid_request",
+			Description: "code and code_verifier are required",
+		}
+	}
+
+	code, err := c.CodeRepository.FindOneByHash(helper.HashToken(request.Code))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.Log.WithError(err).Error("Error while finding authorization code")
+		return nil, serverError()
+	}
+	if code == nil || code.ClientId != client.Id {
+		return nil, invalidGrant("Authorization code is invalid")
+	}
+
+	marked, err := c.CodeRepository.MarkAsUsed(code.Id)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while marking authorization code as used")
+		return nil, serverError()
+	}
+	if !marked {
+		c.Log.WithField("client_id", client.Id).Warn("Authorization code reuse detected")
+		return nil, invalidGrant("Authorization code has already been used")
+	}
+
+	if !time.Now().Before(code.ExpiresAt) {
+		return nil, invalidGrant("Authorization code has expired")
+	}
+	if code.RedirectUri != request.RedirectUri {
+		return nil, invalidGrant("Redirect URI doesn't match the authorization request")
+	}
+	if !verifyCodeChallenge(code.CodeChallenge, request.CodeVerifier) {
+		return nil, invalidGrant("Code verifier doesn't match the code challenge")
+	}
+
+	result, err := c.AuthUsecase.StartClientSession(&entity.Session{
+		UserId:    code.UserId,
+		ClientId:  &client.Id,
+		Scopes:    code.Scopes,
+		UserAgent: client.Name,
+		IpAddress: request.Client.IpAddress,
+		Methods:   code.Methods,
+	}, true)
+	if err != nil {
+		return nil, toOAuthError(err)
+	}
+
+	return toTokenResponse(result, code.Scopes), nil
+}
+
+func (c *OAuthUsecase) refresh(client *entity.OAuthClient, request *models.TokenRequest) (*models.TokenResponse, error) {
+	if request.RefreshToken == "" {
+		return nil, &models.OAuthError{
+			Code:        400,
+			Err:         "invalid_request",
+			Description: "refresh_token is required",
+		}
+	}
+
+	result, err := c.AuthUsecase.RefreshClientToken(request.RefreshToken, client.Id, request.Client)
+	if err != nil {
+		return nil, toOAuthError(err)
+	}
+
+	return toTokenResponse(result, ""), nil
+}
+
+// clientCredentials lets a confidential client act on the account of the
+// user who registered it, within the client's scopes. No refresh token is
+// issued, the client asks for a new access token instead.
+func (c *OAuthUsecase) clientCredentials(client *entity.OAuthClient, request *models.TokenRequest) (*models.TokenResponse, error) {
+	if !client.Confidential() {
+		return nil, &models.OAuthError{
+			Code:        400,
+			Err:         "unauthorized_client",
+			Description: "Public clients can't use the client_credentials grant",
+		}
+	}
+
+	scopes, err := grantedScopes(client, request.Scope)
+	if err != nil {
+		return nil, &models.OAuthError{
+			Code:        400,
+			Err:         "invalid_scope",
+			Description: err.Error(),
+		}
+	}
+
+	result, err := c.AuthUsecase.StartClientSession(&entity.Session{
+		UserId:    client.UserId,
+		ClientId:  &client.Id,
+		Scopes:    strings.Join(scopes, " "),
+		UserAgent: client.Name,
+		IpAddress: request.Client.IpAddress,
+	}, false)
+	if err != nil {
+		return nil, toOAuthError(err)
+	}
+
+	return toTokenResponse(result, strings.Join(scopes, " ")), nil
+}
+
+// grantedScopes returns the scopes asked for in scope, or every scope of the
+// client when scope is empty. Asking for a scope the client wasn't
+// registered with is an error.
+func grantedScopes(client *entity.OAuthClient, scope string) ([]string, error) {
+	allowed := strings.Fields(client.Scopes)
+	requested := strings.Fields(scope)
+	if len(requested) == 0 {
+		return allowed, nil
+	}
+
+	for _, item := range requested {
+		if !containsString(allowed, item) {
+			return nil, fmt.Errorf("Scope %s is not allowed for this client", item)
+		}
+	}
+
+	return uniqueScopes(requested), nil
+}
+
+// verifyCodeChallenge checks a PKCE code verifier against the S256 challenge
+// sent with the authorization request (RFC 7636 section 4.6).
+func verifyCodeChallenge(challenge string, verifier string) bool {
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+// redirectWith adds the non-empty params to the query of redirectUri.
+func redirectWith(redirectUri string, params map[string]string) string {
+	parsed, err := url.Parse(redirectUri)
+	if err != nil {
+		return redirectUri
+	}
+
+	query := parsed.Query()
+	for key, value := range params {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	parsed.RawQuery = query.Encode()
+
+	return parsed.String()
+}
+
+func containsString(values []string, value string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func badAuthorizeRequest(message string) error {
+	return &models.ErrorResponse{
+		Code:    400,
+		Status:  "Bad Request",
+		Message: message,
+	}
+}
+
+func invalidGrant(description string) error {
+	return &models.OAuthError{
+		Code:        400,
+		Err:         "invalid_grant",
+		Description: description,
+	}
+}
+
+func serverError() error {
+	return &models.OAuthError{
+		Code:        500,
+		Err:         "server_error",
+		Description: "Something Error",
+	}
+}
+
+// toOAuthError converts the errors of AuthUsecase: a rejected refresh token
+// is an invalid grant, anything else a server error.
+func toOAuthError(err error) error {
+	if e, ok := err.(*models.ErrorResponse); ok && e.Code == 401 {
+		return invalidGrant(e.Message)
+	}
+	return serverError()
+}
+
+func toTokenResponse(result *models.AuthResponse, scope string) *models.TokenResponse {
+	return &models.TokenResponse{
+		AccessToken:  result.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(AccessTokenLifetime.Seconds()),
+		RefreshToken: result.RefreshToken,
+		Scope:        scope,
+	}
+}
+
+func toOAuthClientResponse(client *entity.OAuthClient) models.OAuthClientResponse {
+	return models.OAuthClientResponse{
+		Id:           client.Id,
+		Name:         client.Name,
+		RedirectUris: strings.Fields(client.RedirectUris),
+		Scopes:       strings.Fields(client.Scopes),
+		Public:       !client.Confidential(),
+		CreatedAt:    client.CreatedAt,
+	}
+}
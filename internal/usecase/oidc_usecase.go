@@ -0,0 +1,336 @@
+package usecase
+
+import (
+	"crypto/subtle"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"go-crud/internal/entity"
+	"go-crud/internal/helper"
+	"go-crud/internal/models"
+	"go-crud/internal/oidc"
+	"go-crud/internal/repository"
+	"gorm.io/gorm"
+	"time"
+)
+
+const oidcStateTokenType = "oidc_state"
+
+// OidcMethod is the amr value of sessions started through the external
+// identity provider. RFC 8176 has no value for federated sign in.
+const OidcMethod = "oidc"
+
+type OidcUsecase struct {
+	Provider           *oidc.Provider
+	UserRepository     repository.UserRepositoryInterface
+	IdentityRepository repository.UserIdentityRepositoryInterface
+	AuthUsecase        *AuthUsecase
+	Validate           *validator.Validate
+	Viper              *viper.Viper
+	Log                *logrus.Logger
+}
+
+func NewOidcUsecase(provider *oidc.Provider, userRepository repository.UserRepositoryInterface, identityRepository repository.UserIdentityRepositoryInterface, authUsecase *AuthUsecase, validate *validator.Validate, viper *viper.Viper, log *logrus.Logger) *OidcUsecase {
+	return &OidcUsecase{
+		Provider:           provider,
+		UserRepository:     userRepository,
+		IdentityRepository: identityRepository,
+		AuthUsecase:        authUsecase,
+		Validate:           validate,
+		Viper:              viper,
+		Log:                log,
+	}
+}
+
+func (c *OidcUsecase) ValidateRequest(request any) error {
+	err := c.Validate.Struct(request)
+	if err != nil {
+		c.Log.WithError(err).Warn("Error validating request")
+		message := helper.GetFirstValidationErrorAndConvert(err)
+		return &models.ErrorResponse{
+			Code:    400,
+			Status:  "Bad Request",
+			Message: message,
+		}
+	}
+	return nil
+}
+
+// Login starts a sign in with the identity provider. The state, nonce and
+// PKCE verifier travel in a signed state token rather than in a table, the
+// browser hands it back through a cookie on the callback.
+func (c *OidcUsecase) Login() (*models.OidcLoginResponse, error) {
+	state, err := helper.GenerateRandomToken(32)
+	if err != nil {
+		return nil, c.serverError(err, "Error while generating oidc state")
+	}
+	nonce, err := helper.GenerateRandomToken(32)
+	if err != nil {
+		return nil, c.serverError(err, "Error while generating oidc nonce")
+	}
+	codeVerifier, err := helper.GenerateRandomToken(32)
+	if err != nil {
+		return nil, c.serverError(err, "Error while generating pkce verifier")
+	}
+
+	authorizationUrl, err := c.Provider.AuthCodeURL(state, nonce, codeVerifier)
+	if err != nil {
+		return nil, c.providerError(err)
+	}
+
+	stateToken, err := c.AuthUsecase.KeySet.Sign(jwt.MapClaims{
+		"exp":   time.Now().Add(time.Duration(c.Viper.GetInt("auth.oidc.state_expiration")) * time.Second).Unix(),
+		"state": state,
+		"nonce": nonce,
+		"cv":    codeVerifier,
+		"typ":   oidcStateTokenType,
+	})
+	if err != nil {
+		return nil, c.serverError(err, "Error while signing oidc state")
+	}
+
+	return &models.OidcLoginResponse{
+		AuthorizationUrl: authorizationUrl,
+		StateToken:       stateToken,
+	}, nil
+}
+
+// Callback finishes the sign in: it checks the state, exchanges the code,
+// verifies the ID token and signs the linked user in like a password would.
+func (c *OidcUsecase) Callback(request *models.OidcCallbackRequest) (*models.AuthResponse, error) {
+	if request.Error != "" {
+		c.Log.WithFields(logrus.Fields{
+			"error":       request.Error,
+			"description": request.ErrorDescription,
+		}).Warn("Identity provider refused the sign in")
+		return nil, &models.ErrorResponse{
+			Code:    401,
+			Message: "Sign in was refused by the identity provider",
+			Status:  "Unauthorized",
+		}
+	}
+
+	err := c.ValidateRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, codeVerifier, err := c.verifyStateToken(request.StateToken, request.State)
+	if err != nil {
+		return nil, err
+	}
+
+	rawIDToken, err := c.Provider.Exchange(request.Code, codeVerifier)
+	if err != nil {
+		return nil, c.providerError(err)
+	}
+
+	idToken, err := c.Provider.VerifyIDToken(rawIDToken, nonce)
+	if err != nil {
+		return nil, c.providerError(err)
+	}
+
+	user, err := c.resolveUser(idToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.DisabledAt != nil {
+		return nil, accountDisabled()
+	}
+
+	methods := []string{OidcMethod}
+	if containsString(idToken.Methods, "mfa") {
+		methods = append(methods, "mfa")
+	} else if user.TotpEnabledAt != nil {
+		// The provider didn't ask for a second factor, so the one set up
+		// here still applies.
+		mfaToken, err := c.AuthUsecase.GenerateMfaToken(user.Id, nil, methods...)
+		if err != nil {
+			return nil, err
+		}
+
+		return &models.AuthResponse{
+			MfaRequired: true,
+			MfaToken:    mfaToken,
+		}, nil
+	}
+
+	return c.AuthUsecase.StartSession(user.Id, request.Client, methods...)
+}
+
+func (c *OidcUsecase) verifyStateToken(stateToken string, state string) (string, string, error) {
+	invalid := &models.ErrorResponse{
+		Code:    401,
+		Message: "Sign in request is invalid or expired, please try again",
+		Status:  "Unauthorized",
+	}
+
+	token, err := jwt.Parse(stateToken, c.AuthUsecase.KeySet.Keyfunc, jwt.WithValidMethods(c.AuthUsecase.KeySet.Algorithms()))
+	if err != nil {
+		c.Log.WithError(err).Warn("Error parsing oidc state token")
+		return "", "", invalid
+	}
+
+	mapClaims, _ := token.Claims.(jwt.MapClaims)
+	if mapClaims["typ"] != oidcStateTokenType {
+		return "", "", invalid
+	}
+
+	expected, _ := mapClaims["state"].(string)
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(state)) != 1 {
+		c.Log.Warn("OIDC state doesn't match the state token")
+		return "", "", invalid
+	}
+
+	nonce, _ := mapClaims["nonce"].(string)
+	codeVerifier, _ := mapClaims["cv"].(string)
+	return nonce, codeVerifier, nil
+}
+
+// resolveUser finds the user an identity belongs to. An identity seen for the
+// first time is linked to the account with the same email, or to a new
+// account, but only when the provider vouches for that email.
+func (c *OidcUsecase) resolveUser(idToken *oidc.IDToken) (*entity.User, error) {
+	identity, err := c.IdentityRepository.FindOneBySubject(idToken.Issuer, idToken.Subject)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, c.serverError(err, "Error while finding user identity")
+	}
+	if identity != nil {
+		user := new(entity.User)
+		err = c.UserRepository.FindOneById(user, identity.UserId)
+		if err != nil {
+			return nil, c.serverError(err, "Error while finding user of identity")
+		}
+		return user, nil
+	}
+
+	if idToken.Email == "" || !idToken.EmailVerified {
+		return nil, &models.ErrorResponse{
+			Code:    403,
+			Message: "Identity provider hasn't verified your email",
+			Status:  "Forbidden",
+		}
+	}
+
+	user, err := c.UserRepository.FindOneByEmail(idToken.Email)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, c.serverError(err, "Error while finding user by email")
+	}
+
+	if user == nil {
+		if !c.Viper.GetBool("auth.oidc.provision") {
+			return nil, &models.ErrorResponse{
+				Code:    403,
+				Message: "No account uses this email",
+				Status:  "Forbidden",
+			}
+		}
+
+		user, err = c.provisionUser(idToken)
+		if err != nil {
+			return nil, err
+		}
+	} else if user.EmailVerifiedAt == nil {
+		err = c.claimUnverifiedUser(user)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = c.IdentityRepository.Save(&entity.UserIdentity{
+		Id:        uuid.New().String(),
+		UserId:    user.Id,
+		Issuer:    idToken.Issuer,
+		Subject:   idToken.Subject,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return nil, c.serverError(err, "Error while linking user identity")
+	}
+
+	return user, nil
+}
+
+// provisionUser creates an account without a password. Its owner signs in
+// through the identity provider, or sets a password with a reset link.
+func (c *OidcUsecase) provisionUser(idToken *oidc.IDToken) (*entity.User, error) {
+	name := idToken.Name
+	if name == "" {
+		name = idToken.Email
+	}
+
+	now := time.Now()
+	user := &entity.User{
+		Id:              uuid.New().String(),
+		Name:            name,
+		Email:           idToken.Email,
+		EmailVerifiedAt: &now,
+	}
+	err := c.UserRepository.Save(user)
+	if err != nil {
+		return nil, c.serverError(err, "Error while provisioning user")
+	}
+
+	return user, nil
+}
+
+// claimUnverifiedUser hands an account whose email was never verified to the
+// person the provider vouches for. Whoever signed it up never proved they own
+// the email, so their password and sessions are dropped rather than kept as a
+// way into the account.
+func (c *OidcUsecase) claimUnverifiedUser(user *entity.User) error {
+	err := c.UserRepository.UpdatePassword(user.Id, "")
+	if err != nil {
+		return c.serverError(err, "Error while clearing password of unverified user")
+	}
+	user.Password = ""
+
+	err = c.AuthUsecase.SessionRepository.RevokeAllByUserIdExcept(user.Id, "")
+	if err != nil {
+		return c.serverError(err, "Error while revoking sessions of unverified user")
+	}
+	err = c.AuthUsecase.RefreshTokenRepository.RevokeAllByUserIdExcept(user.Id, "")
+	if err != nil {
+		return c.serverError(err, "Error while revoking refresh tokens of unverified user")
+	}
+
+	now := time.Now()
+	err = c.UserRepository.MarkEmailVerified(user.Id, now)
+	if err != nil {
+		return c.serverError(err, "Error while marking email verified")
+	}
+	user.EmailVerifiedAt = &now
+
+	return nil
+}
+
+func (c *OidcUsecase) providerError(err error) error {
+	if errors.Is(err, oidc.ErrUnavailable) {
+		c.Log.WithError(err).Error("Error while talking to the identity provider")
+		return &models.ErrorResponse{
+			Code:    502,
+			Message: "Identity provider is unavailable, please try again later",
+			Status:  "Bad Gateway",
+		}
+	}
+
+	c.Log.WithError(err).Warn("Identity provider sign in failed")
+	return &models.ErrorResponse{
+		Code:    401,
+		Message: "Sign in with the identity provider failed",
+		Status:  "Unauthorized",
+	}
+}
+
+func (c *OidcUsecase) serverError(err error, message string) error {
+	c.Log.WithError(err).Error(message)
+	return &models.ErrorResponse{
+		Code:    500,
+		Message: "Something Error",
+		Status:  "Internal Server Error",
+	}
+}
@@ -0,0 +1,514 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"go-crud/internal/entity"
+	"go-crud/internal/helper"
+	"go-crud/internal/mail"
+	"go-crud/internal/models"
+	"go-crud/internal/repository"
+	"gorm.io/gorm"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type OrganizationUsecase struct {
+	Repository           repository.OrganizationRepositoryInterface
+	MemberRepository     repository.OrganizationMemberRepositoryInterface
+	InvitationRepository repository.OrganizationInvitationRepositoryInterface
+	UserRepository       repository.UserRepositoryInterface
+	Products             *ProductUsecase
+	MailSender           mail.Sender
+	Validate             *validator.Validate
+	Viper                *viper.Viper
+	Log                  *logrus.Logger
+}
+
+func NewOrganizationUsecase(repository repository.OrganizationRepositoryInterface, memberRepository repository.OrganizationMemberRepositoryInterface, invitationRepository repository.OrganizationInvitationRepositoryInterface, userRepository repository.UserRepositoryInterface, products *ProductUsecase, mailSender mail.Sender, validate *validator.Validate, viper *viper.Viper, log *logrus.Logger) *OrganizationUsecase {
+	return &OrganizationUsecase{
+		Repository:           repository,
+		MemberRepository:     memberRepository,
+		InvitationRepository: invitationRepository,
+		UserRepository:       userRepository,
+		Products:             products,
+		MailSender:           mailSender,
+		Validate:             validate,
+		Viper:                viper,
+		Log:                  log,
+	}
+}
+
+func (c *OrganizationUsecase) ValidateRequest(request any) error {
+	err := c.Validate.Struct(request)
+	if err != nil {
+		c.Log.WithError(err).Warn("Error validating request")
+		message := helper.GetFirstValidationErrorAndConvert(err)
+		return &models.ErrorResponse{
+			Code:    400,
+			Status:  "Bad Request",
+			Message: message,
+		}
+	}
+	return nil
+}
+
+// CreateOrganization makes the user the first owner of a new organization.
+func (c *OrganizationUsecase) CreateOrganization(userID string, request *models.CreateOrganizationRequest) (*models.OrganizationResponse, error) {
+	err := c.ValidateRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	organization := &entity.Organization{
+		Id:        uuid.New().String(),
+		Name:      request.Name,
+		CreatedAt: now,
+	}
+	err = c.Repository.Create(organization, &entity.OrganizationMember{
+		OrganizationId: organization.Id,
+		UserId:         userID,
+		Role:           models.OrganizationRoleOwner,
+		CreatedAt:      now,
+	})
+	if err != nil {
+		return nil, c.serverError(err, "Error while creating organization")
+	}
+
+	return &models.OrganizationResponse{
+		Id:        organization.Id,
+		Name:      organization.Name,
+		Role:      models.OrganizationRoleOwner,
+		CreatedAt: organization.CreatedAt,
+	}, nil
+}
+
+// GetOrganizations lists the organizations the user belongs to, with their
+// role in each.
+func (c *OrganizationUsecase) GetOrganizations(userID string) (*[]models.OrganizationResponse, error) {
+	members, err := c.MemberRepository.FindManyByUserId(userID)
+	if err != nil {
+		return nil, c.serverError(err, "Error while getting organizations")
+	}
+
+	organizationResponse := make([]models.OrganizationResponse, len(members))
+	for index := range members {
+		organizationResponse[index] = toOrganizationResponse(&members[index])
+	}
+
+	return &organizationResponse, nil
+}
+
+func (c *OrganizationUsecase) GetOrganization(userID string, organizationID string) (*models.OrganizationResponse, error) {
+	member, err := c.requireRole(organizationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := toOrganizationResponse(member)
+	return &response, nil
+}
+
+// DeleteOrganization removes the organization with its products. Only owners
+// may do it.
+func (c *OrganizationUsecase) DeleteOrganization(userID string, organizationID string) error {
+	_, err := c.requireRole(organizationID, userID, models.OrganizationRoleOwner)
+	if err != nil {
+		return err
+	}
+
+	removal, err := c.Products.PrepareOrganizationRemoval(organizationID)
+	if err != nil {
+		return err
+	}
+
+	err = c.Repository.DeleteById(organizationID)
+	if err != nil {
+		return c.serverError(err, "Error while deleting organization")
+	}
+
+	c.Products.FinishRemoval(removal)
+	return nil
+}
+
+func (c *OrganizationUsecase) GetMembers(userID string, organizationID string) (*[]models.OrganizationMemberResponse, error) {
+	_, err := c.requireRole(organizationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := c.MemberRepository.FindManyByOrganizationId(organizationID)
+	if err != nil {
+		return nil, c.serverError(err, "Error while getting organization members")
+	}
+
+	memberResponse := make([]models.OrganizationMemberResponse, len(members))
+	for index, member := range members {
+		memberResponse[index] = models.OrganizationMemberResponse{
+			UserId:    member.UserId,
+			Name:      member.User.Name,
+			Email:     member.User.Email,
+			Role:      member.Role,
+			CreatedAt: member.CreatedAt,
+		}
+	}
+
+	return &memberResponse, nil
+}
+
+// UpdateMember changes the role of a member. Admins manage members and
+// admins, only owners grant or take away ownership.
+func (c *OrganizationUsecase) UpdateMember(userID string, organizationID string, memberID string, request *models.UpdateOrganizationMemberRequest) error {
+	err := c.ValidateRequest(request)
+	if err != nil {
+		return err
+	}
+
+	actor, err := c.requireRole(organizationID, userID, models.OrganizationRoleOwner, models.OrganizationRoleAdmin)
+	if err != nil {
+		return err
+	}
+
+	target, err := c.findMember(organizationID, memberID)
+	if err != nil {
+		return err
+	}
+
+	if (target.Role == models.OrganizationRoleOwner || request.Role == models.OrganizationRoleOwner) && actor.Role != models.OrganizationRoleOwner {
+		return &models.ErrorResponse{
+			Code:    403,
+			Message: "Only owners can grant or revoke ownership",
+			Status:  "Forbidden",
+		}
+	}
+
+	if target.Role == models.OrganizationRoleOwner && request.Role != models.OrganizationRoleOwner {
+		err = c.ensureAnotherOwner(organizationID)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = c.MemberRepository.UpdateRole(organizationID, memberID, request.Role)
+	if err != nil {
+		return c.serverError(err, "Error while updating organization member")
+	}
+
+	return nil
+}
+
+// RemoveMember takes a user out of the organization. Members may remove
+// themselves to leave it. Products they created stay with the organization.
+func (c *OrganizationUsecase) RemoveMember(userID string, organizationID string, memberID string) error {
+	actor, err := c.requireRole(organizationID, userID)
+	if err != nil {
+		return err
+	}
+
+	target := actor
+	if memberID != userID {
+		if actor.Role == models.OrganizationRoleMember {
+			return forbiddenToManage()
+		}
+
+		target, err = c.findMember(organizationID, memberID)
+		if err != nil {
+			return err
+		}
+
+		if target.Role == models.OrganizationRoleOwner && actor.Role != models.OrganizationRoleOwner {
+			return &models.ErrorResponse{
+				Code:    403,
+				Message: "Only owners can remove an owner",
+				Status:  "Forbidden",
+			}
+		}
+	}
+
+	if target.Role == models.OrganizationRoleOwner {
+		err = c.ensureAnotherOwner(organizationID)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = c.MemberRepository.Delete(organizationID, memberID)
+	if err != nil {
+		return c.serverError(err, "Error while removing organization member")
+	}
+
+	return nil
+}
+
+// CreateInvitation mails an invitation to join the organization with a role.
+// The token is only in the email, it is stored hashed.
+func (c *OrganizationUsecase) CreateInvitation(userID string, organizationID string, request *models.CreateInvitationRequest) (*models.InvitationResponse, error) {
+	err := c.ValidateRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	actor, err := c.requireRole(organizationID, userID, models.OrganizationRoleOwner, models.OrganizationRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Role == models.OrganizationRoleOwner && actor.Role != models.OrganizationRoleOwner {
+		return nil, &models.ErrorResponse{
+			Code:    403,
+			Message: "Only owners can grant or revoke ownership",
+			Status:  "Forbidden",
+		}
+	}
+
+	token, err := helper.GenerateRandomToken(32)
+	if err != nil {
+		return nil, c.serverError(err, "Error while generating invitation token")
+	}
+
+	now := time.Now()
+	expiration := time.Duration(c.Viper.GetInt("organization.invitation_expiration")) * time.Second
+	invitation := &entity.OrganizationInvitation{
+		Id:             uuid.New().String(),
+		OrganizationId: organizationID,
+		Email:          request.Email,
+		Role:           request.Role,
+		TokenHash:      helper.HashToken(token),
+		InvitedBy:      &userID,
+		ExpiresAt:      now.Add(expiration),
+		CreatedAt:      now,
+	}
+	err = c.InvitationRepository.Save(invitation)
+	if err != nil {
+		return nil, c.serverError(err, "Error while saving invitation")
+	}
+
+	link := fmt.Sprintf("%s/organizations/invitations/accept?token=%s", c.Viper.GetString("web.url"), url.QueryEscape(token))
+	err = c.MailSender.Send(&mail.Message{
+		From:    c.Viper.GetString("mail.from"),
+		To:      request.Email,
+		Subject: fmt.Sprintf("You're invited to join %s", actor.Organization.Name),
+		Body: fmt.Sprintf("Hi,\n\nYou're invited to join %s as %s. Sign in with this email and open the link below to accept. It expires in %d days.\n\n%s\n\nInvitation token: %s\n\nIf you weren't expecting this, you can ignore this email.",
+			actor.Organization.Name, request.Role, int(expiration.Hours()/24), link, token),
+	})
+	if err != nil {
+		return nil, c.serverError(err, "Error while sending invitation email")
+	}
+
+	response := toInvitationResponse(invitation)
+	return &response, nil
+}
+
+func (c *OrganizationUsecase) GetInvitations(userID string, organizationID string) (*[]models.InvitationResponse, error) {
+	_, err := c.requireRole(organizationID, userID, models.OrganizationRoleOwner, models.OrganizationRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	invitations, err := c.InvitationRepository.FindManyPendingByOrganizationId(organizationID, time.Now())
+	if err != nil {
+		return nil, c.serverError(err, "Error while getting invitations")
+	}
+
+	invitationResponse := make([]models.InvitationResponse, len(invitations))
+	for index := range invitations {
+		invitationResponse[index] = toInvitationResponse(&invitations[index])
+	}
+
+	return &invitationResponse, nil
+}
+
+func (c *OrganizationUsecase) RevokeInvitation(userID string, organizationID string, invitationID string) error {
+	_, err := c.requireRole(organizationID, userID, models.OrganizationRoleOwner, models.OrganizationRoleAdmin)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := c.InvitationRepository.Delete(invitationID, organizationID)
+	if err != nil {
+		return c.serverError(err, "Error while revoking invitation")
+	}
+
+	if !deleted {
+		return &models.ErrorResponse{
+			Code:    404,
+			Message: "Invitation not found",
+			Status:  "Not Found",
+		}
+	}
+
+	return nil
+}
+
+// AcceptInvitation redeems an invitation for the signed in user. The token
+// alone isn't enough: the user must have verified the email it was sent to.
+func (c *OrganizationUsecase) AcceptInvitation(userID string, request *models.AcceptInvitationRequest) (*models.OrganizationResponse, error) {
+	err := c.ValidateRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	invalidInvitation := &models.ErrorResponse{
+		Code:    400,
+		Message: "Invitation is invalid or expired",
+		Status:  "Bad Request",
+	}
+
+	invitation, err := c.InvitationRepository.FindOneByHash(helper.HashToken(request.Token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, invalidInvitation
+		}
+		return nil, c.serverError(err, "Error while finding invitation")
+	}
+
+	if invitation.AcceptedAt != nil || time.Now().After(invitation.ExpiresAt) {
+		return nil, invalidInvitation
+	}
+
+	user := new(entity.User)
+	err = c.UserRepository.FindOneById(user, userID)
+	if err != nil {
+		return nil, c.serverError(err, "Error while finding user to accept invitation")
+	}
+
+	if !strings.EqualFold(user.Email, invitation.Email) || user.EmailVerifiedAt == nil {
+		return nil, &models.ErrorResponse{
+			Code:    403,
+			Message: "Invitation was sent to another email, sign in with a verified account using that email",
+			Status:  "Forbidden",
+		}
+	}
+
+	_, err = c.MemberRepository.FindOne(invitation.OrganizationId, userID)
+	if err == nil {
+		return nil, &models.ErrorResponse{
+			Code:    409,
+			Message: "You're already a member of this organization",
+			Status:  "Conflict",
+		}
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, c.serverError(err, "Error while finding organization member")
+	}
+
+	accepted, err := c.InvitationRepository.MarkAsAccepted(invitation.Id)
+	if err != nil {
+		return nil, c.serverError(err, "Error while marking invitation as accepted")
+	}
+	if !accepted {
+		return nil, invalidInvitation
+	}
+
+	err = c.MemberRepository.Save(&entity.OrganizationMember{
+		OrganizationId: invitation.OrganizationId,
+		UserId:         userID,
+		Role:           invitation.Role,
+		CreatedAt:      time.Now(),
+	})
+	if err != nil {
+		return nil, c.serverError(err, "Error while adding organization member")
+	}
+
+	return c.GetOrganization(userID, invitation.OrganizationId)
+}
+
+// requireRole returns the membership of the user when it has one of the
+// roles, any role when none is given. Non-members get a 404 so they can't
+// tell which organizations exist.
+func (c *OrganizationUsecase) requireRole(organizationID string, userID string, roles ...string) (*entity.OrganizationMember, error) {
+	member, err := c.MemberRepository.FindOne(organizationID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &models.ErrorResponse{
+				Code:    404,
+				Message: "Organization not found",
+				Status:  "Not Found",
+			}
+		}
+		return nil, c.serverError(err, "Error while finding organization member")
+	}
+
+	if len(roles) > 0 && !containsString(roles, member.Role) {
+		return nil, forbiddenToManage()
+	}
+
+	return member, nil
+}
+
+func (c *OrganizationUsecase) findMember(organizationID string, userID string) (*entity.OrganizationMember, error) {
+	member, err := c.MemberRepository.FindOne(organizationID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &models.ErrorResponse{
+				Code:    404,
+				Message: "Member not found",
+				Status:  "Not Found",
+			}
+		}
+		return nil, c.serverError(err, "Error while finding organization member")
+	}
+
+	return member, nil
+}
+
+// ensureAnotherOwner keeps an organization from losing its last owner, who is
+// the only one able to delete it or appoint other owners.
+func (c *OrganizationUsecase) ensureAnotherOwner(organizationID string) error {
+	owners, err := c.MemberRepository.CountByRole(organizationID, models.OrganizationRoleOwner)
+	if err != nil {
+		return c.serverError(err, "Error while counting organization owners")
+	}
+
+	if owners <= 1 {
+		return &models.ErrorResponse{
+			Code:    409,
+			Message: "Organization needs at least one owner, appoint another owner first",
+			Status:  "Conflict",
+		}
+	}
+
+	return nil
+}
+
+func (c *OrganizationUsecase) serverError(err error, message string) error {
+	c.Log.WithError(err).Error(message)
+	return &models.ErrorResponse{
+		Code:    500,
+		Message: "Something Error",
+		Status:  "Internal Server Error",
+	}
+}
+
+func forbiddenToManage() error {
+	return &models.ErrorResponse{
+		Code:    403,
+		Message: "You're not allowed to manage this organization",
+		Status:  "Forbidden",
+	}
+}
+
+func toOrganizationResponse(member *entity.OrganizationMember) models.OrganizationResponse {
+	return models.OrganizationResponse{
+		Id:        member.Organization.Id,
+		Name:      member.Organization.Name,
+		Role:      member.Role,
+		CreatedAt: member.Organization.CreatedAt,
+	}
+}
+
+func toInvitationResponse(invitation *entity.OrganizationInvitation) models.InvitationResponse {
+	return models.InvitationResponse{
+		Id:        invitation.Id,
+		Email:     invitation.Email,
+		Role:      invitation.Role,
+		ExpiresAt: invitation.ExpiresAt,
+		CreatedAt: invitation.CreatedAt,
+	}
+}
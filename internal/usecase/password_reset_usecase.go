@@ -0,0 +1,251 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"go-crud/internal/entity"
+	"go-crud/internal/hasher"
+	"go-crud/internal/helper"
+	"go-crud/internal/mail"
+	"go-crud/internal/models"
+	"go-crud/internal/repository"
+	"gorm.io/gorm"
+	"net/url"
+	"time"
+)
+
+type PasswordResetUsecase struct {
+	UserRepository         repository.UserRepositoryInterface
+	UserTokenRepository    repository.UserTokenRepositoryInterface
+	SessionRepository      repository.SessionRepositoryInterface
+	RefreshTokenRepository repository.RefreshTokenRepositoryInterface
+	LoginAttempts          *LoginAttemptUsecase
+	AuditLog               *AuditLogUsecase
+	Hasher                 *hasher.Hasher
+	MailSender             mail.Sender
+	Validate               *validator.Validate
+	Viper                  *viper.Viper
+	Log                    *logrus.Logger
+}
+
+func NewPasswordResetUsecase(userRepository repository.UserRepositoryInterface, userTokenRepository repository.UserTokenRepositoryInterface, sessionRepository repository.SessionRepositoryInterface, refreshTokenRepository repository.RefreshTokenRepositoryInterface, loginAttempts *LoginAttemptUsecase, auditLog *AuditLogUsecase, passwordHasher *hasher.Hasher, mailSender mail.Sender, validate *validator.Validate, viper *viper.Viper, log *logrus.Logger) *PasswordResetUsecase {
+	return &PasswordResetUsecase{
+		UserRepository:         userRepository,
+		UserTokenRepository:    userTokenRepository,
+		SessionRepository:      sessionRepository,
+		RefreshTokenRepository: refreshTokenRepository,
+		LoginAttempts:          loginAttempts,
+		AuditLog:               auditLog,
+		Hasher:                 passwordHasher,
+		MailSender:             mailSender,
+		Validate:               validate,
+		Viper:                  viper,
+		Log:                    log,
+	}
+}
+
+func (c *PasswordResetUsecase) ValidateRequest(request any) error {
+	err := c.Validate.Struct(request)
+	if err != nil {
+		c.Log.WithError(err).Warn("Error validating request")
+		message := helper.GetFirstValidationErrorAndConvert(err)
+		return &models.ErrorResponse{
+			Code:    400,
+			Status:  "Bad Request",
+			Message: message,
+		}
+	}
+	return nil
+}
+
+// ForgotPassword mails a reset link when the email belongs to a user. It
+// succeeds for unknown emails too, so the endpoint can't be used to find out
+// who has an account.
+func (c *PasswordResetUsecase) ForgotPassword(request *models.ForgotPasswordRequest) error {
+	err := c.ValidateRequest(request)
+	if err != nil {
+		return err
+	}
+
+	user, err := c.UserRepository.FindOneByEmail(request.Email)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.Log.WithError(err).Error("Error while getting user by email")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	if user == nil {
+		c.Log.WithField("email", request.Email).Warn("Password reset requested for unknown email")
+		return nil
+	}
+
+	return c.SendResetLink(user)
+}
+
+// SendResetLink issues a new reset token for the user and mails it. Earlier
+// reset tokens that weren't used yet stop working.
+func (c *PasswordResetUsecase) SendResetLink(user *entity.User) error {
+	err := c.UserTokenRepository.InvalidateByUserId(user.Id, entity.UserTokenPasswordReset)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while invalidating previous reset tokens")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	token, err := helper.GenerateRandomToken(32)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while generating reset token")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	expiration := time.Duration(c.Viper.GetInt("auth.password_reset.expiration")) * time.Second
+	err = c.UserTokenRepository.Save(&entity.UserToken{
+		Id:        uuid.New().String(),
+		UserId:    user.Id,
+		Purpose:   entity.UserTokenPasswordReset,
+		TokenHash: helper.HashToken(token),
+		ExpiresAt: time.Now().Add(expiration),
+	})
+	if err != nil {
+		c.Log.WithError(err).Error("Error while saving reset token")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	link := fmt.Sprintf("%s/auth/password/reset?token=%s", c.Viper.GetString("web.url"), url.QueryEscape(token))
+	err = c.MailSender.Send(&mail.Message{
+		From:    c.Viper.GetString("mail.from"),
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password. It expires in %d minutes and can only be used once.\n\n%s\n\nReset token: %s\n\nIf you didn't ask for this, you can ignore this email.",
+			user.Name, int(expiration.Minutes()), link, token),
+	})
+	if err != nil {
+		c.Log.WithError(err).Error("Error while sending reset email")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	return nil
+}
+
+// ResetPassword redeems a reset token, stores the new password and signs the
+// user out of every session. The attempt is recorded in the audit log, under
+// the user when the token is known.
+func (c *PasswordResetUsecase) ResetPassword(request *models.ResetPasswordRequest) error {
+	userID, err := c.resetPassword(request)
+	c.AuditLog.Record(models.Actor{UserId: userID, Client: request.Client}, entity.AuditActionPasswordReset, "", auditOutcome(err))
+	return err
+}
+
+func (c *PasswordResetUsecase) resetPassword(request *models.ResetPasswordRequest) (string, error) {
+	err := c.ValidateRequest(request)
+	if err != nil {
+		return "", err
+	}
+
+	invalidToken := &models.ErrorResponse{
+		Code:    400,
+		Message: "Reset token is invalid or expired",
+		Status:  "Bad Request",
+	}
+
+	token, err := c.UserTokenRepository.FindOneByHash(helper.HashToken(request.Token), entity.UserTokenPasswordReset)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", invalidToken
+		}
+
+		c.Log.WithError(err).Error("Error while finding reset token")
+		return "", &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	if token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+		return token.UserId, invalidToken
+	}
+
+	marked, err := c.UserTokenRepository.MarkAsUsed(token.Id)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while marking reset token as used")
+		return token.UserId, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+	if !marked {
+		return token.UserId, invalidToken
+	}
+
+	hashedPassword, err := hashPassword(c.Hasher, request.Password)
+	if err != nil {
+		return token.UserId, err
+	}
+
+	err = c.UserRepository.UpdatePassword(token.UserId, hashedPassword)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while updating password")
+		return token.UserId, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	err = c.SessionRepository.RevokeAllByUserId(token.UserId)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while revoking sessions after password reset")
+		return token.UserId, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	err = c.RefreshTokenRepository.RevokeAllByUserId(token.UserId)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while revoking refresh tokens after password reset")
+		return token.UserId, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	user := new(entity.User)
+	err = c.UserRepository.FindOneById(user, token.UserId)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while finding user to unlock after password reset")
+		return token.UserId, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	return token.UserId, c.LoginAttempts.Unlock(user.Email)
+}
@@ -0,0 +1,317 @@
+package usecase
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"go-crud/internal/entity"
+	"go-crud/internal/helper"
+	"go-crud/internal/models"
+	"go-crud/internal/repository"
+	"go-crud/internal/storage"
+	"gorm.io/gorm"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// productImageTypes lists the accepted image types with their file extension.
+// Only types the standard library decodes are accepted, thumbnails are made
+// from every image.
+var productImageTypes = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+}
+
+type ProductImageUsecase struct {
+	Repository repository.ProductImageRepositoryInterface
+	Storage    storage.Storage
+	Validate   *validator.Validate
+	Viper      *viper.Viper
+	Log        *logrus.Logger
+}
+
+func NewProductImageUsecase(repository repository.ProductImageRepositoryInterface, storage storage.Storage, validate *validator.Validate, viper *viper.Viper, log *logrus.Logger) *ProductImageUsecase {
+	return &ProductImageUsecase{
+		Repository: repository,
+		Storage:    storage,
+		Validate:   validate,
+		Viper:      viper,
+		Log:        log,
+	}
+}
+
+// UploadImage stores an image of the product with its thumbnails, after the
+// images it already has. The type is told from the content, whatever the
+// client claims.
+func (c *ProductImageUsecase) UploadImage(productID string, file io.Reader) (*models.ProductImageResponse, error) {
+	maxSize := c.Viper.GetInt("image.max_size")
+	content, err := io.ReadAll(io.LimitReader(file, int64(maxSize)+1))
+	if err != nil {
+		return nil, c.serverError(err, "Error while reading image")
+	}
+	if len(content) > maxSize {
+		return nil, &models.ErrorResponse{
+			Code:    413,
+			Message: fmt.Sprintf("Image must be at most %d KB", maxSize/1024),
+			Status:  "Request Entity Too Large",
+		}
+	}
+
+	contentType := http.DetectContentType(content)
+	extension, ok := productImageTypes[contentType]
+	if !ok {
+		return nil, &models.ErrorResponse{
+			Code:    415,
+			Message: "Image must be a JPEG, PNG or GIF",
+			Status:  "Unsupported Media Type",
+		}
+	}
+	// The dimensions are checked before decoding, a small file can hold an
+	// image too large to fit in memory.
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, invalidProductImage("Image can't be read")
+	}
+	maxPixels := c.Viper.GetInt("image.max_pixels")
+	if config.Width*config.Height > maxPixels {
+		return nil, invalidProductImage(fmt.Sprintf("Image must be at most %d pixels", maxPixels))
+	}
+
+	images, err := c.Repository.FindManyByProductId(productID)
+	if err != nil {
+		return nil, c.serverError(err, "Error while getting product images")
+	}
+	maxCount := c.Viper.GetInt("image.max_count")
+	if len(images) >= maxCount {
+		return nil, &models.ErrorResponse{
+			Code:    409,
+			Message: fmt.Sprintf("A product can have at most %d images", maxCount),
+			Status:  "Conflict",
+		}
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, invalidProductImage("Image can't be read")
+	}
+
+	productImage := &entity.ProductImage{
+		Id:          uuid.New().String(),
+		ProductId:   productID,
+		ContentType: contentType,
+		Width:       config.Width,
+		Height:      config.Height,
+		Size:        len(content),
+		Variants:    map[string]string{},
+		CreatedAt:   time.Now(),
+	}
+	if len(images) > 0 {
+		productImage.Position = images[len(images)-1].Position + 1
+	}
+	prefix := fmt.Sprintf("products/%s/%s/", productID, productImage.Id)
+
+	err = c.store(productImage, "original", prefix+"original."+extension, contentType, content)
+	if err != nil {
+		return nil, err
+	}
+	for _, thumbnail := range c.thumbnailSizes() {
+		content, contentType, extension, err := encodeThumbnail(helper.Thumbnail(decoded, thumbnail.size), productImage.ContentType)
+		if err != nil {
+			c.removeVariants(productImage)
+			return nil, c.serverError(err, "Error while encoding thumbnail")
+		}
+		err = c.store(productImage, thumbnail.name, prefix+thumbnail.name+"."+extension, contentType, content)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = c.Repository.Save(productImage)
+	if err != nil {
+		c.removeVariants(productImage)
+		return nil, c.serverError(err, "Error while saving product image")
+	}
+
+	response := toProductImageResponse(productImage, c.Storage, len(images) == 0)
+	return &response, nil
+}
+
+// store puts a variant of the image in the storage. When that fails, the
+// variants stored before are removed.
+func (c *ProductImageUsecase) store(productImage *entity.ProductImage, variant string, key string, contentType string, content []byte) error {
+	err := c.Storage.Put(key, contentType, content)
+	if err != nil {
+		c.removeVariants(productImage)
+		return c.serverError(err, "Error while storing product image")
+	}
+	productImage.Variants[variant] = key
+	return nil
+}
+
+// ReorderImages orders the images of the product as request, the first one
+// becoming the primary image.
+func (c *ProductImageUsecase) ReorderImages(productID string, request *models.ProductImageOrderRequest) (*[]models.ProductImageResponse, error) {
+	err := c.Validate.Struct(request)
+	if err != nil {
+		c.Log.WithError(err).Warn("Error validating request")
+		return nil, invalidProductImage(helper.GetFirstValidationErrorAndConvert(err))
+	}
+
+	images, err := c.Repository.FindManyByProductId(productID)
+	if err != nil {
+		return nil, c.serverError(err, "Error while getting product images")
+	}
+	byId := make(map[string]entity.ProductImage, len(images))
+	for _, productImage := range images {
+		byId[productImage.Id] = productImage
+	}
+	ordered := make([]entity.ProductImage, 0, len(images))
+	for _, imageID := range request.ImageIds {
+		productImage, ok := byId[imageID]
+		if !ok {
+			return nil, invalidProductImage("image_ids must list every image of the product once")
+		}
+		delete(byId, imageID)
+		ordered = append(ordered, productImage)
+	}
+	if len(byId) > 0 {
+		return nil, invalidProductImage("image_ids must list every image of the product once")
+	}
+
+	err = c.Repository.UpdatePositions(productID, request.ImageIds)
+	if err != nil {
+		return nil, c.serverError(err, "Error while ordering product images")
+	}
+	for index := range ordered {
+		ordered[index].Position = index
+	}
+
+	response := productImageResponses(ordered, c.Storage)
+	return &response, nil
+}
+
+// DeleteImage removes the image and its files. The next image becomes the
+// primary one when it was.
+func (c *ProductImageUsecase) DeleteImage(productID string, imageID string) error {
+	productImage, err := c.Repository.FindOne(productID, imageID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &models.ErrorResponse{
+				Code:    404,
+				Message: "Image not found",
+				Status:  "Not Found",
+			}
+		}
+		return c.serverError(err, "Error while getting product image")
+	}
+
+	err = c.Repository.DeleteById(imageID)
+	if err != nil {
+		return c.serverError(err, "Error while deleting product image")
+	}
+	c.removeVariants(productImage)
+	return nil
+}
+
+type thumbnailSize struct {
+	name string
+	size int
+}
+
+// thumbnailSizes reads the image.thumbnails setting, smallest first.
+func (c *ProductImageUsecase) thumbnailSizes() []thumbnailSize {
+	var sizes []thumbnailSize
+	for name := range c.Viper.GetStringMap("image.thumbnails") {
+		sizes = append(sizes, thumbnailSize{name: name, size: c.Viper.GetInt("image.thumbnails." + name)})
+	}
+	sort.Slice(sizes, func(i, j int) bool {
+		return sizes[i].size < sizes[j].size
+	})
+	return sizes
+}
+
+// removeVariants deletes the stored files of the image. The image is gone
+// for the application either way, so failures are only logged.
+func (c *ProductImageUsecase) removeVariants(productImage *entity.ProductImage) {
+	removeProductImageFiles(c.Storage, c.Log, *productImage)
+}
+
+func (c *ProductImageUsecase) serverError(err error, message string) error {
+	c.Log.WithError(err).Error(message)
+	return &models.ErrorResponse{
+		Code:    500,
+		Message: "Something Wrong",
+		Status:  "Internal Server Error",
+	}
+}
+
+func invalidProductImage(message string) error {
+	return &models.ErrorResponse{
+		Code:    400,
+		Message: message,
+		Status:  "Bad Request",
+	}
+}
+
+// encodeThumbnail keeps JPEG images as JPEG. The others become PNG, which
+// keeps their transparency.
+func encodeThumbnail(thumbnail image.Image, contentType string) ([]byte, string, string, error) {
+	var buffer bytes.Buffer
+	if contentType == "image/jpeg" {
+		err := jpeg.Encode(&buffer, thumbnail, &jpeg.Options{Quality: 85})
+		return buffer.Bytes(), "image/jpeg", "jpg", err
+	}
+	err := png.Encode(&buffer, thumbnail)
+	return buffer.Bytes(), "image/png", "png", err
+}
+
+func removeProductImageFiles(store storage.Storage, log *logrus.Logger, productImage entity.ProductImage) {
+	for _, key := range productImage.Variants {
+		err := store.Delete(key)
+		if err != nil {
+			log.WithError(err).WithField("key", key).Error("Error while deleting product image file")
+		}
+	}
+}
+
+func productImageResponses(images []entity.ProductImage, store storage.Storage) []models.ProductImageResponse {
+	responses := make([]models.ProductImageResponse, len(images))
+	for index := range images {
+		responses[index] = toProductImageResponse(&images[index], store, index == 0)
+	}
+	return responses
+}
+
+func toProductImageResponse(productImage *entity.ProductImage, store storage.Storage, primary bool) models.ProductImageResponse {
+	response := models.ProductImageResponse{
+		Id:          productImage.Id,
+		Url:         store.URL(productImage.Variants["original"]),
+		ContentType: productImage.ContentType,
+		Width:       productImage.Width,
+		Height:      productImage.Height,
+		Size:        productImage.Size,
+		Primary:     primary,
+		CreatedAt:   productImage.CreatedAt,
+	}
+	for name, key := range productImage.Variants {
+		if name == "original" {
+			continue
+		}
+		if response.Thumbnails == nil {
+			response.Thumbnails = map[string]string{}
+		}
+		response.Thumbnails[name] = store.URL(key)
+	}
+	return response
+}
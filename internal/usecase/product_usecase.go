@@ -1,7 +1,9 @@
 package usecase
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
@@ -10,23 +12,41 @@ import (
 	"go-crud/internal/helper"
 	"go-crud/internal/models"
 	"go-crud/internal/repository"
+	"go-crud/internal/storage"
 	"gorm.io/gorm"
 	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type ProductUsecase struct {
-	Repository repository.ProductRepositoryInterface
-	Validate   *validator.Validate
-	Viper      *viper.Viper
-	Log        *logrus.Logger
+	Repository         repository.ProductRepositoryInterface
+	SearchRepository   repository.ProductSearchRepositoryInterface
+	MemberRepository   repository.OrganizationMemberRepositoryInterface
+	CategoryRepository repository.CategoryRepositoryInterface
+	TagRepository      repository.TagRepositoryInterface
+	ImageRepository    repository.ProductImageRepositoryInterface
+	Storage            storage.Storage
+	Validate           *validator.Validate
+	Viper              *viper.Viper
+	Log                *logrus.Logger
 }
 
-func NewProductUsecase(repository repository.ProductRepositoryInterface, validate *validator.Validate, viper *viper.Viper, log *logrus.Logger) *ProductUsecase {
+func NewProductUsecase(repository repository.ProductRepositoryInterface, searchRepository repository.ProductSearchRepositoryInterface, memberRepository repository.OrganizationMemberRepositoryInterface, categoryRepository repository.CategoryRepositoryInterface, tagRepository repository.TagRepositoryInterface, imageRepository repository.ProductImageRepositoryInterface, storage storage.Storage, validate *validator.Validate, viper *viper.Viper, log *logrus.Logger) *ProductUsecase {
 	return &ProductUsecase{
-		Repository: repository,
-		Validate:   validate,
-		Viper:      viper,
-		Log:        log,
+		Repository:         repository,
+		SearchRepository:   searchRepository,
+		MemberRepository:   memberRepository,
+		CategoryRepository: categoryRepository,
+		TagRepository:      tagRepository,
+		ImageRepository:    imageRepository,
+		Storage:            storage,
+		Validate:           validate,
+		Viper:              viper,
+		Log:                log,
 	}
 }
 
@@ -65,6 +85,24 @@ func (c *ProductUsecase) CreateProduct(request *models.ProductRequest, userId st
 	product.Stock = request.Stock
 	product.Price = request.Price
 	product.UserId = userId
+	if request.OrganizationId != "" {
+		err = c.checkMembership(request.OrganizationId, userId)
+		if err != nil {
+			return nil, err
+		}
+		product.OrganizationId = &request.OrganizationId
+	}
+	if request.CategoryId != nil && *request.CategoryId != "" {
+		product.Category, err = c.findCategory(*request.CategoryId)
+		if err != nil {
+			return nil, err
+		}
+		product.CategoryId = &product.Category.Id
+	}
+	product.Tags, err = c.findTags(request.TagIds)
+	if err != nil {
+		return nil, err
+	}
 	err = c.Repository.Save(&product)
 	if err != nil {
 		c.Log.WithError(err).Error("Error while creating product")
@@ -75,7 +113,79 @@ func (c *ProductUsecase) CreateProduct(request *models.ProductRequest, userId st
 		}
 	}
 
-	return &models.ProductResponse{Id: product.Id, Name: product.Name, Price: product.Price, Stock: product.Stock}, nil
+	c.index(&product)
+
+	response := &models.ProductResponse{Id: product.Id, Name: product.Name, Price: product.Price, Stock: product.Stock, OrganizationId: request.OrganizationId}
+	c.setProductRelations(response, &product)
+	return response, nil
+}
+
+// findCategory resolves the category a product is put in.
+func (c *ProductUsecase) findCategory(categoryID string) (*entity.Category, error) {
+	category, err := c.CategoryRepository.FindOneById(categoryID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, invalidProductFilter("Category not found")
+		}
+		c.Log.WithError(err).Error("Error while getting product category")
+		return nil, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Wrong",
+			Status:  "Internal Server Error",
+		}
+	}
+	return category, nil
+}
+
+// findTags resolves the tags a product is given, repeated ids count once.
+func (c *ProductUsecase) findTags(tagIDs []string) ([]entity.Tag, error) {
+	var ids []string
+	for _, id := range tagIDs {
+		if !containsString(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return []entity.Tag{}, nil
+	}
+
+	tags, err := c.TagRepository.FindManyByIds(ids)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while getting product tags")
+		return nil, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Wrong",
+			Status:  "Internal Server Error",
+		}
+	}
+	if len(tags) != len(ids) {
+		return nil, invalidProductFilter("Tag not found")
+	}
+	return tags, nil
+}
+
+// checkMembership only lets members of the organization create products for
+// it. Unknown organizations get the same answer so their ids can't be probed.
+func (c *ProductUsecase) checkMembership(organizationID string, userID string) error {
+	_, err := c.MemberRepository.FindOne(organizationID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &models.ErrorResponse{
+				Code:    403,
+				Message: "You're not a member of this organization",
+				Status:  "Forbidden",
+			}
+		}
+
+		c.Log.WithError(err).Error("Error while finding organization member")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Wrong",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	return nil
 }
 
 func (c *ProductUsecase) UpdateProduct(request *models.ProductRequest, productId string) (*models.ProductResponse, error) {
@@ -117,16 +227,156 @@ func (c *ProductUsecase) UpdateProduct(request *models.ProductRequest, productId
 		}
 	}
 
-	return &models.ProductResponse{
+	err = c.updateRelations(request, result)
+	if err != nil {
+		return nil, err
+	}
+
+	c.index(result)
+
+	response := &models.ProductResponse{
 		Id:    result.Id,
 		Name:  result.Name,
 		Stock: result.Stock,
 		Price: result.Price,
-	}, nil
+	}
+	if result.OrganizationId != nil {
+		response.OrganizationId = *result.OrganizationId
+	}
+	c.setProductRelations(response, result)
+	return response, nil
+}
+
+// updateRelations moves the updated product to the category and gives it the
+// tags of request, when they are given.
+func (c *ProductUsecase) updateRelations(request *models.ProductRequest, product *entity.Product) error {
+	if request.CategoryId != nil {
+		product.CategoryId, product.Category = nil, nil
+		if *request.CategoryId != "" {
+			category, err := c.findCategory(*request.CategoryId)
+			if err != nil {
+				return err
+			}
+			product.CategoryId, product.Category = &category.Id, category
+		}
+		err := c.Repository.UpdateCategoryById(product.Id, product.CategoryId)
+		if err != nil {
+			c.Log.WithError(err).Error("Error while updating product category")
+			return &models.ErrorResponse{
+				Code:    500,
+				Message: "Something Wrong",
+				Status:  "Internal Server Error",
+			}
+		}
+	}
+
+	if request.TagIds != nil {
+		tags, err := c.findTags(request.TagIds)
+		if err != nil {
+			return err
+		}
+		err = c.Repository.ReplaceTags(product.Id, tags)
+		if err != nil {
+			c.Log.WithError(err).Error("Error while updating product tags")
+			return &models.ErrorResponse{
+				Code:    500,
+				Message: "Something Wrong",
+				Status:  "Internal Server Error",
+			}
+		}
+		product.Tags = tags
+	}
+	return nil
+}
+
+// ProductRemoval lists the products a bulk delete is about to take, with
+// their owner or their organization, so what is kept of them outside the
+// database can go once they are deleted.
+type ProductRemoval struct {
+	productIDs []string
+	images     []entity.ProductImage
+}
+
+// PrepareUserRemoval lists the personal products deleted with the user.
+func (c *ProductUsecase) PrepareUserRemoval(userID string) (*ProductRemoval, error) {
+	productIDs, err := c.Repository.FindPersonalIds(userID)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while getting products of user")
+		return nil, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	return c.prepareRemoval(productIDs)
+}
+
+// PrepareOrganizationRemoval lists the products deleted with the
+// organization.
+func (c *ProductUsecase) PrepareOrganizationRemoval(organizationID string) (*ProductRemoval, error) {
+	productIDs, err := c.Repository.FindIdsByOrganizationId(organizationID)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while getting products of organization")
+		return nil, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	return c.prepareRemoval(productIDs)
+}
+
+// prepareRemoval loads the images of the products, their rows are deleted
+// with the products.
+func (c *ProductUsecase) prepareRemoval(productIDs []string) (*ProductRemoval, error) {
+	removal := &ProductRemoval{productIDs: productIDs}
+	if len(productIDs) == 0 {
+		return removal, nil
+	}
+
+	images, err := c.ImageRepository.FindManyByProductIds(productIDs)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while getting product images")
+		return nil, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+	removal.images = images
+	return removal, nil
 }
 
+// FinishRemoval takes the deleted products out of the search index and
+// deletes the files of their images. They are gone either way, so failures
+// are only logged.
+func (c *ProductUsecase) FinishRemoval(removal *ProductRemoval) {
+	for _, productID := range removal.productIDs {
+		err := c.SearchRepository.Remove(productID)
+		if err != nil {
+			c.Log.WithError(err).WithField("product_id", productID).Error("Error while removing product from the search index")
+		}
+	}
+	for _, productImage := range removal.images {
+		removeProductImageFiles(c.Storage, c.Log, productImage)
+	}
+}
+
+// DeleteProduct removes the product, and the files of its images.
 func (c *ProductUsecase) DeleteProduct(productID string) error {
-	err := c.Repository.DeleteById(productID)
+	images, err := c.ImageRepository.FindManyByProductId(productID)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while getting product images")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	err = c.Repository.DeleteById(productID)
 	if err != nil {
 		c.Log.WithError(err).Error("Error while deleting product by id")
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -144,12 +394,175 @@ func (c *ProductUsecase) DeleteProduct(productID string) error {
 		}
 	}
 
+	c.FinishRemoval(&ProductRemoval{productIDs: []string{productID}, images: images})
 	return nil
 }
 
-func (c *ProductUsecase) GetProducts(offset int, limit int) (*[]models.ProductResponse, error) {
+// index keeps the search index in step with a saved product. The product is
+// saved either way, so failures are only logged.
+func (c *ProductUsecase) index(product *entity.Product) {
+	err := c.SearchRepository.Index(product)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while indexing product")
+	}
+}
+
+// productFilterOperators lists the operators every field of the product list
+// can be filtered with. A field without brackets, like owner=, means eq.
+var productFilterOperators = map[string][]string{
+	"name":     {"eq", "contains"},
+	"price":    {"eq", "gt", "gte", "lt", "lte"},
+	"stock":    {"eq", "gt", "gte", "lt", "lte"},
+	"owner":    {"eq"},
+	"category": {"eq"},
+	"tag":      {"eq"},
+}
+
+var productSortFields = []string{"name", "price", "stock", "created_at"}
+
+// ParseFilter reads the filters and the sort of the product list from the
+// query string, such as price[gte]=100&name[contains]=shoe&sort=-price,name.
+// Unknown parameters are refused rather than ignored. With
+// include_descendants=true, category=shoes also lists the products of the
+// subcategories of shoes.
+func (c *ProductUsecase) ParseFilter(query map[string]string) (*models.ProductFilter, error) {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	filter := new(models.ProductFilter)
+	descendants := false
+	for _, key := range keys {
+		value := query[key]
+		switch key {
+		case "page", "limit":
+			continue
+		case "include_descendants":
+			include, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, invalidProductFilter("include_descendants must be true or false")
+			}
+			descendants = include
+			continue
+		case "sort":
+			sorts, err := parseProductSort(value)
+			if err != nil {
+				return nil, err
+			}
+			filter.Sort = sorts
+			continue
+		}
+
+		condition, err := parseProductCondition(key, value)
+		if err != nil {
+			return nil, err
+		}
+		filter.Conditions = append(filter.Conditions, *condition)
+	}
+
+	if _, ok := query["include_descendants"]; ok {
+		category := -1
+		for index, condition := range filter.Conditions {
+			if condition.Field == "category" {
+				category = index
+			}
+		}
+		if category < 0 {
+			return nil, invalidProductFilter("include_descendants needs a category")
+		}
+		if descendants {
+			filter.Conditions[category].Operator = "descendants"
+		}
+	}
+
+	return filter, nil
+}
+
+func parseProductCondition(key string, value string) (*models.ProductCondition, error) {
+	field, operator := key, "eq"
+	if name, rest, found := strings.Cut(key, "["); found && strings.HasSuffix(rest, "]") {
+		field, operator = name, strings.TrimSuffix(rest, "]")
+	}
+
+	operators, ok := productFilterOperators[field]
+	if !ok {
+		return nil, invalidProductFilter(fmt.Sprintf("Unknown query parameter %s", key))
+	}
+	if !containsString(operators, operator) {
+		return nil, invalidProductFilter(fmt.Sprintf("%s can't be filtered with %s", field, operator))
+	}
+
+	condition := &models.ProductCondition{Field: field, Operator: operator, Value: value}
+	if field == "price" || field == "stock" {
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, invalidProductFilter(fmt.Sprintf("%s must be a number", key))
+		}
+		condition.Value = number
+	}
+	return condition, nil
+}
+
+// parseProductSort reads a comma separated list of fields, each descending
+// when prefixed with a minus.
+func parseProductSort(value string) ([]models.ProductSort, error) {
+	var sorts []models.ProductSort
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		descending := strings.HasPrefix(field, "-")
+		field = strings.TrimPrefix(field, "-")
+		if !containsString(productSortFields, field) {
+			return nil, invalidProductFilter(fmt.Sprintf("Products can't be sorted by %q", field))
+		}
+		sorts = append(sorts, models.ProductSort{Field: field, Descending: descending})
+	}
+
+	return sorts, nil
+}
+
+func invalidProductFilter(message string) error {
+	return &models.ErrorResponse{
+		Code:    400,
+		Message: message,
+		Status:  "Bad Request",
+	}
+}
+
+// productFilterQuery writes filter back as query parameters, so the
+// pagination links keep it.
+func productFilterQuery(filter *models.ProductFilter) url.Values {
+	query := url.Values{}
+	for _, condition := range filter.Conditions {
+		key := condition.Field
+		if condition.Operator == "descendants" {
+			query.Set(key, fmt.Sprint(condition.Value))
+			query.Set("include_descendants", "true")
+			continue
+		}
+		if condition.Operator != "eq" {
+			key = fmt.Sprintf("%s[%s]", condition.Field, condition.Operator)
+		}
+		query.Set(key, fmt.Sprint(condition.Value))
+	}
+	if len(filter.Sort) > 0 {
+		fields := make([]string, len(filter.Sort))
+		for index, order := range filter.Sort {
+			fields[index] = order.Field
+			if order.Descending {
+				fields[index] = "-" + order.Field
+			}
+		}
+		query.Set("sort", strings.Join(fields, ","))
+	}
+
+	return query
+}
+
+func (c *ProductUsecase) GetProducts(filter *models.ProductFilter, offset int, limit int) (*[]models.ProductResponse, error) {
 	var products []entity.Product
-	err := c.Repository.FindMany(&products, offset, limit)
+	err := c.Repository.FindMany(&products, filter, offset, limit)
 	if err != nil {
 		c.Log.WithError(err).Error("Error while getting products")
 		return nil, &models.ErrorResponse{
@@ -159,21 +572,211 @@ func (c *ProductUsecase) GetProducts(offset int, limit int) (*[]models.ProductRe
 		}
 	}
 
+	return c.productResponses(products), nil
+}
+
+func (c *ProductUsecase) productResponses(products []entity.Product) *[]models.ProductResponse {
 	productResponse := make([]models.ProductResponse, len(products))
 	for index, product := range products {
 		productResponse[index].Id = product.Id
 		productResponse[index].Name = product.Name
 		productResponse[index].Price = product.Price
 		productResponse[index].Stock = product.Stock
+		productResponse[index].CreatedAt = product.CreatedAt
 		productResponse[index].User.Id = product.User.Id
 		productResponse[index].User.Name = product.User.Name
+		if product.OrganizationId != nil {
+			productResponse[index].OrganizationId = *product.OrganizationId
+		}
+		c.setProductRelations(&productResponse[index], &products[index])
+	}
+	return &productResponse
+}
 
+// setProductRelations adds the category, the tags and the images of product
+// to response.
+func (c *ProductUsecase) setProductRelations(response *models.ProductResponse, product *entity.Product) {
+	response.Category = nil
+	if product.Category != nil && product.CategoryId != nil {
+		category := toCategoryResponse(product.Category)
+		response.Category = &category
 	}
-	return &productResponse, nil
+	response.Tags = nil
+	for index := range product.Tags {
+		response.Tags = append(response.Tags, toTagResponse(&product.Tags[index]))
+	}
+	response.Images = nil
+	if len(product.Images) > 0 {
+		response.Images = productImageResponses(product.Images, c.Storage)
+	}
+}
+
+// productCursor is the signed content of a cursor. It carries the filters and
+// the sort of the list it was made for, so later pages can't drift from it.
+type productCursor struct {
+	Query    string   `json:"q"`
+	Values   []string `json:"v"`
+	Id       string   `json:"id"`
+	Backward bool     `json:"b,omitempty"`
 }
 
-func (c *ProductUsecase) GetMetadataPagination(pageNumber int, limit int) (*models.Metadata, error) {
-	count, err := c.Repository.Count()
+// GetProductsByCursor lists limit products next to the cursor of query. An
+// empty cursor starts the list with the filters and the sort of query. Later
+// cursors carry those, so they can't be combined with other parameters.
+func (c *ProductUsecase) GetProductsByCursor(query map[string]string, limit int) (*[]models.ProductResponse, *models.Metadata, error) {
+	filter, listQuery, err := c.parseCursor(query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// One more product than asked tells whether the list goes on.
+	var products []entity.Product
+	err = c.Repository.FindMany(&products, filter, 0, limit+1)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while getting products by cursor")
+		return nil, nil, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+	backward := filter.Cursor != nil && filter.Cursor.Backward
+	more := len(products) > limit
+	if more && backward {
+		products = products[1:]
+	} else if more {
+		products = products[:limit]
+	}
+
+	metadata := new(models.Metadata)
+	if len(products) > 0 {
+		if more || backward {
+			metadata.NextCursor, err = c.signProductCursor(listQuery, filter, products[len(products)-1], false)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		if (more && backward) || (!backward && filter.Cursor != nil) {
+			metadata.PrevCursor, err = c.signProductCursor(listQuery, filter, products[0], true)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	metadata.Next = helper.FormatCursorURLPagination("products", metadata.NextCursor, limit)
+	metadata.Prev = helper.FormatCursorURLPagination("products", metadata.PrevCursor, limit)
+
+	return c.productResponses(products), metadata, nil
+}
+
+// parseCursor returns the filter of the page asked for and the query of the
+// list it belongs to.
+func (c *ProductUsecase) parseCursor(query map[string]string) (*models.ProductFilter, string, error) {
+	if _, ok := query["page"]; ok {
+		return nil, "", invalidProductFilter("page can't be combined with cursor")
+	}
+
+	cursor := query["cursor"]
+	if cursor == "" {
+		firstPage := make(map[string]string, len(query))
+		for key, value := range query {
+			if key != "cursor" {
+				firstPage[key] = value
+			}
+		}
+		filter, err := c.ParseFilter(firstPage)
+		if err != nil {
+			return nil, "", err
+		}
+		return filter, productFilterQuery(filter).Encode(), nil
+	}
+
+	for key := range query {
+		if key != "cursor" && key != "limit" {
+			return nil, "", invalidProductFilter("Filters and sort are kept in the cursor and can't be changed")
+		}
+	}
+
+	payload, err := helper.VerifyCursor(cursor, []byte(c.Viper.GetString("token.key.cursor")))
+	if err != nil {
+		return nil, "", invalidProductFilter("Invalid cursor")
+	}
+	content := new(productCursor)
+	err = json.Unmarshal(payload, content)
+	if err != nil {
+		return nil, "", invalidProductFilter("Invalid cursor")
+	}
+	listQuery, err := url.ParseQuery(content.Query)
+	if err != nil {
+		return nil, "", invalidProductFilter("Invalid cursor")
+	}
+	flattened := make(map[string]string, len(listQuery))
+	for key := range listQuery {
+		flattened[key] = listQuery.Get(key)
+	}
+	filter, err := c.ParseFilter(flattened)
+	if err != nil {
+		return nil, "", invalidProductFilter("Invalid cursor")
+	}
+
+	order := filter.SortOrder()
+	if len(content.Values) != len(order) {
+		return nil, "", invalidProductFilter("Invalid cursor")
+	}
+	filter.Cursor = &models.ProductCursor{Id: content.Id, Backward: content.Backward}
+	for index, field := range order {
+		value, err := parseProductSortValue(field.Field, content.Values[index])
+		if err != nil {
+			return nil, "", invalidProductFilter("Invalid cursor")
+		}
+		filter.Cursor.Values = append(filter.Cursor.Values, value)
+	}
+
+	return filter, content.Query, nil
+}
+
+func (c *ProductUsecase) signProductCursor(listQuery string, filter *models.ProductFilter, product entity.Product, backward bool) (string, error) {
+	content := productCursor{Query: listQuery, Id: product.Id, Backward: backward}
+	for _, field := range filter.SortOrder() {
+		content.Values = append(content.Values, productSortValue(product, field.Field))
+	}
+
+	payload, err := json.Marshal(content)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while encoding product cursor")
+		return "", &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+	return helper.SignCursor(payload, []byte(c.Viper.GetString("token.key.cursor"))), nil
+}
+
+func productSortValue(product entity.Product, field string) string {
+	switch field {
+	case "name":
+		return product.Name
+	case "price":
+		return strconv.Itoa(product.Price)
+	case "stock":
+		return strconv.Itoa(product.Stock)
+	}
+	return product.CreatedAt.UTC().Format(time.RFC3339Nano)
+}
+
+func parseProductSortValue(field string, value string) (any, error) {
+	switch field {
+	case "name":
+		return value, nil
+	case "price", "stock":
+		return strconv.Atoi(value)
+	}
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+func (c *ProductUsecase) GetMetadataPagination(filter *models.ProductFilter, pageNumber int, limit int) (*models.Metadata, error) {
+	count, err := c.Repository.Count(filter)
 	if err != nil {
 		c.Log.WithError(err).Error("Error while count total product record")
 		return nil, &models.ErrorResponse{
@@ -182,6 +785,13 @@ func (c *ProductUsecase) GetMetadataPagination(pageNumber int, limit int) (*mode
 			Status:  "Internal Server Error",
 		}
 	}
+
+	return pageMetadata("products", productFilterQuery(filter), count, pageNumber, limit), nil
+}
+
+// pageMetadata describes page pageNumber of count items, with links keeping
+// query.
+func pageMetadata(path string, query url.Values, count int64, pageNumber int, limit int) *models.Metadata {
 	size := float64(count) / float64(limit)
 	pageSize := int64(math.Ceil(size))
 
@@ -189,10 +799,69 @@ func (c *ProductUsecase) GetMetadataPagination(pageNumber int, limit int) (*mode
 	metadata.PageSize = pageSize
 	metadata.TotalItemCount = count
 	metadata.PageNumber = pageNumber
-	metadata.Next = helper.FormatNextURLPagination("products", pageNumber, limit, pageSize)
-	metadata.Prev = helper.FormatPrevURLPagination("products", pageNumber, limit)
+	metadata.Next = helper.AppendQuery(helper.FormatNextURLPagination(path, pageNumber, limit, pageSize), query)
+	metadata.Prev = helper.AppendQuery(helper.FormatPrevURLPagination(path, pageNumber, limit), query)
+
+	return metadata
+}
+
+// SearchProducts finds products by the words of their name, best match
+// first. Every word of q has to match, exactly, as a prefix or with a typo.
+func (c *ProductUsecase) SearchProducts(q string, pageNumber int, limit int) (*[]models.ProductSearchResponse, *models.Metadata, error) {
+	if len(q) > 255 {
+		return nil, nil, invalidProductFilter("q must be at most 255 characters")
+	}
+	terms := helper.SearchTerms(q)
+	if len(terms) == 0 {
+		return nil, nil, invalidProductFilter("q must have at least one word")
+	}
+
+	hits, count, err := c.SearchRepository.Search(terms, (pageNumber-1)*limit, limit)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while searching products")
+		return nil, nil, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	results := make([]models.ProductSearchResponse, 0, len(hits))
+	if len(hits) > 0 {
+		ids := make([]string, len(hits))
+		for index, hit := range hits {
+			ids[index] = hit.ProductId
+		}
+		var products []entity.Product
+		err = c.Repository.FindManyByIds(&products, ids)
+		if err != nil {
+			c.Log.WithError(err).Error("Error while getting found products")
+			return nil, nil, &models.ErrorResponse{
+				Code:    500,
+				Message: "Something Error",
+				Status:  "Internal Server Error",
+			}
+		}
+
+		found := make(map[string]models.ProductResponse, len(products))
+		for _, product := range *c.productResponses(products) {
+			found[product.Id] = product
+		}
+		// Products deleted since they were indexed are skipped.
+		for _, hit := range hits {
+			product, ok := found[hit.ProductId]
+			if !ok {
+				continue
+			}
+			results = append(results, models.ProductSearchResponse{
+				ProductResponse: product,
+				Highlight:       helper.HighlightSearch(terms, product.Name),
+				Score:           hit.Score,
+			})
+		}
+	}
 
-	return metadata, nil
+	return &results, pageMetadata("products/search", url.Values{"q": {q}}, count, pageNumber, limit), nil
 }
 
 func (c *ProductUsecase) GetDetailProduct(productID string) (*models.ProductResponse, error) {
@@ -214,14 +883,20 @@ func (c *ProductUsecase) GetDetailProduct(productID string) (*models.ProductResp
 			Status:  "Internal Server Error",
 		}
 	}
-	return &models.ProductResponse{
-		Id:    product.Id,
-		Name:  product.Name,
-		Price: product.Price,
-		Stock: product.Stock,
+	response := &models.ProductResponse{
+		Id:        product.Id,
+		Name:      product.Name,
+		Price:     product.Price,
+		Stock:     product.Stock,
+		CreatedAt: product.CreatedAt,
 		User: models.UserResponse{
 			Id:   product.User.Id,
 			Name: product.User.Name,
 		},
-	}, nil
+	}
+	if product.OrganizationId != nil {
+		response.OrganizationId = *product.OrganizationId
+	}
+	c.setProductRelations(response, product)
+	return response, nil
 }
@@ -0,0 +1,118 @@
+package usecase
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"go-crud/internal/models"
+	"go-crud/internal/repository"
+	"gorm.io/gorm"
+)
+
+type SessionUsecase struct {
+	Repository             repository.SessionRepositoryInterface
+	RefreshTokenRepository repository.RefreshTokenRepositoryInterface
+	Log                    *logrus.Logger
+}
+
+func NewSessionUsecase(repository repository.SessionRepositoryInterface, refreshTokenRepository repository.RefreshTokenRepositoryInterface, log *logrus.Logger) *SessionUsecase {
+	return &SessionUsecase{
+		Repository:             repository,
+		RefreshTokenRepository: refreshTokenRepository,
+		Log:                    log,
+	}
+}
+
+func (c *SessionUsecase) GetSessions(userID string, currentSessionID string) (*[]models.SessionResponse, error) {
+	sessions, err := c.Repository.FindManyActiveByUserId(userID)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while getting sessions")
+		return nil, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	sessionResponse := make([]models.SessionResponse, len(sessions))
+	for index, session := range sessions {
+		sessionResponse[index].Id = session.Id
+		if session.ClientId != nil {
+			sessionResponse[index].ClientId = *session.ClientId
+		}
+		sessionResponse[index].UserAgent = session.UserAgent
+		sessionResponse[index].IpAddress = session.IpAddress
+		sessionResponse[index].Current = session.Id == currentSessionID
+		sessionResponse[index].CreatedAt = session.CreatedAt
+		sessionResponse[index].LastSeenAt = session.LastSeenAt
+	}
+
+	return &sessionResponse, nil
+}
+
+func (c *SessionUsecase) RevokeSession(userID string, sessionID string) error {
+	session, err := c.Repository.FindOneById(sessionID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.Log.WithError(err).Error("Error while finding session")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	// Someone else's session is reported as missing rather than forbidden so
+	// session ids can't be probed.
+	if session == nil || session.UserId != userID || session.RevokedAt != nil {
+		return &models.ErrorResponse{
+			Code:    404,
+			Message: "Session not found",
+			Status:  "Not Found",
+		}
+	}
+
+	err = c.Repository.Revoke(session.Id)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while revoking session")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	err = c.RefreshTokenRepository.RevokeFamily(session.Id)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while revoking refresh tokens of session")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	return nil
+}
+
+func (c *SessionUsecase) RevokeAllSessions(userID string) error {
+	err := c.Repository.RevokeAllByUserId(userID)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while revoking sessions")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	err = c.RefreshTokenRepository.RevokeAllByUserId(userID)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while revoking refresh tokens")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	return nil
+}
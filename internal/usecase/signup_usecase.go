@@ -6,24 +6,28 @@ import (
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"go-crud/internal/entity"
+	"go-crud/internal/hasher"
 	"go-crud/internal/helper"
 	"go-crud/internal/models"
 	"go-crud/internal/repository"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 type SignUpUsecase struct {
-	Repository repository.UserRepositoryInterface
-	Validate   *validator.Validate
-	Log        *logrus.Logger
+	Repository        repository.UserRepositoryInterface
+	EmailVerification *EmailVerificationUsecase
+	Hasher            *hasher.Hasher
+	Validate          *validator.Validate
+	Log               *logrus.Logger
 }
 
-func NewSignUpUsecase(repository repository.UserRepositoryInterface, validator *validator.Validate, log *logrus.Logger) *SignUpUsecase {
+func NewSignUpUsecase(repository repository.UserRepositoryInterface, emailVerification *EmailVerificationUsecase, passwordHasher *hasher.Hasher, validator *validator.Validate, log *logrus.Logger) *SignUpUsecase {
 	return &SignUpUsecase{
-		Repository: repository,
-		Validate:   validator,
-		Log:        log,
+		Repository:        repository,
+		EmailVerification: emailVerification,
+		Hasher:            passwordHasher,
+		Validate:          validator,
+		Log:               log,
 	}
 }
 
@@ -83,6 +87,13 @@ func (u *SignUpUsecase) CreateUser(req *models.SignUpRequest) (*models.SignUpRes
 		return nil, &models.ErrorResponse{Code: 500, Message: "Something error", Status: "Internal Server Error"}
 	}
 
+	// The account exists at this point, a failed email is recovered through
+	// the resend endpoint rather than failing the whole sign up.
+	err = u.EmailVerification.SendVerification(user)
+	if err != nil {
+		u.Log.Warnf("Error while sending verification email: %v", err)
+	}
+
 	return &models.SignUpResponse{
 		Id:        user.Id,
 		Name:      user.Name,
@@ -93,7 +104,13 @@ func (u *SignUpUsecase) CreateUser(req *models.SignUpRequest) (*models.SignUpRes
 }
 
 func (u *SignUpUsecase) HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return hashPassword(u.Hasher, password)
+}
+
+// hashPassword is shared by every flow that stores a password so they all
+// hash with the configured algorithm.
+func hashPassword(passwordHasher *hasher.Hasher, password string) (string, error) {
+	hashedPassword, err := passwordHasher.Hash(password)
 	if err != nil {
 		return "", &models.ErrorResponse{
 			Code:    500,
@@ -102,5 +119,12 @@ func (u *SignUpUsecase) HashPassword(password string) (string, error) {
 		}
 	}
 
-	return string(hashedPassword), nil
+	return hashedPassword, nil
+}
+
+// verifyPassword reports whether password matches the stored hash. A hash in
+// an unknown format never matches.
+func verifyPassword(passwordHasher *hasher.Hasher, hashedPassword string, password string) bool {
+	ok, err := passwordHasher.Verify(hashedPassword, password)
+	return err == nil && ok
 }
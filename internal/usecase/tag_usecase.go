@@ -0,0 +1,178 @@
+package usecase
+
+import (
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go-crud/internal/entity"
+	"go-crud/internal/helper"
+	"go-crud/internal/models"
+	"go-crud/internal/repository"
+	"gorm.io/gorm"
+	"time"
+)
+
+type TagUsecase struct {
+	Repository repository.TagRepositoryInterface
+	Validate   *validator.Validate
+	Log        *logrus.Logger
+}
+
+func NewTagUsecase(repository repository.TagRepositoryInterface, validate *validator.Validate, log *logrus.Logger) *TagUsecase {
+	return &TagUsecase{
+		Repository: repository,
+		Validate:   validate,
+		Log:        log,
+	}
+}
+
+func (c *TagUsecase) ValidateRequest(request any) error {
+	err := c.Validate.Struct(request)
+	if err != nil {
+		c.Log.WithError(err).Warn("Error validating request")
+		message := helper.GetFirstValidationErrorAndConvert(err)
+		return &models.ErrorResponse{
+			Code:    400,
+			Status:  "Bad Request",
+			Message: message,
+		}
+	}
+	return nil
+}
+
+func (c *TagUsecase) CreateTag(request *models.TagRequest) (*models.TagResponse, error) {
+	err := c.ValidateRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	slug, err := c.availableSlug(request, "")
+	if err != nil {
+		return nil, err
+	}
+
+	tag := &entity.Tag{
+		Id:        uuid.New().String(),
+		Name:      request.Name,
+		Slug:      slug,
+		CreatedAt: time.Now(),
+	}
+	err = c.Repository.Save(tag)
+	if err != nil {
+		return nil, c.serverError(err, "Error while creating tag")
+	}
+
+	response := toTagResponse(tag)
+	return &response, nil
+}
+
+func (c *TagUsecase) GetTags() (*[]models.TagResponse, error) {
+	tags, err := c.Repository.FindAll()
+	if err != nil {
+		return nil, c.serverError(err, "Error while getting tags")
+	}
+
+	tagResponse := make([]models.TagResponse, len(tags))
+	for index := range tags {
+		tagResponse[index] = toTagResponse(&tags[index])
+	}
+	return &tagResponse, nil
+}
+
+func (c *TagUsecase) UpdateTag(tagID string, request *models.TagRequest) (*models.TagResponse, error) {
+	err := c.ValidateRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	tag, err := c.Repository.FindOneById(tagID)
+	if err != nil {
+		return nil, c.notFound(err, "Error while getting tag")
+	}
+	slug, err := c.availableSlug(request, tag.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	tag.Name = request.Name
+	tag.Slug = slug
+	err = c.Repository.UpdateById(tag)
+	if err != nil {
+		return nil, c.serverError(err, "Error while updating tag")
+	}
+
+	response := toTagResponse(tag)
+	return &response, nil
+}
+
+// DeleteTag removes the tag, and so takes it off every product.
+func (c *TagUsecase) DeleteTag(tagID string) error {
+	_, err := c.Repository.FindOneById(tagID)
+	if err != nil {
+		return c.notFound(err, "Error while getting tag")
+	}
+
+	err = c.Repository.DeleteById(tagID)
+	if err != nil {
+		return c.serverError(err, "Error while deleting tag")
+	}
+	return nil
+}
+
+// availableSlug returns the slug of request, unless another tag than tagID
+// already has it.
+func (c *TagUsecase) availableSlug(request *models.TagRequest, tagID string) (string, error) {
+	slug := request.Slug
+	if slug == "" {
+		slug = request.Name
+	}
+	slug = helper.Slugify(slug)
+	if slug == "" {
+		return "", &models.ErrorResponse{
+			Code:    400,
+			Message: "Slug needs a letter or a digit",
+			Status:  "Bad Request",
+		}
+	}
+
+	existing, err := c.Repository.FindOneBySlug(slug)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", c.serverError(err, "Error while checking tag slug")
+	}
+	if err == nil && existing.Id != tagID {
+		return "", &models.ErrorResponse{
+			Code:    409,
+			Message: "Slug is already taken",
+			Status:  "Conflict",
+		}
+	}
+	return slug, nil
+}
+
+func (c *TagUsecase) notFound(err error, message string) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.ErrorResponse{
+			Code:    404,
+			Message: "Tag not found",
+			Status:  "Not Found",
+		}
+	}
+	return c.serverError(err, message)
+}
+
+func (c *TagUsecase) serverError(err error, message string) error {
+	c.Log.WithError(err).Error(message)
+	return &models.ErrorResponse{
+		Code:    500,
+		Message: "Something Wrong",
+		Status:  "Internal Server Error",
+	}
+}
+
+func toTagResponse(tag *entity.Tag) models.TagResponse {
+	return models.TagResponse{
+		Id:        tag.Id,
+		Name:      tag.Name,
+		Slug:      tag.Slug,
+		CreatedAt: tag.CreatedAt,
+	}
+}
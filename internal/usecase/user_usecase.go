@@ -0,0 +1,328 @@
+package usecase
+
+import (
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/sirupsen/logrus"
+	"go-crud/internal/entity"
+	"go-crud/internal/hasher"
+	"go-crud/internal/helper"
+	"go-crud/internal/models"
+	"go-crud/internal/repository"
+	"gorm.io/gorm"
+	"strings"
+)
+
+type UserUsecase struct {
+	Repository             repository.UserRepositoryInterface
+	UserTokenRepository    repository.UserTokenRepositoryInterface
+	SessionRepository      repository.SessionRepositoryInterface
+	RefreshTokenRepository repository.RefreshTokenRepositoryInterface
+	MemberRepository       repository.OrganizationMemberRepositoryInterface
+	EmailVerification      *EmailVerificationUsecase
+	LoginAttempts          *LoginAttemptUsecase
+	AuditLog               *AuditLogUsecase
+	Products               *ProductUsecase
+	Hasher                 *hasher.Hasher
+	Validate               *validator.Validate
+	Log                    *logrus.Logger
+}
+
+func NewUserUsecase(repository repository.UserRepositoryInterface, userTokenRepository repository.UserTokenRepositoryInterface, sessionRepository repository.SessionRepositoryInterface, refreshTokenRepository repository.RefreshTokenRepositoryInterface, memberRepository repository.OrganizationMemberRepositoryInterface, emailVerification *EmailVerificationUsecase, loginAttempts *LoginAttemptUsecase, auditLog *AuditLogUsecase, products *ProductUsecase, passwordHasher *hasher.Hasher, validate *validator.Validate, log *logrus.Logger) *UserUsecase {
+	return &UserUsecase{
+		Repository:             repository,
+		UserTokenRepository:    userTokenRepository,
+		SessionRepository:      sessionRepository,
+		RefreshTokenRepository: refreshTokenRepository,
+		MemberRepository:       memberRepository,
+		EmailVerification:      emailVerification,
+		LoginAttempts:          loginAttempts,
+		AuditLog:               auditLog,
+		Products:               products,
+		Hasher:                 passwordHasher,
+		Validate:               validate,
+		Log:                    log,
+	}
+}
+
+func (c *UserUsecase) ValidateRequest(request any) error {
+	err := c.Validate.Struct(request)
+	if err != nil {
+		c.Log.WithError(err).Warn("Error validating request")
+		message := helper.GetFirstValidationErrorAndConvert(err)
+		return &models.ErrorResponse{
+			Code:    400,
+			Status:  "Bad Request",
+			Message: message,
+		}
+	}
+	return nil
+}
+
+func (c *UserUsecase) findUser(userID string) (*entity.User, error) {
+	user := new(entity.User)
+	err := c.Repository.FindOneById(user, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &models.ErrorResponse{
+				Code:    401,
+				Message: "You're unauthorized",
+				Status:  "Unauthorized",
+			}
+		}
+
+		c.Log.WithError(err).Error("Error while finding user")
+		return nil, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	return user, nil
+}
+
+// checkPassword re-authenticates the user before a sensitive change. Wrong
+// passwords count towards the sign in lockout, so a stolen access token
+// can't be used to guess the password either.
+func (c *UserUsecase) checkPassword(user *entity.User, password string) error {
+	err := c.LoginAttempts.Check(user.Email, "")
+	if err != nil {
+		return err
+	}
+
+	if !verifyPassword(c.Hasher, user.Password, password) {
+		c.LoginAttempts.RegisterFailure(user.Email, "")
+		return &models.ErrorResponse{
+			Code:    401,
+			Message: "Password is incorrect",
+			Status:  "Unauthorized",
+		}
+	}
+
+	c.LoginAttempts.RegisterSuccess(user.Email)
+	return nil
+}
+
+func (c *UserUsecase) GetProfile(userID string) (*models.ProfileResponse, error) {
+	user, err := c.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return toProfileResponse(user), nil
+}
+
+// UpdateProfile changes the name and asks for an email change. As the email
+// receives password reset links, changing it takes the current password, and
+// the new email only replaces the old one once confirmed from a link mailed
+// to it. The old email is told about the change.
+func (c *UserUsecase) UpdateProfile(userID string, request *models.UpdateProfileRequest) (*models.ProfileResponse, error) {
+	err := c.ValidateRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := c.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Name != nil {
+		user.Name = *request.Name
+	}
+
+	emailChanged := request.Email != nil && !strings.EqualFold(*request.Email, user.Email)
+	if request.Email != nil && !emailChanged {
+		// Asking for the current email again cancels a pending change.
+		user.PendingEmail = nil
+	}
+	if emailChanged {
+		if request.CurrentPassword == "" {
+			return nil, &models.ErrorResponse{
+				Code:    400,
+				Message: "current_password is required to change the email",
+				Status:  "Bad Request",
+			}
+		}
+		err = c.checkPassword(user, request.CurrentPassword)
+		if err != nil {
+			return nil, err
+		}
+
+		existing, err := c.Repository.FindOneByEmail(*request.Email)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.Log.WithError(err).Error("Error while finding user by email")
+			return nil, &models.ErrorResponse{
+				Code:    500,
+				Message: "Something Error",
+				Status:  "Internal Server Error",
+			}
+		}
+		if existing != nil {
+			return nil, &models.ErrorResponse{
+				Code:    400,
+				Message: "Email already exists",
+				Status:  "Bad Request",
+			}
+		}
+
+		user.PendingEmail = request.Email
+	}
+
+	err = c.Repository.UpdateProfile(user)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while updating profile")
+		return nil, &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	if emailChanged {
+		// A failed email is recovered by asking for the change again rather
+		// than failing it.
+		err = c.EmailVerification.SendEmailChange(user)
+		if err != nil {
+			c.Log.WithError(err).Warn("Error while sending email change confirmation")
+		}
+	}
+
+	return toProfileResponse(user), nil
+}
+
+// ChangePassword keeps the current session signed in and signs out every
+// other one. The attempt is recorded in the audit log.
+func (c *UserUsecase) ChangePassword(userID string, sessionID string, request *models.ChangePasswordRequest) error {
+	err := c.changePassword(userID, sessionID, request)
+	c.AuditLog.Record(models.Actor{UserId: userID, Client: request.Client}, entity.AuditActionPasswordChange, "", auditOutcome(err))
+	return err
+}
+
+func (c *UserUsecase) changePassword(userID string, sessionID string, request *models.ChangePasswordRequest) error {
+	err := c.ValidateRequest(request)
+	if err != nil {
+		return err
+	}
+
+	user, err := c.findUser(userID)
+	if err != nil {
+		return err
+	}
+
+	err = c.checkPassword(user, request.CurrentPassword)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := hashPassword(c.Hasher, request.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	err = c.Repository.UpdatePassword(user.Id, hashedPassword)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while updating password")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	err = c.SessionRepository.RevokeAllByUserIdExcept(user.Id, sessionID)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while revoking other sessions after password change")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	err = c.RefreshTokenRepository.RevokeAllByUserIdExcept(user.Id, sessionID)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while revoking other refresh tokens after password change")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	return nil
+}
+
+// DeleteAccount removes the user. Their products, sessions and every other
+// row owned by the user go with it, products of an organization stay. The
+// last owner of an organization has to hand it over first, as when leaving
+// it.
+func (c *UserUsecase) DeleteAccount(userID string, request *models.DeleteAccountRequest) error {
+	err := c.ValidateRequest(request)
+	if err != nil {
+		return err
+	}
+
+	user, err := c.findUser(userID)
+	if err != nil {
+		return err
+	}
+
+	err = c.checkPassword(user, request.Password)
+	if err != nil {
+		return err
+	}
+
+	soleOwnerships, err := c.MemberRepository.CountSoleByRole(user.Id, models.OrganizationRoleOwner)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while counting owned organizations")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+	if soleOwnerships > 0 {
+		return &models.ErrorResponse{
+			Code:    409,
+			Message: "You're the only owner of an organization, transfer ownership first",
+			Status:  "Conflict",
+		}
+	}
+
+	removal, err := c.Products.PrepareUserRemoval(user.Id)
+	if err != nil {
+		return err
+	}
+
+	err = c.Repository.DeleteOneById(user.Id)
+	if err != nil {
+		c.Log.WithError(err).Error("Error while deleting user")
+		return &models.ErrorResponse{
+			Code:    500,
+			Message: "Something Error",
+			Status:  "Internal Server Error",
+		}
+	}
+
+	c.Products.FinishRemoval(removal)
+	return nil
+}
+
+func toProfileResponse(user *entity.User) *models.ProfileResponse {
+	pendingEmail := ""
+	if user.PendingEmail != nil {
+		pendingEmail = *user.PendingEmail
+	}
+	return &models.ProfileResponse{
+		Id:               user.Id,
+		Name:             user.Name,
+		Email:            user.Email,
+		EmailVerified:    user.EmailVerifiedAt != nil,
+		PendingEmail:     pendingEmail,
+		TwoFactorEnabled: user.TotpEnabledAt != nil,
+		CreatedAt:        user.CreatedAt,
+		UpdatedAt:        user.UpdatedAt,
+	}
+}
@@ -0,0 +1,219 @@
+package test
+
+import (
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go-crud/internal/delivery/http/controllers"
+	"go-crud/internal/delivery/http/middleware"
+	"go-crud/internal/delivery/http/routes"
+	"go-crud/internal/entity"
+	"go-crud/internal/helper"
+	"go-crud/internal/mail"
+	"go-crud/internal/models"
+	"go-crud/internal/usecase"
+	"go-crud/test/mocks"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAdmin(t *testing.T) {
+	mailSender := mail.NewFileSender(t.TempDir())
+	userRepository := mocks.NewRepositoryMock()
+	productRepository := mocks.NewProductRepositoryMock()
+	sessionRepository := mocks.NewSessionRepositoryMock()
+	refreshTokenRepository := mocks.NewRefreshTokenRepositoryMock()
+	userTokenRepository := mocks.NewUserTokenRepositoryMock()
+	apiKeyRepository := mocks.NewApiKeyRepositoryMock()
+	auditLogRepository := mocks.NewAuditLogRepositoryMock()
+	sessionRepository.Mock.On("RevokeAllByUserId", mock.Anything).Return(nil)
+	refreshTokenRepository.Mock.On("RevokeAllByUserId", mock.Anything).Return(nil)
+	userTokenRepository.Mock.On("InvalidateByUserId", mock.Anything, mock.Anything).Return(nil)
+	userTokenRepository.Mock.On("Save", mock.Anything).Return(nil)
+	auditLogRepository.Mock.On("Save", mock.Anything).Return(nil)
+
+	auditLogUsecase := usecase.NewAuditLogUsecase(auditLogRepository, log)
+	sessionUsecase := usecase.NewSessionUsecase(sessionRepository, refreshTokenRepository, log)
+	passwordResetUsecase := usecase.NewPasswordResetUsecase(userRepository, userTokenRepository, sessionRepository, refreshTokenRepository, loginAttemptUsecase, auditLogUsecase, passwordHasher, mailSender, validate, viperConfig, log)
+	authUsecase := usecase.NewAuthUsecase(userRepository, refreshTokenRepository, sessionRepository, revokedAccessTokenRepositoryMock, loginAttemptUsecase, auditLogUsecase, passwordHasher, keySet, validate, viperConfig, log)
+	adminUsecase := usecase.NewAdminUsecase(userRepository, productRepository, authUsecase, sessionUsecase, passwordResetUsecase, auditLogUsecase, viperConfig, log)
+
+	actor := models.Actor{UserId: "admin-actor", Client: models.ClientInfo{IpAddress: "10.0.0.1", UserAgent: "support-console"}}
+	disabledAt := time.Now().Add(-time.Hour)
+	findUser := func(user *entity.User) {
+		userRepository.Mock.On("FindOneById", mock.Anything, user.Id).Return(nil).Run(func(args mock.Arguments) {
+			*args.Get(0).(*entity.User) = *user
+		})
+	}
+	findUser(&entity.User{Id: "admin-target", Name: "Danar", Email: "admin-target@gmail.com"})
+	findUser(&entity.User{Id: "admin-disabled", Name: "Disabled", Email: "admin-disabled@gmail.com", DisabledAt: &disabledAt})
+	userRepository.Mock.On("FindOneById", mock.Anything, mock.Anything).Return(gorm.ErrRecordNotFound)
+	userRepository.Mock.On("IsDisabled", "admin-disabled").Return(true, nil)
+	userRepository.Mock.On("IsDisabled", mock.Anything).Return(false, nil)
+
+	audited := func(action string, targetID string, outcome string) {
+		auditLogRepository.Mock.AssertCalled(t, "Save", mock.MatchedBy(func(entry *entity.AuditLog) bool {
+			return entry.Action == action &&
+				entry.Outcome == outcome &&
+				*entry.ActorId == actor.UserId &&
+				entry.IpAddress == actor.Client.IpAddress &&
+				entry.UserAgent == actor.Client.UserAgent &&
+				(targetID == "" && entry.TargetId == nil || entry.TargetId != nil && *entry.TargetId == targetID)
+		}))
+	}
+
+	t.Run("List users", func(t *testing.T) {
+		t.Run("Should search and keep the search in pagination links", func(t *testing.T) {
+			userRepository.Mock.On("FindMany", mock.Anything, "danar", 0, 1).Return(nil).Run(func(args mock.Arguments) {
+				*args.Get(0).(*[]entity.User) = []entity.User{{Id: "admin-target", Name: "Danar", Email: "admin-target@gmail.com"}}
+			})
+			userRepository.Mock.On("Count", "danar").Return(int64(2), nil)
+
+			users, err := adminUsecase.GetUsers(actor, "danar", 0, 1)
+			require.Nil(t, err)
+			require.Len(t, *users, 1)
+			require.Equal(t, "admin-target@gmail.com", (*users)[0].Email)
+			require.False(t, (*users)[0].Disabled)
+			require.Nil(t, (*users)[0].ProductCount)
+			audited(entity.AuditActionAdminUserList, "", entity.AuditOutcomeSuccess)
+
+			metadata, err := adminUsecase.GetMetadataPagination("danar", 1, 1)
+			require.Nil(t, err)
+			require.Equal(t, int64(2), metadata.PageSize)
+			require.Equal(t, "http://localhost:8080/admin/users?page=2&limit=1&q=danar", metadata.Next)
+			require.Equal(t, "", metadata.Prev)
+		})
+	})
+
+	t.Run("Get user", func(t *testing.T) {
+		t.Run("Should return the product count", func(t *testing.T) {
+			productRepository.Mock.On("CountByUserId", "admin-target").Return(int64(3), nil)
+
+			user, err := adminUsecase.GetUser(actor, "admin-target")
+			require.Nil(t, err)
+			require.Equal(t, int64(3), *user.ProductCount)
+			audited(entity.AuditActionAdminUserView, "admin-target", entity.AuditOutcomeSuccess)
+		})
+
+		t.Run("Should audit a failure for an unknown user", func(t *testing.T) {
+			_, err := adminUsecase.GetUser(actor, "admin-unknown")
+			require.Equal(t, &models.ErrorResponse{Code: 404, Message: "User not found", Status: "Not Found"}, err)
+			audited(entity.AuditActionAdminUserView, "admin-unknown", entity.AuditOutcomeFailure)
+		})
+	})
+
+	t.Run("Disable user", func(t *testing.T) {
+		t.Run("Should disable and sign out the user", func(t *testing.T) {
+			userRepository.Mock.On("UpdateDisabledAt", "admin-target", mock.AnythingOfType("*time.Time")).Return(nil).Once()
+
+			err := adminUsecase.DisableUser(actor, "admin-target")
+			require.Nil(t, err)
+			userRepository.Mock.AssertCalled(t, "UpdateDisabledAt", "admin-target", mock.AnythingOfType("*time.Time"))
+			sessionRepository.Mock.AssertCalled(t, "RevokeAllByUserId", "admin-target")
+			refreshTokenRepository.Mock.AssertCalled(t, "RevokeAllByUserId", "admin-target")
+			audited(entity.AuditActionAdminUserDisable, "admin-target", entity.AuditOutcomeSuccess)
+		})
+
+		t.Run("Should refuse to disable the own account", func(t *testing.T) {
+			err := adminUsecase.DisableUser(actor, actor.UserId)
+			require.Equal(t, &models.ErrorResponse{Code: 400, Message: "You can't disable your own account", Status: "Bad Request"}, err)
+			audited(entity.AuditActionAdminUserDisable, actor.UserId, entity.AuditOutcomeFailure)
+		})
+
+		t.Run("Should refuse sign in with the right password", func(t *testing.T) {
+			hashedPassword, err := passwordHasher.Hash("disabled-password")
+			require.Nil(t, err)
+			userRepository.Mock.On("FindOneByEmail", "admin-disabled@gmail.com").Return(&entity.User{Id: "admin-disabled", Email: "admin-disabled@gmail.com", Password: hashedPassword, DisabledAt: &disabledAt}, nil)
+
+			_, err = authUsecase.SignIn(&models.SignInRequest{Email: "admin-disabled@gmail.com", Password: "disabled-password"})
+			require.Equal(t, &models.ErrorResponse{Code: 403, Message: "Account is disabled", Status: "Forbidden"}, err)
+		})
+
+		t.Run("Should refuse the access tokens and API keys of the user", func(t *testing.T) {
+			now := time.Now()
+			apiKeyRepository.Mock.On("FindOneByHash", helper.HashToken("gck_disabled")).Return(&entity.ApiKey{Id: "disabled-key", UserId: "admin-disabled", LastUsedAt: &now}, nil)
+			authMiddleware := middleware.NewAuthMiddleware(authUsecase, usecase.NewApiKeyUsecase(apiKeyRepository, validate, log), auditLogUsecase, log)
+			app := fiber.New()
+			app.Get("/me", authMiddleware.Auth, func(ctx *fiber.Ctx) error {
+				return ctx.SendStatus(fiber.StatusOK)
+			})
+
+			send := func(header string, value string) int {
+				request := httptest.NewRequest(fiber.MethodGet, "/me", nil)
+				request.Header.Set(header, value)
+				response, err := app.Test(request)
+				require.Nil(t, err)
+				return response.StatusCode
+			}
+
+			disabledToken, err := authUsecase.GenerateAccessToken(&models.AccessTokenClaims{Subject: "admin-disabled"})
+			require.Nil(t, err)
+			enabledToken, err := authUsecase.GenerateAccessToken(&models.AccessTokenClaims{Subject: "admin-target"})
+			require.Nil(t, err)
+
+			require.Equal(t, 403, send("Authorization", "Bearer "+disabledToken))
+			require.Equal(t, 403, send("X-API-Key", "gck_disabled"))
+			require.Equal(t, 200, send("Authorization", "Bearer "+enabledToken))
+		})
+	})
+
+	t.Run("Enable user", func(t *testing.T) {
+		userRepository.Mock.On("UpdateDisabledAt", "admin-disabled", (*time.Time)(nil)).Return(nil).Once()
+
+		err := adminUsecase.EnableUser(actor, "admin-disabled")
+		require.Nil(t, err)
+		userRepository.Mock.AssertCalled(t, "UpdateDisabledAt", "admin-disabled", (*time.Time)(nil))
+		audited(entity.AuditActionAdminUserEnable, "admin-disabled", entity.AuditOutcomeSuccess)
+	})
+
+	t.Run("Force logout", func(t *testing.T) {
+		err := adminUsecase.LogoutUser(actor, "admin-target")
+		require.Nil(t, err)
+		sessionRepository.Mock.AssertCalled(t, "RevokeAllByUserId", "admin-target")
+		audited(entity.AuditActionAdminUserLogout, "admin-target", entity.AuditOutcomeSuccess)
+	})
+
+	t.Run("Password reset", func(t *testing.T) {
+		err := adminUsecase.SendPasswordReset(actor, "admin-target")
+		require.Nil(t, err)
+
+		message, err := mailSender.Last("admin-target@gmail.com")
+		require.Nil(t, err)
+		require.Contains(t, message, "Reset token: ")
+		audited(entity.AuditActionAdminUserPasswordReset, "admin-target", entity.AuditOutcomeSuccess)
+	})
+
+	t.Run("Routes need the user permissions", func(t *testing.T) {
+		authMiddleware := middleware.NewAuthMiddleware(authUsecase, usecase.NewApiKeyUsecase(apiKeyRepository, validate, log), auditLogUsecase, log)
+		app := fiber.New()
+		routes.NewAdminRoute(app, controllers.NewAdminController(log, adminUsecase), authMiddleware).Setup()
+
+		send := func(method string, path string, claims *models.AccessTokenClaims) *http.Response {
+			token, err := authUsecase.GenerateAccessToken(claims)
+			require.Nil(t, err)
+			request := httptest.NewRequest(method, path, nil)
+			request.Header.Set("Authorization", "Bearer "+token)
+			response, err := app.Test(request)
+			require.Nil(t, err)
+			return response
+		}
+
+		user := &models.AccessTokenClaims{Subject: "admin-target"}
+		reader := &models.AccessTokenClaims{Subject: actor.UserId, Permissions: []string{models.PermissionUserReadAny}}
+
+		require.Equal(t, 403, send(fiber.MethodGet, "/admin/users/admin-target", user).StatusCode)
+		require.Equal(t, 403, send(fiber.MethodPost, "/admin/users/admin-target/logout", reader).StatusCode)
+
+		response := send(fiber.MethodGet, "/admin/users/admin-target", reader)
+		require.Equal(t, 200, response.StatusCode)
+		body := new(models.Response[*models.AdminUserResponse])
+		require.Nil(t, json.NewDecoder(response.Body).Decode(body))
+		require.Equal(t, "admin-target", body.Data.Id)
+
+		require.Equal(t, 400, send(fiber.MethodGet, "/admin/users?limit=101", reader).StatusCode)
+	})
+}
@@ -0,0 +1,151 @@
+package test
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go-crud/internal/delivery/http/middleware"
+	"go-crud/internal/entity"
+	"go-crud/internal/helper"
+	"go-crud/internal/models"
+	"go-crud/internal/usecase"
+	"gorm.io/gorm"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApiKey(t *testing.T) {
+	apiKeyUsecase := usecase.NewApiKeyUsecase(apiKeyRepositoryMock, validate, log)
+	apiKeyRepositoryMock.Mock.On("Touch", mock.Anything, mock.Anything).Return(nil)
+
+	t.Run("Create api key", func(t *testing.T) {
+		t.Run("Should return the key once and store its hash", func(t *testing.T) {
+			apiKeyRepositoryMock.Mock.On("Save", mock.MatchedBy(func(apiKey *entity.ApiKey) bool {
+				return apiKey.UserId == "api-key-user"
+			})).Return(nil)
+
+			result, err := apiKeyUsecase.CreateApiKey("api-key-user", &models.CreateApiKeyRequest{
+				Name:   "CI",
+				Scopes: []string{models.ScopeProductsRead, models.ScopeProductsRead},
+			})
+			require.Nil(t, err)
+			require.True(t, strings.HasPrefix(result.Key, usecase.ApiKeyPrefix))
+			require.Equal(t, result.Key[:len(result.Prefix)], result.Prefix)
+			require.Equal(t, []string{models.ScopeProductsRead}, result.Scopes)
+			apiKeyRepositoryMock.Mock.AssertCalled(t, "Save", mock.MatchedBy(func(apiKey *entity.ApiKey) bool {
+				return apiKey.KeyHash == helper.HashToken(result.Key) && apiKey.Scopes == models.ScopeProductsRead
+			}))
+		})
+
+		t.Run("Should reject an unknown scope", func(t *testing.T) {
+			result, err := apiKeyUsecase.CreateApiKey("api-key-user", &models.CreateApiKeyRequest{
+				Name:   "CI",
+				Scopes: []string{"users:delete"},
+			})
+			require.Nil(t, result)
+			require.Equal(t, 400, err.(*models.ErrorResponse).Code)
+		})
+
+		t.Run("Should reject an expiration in the past", func(t *testing.T) {
+			expiresAt := time.Now().Add(-time.Hour)
+			result, err := apiKeyUsecase.CreateApiKey("api-key-user", &models.CreateApiKeyRequest{
+				Name:      "CI",
+				Scopes:    []string{models.ScopeProductsRead},
+				ExpiresAt: &expiresAt,
+			})
+			require.Nil(t, result)
+			require.Equal(t, &models.ErrorResponse{Code: 400, Message: "Expiration must be in the future", Status: "Bad Request"}, err)
+		})
+	})
+
+	t.Run("Verify api key", func(t *testing.T) {
+		t.Run("Should accept an active key", func(t *testing.T) {
+			stored := &entity.ApiKey{Id: "active-key", UserId: "api-key-user", Scopes: models.ScopeProductsRead}
+			apiKeyRepositoryMock.Mock.On("FindOneByHash", helper.HashToken("gck_active")).Return(stored, nil)
+
+			result, err := apiKeyUsecase.VerifyApiKey("gck_active")
+			require.Nil(t, err)
+			require.Equal(t, "api-key-user", result.UserId)
+			apiKeyRepositoryMock.Mock.AssertCalled(t, "Touch", "active-key", mock.Anything)
+		})
+
+		t.Run("Should reject an expired key", func(t *testing.T) {
+			expiresAt := time.Now().Add(-time.Minute)
+			stored := &entity.ApiKey{Id: "expired-key", UserId: "api-key-user", ExpiresAt: &expiresAt}
+			apiKeyRepositoryMock.Mock.On("FindOneByHash", helper.HashToken("gck_expired")).Return(stored, nil)
+
+			result, err := apiKeyUsecase.VerifyApiKey("gck_expired")
+			require.Nil(t, result)
+			require.Equal(t, &models.ErrorResponse{Code: 401, Message: "Invalid API key", Status: "Unauthorized"}, err)
+		})
+
+		t.Run("Should reject a revoked key", func(t *testing.T) {
+			revokedAt := time.Now()
+			stored := &entity.ApiKey{Id: "revoked-key", UserId: "api-key-user", RevokedAt: &revokedAt}
+			apiKeyRepositoryMock.Mock.On("FindOneByHash", helper.HashToken("gck_revoked")).Return(stored, nil)
+
+			result, err := apiKeyUsecase.VerifyApiKey("gck_revoked")
+			require.Nil(t, result)
+			require.Equal(t, 401, err.(*models.ErrorResponse).Code)
+		})
+
+		t.Run("Should reject an unknown key", func(t *testing.T) {
+			apiKeyRepositoryMock.Mock.On("FindOneByHash", helper.HashToken("gck_unknown")).Return(nil, gorm.ErrRecordNotFound)
+
+			result, err := apiKeyUsecase.VerifyApiKey("gck_unknown")
+			require.Nil(t, result)
+			require.Equal(t, 401, err.(*models.ErrorResponse).Code)
+		})
+	})
+
+	t.Run("Revoke api key of another user is reported as not found", func(t *testing.T) {
+		apiKeyRepositoryMock.Mock.On("Revoke", "someone-else-key", "api-key-user").Return(false, nil)
+
+		err := apiKeyUsecase.RevokeApiKey("api-key-user", "someone-else-key")
+		require.Equal(t, &models.ErrorResponse{Code: 404, Message: "API key not found", Status: "Not Found"}, err)
+	})
+
+	t.Run("Middleware enforces api key scopes", func(t *testing.T) {
+		authUsecase := usecase.NewAuthUsecase(userRepositoryMock, refreshTokenRepositoryMock, sessionRepositoryMock, revokedAccessTokenRepositoryMock, loginAttemptUsecase, auditLogUsecase, passwordHasher, keySet, validate, viperConfig, log)
+		authMiddleware := middleware.NewAuthMiddleware(authUsecase, apiKeyUsecase, auditLogUsecase, log)
+		app := fiber.New()
+		ok := func(ctx *fiber.Ctx) error {
+			return ctx.SendString(ctx.Locals("user_id").(string))
+		}
+		app.Get("/read", authMiddleware.Auth, authMiddleware.RequireScope(models.ScopeProductsRead), ok)
+		app.Post("/write", authMiddleware.Auth, authMiddleware.RequireScope(models.ScopeProductsWrite), ok)
+		app.Get("/account", authMiddleware.Auth, authMiddleware.RequireSession, ok)
+
+		request := httptest.NewRequest("GET", "/read", nil)
+		request.Header.Set("X-API-Key", "gck_active")
+		response, err := app.Test(request)
+		require.Nil(t, err)
+		require.Equal(t, 200, response.StatusCode)
+
+		request = httptest.NewRequest("GET", "/read", nil)
+		request.Header.Set("Authorization", "Bearer gck_active")
+		response, err = app.Test(request)
+		require.Nil(t, err)
+		require.Equal(t, 200, response.StatusCode)
+
+		request = httptest.NewRequest("POST", "/write", nil)
+		request.Header.Set("X-API-Key", "gck_active")
+		response, err = app.Test(request)
+		require.Nil(t, err)
+		require.Equal(t, 403, response.StatusCode)
+
+		request = httptest.NewRequest("GET", "/account", nil)
+		request.Header.Set("X-API-Key", "gck_active")
+		response, err = app.Test(request)
+		require.Nil(t, err)
+		require.Equal(t, 403, response.StatusCode)
+
+		request = httptest.NewRequest("GET", "/read", nil)
+		request.Header.Set("X-API-Key", "gck_revoked")
+		response, err = app.Test(request)
+		require.Nil(t, err)
+		require.Equal(t, 401, response.StatusCode)
+	})
+}
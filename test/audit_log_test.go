@@ -0,0 +1,239 @@
+package test
+
+import (
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go-crud/internal/delivery/http/controllers"
+	"go-crud/internal/delivery/http/middleware"
+	"go-crud/internal/delivery/http/routes"
+	"go-crud/internal/entity"
+	"go-crud/internal/helper"
+	"go-crud/internal/models"
+	"go-crud/internal/usecase"
+	"go-crud/test/mocks"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAuditLog(t *testing.T) {
+	userRepository := mocks.NewRepositoryMock()
+	sessionRepository := mocks.NewSessionRepositoryMock()
+	refreshTokenRepository := mocks.NewRefreshTokenRepositoryMock()
+	productRepository := mocks.NewProductRepositoryMock()
+	auditLogRepository := mocks.NewAuditLogRepositoryMock()
+
+	var mutex sync.Mutex
+	var entries []*entity.AuditLog
+	auditLogRepository.Mock.On("Save", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		entries = append(entries, args.Get(0).(*entity.AuditLog))
+	})
+	last := func() *entity.AuditLog {
+		mutex.Lock()
+		defer mutex.Unlock()
+		require.NotEmpty(t, entries)
+		return entries[len(entries)-1]
+	}
+
+	auditLogUsecase := usecase.NewAuditLogUsecase(auditLogRepository, log)
+	authUsecase := usecase.NewAuthUsecase(userRepository, refreshTokenRepository, sessionRepository, revokedAccessTokenRepositoryMock, loginAttemptUsecase, auditLogUsecase, passwordHasher, keySet, validate, viperConfig, log)
+	authMiddleware := middleware.NewAuthMiddleware(authUsecase, usecase.NewApiKeyUsecase(apiKeyRepositoryMock, validate, log), auditLogUsecase, log)
+
+	hashedPassword, err := passwordHasher.Hash("audit-password")
+	require.Nil(t, err)
+	now := time.Now()
+	userRepository.Mock.On("FindOneByEmail", "audit@gmail.com").Return(&entity.User{Id: "audit-user", Email: "audit@gmail.com", Password: hashedPassword, EmailVerifiedAt: &now}, nil)
+	userRepository.Mock.On("FindOneByEmail", mock.Anything).Return(nil, nil)
+	userRepository.Mock.On("FindRolesByUserId", mock.Anything).Return([]entity.Role{}, nil)
+	userRepository.Mock.On("IsDisabled", mock.Anything).Return(false, nil)
+	sessionRepository.Mock.On("Save", mock.Anything).Return(nil)
+	sessionRepository.Mock.On("Touch", mock.Anything, mock.Anything).Return(nil)
+	sessionRepository.Mock.On("FindOneById", "audit-family").Return(&entity.Session{Id: "audit-family", UserId: "audit-user", Methods: "pwd"}, nil)
+	sessionRepository.Mock.On("FindOneById", "audit-admin-session").Return(&entity.Session{Id: "audit-admin-session", UserId: "audit-admin"}, nil)
+	refreshTokenRepository.Mock.On("Save", mock.Anything).Return(nil)
+
+	client := models.ClientInfo{IpAddress: "10.0.0.3", UserAgent: "audit-agent"}
+
+	t.Run("Sign in", func(t *testing.T) {
+		t.Run("Should record an unknown email", func(t *testing.T) {
+			_, err := authUsecase.SignIn(&models.SignInRequest{Email: "ghost@gmail.com", Password: "audit-password", Client: client})
+			require.NotNil(t, err)
+
+			entry := last()
+			require.Equal(t, entity.AuditActionSignIn, entry.Action)
+			require.Equal(t, entity.AuditOutcomeFailure, entry.Outcome)
+			require.Nil(t, entry.ActorId)
+			require.Equal(t, "unknown email ghost@gmail.com", entry.Details)
+			require.Equal(t, "10.0.0.3", entry.IpAddress)
+			require.Equal(t, "audit-agent", entry.UserAgent)
+		})
+
+		t.Run("Should record a wrong password without the password", func(t *testing.T) {
+			_, err := authUsecase.SignIn(&models.SignInRequest{Email: "audit@gmail.com", Password: "guessed-password", Client: client})
+			require.NotNil(t, err)
+
+			entry := last()
+			require.Equal(t, entity.AuditOutcomeFailure, entry.Outcome)
+			require.Equal(t, "audit-user", *entry.ActorId)
+			require.Equal(t, "wrong password", entry.Details)
+			require.NotContains(t, entry.Details, "guessed-password")
+		})
+
+		t.Run("Should record a success with the methods", func(t *testing.T) {
+			_, err := authUsecase.SignIn(&models.SignInRequest{Email: "audit@gmail.com", Password: "audit-password", Client: client})
+			require.Nil(t, err)
+
+			entry := last()
+			require.Equal(t, entity.AuditActionSignIn, entry.Action)
+			require.Equal(t, entity.AuditOutcomeSuccess, entry.Outcome)
+			require.Equal(t, "audit-user", *entry.ActorId)
+			require.Equal(t, "methods pwd", entry.Details)
+		})
+	})
+
+	t.Run("Refresh and sign out", func(t *testing.T) {
+		t.Run("Should record a refresh under the user", func(t *testing.T) {
+			refreshToken, err := authUsecase.GenerateRefreshToken("audit-user", "audit-family")
+			require.Nil(t, err)
+			refreshTokenRepository.Mock.On("FindOneByHash", helper.HashToken(refreshToken)).Return(&entity.RefreshToken{Id: "audit-refresh", UserId: "audit-user", FamilyId: "audit-family"}, nil)
+			refreshTokenRepository.Mock.On("MarkAsUsed", "audit-refresh").Return(true, nil)
+
+			_, err = authUsecase.RefreshToken(refreshToken, client)
+			require.Nil(t, err)
+
+			entry := last()
+			require.Equal(t, entity.AuditActionRefresh, entry.Action)
+			require.Equal(t, entity.AuditOutcomeSuccess, entry.Outcome)
+			require.Equal(t, "audit-user", *entry.ActorId)
+		})
+
+		t.Run("Should record an invalid refresh token", func(t *testing.T) {
+			_, err := authUsecase.RefreshToken("not-a-token", client)
+			require.NotNil(t, err)
+
+			entry := last()
+			require.Equal(t, entity.AuditActionRefresh, entry.Action)
+			require.Equal(t, entity.AuditOutcomeFailure, entry.Outcome)
+			require.Nil(t, entry.ActorId)
+		})
+
+		t.Run("Should record a refresh token reuse", func(t *testing.T) {
+			refreshToken, err := authUsecase.GenerateRefreshToken("audit-user", "audit-reused-family")
+			require.Nil(t, err)
+			usedAt := time.Now()
+			refreshTokenRepository.Mock.On("FindOneByHash", helper.HashToken(refreshToken)).Return(&entity.RefreshToken{Id: "audit-reused", UserId: "audit-user", FamilyId: "audit-reused-family", UsedAt: &usedAt}, nil)
+			refreshTokenRepository.Mock.On("RevokeFamily", "audit-reused-family").Return(nil)
+
+			_, err = authUsecase.RefreshToken(refreshToken, client)
+			require.NotNil(t, err)
+
+			auditLogRepository.Mock.AssertCalled(t, "Save", mock.MatchedBy(func(entry *entity.AuditLog) bool {
+				return entry.Action == entity.AuditActionRefreshReuse &&
+					*entry.ActorId == "audit-user" &&
+					entry.Details == "session audit-reused-family"
+			}))
+		})
+
+		t.Run("Should record a sign out", func(t *testing.T) {
+			refreshTokenRepository.Mock.On("FindOneByHash", helper.HashToken("audit-signout")).Return(&entity.RefreshToken{Id: "audit-signout", UserId: "audit-user", FamilyId: "audit-signout-family"}, nil)
+			refreshTokenRepository.Mock.On("RevokeFamily", "audit-signout-family").Return(nil)
+
+			err := authUsecase.SignOut("audit-signout", client)
+			require.Nil(t, err)
+
+			entry := last()
+			require.Equal(t, entity.AuditActionSignOut, entry.Action)
+			require.Equal(t, entity.AuditOutcomeSuccess, entry.Outcome)
+			require.Equal(t, "audit-user", *entry.ActorId)
+		})
+	})
+
+	t.Run("Should record products the user isn't allowed to touch", func(t *testing.T) {
+		productMiddleware := middleware.NewProductMiddleware(productRepository, organizationMemberRepositoryMock, auditLogUsecase, log)
+		productRepository.Mock.On("FindOneById", mock.Anything, "audit-product").Return(nil).Run(func(args mock.Arguments) {
+			*args.Get(0).(*entity.Product) = entity.Product{Id: "audit-product", UserId: "audit-owner"}
+		})
+
+		app := fiber.New()
+		app.Delete("/products/:id", authMiddleware.Auth, productMiddleware.ProductAuth, func(ctx *fiber.Ctx) error {
+			return ctx.SendStatus(fiber.StatusOK)
+		})
+
+		token, err := authUsecase.GenerateAccessToken(&models.AccessTokenClaims{Subject: "audit-user"})
+		require.Nil(t, err)
+		request := httptest.NewRequest(fiber.MethodDelete, "/products/audit-product", nil)
+		request.Header.Set("Authorization", "Bearer "+token)
+		response, err := app.Test(request)
+		require.Nil(t, err)
+		require.Equal(t, 403, response.StatusCode)
+
+		entry := last()
+		require.Equal(t, entity.AuditActionAccessDenied, entry.Action)
+		require.Equal(t, entity.AuditOutcomeFailure, entry.Outcome)
+		require.Equal(t, "audit-user", *entry.ActorId)
+		require.Equal(t, "audit-product", *entry.TargetId)
+		require.Equal(t, "DELETE /products/audit-product", entry.Details)
+	})
+
+	t.Run("Query", func(t *testing.T) {
+		var filter *models.AuditLogFilter
+		auditLogRepository.Mock.On("FindMany", mock.Anything, mock.Anything, 0, 1).Return(nil).Run(func(args mock.Arguments) {
+			filter = args.Get(1).(*models.AuditLogFilter)
+			actorID := "audit-user"
+			*args.Get(0).(*[]entity.AuditLog) = []entity.AuditLog{{Id: "audit-entry", ActorId: &actorID, Action: entity.AuditActionSignIn, Outcome: entity.AuditOutcomeFailure, Details: "wrong password"}}
+		})
+		auditLogRepository.Mock.On("Count", mock.Anything).Return(int64(3), nil)
+
+		app := fiber.New()
+		routes.NewAuditLogRoute(app, controllers.NewAuditLogController(log, auditLogUsecase), authMiddleware).Setup()
+		get := func(path string, permissions []string) (int, *models.Response[[]models.AuditLogResponse]) {
+			token, err := authUsecase.GenerateAccessToken(&models.AccessTokenClaims{Subject: "audit-admin", SessionId: "audit-admin-session", Permissions: permissions})
+			require.Nil(t, err)
+			request := httptest.NewRequest(fiber.MethodGet, path, nil)
+			request.Header.Set("Authorization", "Bearer "+token)
+			response, err := app.Test(request)
+			require.Nil(t, err)
+			body := new(models.Response[[]models.AuditLogResponse])
+			_ = json.NewDecoder(response.Body).Decode(body)
+			return response.StatusCode, body
+		}
+		canRead := []string{models.PermissionAuditLogReadAny}
+
+		t.Run("Should filter and keep the filter in the links", func(t *testing.T) {
+			status, body := get("/admin/audit-logs?limit=1&actor_id=audit-user&outcome=failure&from=2024-05-01T00:00:00Z", canRead)
+			require.Equal(t, 200, status)
+			require.Len(t, body.Data, 1)
+			require.Equal(t, "wrong password", body.Data[0].Details)
+
+			from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+			require.Equal(t, &models.AuditLogFilter{ActorId: "audit-user", Outcome: entity.AuditOutcomeFailure, From: &from}, filter)
+			require.Equal(t, "http://localhost:8080/admin/audit-logs?page=2&limit=1&actor_id=audit-user&from=2024-05-01T00%3A00%3A00Z&outcome=failure", body.Metadata.Next)
+
+			entry := last()
+			require.Equal(t, entity.AuditActionAuditLogList, entry.Action)
+			require.Equal(t, "audit-admin", *entry.ActorId)
+		})
+
+		t.Run("Should refuse an unknown outcome or time", func(t *testing.T) {
+			status, _ := get("/admin/audit-logs?outcome=maybe", canRead)
+			require.Equal(t, 400, status)
+			status, _ = get("/admin/audit-logs?to=yesterday", canRead)
+			require.Equal(t, 400, status)
+		})
+
+		t.Run("Should need the permission and record the refusal", func(t *testing.T) {
+			status, _ := get("/admin/audit-logs", nil)
+			require.Equal(t, 403, status)
+
+			entry := last()
+			require.Equal(t, entity.AuditActionAccessDenied, entry.Action)
+			require.Equal(t, "audit-admin", *entry.ActorId)
+			require.Equal(t, "GET /admin/audit-logs needs audit_log:read:any", entry.Details)
+		})
+	})
+}
@@ -2,8 +2,10 @@ package test
 
 import (
 	"fmt"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"go-crud/internal/entity"
+	"go-crud/internal/helper"
 	"go-crud/internal/models"
 	"go-crud/internal/usecase"
 	"sync"
@@ -12,7 +14,13 @@ import (
 )
 
 func TestAuth(t *testing.T) {
-	authUsecase := usecase.NewAuthUsecase(userRepositoryMock, validate, viperConfig, log)
+	authUsecase := usecase.NewAuthUsecase(userRepositoryMock, refreshTokenRepositoryMock, sessionRepositoryMock, revokedAccessTokenRepositoryMock, loginAttemptUsecase, auditLogUsecase, passwordHasher, keySet, validate, viperConfig, log)
+	refreshTokenRepositoryMock.Mock.On("Save", mock.Anything).Return(nil)
+	sessionRepositoryMock.Mock.On("Save", mock.Anything).Return(nil)
+	sessionRepositoryMock.Mock.On("Touch", mock.Anything, mock.Anything).Return(nil)
+	userRepositoryMock.Mock.On("FindRolesByUserId", mock.Anything).Return([]entity.Role{}, nil)
+	// The bcrypt fixtures are upgraded to argon2id on sign in.
+	userRepositoryMock.Mock.On("UpdatePassword", mock.Anything, mock.Anything).Return(nil)
 	t.Run("Validate request", func(t *testing.T) {
 		req := &models.SignInRequest{
 			Email:    "",
@@ -25,13 +33,13 @@ func TestAuth(t *testing.T) {
 	})
 
 	t.Run("Generate access token", func(t *testing.T) {
-		token, err := authUsecase.GenerateAccessToken("my-id")
+		token, err := authUsecase.GenerateAccessToken(&models.AccessTokenClaims{Subject: "my-id"})
 		require.Nil(t, err)
 		require.NotNil(t, token)
 	})
 
 	t.Run("Generate refresh token", func(t *testing.T) {
-		token, err := authUsecase.GenerateRefreshToken("my-id")
+		token, err := authUsecase.GenerateRefreshToken("my-id", "family-id")
 		require.Nil(t, err)
 		require.NotNil(t, token)
 	})
@@ -42,14 +50,14 @@ func TestAuth(t *testing.T) {
 
 		go func() {
 			defer wg.Done()
-			accessToken, err := authUsecase.GenerateAccessToken("my-id")
+			accessToken, err := authUsecase.GenerateAccessToken(&models.AccessTokenClaims{Subject: "my-id"})
 			require.Nil(t, err)
 			require.NotNil(t, accessToken)
 		}()
 
 		go func() {
 			defer wg.Done()
-			refreshToken, err := authUsecase.GenerateRefreshToken("my-id")
+			refreshToken, err := authUsecase.GenerateRefreshToken("my-id", "family-id")
 			require.Nil(t, err)
 			require.NotNil(t, refreshToken)
 		}()
@@ -96,14 +104,22 @@ func TestAuth(t *testing.T) {
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		}
+		user.EmailVerifiedAt = &user.CreatedAt
 		request := &models.SignInRequest{
 			Email:    "danar@gmail.com",
 			Password: "12345678",
+			Client: models.ClientInfo{
+				UserAgent: "Mozilla/5.0",
+				IpAddress: "127.0.0.1",
+			},
 		}
 		userRepositoryMock.Mock.On("FindOneByEmail", request.Email).Return(user, nil)
 		result, err := authUsecase.SignIn(request)
 		require.Nil(t, err)
 		require.NotNil(t, result)
+		sessionRepositoryMock.Mock.AssertCalled(t, "Save", mock.MatchedBy(func(session *entity.Session) bool {
+			return session.UserId == "my-id" && session.UserAgent == "Mozilla/5.0" && session.IpAddress == "127.0.0.1"
+		}))
 	})
 
 	t.Run("Signin with invalid email", func(t *testing.T) {
@@ -131,6 +147,7 @@ func TestAuth(t *testing.T) {
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		}
+		user.EmailVerifiedAt = &user.CreatedAt
 		request := &models.SignInRequest{
 			Email:    "danar@gmail.com",
 			Password: "wrongpassword",
@@ -146,24 +163,92 @@ func TestAuth(t *testing.T) {
 
 	})
 
+	t.Run("Should refuse sign in with unverified email", func(t *testing.T) {
+		user := &entity.User{
+			Id:       "unverified-id",
+			Name:     "Danar Cahyadi",
+			Email:    "unverified@gmail.com",
+			Password: "$2a$10$aOySpFRuA2uE8gGNNCuAleiBvNRyMJpZuyhZ21kf/Tpy5c8KHNRTe",
+		}
+		request := &models.SignInRequest{
+			Email:    "unverified@gmail.com",
+			Password: "12345678",
+		}
+		userRepositoryMock.Mock.On("FindOneByEmail", request.Email).Return(user, nil)
+		result, err := authUsecase.SignIn(request)
+		require.Nil(t, result)
+		require.Equal(t, &models.ErrorResponse{
+			Code:    403,
+			Message: "Email is not verified",
+			Status:  "Forbidden",
+		}, err)
+	})
+
 	t.Run("Verify refresh token", func(t *testing.T) {
-		refreshToken, err := authUsecase.GenerateRefreshToken("user-id")
+		refreshToken, err := authUsecase.GenerateRefreshToken("user-id", "family-id")
 		require.Nil(t, err)
 		require.NotNil(t, refreshToken)
 
-		sub, err := authUsecase.VerifyRefreshToken(refreshToken)
+		stored := &entity.RefreshToken{Id: "token-id", UserId: "user-id", FamilyId: "family-id"}
+		refreshTokenRepositoryMock.Mock.On("FindOneByHash", helper.HashToken(refreshToken)).Return(stored, nil)
+		result, err := authUsecase.VerifyRefreshToken(refreshToken)
 		require.Nil(t, err)
-		require.NotNil(t, sub)
+		require.Equal(t, "user-id", result.UserId)
 	})
 
-	t.Run("Verify refresh token and generate new access token", func(t *testing.T) {
-		refreshToken, err := authUsecase.GenerateRefreshToken("user-id")
+	t.Run("Verify refresh token and rotate it", func(t *testing.T) {
+		refreshToken, err := authUsecase.GenerateRefreshToken("user-id", "rotate-family")
 		require.Nil(t, err)
 		require.NotNil(t, refreshToken)
 
-		result, err := authUsecase.RefreshToken(refreshToken)
+		stored := &entity.RefreshToken{Id: "rotate-id", UserId: "user-id", FamilyId: "rotate-family"}
+		refreshTokenRepositoryMock.Mock.On("FindOneByHash", helper.HashToken(refreshToken)).Return(stored, nil)
+		refreshTokenRepositoryMock.Mock.On("MarkAsUsed", "rotate-id").Return(true, nil)
+		sessionRepositoryMock.Mock.On("FindOneById", "rotate-family").Return(&entity.Session{Id: "rotate-family", UserId: "user-id", Methods: "pwd"}, nil)
+		result, err := authUsecase.RefreshToken(refreshToken, models.ClientInfo{})
 		require.Nil(t, err)
-		require.NotNil(t, result)
+		require.NotEmpty(t, result.AccessToken)
+		require.NotEmpty(t, result.RefreshToken)
+		require.NotEqual(t, refreshToken, result.RefreshToken)
+	})
+
+	t.Run("Should revoke the family when a used refresh token is presented again", func(t *testing.T) {
+		refreshToken, err := authUsecase.GenerateRefreshToken("user-id", "reused-family")
+		require.Nil(t, err)
+
+		usedAt := time.Now()
+		stored := &entity.RefreshToken{Id: "reused-id", UserId: "user-id", FamilyId: "reused-family", UsedAt: &usedAt}
+		refreshTokenRepositoryMock.Mock.On("FindOneByHash", helper.HashToken(refreshToken)).Return(stored, nil)
+		refreshTokenRepositoryMock.Mock.On("RevokeFamily", "reused-family").Return(nil)
+		result, err := authUsecase.RefreshToken(refreshToken, models.ClientInfo{})
+		require.Nil(t, result)
+		require.Equal(t, &models.ErrorResponse{
+			Code:    401,
+			Message: "Token has been revoked",
+			Status:  "Unauthorized",
+		}, err)
+		refreshTokenRepositoryMock.Mock.AssertCalled(t, "RevokeFamily", "reused-family")
+	})
+
+	t.Run("Should reject a revoked refresh token", func(t *testing.T) {
+		refreshToken, err := authUsecase.GenerateRefreshToken("user-id", "revoked-family")
+		require.Nil(t, err)
+
+		revokedAt := time.Now()
+		stored := &entity.RefreshToken{Id: "revoked-id", UserId: "user-id", FamilyId: "revoked-family", RevokedAt: &revokedAt}
+		refreshTokenRepositoryMock.Mock.On("FindOneByHash", helper.HashToken(refreshToken)).Return(stored, nil)
+		result, err := authUsecase.RefreshToken(refreshToken, models.ClientInfo{})
+		require.Nil(t, result)
+		require.NotNil(t, err)
+	})
+
+	t.Run("Sign out revokes the refresh token family", func(t *testing.T) {
+		stored := &entity.RefreshToken{Id: "signout-id", UserId: "user-id", FamilyId: "signout-family"}
+		refreshTokenRepositoryMock.Mock.On("FindOneByHash", helper.HashToken("signout-token")).Return(stored, nil)
+		refreshTokenRepositoryMock.Mock.On("RevokeFamily", "signout-family").Return(nil)
+		err := authUsecase.SignOut("signout-token", models.ClientInfo{})
+		require.Nil(t, err)
+		refreshTokenRepositoryMock.Mock.AssertCalled(t, "RevokeFamily", "signout-family")
 	})
 
 	t.Run("Parsing token from authorization header", func(t *testing.T) {
@@ -184,13 +269,40 @@ func TestAuth(t *testing.T) {
 	})
 
 	t.Run("Verify access token", func(t *testing.T) {
-		accessToken, err := authUsecase.GenerateAccessToken("user-id")
+		accessToken, err := authUsecase.GenerateAccessToken(&models.AccessTokenClaims{Subject: "user-id"})
 		require.Nil(t, err)
 		require.NotNil(t, accessToken)
 
-		sub, err := authUsecase.VerifyAccessToken(accessToken)
+		claims, err := authUsecase.VerifyAccessToken(accessToken)
+		require.Nil(t, err)
+		require.Equal(t, "user-id", claims.Subject)
+	})
+
+	t.Run("Verify access token bound to an active session", func(t *testing.T) {
+		session := &entity.Session{Id: "active-session", UserId: "user-id", LastSeenAt: time.Now()}
+		sessionRepositoryMock.Mock.On("FindOneById", "active-session").Return(session, nil)
+		accessToken, err := authUsecase.GenerateAccessToken(&models.AccessTokenClaims{Subject: "user-id", SessionId: "active-session"})
+		require.Nil(t, err)
+
+		claims, err := authUsecase.VerifyAccessToken(accessToken)
+		require.Nil(t, err)
+		require.Equal(t, "active-session", claims.SessionId)
+	})
+
+	t.Run("Should reject access token whose session has been revoked", func(t *testing.T) {
+		revokedAt := time.Now()
+		session := &entity.Session{Id: "revoked-session", UserId: "user-id", RevokedAt: &revokedAt}
+		sessionRepositoryMock.Mock.On("FindOneById", "revoked-session").Return(session, nil)
+		accessToken, err := authUsecase.GenerateAccessToken(&models.AccessTokenClaims{Subject: "user-id", SessionId: "revoked-session"})
 		require.Nil(t, err)
-		require.NotNil(t, sub)
+
+		claims, err := authUsecase.VerifyAccessToken(accessToken)
+		require.Nil(t, claims)
+		require.Equal(t, &models.ErrorResponse{
+			Code:    401,
+			Message: "Session has been revoked",
+			Status:  "Unauthorized",
+		}, err)
 	})
 
 }
@@ -0,0 +1,255 @@
+package test
+
+import (
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go-crud/internal/entity"
+	"go-crud/internal/models"
+	"go-crud/internal/repository"
+	"go-crud/internal/usecase"
+	"go-crud/test/mocks"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func TestCatalog(t *testing.T) {
+	parentID := "category-shoes"
+	shoes := entity.Category{Id: "category-shoes", Name: "Shoes", Slug: "shoes", Path: "shoes"}
+	running := entity.Category{Id: "category-running", ParentId: &parentID, Name: "Running", Slug: "running", Path: "shoes/running"}
+	trail := entity.Category{Id: "category-trail", ParentId: &running.Id, Name: "Trail", Slug: "trail", Path: "shoes/running/trail"}
+	bags := entity.Category{Id: "category-bags", Name: "Bags", Slug: "bags", Path: "bags"}
+
+	t.Run("Categories", func(t *testing.T) {
+		t.Run("Should create a category under its parent", func(t *testing.T) {
+			categoryRepository := mocks.NewCategoryRepositoryMock()
+			categoryUsecase := usecase.NewCategoryUsecase(categoryRepository, validate, log)
+			categoryRepository.Mock.On("FindOneBySlug", "running-shoes").Return(nil, gorm.ErrRecordNotFound)
+			categoryRepository.Mock.On("FindOneById", shoes.Id).Return(&shoes, nil)
+			var saved *entity.Category
+			categoryRepository.Mock.On("Save", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+				saved = args.Get(0).(*entity.Category)
+			})
+
+			result, err := categoryUsecase.CreateCategory(&models.CategoryRequest{Name: "Running Shoes!", ParentId: shoes.Id})
+			require.Nil(t, err)
+			require.Equal(t, "running-shoes", result.Slug)
+			require.Equal(t, "shoes/running-shoes", result.Path)
+			require.Equal(t, shoes.Id, result.ParentId)
+			require.Equal(t, shoes.Id, *saved.ParentId)
+		})
+
+		t.Run("Should refuse a taken slug or a missing parent", func(t *testing.T) {
+			categoryRepository := mocks.NewCategoryRepositoryMock()
+			categoryUsecase := usecase.NewCategoryUsecase(categoryRepository, validate, log)
+			categoryRepository.Mock.On("FindOneBySlug", "shoes").Return(&shoes, nil)
+			categoryRepository.Mock.On("FindOneBySlug", "socks").Return(nil, gorm.ErrRecordNotFound)
+			categoryRepository.Mock.On("FindOneById", "missing").Return(nil, gorm.ErrRecordNotFound)
+
+			_, err := categoryUsecase.CreateCategory(&models.CategoryRequest{Name: "Shoes"})
+			require.Equal(t, &models.ErrorResponse{Code: 409, Message: "Slug is already taken", Status: "Conflict"}, err)
+			_, err = categoryUsecase.CreateCategory(&models.CategoryRequest{Name: "Socks", ParentId: "missing"})
+			require.Equal(t, &models.ErrorResponse{Code: 400, Message: "Parent category not found", Status: "Bad Request"}, err)
+			_, err = categoryUsecase.CreateCategory(&models.CategoryRequest{Name: "!!!"})
+			require.Equal(t, &models.ErrorResponse{Code: 400, Message: "Slug needs a letter or a digit", Status: "Bad Request"}, err)
+		})
+
+		t.Run("Should nest the tree", func(t *testing.T) {
+			categoryRepository := mocks.NewCategoryRepositoryMock()
+			categoryUsecase := usecase.NewCategoryUsecase(categoryRepository, validate, log)
+			categoryRepository.Mock.On("FindAll").Return([]entity.Category{bags, shoes, running, trail}, nil)
+
+			result, err := categoryUsecase.GetCategoryTree()
+			require.Nil(t, err)
+			require.Len(t, *result, 2)
+			require.Equal(t, "bags", (*result)[0].Slug)
+			require.Empty(t, (*result)[0].Children)
+			require.Equal(t, "running", (*result)[1].Children[0].Slug)
+			require.Equal(t, "trail", (*result)[1].Children[0].Children[0].Slug)
+		})
+
+		t.Run("Should return a category with its subtree and ancestors", func(t *testing.T) {
+			categoryRepository := mocks.NewCategoryRepositoryMock()
+			categoryUsecase := usecase.NewCategoryUsecase(categoryRepository, validate, log)
+			categoryRepository.Mock.On("FindOneBySlug", "running").Return(&running, nil)
+			categoryRepository.Mock.On("FindSubtree", "shoes/running").Return([]entity.Category{running, trail}, nil)
+			categoryRepository.Mock.On("FindManyByPaths", []string{"shoes"}).Return([]entity.Category{shoes}, nil)
+
+			result, err := categoryUsecase.GetCategory("running")
+			require.Nil(t, err)
+			require.Equal(t, running.Id, result.Id)
+			require.Equal(t, "trail", result.Children[0].Slug)
+			require.Equal(t, "shoes", result.Ancestors[0].Slug)
+		})
+
+		t.Run("Should move a category with its subcategories", func(t *testing.T) {
+			categoryRepository := mocks.NewCategoryRepositoryMock()
+			categoryUsecase := usecase.NewCategoryUsecase(categoryRepository, validate, log)
+			moved := running
+			categoryRepository.Mock.On("FindOneById", running.Id).Return(&moved, nil)
+			categoryRepository.Mock.On("FindOneById", bags.Id).Return(&bags, nil)
+			categoryRepository.Mock.On("FindOneBySlug", "running").Return(&running, nil)
+			categoryRepository.Mock.On("Update", mock.Anything, "shoes/running").Return(nil)
+
+			result, err := categoryUsecase.UpdateCategory(running.Id, &models.CategoryRequest{Name: "Running", ParentId: bags.Id})
+			require.Nil(t, err)
+			require.Equal(t, "bags/running", result.Path)
+			categoryRepository.Mock.AssertCalled(t, "Update", mock.Anything, "shoes/running")
+		})
+
+		t.Run("Should refuse to move a category under itself", func(t *testing.T) {
+			categoryRepository := mocks.NewCategoryRepositoryMock()
+			categoryUsecase := usecase.NewCategoryUsecase(categoryRepository, validate, log)
+			moved := shoes
+			categoryRepository.Mock.On("FindOneById", shoes.Id).Return(&moved, nil)
+			categoryRepository.Mock.On("FindOneById", trail.Id).Return(&trail, nil)
+			categoryRepository.Mock.On("FindOneBySlug", "shoes").Return(&shoes, nil)
+
+			_, err := categoryUsecase.UpdateCategory(shoes.Id, &models.CategoryRequest{Name: "Shoes", ParentId: trail.Id})
+			require.Equal(t, &models.ErrorResponse{Code: 400, Message: "A category can't be moved under itself", Status: "Bad Request"}, err)
+			categoryRepository.Mock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+		})
+
+		t.Run("Should only delete categories without subcategories", func(t *testing.T) {
+			categoryRepository := mocks.NewCategoryRepositoryMock()
+			categoryUsecase := usecase.NewCategoryUsecase(categoryRepository, validate, log)
+			categoryRepository.Mock.On("FindOneById", shoes.Id).Return(&shoes, nil)
+			categoryRepository.Mock.On("FindOneById", trail.Id).Return(&trail, nil)
+			categoryRepository.Mock.On("FindOneById", "missing").Return(nil, gorm.ErrRecordNotFound)
+			categoryRepository.Mock.On("CountChildren", shoes.Id).Return(int64(1), nil)
+			categoryRepository.Mock.On("CountChildren", trail.Id).Return(int64(0), nil)
+			categoryRepository.Mock.On("DeleteById", trail.Id).Return(nil)
+
+			err := categoryUsecase.DeleteCategory(shoes.Id)
+			require.Equal(t, &models.ErrorResponse{Code: 409, Message: "Move or delete the subcategories first", Status: "Conflict"}, err)
+			require.Nil(t, categoryUsecase.DeleteCategory(trail.Id))
+			err = categoryUsecase.DeleteCategory("missing")
+			require.Equal(t, &models.ErrorResponse{Code: 404, Message: "Category not found", Status: "Not Found"}, err)
+		})
+	})
+
+	sale := entity.Tag{Id: "tag-sale", Name: "Sale", Slug: "sale"}
+	newTag := entity.Tag{Id: "tag-new", Name: "New", Slug: "new"}
+
+	t.Run("Tags", func(t *testing.T) {
+		t.Run("Should create a tag with a unique slug", func(t *testing.T) {
+			tagRepository := mocks.NewTagRepositoryMock()
+			tagUsecase := usecase.NewTagUsecase(tagRepository, validate, log)
+			tagRepository.Mock.On("FindOneBySlug", "sale").Return(&sale, nil)
+			tagRepository.Mock.On("FindOneBySlug", "black-friday").Return(nil, gorm.ErrRecordNotFound)
+			tagRepository.Mock.On("Save", mock.Anything).Return(nil)
+
+			result, err := tagUsecase.CreateTag(&models.TagRequest{Name: "Black Friday"})
+			require.Nil(t, err)
+			require.Equal(t, "black-friday", result.Slug)
+			_, err = tagUsecase.CreateTag(&models.TagRequest{Name: "On sale", Slug: "Sale"})
+			require.Equal(t, &models.ErrorResponse{Code: 409, Message: "Slug is already taken", Status: "Conflict"}, err)
+		})
+
+		t.Run("Should keep the slug of a renamed tag", func(t *testing.T) {
+			tagRepository := mocks.NewTagRepositoryMock()
+			tagUsecase := usecase.NewTagUsecase(tagRepository, validate, log)
+			renamed := sale
+			tagRepository.Mock.On("FindOneById", sale.Id).Return(&renamed, nil)
+			tagRepository.Mock.On("FindOneBySlug", "sale").Return(&sale, nil)
+			tagRepository.Mock.On("UpdateById", mock.Anything).Return(nil)
+
+			result, err := tagUsecase.UpdateTag(sale.Id, &models.TagRequest{Name: "SALE", Slug: "sale"})
+			require.Nil(t, err)
+			require.Equal(t, "SALE", result.Name)
+		})
+
+		t.Run("Should not delete a missing tag", func(t *testing.T) {
+			tagRepository := mocks.NewTagRepositoryMock()
+			tagUsecase := usecase.NewTagUsecase(tagRepository, validate, log)
+			tagRepository.Mock.On("FindOneById", "missing").Return(nil, gorm.ErrRecordNotFound)
+
+			err := tagUsecase.DeleteTag("missing")
+			require.Equal(t, &models.ErrorResponse{Code: 404, Message: "Tag not found", Status: "Not Found"}, err)
+		})
+	})
+
+	t.Run("Products", func(t *testing.T) {
+		productRepository := mocks.NewProductRepositoryMock()
+		categoryRepository := mocks.NewCategoryRepositoryMock()
+		tagRepository := mocks.NewTagRepositoryMock()
+		catalogUsecase := usecase.NewProductUsecase(productRepository, repository.NewMemoryProductSearchRepository(), organizationMemberRepositoryMock, categoryRepository, tagRepository, productImageRepositoryMock, fileStorage, validate, viperConfig, log)
+		categoryRepository.Mock.On("FindOneById", running.Id).Return(&running, nil)
+		categoryRepository.Mock.On("FindOneById", "missing").Return(nil, gorm.ErrRecordNotFound)
+		tagRepository.Mock.On("FindManyByIds", []string{sale.Id, newTag.Id}).Return([]entity.Tag{newTag, sale}, nil)
+		tagRepository.Mock.On("FindManyByIds", []string{sale.Id, "missing"}).Return([]entity.Tag{sale}, nil)
+
+		t.Run("Should create a product in a category with tags", func(t *testing.T) {
+			var saved *entity.Product
+			productRepository.Mock.On("Save", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+				saved = args.Get(0).(*entity.Product)
+			}).Once()
+
+			result, err := catalogUsecase.CreateProduct(&models.ProductRequest{Name: "Trail runner", Price: 100, CategoryId: &running.Id, TagIds: []string{sale.Id, newTag.Id, sale.Id}}, "catalog-user")
+			require.Nil(t, err)
+			require.Equal(t, running.Id, *saved.CategoryId)
+			require.Len(t, saved.Tags, 2)
+			require.Equal(t, "running", result.Category.Slug)
+			require.Equal(t, []string{"new", "sale"}, []string{result.Tags[0].Slug, result.Tags[1].Slug})
+		})
+
+		t.Run("Should refuse missing categories and tags", func(t *testing.T) {
+			missing := "missing"
+			_, err := catalogUsecase.CreateProduct(&models.ProductRequest{Name: "Trail runner", CategoryId: &missing}, "catalog-user")
+			require.Equal(t, &models.ErrorResponse{Code: 400, Message: "Category not found", Status: "Bad Request"}, err)
+			_, err = catalogUsecase.CreateProduct(&models.ProductRequest{Name: "Trail runner", TagIds: []string{sale.Id, missing}}, "catalog-user")
+			require.Equal(t, &models.ErrorResponse{Code: 400, Message: "Tag not found", Status: "Bad Request"}, err)
+		})
+
+		t.Run("Should only change the category and tags given on update", func(t *testing.T) {
+			updated := entity.Product{Id: "catalog-product", Name: "Trail runner", CategoryId: &running.Id, Category: &running, Tags: []entity.Tag{sale}}
+			productRepository.Mock.On("UpdateById", mock.Anything, updated.Id).Return(&updated, nil).Once()
+
+			result, err := catalogUsecase.UpdateProduct(&models.ProductRequest{Name: "Trail runner"}, updated.Id)
+			require.Nil(t, err)
+			require.Equal(t, "running", result.Category.Slug)
+			require.Len(t, result.Tags, 1)
+			productRepository.Mock.AssertNotCalled(t, "UpdateCategoryById", mock.Anything, mock.Anything)
+			productRepository.Mock.AssertNotCalled(t, "ReplaceTags", mock.Anything, mock.Anything)
+
+			cleared := entity.Product{Id: "catalog-product", Name: "Trail runner", CategoryId: &running.Id, Category: &running, Tags: []entity.Tag{sale}}
+			productRepository.Mock.On("UpdateById", mock.Anything, cleared.Id).Return(&cleared, nil).Once()
+			productRepository.Mock.On("UpdateCategoryById", cleared.Id, (*string)(nil)).Return(nil).Once()
+			productRepository.Mock.On("ReplaceTags", cleared.Id, []entity.Tag{}).Return(nil).Once()
+
+			empty := ""
+			result, err = catalogUsecase.UpdateProduct(&models.ProductRequest{Name: "Trail runner", CategoryId: &empty, TagIds: []string{}}, cleared.Id)
+			require.Nil(t, err)
+			require.Nil(t, result.Category)
+			require.Empty(t, result.Tags)
+			productRepository.Mock.AssertExpectations(t)
+		})
+
+		t.Run("Should filter by category and tag", func(t *testing.T) {
+			filter, err := catalogUsecase.ParseFilter(map[string]string{"category": "shoes", "include_descendants": "true", "tag": "sale"})
+			require.Nil(t, err)
+			require.Equal(t, []models.ProductCondition{
+				{Field: "category", Operator: "descendants", Value: "shoes"},
+				{Field: "tag", Operator: "eq", Value: "sale"},
+			}, filter.Conditions)
+
+			filter, err = catalogUsecase.ParseFilter(map[string]string{"category": "shoes", "include_descendants": "false"})
+			require.Nil(t, err)
+			require.Equal(t, "eq", filter.Conditions[0].Operator)
+
+			_, err = catalogUsecase.ParseFilter(map[string]string{"include_descendants": "true"})
+			require.Equal(t, &models.ErrorResponse{Code: 400, Message: "include_descendants needs a category", Status: "Bad Request"}, err)
+			_, err = catalogUsecase.ParseFilter(map[string]string{"category": "shoes", "include_descendants": "maybe"})
+			require.Equal(t, &models.ErrorResponse{Code: 400, Message: "include_descendants must be true or false", Status: "Bad Request"}, err)
+		})
+
+		t.Run("Should keep the category filter on the pagination links", func(t *testing.T) {
+			filter, err := catalogUsecase.ParseFilter(map[string]string{"category": "shoes", "include_descendants": "true"})
+			require.Nil(t, err)
+			productRepository.Mock.On("Count", filter).Return(int64(30), nil).Once()
+
+			metadata, err := catalogUsecase.GetMetadataPagination(filter, 1, 10)
+			require.Nil(t, err)
+			require.Equal(t, "http://localhost:8080/products?page=2&limit=10&category=shoes&include_descendants=true", metadata.Next)
+		})
+	})
+}
@@ -0,0 +1,146 @@
+package test
+
+import (
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go-crud/internal/config"
+	"go-crud/internal/entity"
+	"go-crud/internal/helper"
+	"go-crud/internal/mail"
+	"go-crud/internal/models"
+	"go-crud/internal/usecase"
+	"gorm.io/gorm"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestEmailVerification(t *testing.T) {
+	mailSender := mail.NewFileSender(t.TempDir())
+	emailVerificationUsecase := usecase.NewEmailVerificationUsecase(userRepositoryMock, userTokenRepositoryMock, mailSender, validate, viperConfig, log)
+
+	t.Run("Send verification mails a token and stores its hash", func(t *testing.T) {
+		user := &entity.User{Id: "verify-user", Name: "Danar", Email: "verify@gmail.com"}
+		userTokenRepositoryMock.Mock.On("InvalidateByUserId", "verify-user", entity.UserTokenEmailVerification).Return(nil)
+		userTokenRepositoryMock.Mock.On("Save", mock.MatchedBy(func(token *entity.UserToken) bool {
+			return token.Purpose == entity.UserTokenEmailVerification
+		})).Return(nil)
+
+		err := emailVerificationUsecase.SendVerification(user)
+		require.Nil(t, err)
+
+		message, err := mailSender.Last("verify@gmail.com")
+		require.Nil(t, err)
+		token := regexp.MustCompile(`Verification token: (\S+)`).FindStringSubmatch(message)[1]
+		userTokenRepositoryMock.Mock.AssertCalled(t, "Save", mock.MatchedBy(func(saved *entity.UserToken) bool {
+			return saved.UserId == "verify-user" && saved.TokenHash == helper.HashToken(token)
+		}))
+	})
+
+	t.Run("Verify email", func(t *testing.T) {
+		t.Run("Should mark the email as verified", func(t *testing.T) {
+			token := &entity.UserToken{Id: "verify-token-id", UserId: "verify-user", ExpiresAt: time.Now().Add(time.Hour)}
+			userTokenRepositoryMock.Mock.On("FindOneByHash", helper.HashToken("verify-token"), entity.UserTokenEmailVerification).Return(token, nil)
+			userTokenRepositoryMock.Mock.On("MarkAsUsed", "verify-token-id").Return(true, nil)
+			userRepositoryMock.Mock.On("MarkEmailVerified", "verify-user", mock.Anything).Return(nil)
+
+			err := emailVerificationUsecase.VerifyEmail(&models.VerifyEmailRequest{Token: "verify-token"})
+			require.Nil(t, err)
+			userRepositoryMock.Mock.AssertCalled(t, "MarkEmailVerified", "verify-user", mock.Anything)
+		})
+
+		t.Run("Should reject an unknown token", func(t *testing.T) {
+			userTokenRepositoryMock.Mock.On("FindOneByHash", helper.HashToken("unknown-verify-token"), entity.UserTokenEmailVerification).Return(nil, gorm.ErrRecordNotFound)
+			err := emailVerificationUsecase.VerifyEmail(&models.VerifyEmailRequest{Token: "unknown-verify-token"})
+			require.Equal(t, &models.ErrorResponse{Code: 400, Message: "Verification token is invalid or expired", Status: "Bad Request"}, err)
+		})
+	})
+
+	t.Run("Confirm email change", func(t *testing.T) {
+		t.Run("Should swap in the pending email", func(t *testing.T) {
+			pending := "changed@gmail.com"
+			findUserReturns("change-user", &entity.User{Id: "change-user", Email: "before@gmail.com", PendingEmail: &pending})
+			token := &entity.UserToken{Id: "change-token-id", UserId: "change-user", ExpiresAt: time.Now().Add(time.Hour)}
+			userTokenRepositoryMock.Mock.On("FindOneByHash", helper.HashToken("change-token"), entity.UserTokenEmailChange).Return(token, nil)
+			userTokenRepositoryMock.Mock.On("MarkAsUsed", "change-token-id").Return(true, nil)
+			userRepositoryMock.Mock.On("FindOneByEmail", "changed@gmail.com").Return(nil, nil)
+			userRepositoryMock.Mock.On("ConfirmPendingEmail", "change-user", "changed@gmail.com", mock.Anything).Return(true, nil)
+			userTokenRepositoryMock.Mock.On("InvalidateByUserId", "change-user", entity.UserTokenPasswordReset).Return(nil)
+			userTokenRepositoryMock.Mock.On("InvalidateByUserId", "change-user", entity.UserTokenMagicLink).Return(nil)
+
+			err := emailVerificationUsecase.ConfirmEmailChange(&models.VerifyEmailRequest{Token: "change-token"})
+			require.Nil(t, err)
+			userRepositoryMock.Mock.AssertCalled(t, "ConfirmPendingEmail", "change-user", "changed@gmail.com", mock.Anything)
+			userTokenRepositoryMock.Mock.AssertCalled(t, "InvalidateByUserId", "change-user", entity.UserTokenPasswordReset)
+			userTokenRepositoryMock.Mock.AssertCalled(t, "InvalidateByUserId", "change-user", entity.UserTokenMagicLink)
+		})
+
+		t.Run("Should refuse an email taken in the meantime", func(t *testing.T) {
+			pending := "claimed@gmail.com"
+			findUserReturns("late-user", &entity.User{Id: "late-user", Email: "late@gmail.com", PendingEmail: &pending})
+			token := &entity.UserToken{Id: "late-token-id", UserId: "late-user", ExpiresAt: time.Now().Add(time.Hour)}
+			userTokenRepositoryMock.Mock.On("FindOneByHash", helper.HashToken("late-token"), entity.UserTokenEmailChange).Return(token, nil)
+			userTokenRepositoryMock.Mock.On("MarkAsUsed", "late-token-id").Return(true, nil)
+			userRepositoryMock.Mock.On("FindOneByEmail", "claimed@gmail.com").Return(&entity.User{Id: "claimer", Email: "claimed@gmail.com"}, nil)
+
+			err := emailVerificationUsecase.ConfirmEmailChange(&models.VerifyEmailRequest{Token: "late-token"})
+			require.Equal(t, &models.ErrorResponse{Code: 400, Message: "Email already exists", Status: "Bad Request"}, err)
+			userRepositoryMock.Mock.AssertNotCalled(t, "ConfirmPendingEmail", "late-user", mock.Anything, mock.Anything)
+		})
+
+		t.Run("Should reject a verification token", func(t *testing.T) {
+			userTokenRepositoryMock.Mock.On("FindOneByHash", helper.HashToken("verify-token"), entity.UserTokenEmailChange).Return(nil, gorm.ErrRecordNotFound)
+			err := emailVerificationUsecase.ConfirmEmailChange(&models.VerifyEmailRequest{Token: "verify-token"})
+			require.Equal(t, &models.ErrorResponse{Code: 400, Message: "Confirmation token is invalid or expired", Status: "Bad Request"}, err)
+		})
+	})
+
+	t.Run("Resend verification", func(t *testing.T) {
+		t.Run("Should be throttled", func(t *testing.T) {
+			user := &entity.User{Id: "throttled-user", Email: "throttled@gmail.com"}
+			latest := &entity.UserToken{Id: "latest", UserId: "throttled-user", CreatedAt: time.Now()}
+			userRepositoryMock.Mock.On("FindOneByEmail", "throttled@gmail.com").Return(user, nil)
+			userTokenRepositoryMock.Mock.On("FindLatestByUserId", "throttled-user", entity.UserTokenEmailVerification).Return(latest, nil)
+
+			err := emailVerificationUsecase.ResendVerification(&models.ResendVerificationRequest{Email: "throttled@gmail.com"})
+			require.Nil(t, err)
+			userTokenRepositoryMock.Mock.AssertNotCalled(t, "InvalidateByUserId", "throttled-user", entity.UserTokenEmailVerification)
+		})
+
+		t.Run("Should send again once the interval has passed", func(t *testing.T) {
+			user := &entity.User{Id: "resend-user", Email: "resend@gmail.com"}
+			latest := &entity.UserToken{Id: "old", UserId: "resend-user", CreatedAt: time.Now().Add(-time.Hour)}
+			userRepositoryMock.Mock.On("FindOneByEmail", "resend@gmail.com").Return(user, nil)
+			userTokenRepositoryMock.Mock.On("FindLatestByUserId", "resend-user", entity.UserTokenEmailVerification).Return(latest, nil)
+			userTokenRepositoryMock.Mock.On("InvalidateByUserId", "resend-user", entity.UserTokenEmailVerification).Return(nil)
+
+			err := emailVerificationUsecase.ResendVerification(&models.ResendVerificationRequest{Email: "resend@gmail.com"})
+			require.Nil(t, err)
+			_, err = mailSender.Last("resend@gmail.com")
+			require.Nil(t, err)
+		})
+
+		t.Run("Should ignore already verified accounts", func(t *testing.T) {
+			verifiedAt := time.Now()
+			user := &entity.User{Id: "verified-user", Email: "verified@gmail.com", EmailVerifiedAt: &verifiedAt}
+			userRepositoryMock.Mock.On("FindOneByEmail", "verified@gmail.com").Return(user, nil)
+
+			err := emailVerificationUsecase.ResendVerification(&models.ResendVerificationRequest{Email: "verified@gmail.com"})
+			require.Nil(t, err)
+			_, err = mailSender.Last("verified@gmail.com")
+			require.NotNil(t, err)
+		})
+	})
+
+	t.Run("Unverified sign in policy", func(t *testing.T) {
+		policyViper := viper.New()
+		for _, policy := range []string{"refuse", "allow"} {
+			policyViper.Set("auth.email_verification.unverified_sign_in", policy)
+			require.NotPanics(t, func() { config.ValidateViper(policyViper) })
+		}
+
+		policyViper.Set("auth.email_verification.unverified_sign_in", "refused")
+		require.Panics(t, func() { config.ValidateViper(policyViper) })
+	})
+}
@@ -0,0 +1,141 @@
+package test
+
+import (
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go-crud/internal/entity"
+	"go-crud/internal/hasher"
+	"go-crud/internal/models"
+	"go-crud/internal/usecase"
+	"go-crud/test/mocks"
+	"strings"
+	"testing"
+	"time"
+)
+
+// verified reports whether hash is a hash of password, whatever algorithm
+// made it.
+func verified(hash string, password string) bool {
+	ok, err := passwordHasher.Verify(hash, password)
+	return err == nil && ok
+}
+
+func TestHasher(t *testing.T) {
+	argon2id := &hasher.Argon2id{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	bcrypt := &hasher.Bcrypt{Cost: 4}
+	passwordHasher := hasher.New(argon2id, bcrypt)
+	legacyHash := "$2a$10$aOySpFRuA2uE8gGNNCuAleiBvNRyMJpZuyhZ21kf/Tpy5c8KHNRTe"
+
+	t.Run("Argon2id", func(t *testing.T) {
+		t.Run("Should encode in the PHC string format", func(t *testing.T) {
+			hash, err := passwordHasher.Hash("12345678")
+			require.Nil(t, err)
+			require.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+			require.Len(t, strings.Split(hash, "$"), 6)
+
+			other, err := passwordHasher.Hash("12345678")
+			require.Nil(t, err)
+			require.NotEqual(t, hash, other)
+		})
+
+		t.Run("Should verify the password", func(t *testing.T) {
+			hash, err := passwordHasher.Hash("12345678")
+			require.Nil(t, err)
+
+			ok, err := passwordHasher.Verify(hash, "12345678")
+			require.Nil(t, err)
+			require.True(t, ok)
+
+			ok, err = passwordHasher.Verify(hash, "wrong-password")
+			require.Nil(t, err)
+			require.False(t, ok)
+		})
+
+		t.Run("Should reject a malformed hash", func(t *testing.T) {
+			ok, err := passwordHasher.Verify("$argon2id$v=19$m=1024$salt", "12345678")
+			require.False(t, ok)
+			require.Equal(t, hasher.ErrInvalidArgon2idHash, err)
+
+			for _, parameters := range []string{"m=1024,t=0,p=1", "m=1024,t=1,p=0", "m=7,t=1,p=1", "m=31,t=1,p=4"} {
+				ok, err = passwordHasher.Verify("$argon2id$v=19$"+parameters+"$c2FsdHNhbHRzYWx0c2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U", "12345678")
+				require.False(t, ok, parameters)
+				require.Equal(t, hasher.ErrInvalidArgon2idHash, err, parameters)
+			}
+
+			ok, err = passwordHasher.Verify("plaintext", "plaintext")
+			require.False(t, ok)
+			require.Equal(t, hasher.ErrUnknownHash, err)
+		})
+	})
+
+	t.Run("Bcrypt hashes are still verified", func(t *testing.T) {
+		ok, err := passwordHasher.Verify(legacyHash, "12345678")
+		require.Nil(t, err)
+		require.True(t, ok)
+
+		ok, err = passwordHasher.Verify(legacyHash, "wrong-password")
+		require.Nil(t, err)
+		require.False(t, ok)
+	})
+
+	t.Run("Needs rehash", func(t *testing.T) {
+		hash, err := passwordHasher.Hash("12345678")
+		require.Nil(t, err)
+		require.False(t, passwordHasher.NeedsRehash(hash))
+		require.True(t, passwordHasher.NeedsRehash(legacyHash))
+
+		stronger := hasher.New(&hasher.Argon2id{Memory: 2048, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32})
+		require.True(t, stronger.NeedsRehash(hash))
+
+		bcryptHasher := hasher.New(bcrypt, argon2id)
+		require.True(t, bcryptHasher.NeedsRehash(legacyHash))
+		require.True(t, bcryptHasher.NeedsRehash(hash))
+		bcryptHash, err := bcryptHasher.Hash("12345678")
+		require.Nil(t, err)
+		require.False(t, bcryptHasher.NeedsRehash(bcryptHash))
+	})
+
+	t.Run("Sign in upgrades an outdated hash", func(t *testing.T) {
+		userRepository := mocks.NewRepositoryMock()
+		authUsecase := usecase.NewAuthUsecase(userRepository, refreshTokenRepositoryMock, sessionRepositoryMock, revokedAccessTokenRepositoryMock, loginAttemptUsecase, auditLogUsecase, passwordHasher, keySet, validate, viperConfig, log)
+		verifiedAt := time.Now()
+		refreshTokenRepositoryMock.Mock.On("Save", mock.Anything).Return(nil)
+		sessionRepositoryMock.Mock.On("Save", mock.Anything).Return(nil)
+		userRepository.Mock.On("FindRolesByUserId", mock.Anything).Return([]entity.Role{}, nil)
+		userRepository.Mock.On("FindOneByEmail", "rehash@gmail.com").Return(&entity.User{
+			Id:              "rehash-user",
+			Email:           "rehash@gmail.com",
+			Password:        legacyHash,
+			EmailVerifiedAt: &verifiedAt,
+		}, nil)
+		userRepository.Mock.On("UpdatePassword", "rehash-user", mock.Anything).Return(nil)
+
+		result, err := authUsecase.SignIn(&models.SignInRequest{Email: "rehash@gmail.com", Password: "12345678"})
+		require.Nil(t, err)
+		require.NotEmpty(t, result.AccessToken)
+		userRepository.Mock.AssertCalled(t, "UpdatePassword", "rehash-user", mock.MatchedBy(func(hash string) bool {
+			ok, err := passwordHasher.Verify(hash, "12345678")
+			return strings.HasPrefix(hash, "$argon2id$") && err == nil && ok
+		}))
+	})
+
+	t.Run("Sign in keeps a current hash", func(t *testing.T) {
+		userRepository := mocks.NewRepositoryMock()
+		authUsecase := usecase.NewAuthUsecase(userRepository, refreshTokenRepositoryMock, sessionRepositoryMock, revokedAccessTokenRepositoryMock, loginAttemptUsecase, auditLogUsecase, passwordHasher, keySet, validate, viperConfig, log)
+		verifiedAt := time.Now()
+		hash, err := passwordHasher.Hash("12345678")
+		require.Nil(t, err)
+		userRepository.Mock.On("FindRolesByUserId", mock.Anything).Return([]entity.Role{}, nil)
+		userRepository.Mock.On("FindOneByEmail", "current-hash@gmail.com").Return(&entity.User{
+			Id:              "current-hash-user",
+			Email:           "current-hash@gmail.com",
+			Password:        hash,
+			EmailVerifiedAt: &verifiedAt,
+		}, nil)
+
+		result, err := authUsecase.SignIn(&models.SignInRequest{Email: "current-hash@gmail.com", Password: "12345678"})
+		require.Nil(t, err)
+		require.NotEmpty(t, result.AccessToken)
+		userRepository.Mock.AssertNotCalled(t, "UpdatePassword", "current-hash-user", mock.Anything)
+	})
+}
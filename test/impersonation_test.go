@@ -0,0 +1,167 @@
+package test
+
+import (
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go-crud/internal/delivery/http/controllers"
+	"go-crud/internal/delivery/http/middleware"
+	"go-crud/internal/delivery/http/routes"
+	"go-crud/internal/entity"
+	"go-crud/internal/mail"
+	"go-crud/internal/models"
+	"go-crud/internal/usecase"
+	"go-crud/test/mocks"
+	"gorm.io/gorm"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestImpersonation(t *testing.T) {
+	userRepository := mocks.NewRepositoryMock()
+	sessionRepository := mocks.NewSessionRepositoryMock()
+	refreshTokenRepository := mocks.NewRefreshTokenRepositoryMock()
+	auditLogRepository := mocks.NewAuditLogRepositoryMock()
+	auditLogRepository.Mock.On("Save", mock.Anything).Return(nil)
+
+	auditLogUsecase := usecase.NewAuditLogUsecase(auditLogRepository, log)
+	authUsecase := usecase.NewAuthUsecase(userRepository, refreshTokenRepository, sessionRepository, revokedAccessTokenRepositoryMock, loginAttemptUsecase, auditLogUsecase, passwordHasher, keySet, validate, viperConfig, log)
+	sessionUsecase := usecase.NewSessionUsecase(sessionRepository, refreshTokenRepository, log)
+	passwordResetUsecase := usecase.NewPasswordResetUsecase(userRepository, mocks.NewUserTokenRepositoryMock(), sessionRepository, refreshTokenRepository, loginAttemptUsecase, auditLogUsecase, passwordHasher, mail.NewFileSender(t.TempDir()), validate, viperConfig, log)
+	adminUsecase := usecase.NewAdminUsecase(userRepository, productRepositoryMock, authUsecase, sessionUsecase, passwordResetUsecase, auditLogUsecase, viperConfig, log)
+	authMiddleware := middleware.NewAuthMiddleware(authUsecase, usecase.NewApiKeyUsecase(apiKeyRepositoryMock, validate, log), auditLogUsecase, log)
+
+	disabledAt := time.Now().Add(-time.Hour)
+	findUser := func(user *entity.User) {
+		userRepository.Mock.On("FindOneById", mock.Anything, user.Id).Return(nil).Run(func(args mock.Arguments) {
+			*args.Get(0).(*entity.User) = *user
+		})
+	}
+	findUser(&entity.User{Id: "impersonated-user", Email: "impersonated@gmail.com"})
+	findUser(&entity.User{Id: "impersonated-disabled", Email: "impersonated-disabled@gmail.com", DisabledAt: &disabledAt})
+	userRepository.Mock.On("FindOneById", mock.Anything, mock.Anything).Return(gorm.ErrRecordNotFound)
+	userRepository.Mock.On("IsDisabled", "impersonating-disabled-admin").Return(true, nil)
+	userRepository.Mock.On("IsDisabled", mock.Anything).Return(false, nil)
+	sessionRepository.Mock.On("FindOneById", "support-session").Return(&entity.Session{Id: "support-session", UserId: "support-admin", LastSeenAt: time.Now()}, nil)
+	sessionRepository.Mock.On("FindOneById", "ended-support-session").Return(&entity.Session{Id: "ended-support-session", UserId: "support-admin", RevokedAt: &disabledAt}, nil)
+
+	actor := models.Actor{UserId: "support-admin", Client: models.ClientInfo{IpAddress: "10.0.0.2", UserAgent: "support-console"}}
+	impersonate := func(t *testing.T, adminID string, sessionID string) string {
+		result, err := adminUsecase.Impersonate(models.Actor{UserId: adminID}, &models.ImpersonateRequest{UserId: "impersonated-user", SessionId: sessionID, Methods: []string{"pwd", "mfa"}})
+		require.Nil(t, err)
+		return result.AccessToken
+	}
+
+	app := fiber.New()
+	app.Get("/products", authMiddleware.Auth, func(ctx *fiber.Ctx) error {
+		return ctx.JSON(fiber.Map{"user_id": ctx.Locals("user_id"), "actor_id": ctx.Locals("actor_id")})
+	})
+	app.Post("/me/password", authMiddleware.Auth, authMiddleware.RequireSession, func(ctx *fiber.Ctx) error {
+		return ctx.SendStatus(fiber.StatusOK)
+	})
+	send := func(method string, path string, token string) (int, map[string]string) {
+		request := httptest.NewRequest(method, path, nil)
+		request.Header.Set("Authorization", "Bearer "+token)
+		response, err := app.Test(request)
+		require.Nil(t, err)
+		body := map[string]string{}
+		_ = json.NewDecoder(response.Body).Decode(&body)
+		return response.StatusCode, body
+	}
+
+	t.Run("Issue token", func(t *testing.T) {
+		t.Run("Should carry the user and the admin", func(t *testing.T) {
+			result, err := adminUsecase.Impersonate(actor, &models.ImpersonateRequest{UserId: "impersonated-user", SessionId: "support-session", Methods: []string{"pwd", "mfa"}})
+			require.Nil(t, err)
+			require.True(t, result.ExpiresAt.Before(time.Now().Add(16*time.Minute)))
+
+			claims, err := authUsecase.VerifyAccessToken(result.AccessToken)
+			require.Nil(t, err)
+			require.Equal(t, "impersonated-user", claims.Subject)
+			require.Equal(t, "support-admin", claims.Actor)
+			require.Equal(t, "support-session", claims.SessionId)
+			require.Equal(t, []string{"pwd", "mfa"}, claims.Methods)
+			require.Empty(t, claims.Roles)
+			require.Empty(t, claims.Permissions)
+			require.Equal(t, result.ExpiresAt.Unix(), claims.ExpiresAt.Unix())
+
+			auditLogRepository.Mock.AssertCalled(t, "Save", mock.MatchedBy(func(entry *entity.AuditLog) bool {
+				return entry.Action == entity.AuditActionAdminUserImpersonate &&
+					*entry.ActorId == "support-admin" &&
+					*entry.TargetId == "impersonated-user" &&
+					entry.Outcome == entity.AuditOutcomeSuccess
+			}))
+		})
+
+		t.Run("Should refuse to impersonate yourself", func(t *testing.T) {
+			_, err := adminUsecase.Impersonate(actor, &models.ImpersonateRequest{UserId: "support-admin"})
+			require.Equal(t, &models.ErrorResponse{Code: 400, Message: "You can't impersonate yourself", Status: "Bad Request"}, err)
+		})
+
+		t.Run("Should refuse to impersonate a disabled user", func(t *testing.T) {
+			_, err := adminUsecase.Impersonate(actor, &models.ImpersonateRequest{UserId: "impersonated-disabled"})
+			require.Equal(t, &models.ErrorResponse{Code: 400, Message: "Disabled users can't be impersonated", Status: "Bad Request"}, err)
+		})
+	})
+
+	t.Run("Requests", func(t *testing.T) {
+		t.Run("Should act as the user and audit the request", func(t *testing.T) {
+			status, body := send(fiber.MethodGet, "/products?page=2", impersonate(t, "support-admin", "support-session"))
+			require.Equal(t, 200, status)
+			require.Equal(t, "impersonated-user", body["user_id"])
+			require.Equal(t, "support-admin", body["actor_id"])
+
+			auditLogRepository.Mock.AssertCalled(t, "Save", mock.MatchedBy(func(entry *entity.AuditLog) bool {
+				return entry.Action == entity.AuditActionImpersonatedRequest &&
+					*entry.ActorId == "support-admin" &&
+					*entry.TargetId == "impersonated-user" &&
+					entry.Outcome == entity.AuditOutcomeSuccess &&
+					entry.Details == "GET /products?page=2 200"
+			}))
+		})
+
+		t.Run("Should block sensitive actions and audit the refusal", func(t *testing.T) {
+			status, _ := send(fiber.MethodPost, "/me/password", impersonate(t, "support-admin", "support-session"))
+			require.Equal(t, 403, status)
+
+			auditLogRepository.Mock.AssertCalled(t, "Save", mock.MatchedBy(func(entry *entity.AuditLog) bool {
+				return entry.Action == entity.AuditActionImpersonatedRequest &&
+					entry.Outcome == entity.AuditOutcomeFailure &&
+					entry.Details == "POST /me/password 403"
+			}))
+		})
+
+		t.Run("Should end with the session of the admin", func(t *testing.T) {
+			status, _ := send(fiber.MethodGet, "/products", impersonate(t, "support-admin", "ended-support-session"))
+			require.Equal(t, 401, status)
+		})
+
+		t.Run("Should refuse a disabled admin", func(t *testing.T) {
+			status, _ := send(fiber.MethodGet, "/products", impersonate(t, "impersonating-disabled-admin", "support-session"))
+			require.Equal(t, 403, status)
+		})
+	})
+
+	t.Run("Route needs the permission and a real session", func(t *testing.T) {
+		adminApp := fiber.New()
+		routes.NewAdminRoute(adminApp, controllers.NewAdminController(log, adminUsecase), authMiddleware).Setup()
+		post := func(token string) int {
+			request := httptest.NewRequest(fiber.MethodPost, "/admin/users/impersonated-user/impersonate", nil)
+			request.Header.Set("Authorization", "Bearer "+token)
+			response, err := adminApp.Test(request)
+			require.Nil(t, err)
+			return response.StatusCode
+		}
+
+		admin, err := authUsecase.GenerateAccessToken(&models.AccessTokenClaims{Subject: "support-admin", SessionId: "support-session", Permissions: []string{models.PermissionUserImpersonateAny}})
+		require.Nil(t, err)
+		user, err := authUsecase.GenerateAccessToken(&models.AccessTokenClaims{Subject: "impersonated-user", SessionId: "support-session"})
+		require.Nil(t, err)
+
+		require.Equal(t, 201, post(admin))
+		require.Equal(t, 403, post(user))
+		require.Equal(t, 403, post(impersonate(t, "support-admin", "support-session")))
+	})
+}
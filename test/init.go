@@ -4,20 +4,65 @@ import (
 	"github.com/go-playground/validator/v10"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
+	"github.com/stretchr/testify/mock"
 	"go-crud/internal/config"
+	"go-crud/internal/entity"
+	"go-crud/internal/hasher"
+	"go-crud/internal/keyset"
+	"go-crud/internal/repository"
+	"go-crud/internal/storage"
+	"go-crud/internal/usecase"
 	"go-crud/test/mocks"
+	"os"
+	"path/filepath"
 )
 
 var viperConfig *viper.Viper
 var userRepositoryMock *mocks.UserRepositoryMock
 var productRepositoryMock *mocks.ProductRepositoryMock
+var refreshTokenRepositoryMock *mocks.RefreshTokenRepositoryMock
+var sessionRepositoryMock *mocks.SessionRepositoryMock
+var userTokenRepositoryMock *mocks.UserTokenRepositoryMock
+var recoveryCodeRepositoryMock *mocks.RecoveryCodeRepositoryMock
+var apiKeyRepositoryMock *mocks.ApiKeyRepositoryMock
+var revokedAccessTokenRepositoryMock *mocks.RevokedAccessTokenRepositoryMock
+var organizationMemberRepositoryMock *mocks.OrganizationMemberRepositoryMock
+var auditLogRepositoryMock *mocks.AuditLogRepositoryMock
+var productImageRepositoryMock *mocks.ProductImageRepositoryMock
+var fileStorage *storage.LocalStorage
+var loginAttemptUsecase *usecase.LoginAttemptUsecase
+var auditLogUsecase *usecase.AuditLogUsecase
+var keySet *keyset.KeySet
+var passwordHasher *hasher.Hasher
 var validate *validator.Validate
 var log *logrus.Logger
 
 func init() {
 	viperConfig = config.NewViper("./../")
+	keySet = config.NewKeySet(viperConfig)
+	passwordHasher = config.NewHasher(viperConfig)
 	userRepositoryMock = mocks.NewRepositoryMock()
+	// No account is disabled unless a test uses its own mock.
+	userRepositoryMock.Mock.On("IsDisabled", mock.Anything).Return(false, nil)
 	productRepositoryMock = mocks.NewProductRepositoryMock()
+	refreshTokenRepositoryMock = mocks.NewRefreshTokenRepositoryMock()
+	sessionRepositoryMock = mocks.NewSessionRepositoryMock()
+	userTokenRepositoryMock = mocks.NewUserTokenRepositoryMock()
+	recoveryCodeRepositoryMock = mocks.NewRecoveryCodeRepositoryMock()
+	apiKeyRepositoryMock = mocks.NewApiKeyRepositoryMock()
+	revokedAccessTokenRepositoryMock = mocks.NewRevokedAccessTokenRepositoryMock()
+	// No access token is revoked unless a test uses its own mock.
+	revokedAccessTokenRepositoryMock.Mock.On("Exists", mock.Anything).Return(false, nil)
+	organizationMemberRepositoryMock = mocks.NewOrganizationMemberRepositoryMock()
+	auditLogRepositoryMock = mocks.NewAuditLogRepositoryMock()
+	auditLogRepositoryMock.Mock.On("Save", mock.Anything).Return(nil)
+	// Products have no images unless a test uses its own mock.
+	productImageRepositoryMock = mocks.NewProductImageRepositoryMock()
+	productImageRepositoryMock.Mock.On("FindManyByProductId", mock.Anything).Return([]entity.ProductImage{}, nil)
+	productImageRepositoryMock.Mock.On("FindManyByProductIds", mock.Anything).Return([]entity.ProductImage{}, nil)
+	fileStorage = storage.NewLocalStorage(filepath.Join(os.TempDir(), "go-crud-test-uploads"), "http://localhost:8080/uploads")
 	validate = config.NewValidator()
 	log = config.NewLogrus()
+	loginAttemptUsecase = usecase.NewLoginAttemptUsecase(repository.NewMemoryLoginAttemptRepository(), viperConfig, log)
+	auditLogUsecase = usecase.NewAuditLogUsecase(auditLogRepositoryMock, log)
 }
@@ -0,0 +1,158 @@
+package test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"go-crud/internal/config"
+	"go-crud/internal/keyset"
+	"go-crud/internal/models"
+	"go-crud/internal/usecase"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writePrivateKey(t *testing.T, dir string, name string, key any) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.Nil(t, err)
+	path := filepath.Join(dir, name)
+	err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+	require.Nil(t, err)
+	return path
+}
+
+func newSigningViper(active string, keys []map[string]any) *viper.Viper {
+	signingViper := viper.New()
+	signingViper.Set("token.signing.active", active)
+	signingViper.Set("token.signing.keys", keys)
+	return signingViper
+}
+
+func TestKeySet(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.Nil(t, err)
+	rsaPath := writePrivateKey(t, dir, "rsa.pem", rsaKey)
+	edPath := writePrivateKey(t, dir, "ed25519.pem", edKey)
+
+	t.Run("Fallback to HS256 with the configured access key", func(t *testing.T) {
+		require.Equal(t, "HS256", keySet.Active().Algorithm)
+		require.Empty(t, keySet.JWKS().Keys)
+	})
+
+	t.Run("Sign and verify with RS256", func(t *testing.T) {
+		set := config.NewKeySet(newSigningViper("rsa-1", []map[string]any{
+			{"kid": "rsa-1", "alg": "RS256", "private_key_file": rsaPath},
+		}))
+		authUsecase := usecase.NewAuthUsecase(userRepositoryMock, refreshTokenRepositoryMock, sessionRepositoryMock, revokedAccessTokenRepositoryMock, loginAttemptUsecase, auditLogUsecase, passwordHasher, set, validate, viperConfig, log)
+
+		accessToken, err := authUsecase.GenerateAccessToken(&models.AccessTokenClaims{Subject: "user-id"})
+		require.Nil(t, err)
+		token, _, err := jwt.NewParser().ParseUnverified(accessToken, jwt.MapClaims{})
+		require.Nil(t, err)
+		require.Equal(t, "RS256", token.Method.Alg())
+		require.Equal(t, "rsa-1", token.Header["kid"])
+
+		claims, err := authUsecase.VerifyAccessToken(accessToken)
+		require.Nil(t, err)
+		require.Equal(t, "user-id", claims.Subject)
+	})
+
+	t.Run("Tokens of the previous key stay valid until it is retired", func(t *testing.T) {
+		oldSet := config.NewKeySet(newSigningViper("rsa-1", []map[string]any{
+			{"kid": "rsa-1", "alg": "RS256", "private_key_file": rsaPath},
+		}))
+		oldUsecase := usecase.NewAuthUsecase(userRepositoryMock, refreshTokenRepositoryMock, sessionRepositoryMock, revokedAccessTokenRepositoryMock, loginAttemptUsecase, auditLogUsecase, passwordHasher, oldSet, validate, viperConfig, log)
+		oldToken, err := oldUsecase.GenerateAccessToken(&models.AccessTokenClaims{Subject: "user-id"})
+		require.Nil(t, err)
+
+		rotatedSet := config.NewKeySet(newSigningViper("ed-1", []map[string]any{
+			{"kid": "rsa-1", "alg": "RS256", "private_key_file": rsaPath},
+			{"kid": "ed-1", "alg": "EdDSA", "private_key_file": edPath},
+		}))
+		rotatedUsecase := usecase.NewAuthUsecase(userRepositoryMock, refreshTokenRepositoryMock, sessionRepositoryMock, revokedAccessTokenRepositoryMock, loginAttemptUsecase, auditLogUsecase, passwordHasher, rotatedSet, validate, viperConfig, log)
+		newToken, err := rotatedUsecase.GenerateAccessToken(&models.AccessTokenClaims{Subject: "user-id"})
+		require.Nil(t, err)
+
+		_, err = rotatedUsecase.VerifyAccessToken(oldToken)
+		require.Nil(t, err)
+		_, err = rotatedUsecase.VerifyAccessToken(newToken)
+		require.Nil(t, err)
+
+		retiredSet := config.NewKeySet(newSigningViper("ed-1", []map[string]any{
+			{"kid": "rsa-1", "alg": "RS256", "private_key_file": rsaPath, "retired": true},
+			{"kid": "ed-1", "alg": "EdDSA", "private_key_file": edPath},
+		}))
+		retiredUsecase := usecase.NewAuthUsecase(userRepositoryMock, refreshTokenRepositoryMock, sessionRepositoryMock, revokedAccessTokenRepositoryMock, loginAttemptUsecase, auditLogUsecase, passwordHasher, retiredSet, validate, viperConfig, log)
+		claims, err := retiredUsecase.VerifyAccessToken(oldToken)
+		require.Nil(t, claims)
+		require.Equal(t, 401, err.(*models.ErrorResponse).Code)
+		_, err = retiredUsecase.VerifyAccessToken(newToken)
+		require.Nil(t, err)
+
+		jwks := retiredSet.JWKS()
+		require.Len(t, jwks.Keys, 1)
+		require.Equal(t, "ed-1", jwks.Keys[0].Kid)
+		require.Equal(t, "OKP", jwks.Keys[0].Kty)
+	})
+
+	t.Run("JWKS publishes the RSA public key", func(t *testing.T) {
+		set := config.NewKeySet(newSigningViper("rsa-1", []map[string]any{
+			{"kid": "rsa-1", "alg": "RS256", "private_key_file": rsaPath},
+		}))
+		jwks := set.JWKS()
+		require.Len(t, jwks.Keys, 1)
+		require.Equal(t, "RSA", jwks.Keys[0].Kty)
+		require.Equal(t, "AQAB", jwks.Keys[0].E)
+		require.NotEmpty(t, jwks.Keys[0].N)
+	})
+
+	t.Run("Published keys parse back to the public keys", func(t *testing.T) {
+		set := config.NewKeySet(newSigningViper("rsa-1", []map[string]any{
+			{"kid": "rsa-1", "alg": "RS256", "private_key_file": rsaPath},
+			{"kid": "ed-1", "alg": "EdDSA", "private_key_file": edPath},
+		}))
+		jwks := set.JWKS()
+		require.Len(t, jwks.Keys, 2)
+
+		public, err := jwks.Keys[0].PublicKey()
+		require.Nil(t, err)
+		require.True(t, rsaKey.PublicKey.Equal(public))
+		public, err = jwks.Keys[1].PublicKey()
+		require.Nil(t, err)
+		require.True(t, edKey.Public().(ed25519.PublicKey).Equal(public))
+
+		_, err = keyset.JWK{Kty: "EC", Crv: "P-256", X: "AQ", Y: "AQ"}.PublicKey()
+		require.NotNil(t, err)
+	})
+
+	t.Run("Should reject a token signed with HS256 using the public key", func(t *testing.T) {
+		set := config.NewKeySet(newSigningViper("rsa-1", []map[string]any{
+			{"kid": "rsa-1", "alg": "RS256", "private_key_file": rsaPath},
+		}))
+		authUsecase := usecase.NewAuthUsecase(userRepositoryMock, refreshTokenRepositoryMock, sessionRepositoryMock, revokedAccessTokenRepositoryMock, loginAttemptUsecase, auditLogUsecase, passwordHasher, set, validate, viperConfig, log)
+
+		publicDer, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+		require.Nil(t, err)
+		forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"sub": "attacker",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+		forged.Header["kid"] = "rsa-1"
+		forgedToken, err := forged.SignedString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDer}))
+		require.Nil(t, err)
+
+		claims, err := authUsecase.VerifyAccessToken(forgedToken)
+		require.Nil(t, claims)
+		require.NotNil(t, err)
+	})
+}
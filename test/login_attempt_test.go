@@ -0,0 +1,89 @@
+package test
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"go-crud/internal/models"
+	"go-crud/internal/repository"
+	"go-crud/internal/usecase"
+	"testing"
+	"time"
+)
+
+func TestLoginAttempt(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	loginAttempts := usecase.NewLoginAttemptUsecase(repository.NewMemoryLoginAttemptRepository(), viperConfig, log)
+	loginAttempts.Now = func() time.Time {
+		return now
+	}
+	maxAttempts := viperConfig.GetInt("auth.lockout.max_attempts")
+	lockout := time.Duration(viperConfig.GetInt("auth.lockout.duration")) * time.Second
+
+	t.Run("Should delay an account progressively after a few failures", func(t *testing.T) {
+		for i := 0; i < viperConfig.GetInt("auth.lockout.delay_after"); i++ {
+			require.Nil(t, loginAttempts.Check("delay@gmail.com", ""))
+			loginAttempts.RegisterFailure("delay@gmail.com", "")
+		}
+
+		err := loginAttempts.Check("delay@gmail.com", "")
+		require.Equal(t, &models.ErrorResponse{Code: 429, Message: "Too many sign in attempts, try again in 1 seconds", Status: "Too Many Requests"}, err)
+
+		now = now.Add(time.Second)
+		require.Nil(t, loginAttempts.Check("delay@gmail.com", ""))
+		loginAttempts.RegisterFailure("delay@gmail.com", "")
+		err = loginAttempts.Check("delay@gmail.com", "")
+		require.Equal(t, "Too many sign in attempts, try again in 2 seconds", err.Error())
+	})
+
+	t.Run("Should lock an account after too many failures", func(t *testing.T) {
+		for i := 0; i < maxAttempts; i++ {
+			loginAttempts.RegisterFailure("locked@gmail.com", "")
+			now = now.Add(time.Minute)
+		}
+
+		err := loginAttempts.Check("locked@gmail.com", "")
+		require.Equal(t, 423, err.(*models.ErrorResponse).Code)
+		require.Equal(t, "Locked", err.(*models.ErrorResponse).Status)
+
+		now = now.Add(lockout)
+		require.Nil(t, loginAttempts.Check("locked@gmail.com", ""))
+		loginAttempts.RegisterFailure("locked@gmail.com", "")
+		require.Nil(t, loginAttempts.Check("locked@gmail.com", ""))
+	})
+
+	t.Run("Should block an address failing across accounts", func(t *testing.T) {
+		for i := 0; i < viperConfig.GetInt("auth.lockout.ip_max_attempts"); i++ {
+			loginAttempts.RegisterFailure(fmt.Sprintf("spray-%d@gmail.com", i), "10.0.0.1")
+		}
+
+		err := loginAttempts.Check("someone-else@gmail.com", "10.0.0.1")
+		require.Equal(t, 429, err.(*models.ErrorResponse).Code)
+		require.Nil(t, loginAttempts.Check("someone-else@gmail.com", "10.0.0.2"))
+	})
+
+	t.Run("Success and unlock clear the account", func(t *testing.T) {
+		loginAttempts.RegisterFailure("success@gmail.com", "")
+		loginAttempts.RegisterFailure("success@gmail.com", "")
+		loginAttempts.RegisterFailure("success@gmail.com", "")
+		loginAttempts.RegisterSuccess("success@gmail.com")
+		require.Nil(t, loginAttempts.Check("success@gmail.com", ""))
+
+		for i := 0; i < maxAttempts; i++ {
+			loginAttempts.RegisterFailure("unlock@gmail.com", "")
+		}
+		require.NotNil(t, loginAttempts.Check("unlock@gmail.com", ""))
+		require.Nil(t, loginAttempts.Unlock("UNLOCK@gmail.com"))
+		require.Nil(t, loginAttempts.Check("unlock@gmail.com", ""))
+	})
+
+	t.Run("Sign in is refused while the account is locked", func(t *testing.T) {
+		authUsecase := usecase.NewAuthUsecase(userRepositoryMock, refreshTokenRepositoryMock, sessionRepositoryMock, revokedAccessTokenRepositoryMock, loginAttempts, auditLogUsecase, passwordHasher, keySet, validate, viperConfig, log)
+		for i := 0; i < maxAttempts; i++ {
+			loginAttempts.RegisterFailure("signin-locked@gmail.com", "")
+		}
+
+		result, err := authUsecase.SignIn(&models.SignInRequest{Email: "signin-locked@gmail.com", Password: "12345678"})
+		require.Nil(t, result)
+		require.Equal(t, 423, err.(*models.ErrorResponse).Code)
+	})
+}
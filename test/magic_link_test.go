@@ -0,0 +1,173 @@
+package test
+
+import (
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go-crud/internal/delivery/http/controllers"
+	"go-crud/internal/delivery/http/routes"
+	"go-crud/internal/entity"
+	"go-crud/internal/helper"
+	"go-crud/internal/mail"
+	"go-crud/internal/models"
+	"go-crud/internal/usecase"
+	"go-crud/test/mocks"
+	"gorm.io/gorm"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMagicLink(t *testing.T) {
+	mailSender := mail.NewFileSender(t.TempDir())
+	userRepository := mocks.NewRepositoryMock()
+	userTokenRepository := mocks.NewUserTokenRepositoryMock()
+	sessionRepository := mocks.NewSessionRepositoryMock()
+	refreshTokenRepository := mocks.NewRefreshTokenRepositoryMock()
+	sessionRepository.Mock.On("Save", mock.Anything).Return(nil)
+	refreshTokenRepository.Mock.On("Save", mock.Anything).Return(nil)
+	userRepository.Mock.On("FindRolesByUserId", mock.Anything).Return([]entity.Role{}, nil)
+
+	authUsecase := usecase.NewAuthUsecase(userRepository, refreshTokenRepository, sessionRepository, revokedAccessTokenRepositoryMock, loginAttemptUsecase, auditLogUsecase, passwordHasher, keySet, validate, viperConfig, log)
+	magicLinkUsecase := usecase.NewMagicLinkUsecase(userRepository, userTokenRepository, authUsecase, mailSender, validate, viperConfig, log)
+
+	verifiedAt := time.Now().Add(-time.Hour)
+	findUser := func(user *entity.User) {
+		userRepository.Mock.On("FindOneById", mock.Anything, user.Id).Return(nil).Run(func(args mock.Arguments) {
+			*args.Get(0).(*entity.User) = *user
+		})
+	}
+	validToken := func(token string, userID string) {
+		userTokenRepository.Mock.On("FindOneByHash", helper.HashToken(token), entity.UserTokenMagicLink).Return(&entity.UserToken{Id: token, UserId: userID, Purpose: entity.UserTokenMagicLink, ExpiresAt: time.Now().Add(time.Minute)}, nil)
+		userTokenRepository.Mock.On("MarkAsUsed", token).Return(true, nil).Once()
+	}
+
+	t.Run("Request link", func(t *testing.T) {
+		t.Run("Should mail a sign in token and store only its hash", func(t *testing.T) {
+			userRepository.Mock.On("FindOneByEmail", "magic@gmail.com").Return(&entity.User{Id: "magic-user", Name: "Danar", Email: "magic@gmail.com"}, nil)
+			userTokenRepository.Mock.On("InvalidateByUserId", "magic-user", entity.UserTokenMagicLink).Return(nil)
+			userTokenRepository.Mock.On("Save", mock.Anything).Return(nil)
+
+			err := magicLinkUsecase.RequestLink(&models.MagicLinkRequest{Email: "magic@gmail.com"})
+			require.Nil(t, err)
+
+			message, err := mailSender.Last("magic@gmail.com")
+			require.Nil(t, err)
+			token := regexp.MustCompile(`Sign in token: (\S+)`).FindStringSubmatch(message)[1]
+			require.Contains(t, message, "/auth/magic-link?token=")
+
+			userTokenRepository.Mock.AssertCalled(t, "InvalidateByUserId", "magic-user", entity.UserTokenMagicLink)
+			userTokenRepository.Mock.AssertCalled(t, "Save", mock.MatchedBy(func(saved *entity.UserToken) bool {
+				return saved.UserId == "magic-user" &&
+					saved.Purpose == entity.UserTokenMagicLink &&
+					saved.TokenHash == helper.HashToken(token) &&
+					saved.ExpiresAt.Before(time.Now().Add(16*time.Minute))
+			}))
+		})
+
+		t.Run("Should succeed without sending anything for unknown email", func(t *testing.T) {
+			userRepository.Mock.On("FindOneByEmail", "nobody-magic@gmail.com").Return(nil, gorm.ErrRecordNotFound)
+			err := magicLinkUsecase.RequestLink(&models.MagicLinkRequest{Email: "nobody-magic@gmail.com"})
+			require.Nil(t, err)
+
+			_, err = mailSender.Last("nobody-magic@gmail.com")
+			require.NotNil(t, err)
+		})
+
+		t.Run("Should validate the email", func(t *testing.T) {
+			err := magicLinkUsecase.RequestLink(&models.MagicLinkRequest{Email: "invalid"})
+			require.Equal(t, &models.ErrorResponse{Code: 400, Message: "Email format is invalid", Status: "Bad Request"}, err)
+		})
+	})
+
+	t.Run("Sign in", func(t *testing.T) {
+		t.Run("Should start a session once", func(t *testing.T) {
+			findUser(&entity.User{Id: "magic-user", Email: "magic@gmail.com", EmailVerifiedAt: &verifiedAt})
+			validToken("magic-token", "magic-user")
+
+			result, err := magicLinkUsecase.SignIn(&models.MagicLinkSignInRequest{Token: "magic-token"})
+			require.Nil(t, err)
+			require.NotEmpty(t, result.AccessToken)
+			require.NotEmpty(t, result.RefreshToken)
+			sessionRepository.Mock.AssertCalled(t, "Save", mock.MatchedBy(func(session *entity.Session) bool {
+				return session.UserId == "magic-user" && session.Methods == usecase.MagicLinkMethod
+			}))
+
+			userTokenRepository.Mock.On("MarkAsUsed", "magic-token").Return(false, nil)
+			_, err = magicLinkUsecase.SignIn(&models.MagicLinkSignInRequest{Token: "magic-token"})
+			require.Equal(t, &models.ErrorResponse{Code: 401, Message: "Sign in link is invalid or expired", Status: "Unauthorized"}, err)
+		})
+
+		t.Run("Should verify the email and unlock the account", func(t *testing.T) {
+			findUser(&entity.User{Id: "magic-unverified", Email: "magic-unverified@gmail.com"})
+			validToken("unverified-token", "magic-unverified")
+			userRepository.Mock.On("MarkEmailVerified", "magic-unverified", mock.Anything).Return(nil)
+
+			for i := 0; i < viperConfig.GetInt("auth.lockout.max_attempts"); i++ {
+				loginAttemptUsecase.RegisterFailure("magic-unverified@gmail.com", "")
+			}
+			require.NotNil(t, loginAttemptUsecase.Check("magic-unverified@gmail.com", ""))
+
+			result, err := magicLinkUsecase.SignIn(&models.MagicLinkSignInRequest{Token: "unverified-token"})
+			require.Nil(t, err)
+			require.NotEmpty(t, result.AccessToken)
+			userRepository.Mock.AssertCalled(t, "MarkEmailVerified", "magic-unverified", mock.Anything)
+			require.Nil(t, loginAttemptUsecase.Check("magic-unverified@gmail.com", ""))
+		})
+
+		t.Run("Should ask for the second factor", func(t *testing.T) {
+			findUser(&entity.User{Id: "magic-totp", Email: "magic-totp@gmail.com", EmailVerifiedAt: &verifiedAt, TotpEnabledAt: &verifiedAt})
+			validToken("totp-token", "magic-totp")
+
+			result, err := magicLinkUsecase.SignIn(&models.MagicLinkSignInRequest{Token: "totp-token"})
+			require.Nil(t, err)
+			require.True(t, result.MfaRequired)
+			require.Empty(t, result.AccessToken)
+
+			userID, methods, _, err := authUsecase.VerifyMfaToken(result.MfaToken)
+			require.Nil(t, err)
+			require.Equal(t, "magic-totp", userID)
+			require.Equal(t, []string{usecase.MagicLinkMethod}, methods)
+		})
+
+		t.Run("Should reject an expired token", func(t *testing.T) {
+			userTokenRepository.Mock.On("FindOneByHash", helper.HashToken("expired-magic"), entity.UserTokenMagicLink).Return(&entity.UserToken{Id: "expired-magic", UserId: "magic-user", ExpiresAt: time.Now().Add(-time.Minute)}, nil)
+			_, err := magicLinkUsecase.SignIn(&models.MagicLinkSignInRequest{Token: "expired-magic"})
+			require.Equal(t, "Sign in link is invalid or expired", err.Error())
+		})
+
+		t.Run("Should reject an unknown token", func(t *testing.T) {
+			userTokenRepository.Mock.On("FindOneByHash", helper.HashToken("unknown-magic"), entity.UserTokenMagicLink).Return(nil, gorm.ErrRecordNotFound)
+			_, err := magicLinkUsecase.SignIn(&models.MagicLinkSignInRequest{Token: "unknown-magic"})
+			require.Equal(t, "Sign in link is invalid or expired", err.Error())
+		})
+	})
+
+	t.Run("Controller sets the refresh token cookie", func(t *testing.T) {
+		findUser(&entity.User{Id: "magic-cookie", Email: "magic-cookie@gmail.com", EmailVerifiedAt: &verifiedAt})
+		validToken("cookie-token", "magic-cookie")
+
+		app := fiber.New()
+		routes.NewMagicLinkRoute(app, controllers.NewMagicLinkController(log, magicLinkUsecase)).Setup()
+		request := httptest.NewRequest(fiber.MethodPost, "/auth/magic-link/signin", strings.NewReader(`{"token":"cookie-token"}`))
+		request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		response, err := app.Test(request)
+		require.Nil(t, err)
+		require.Equal(t, fiber.StatusOK, response.StatusCode)
+
+		body := new(models.Response[*models.AuthResponse])
+		require.Nil(t, json.NewDecoder(response.Body).Decode(body))
+		require.NotEmpty(t, body.Data.AccessToken)
+
+		var refreshCookie string
+		for _, cookie := range response.Cookies() {
+			if cookie.Name == "refresh_token" {
+				refreshCookie = cookie.Value
+			}
+		}
+		require.Equal(t, body.Data.RefreshToken, refreshCookie)
+	})
+}
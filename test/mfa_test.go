@@ -0,0 +1,178 @@
+package test
+
+import (
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go-crud/internal/entity"
+	"go-crud/internal/helper"
+	"go-crud/internal/models"
+	"go-crud/internal/usecase"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the base32 form of the RFC 6238 SHA1 test seed "12345678901234567890".
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func findUserReturns(id string, user *entity.User) {
+	userRepositoryMock.Mock.On("FindOneById", mock.Anything, id).Return(nil).Run(func(args mock.Arguments) {
+		*args.Get(0).(*entity.User) = *user
+	})
+}
+
+func TestMfa(t *testing.T) {
+	authUsecase := usecase.NewAuthUsecase(userRepositoryMock, refreshTokenRepositoryMock, sessionRepositoryMock, revokedAccessTokenRepositoryMock, loginAttemptUsecase, auditLogUsecase, passwordHasher, keySet, validate, viperConfig, log)
+	mfaUsecase := usecase.NewMfaUsecase(userRepositoryMock, recoveryCodeRepositoryMock, authUsecase, validate, viperConfig, log)
+	mfaUsecase.Now = func() time.Time {
+		return time.Unix(59, 0)
+	}
+	refreshTokenRepositoryMock.Mock.On("Save", mock.Anything).Return(nil)
+	sessionRepositoryMock.Mock.On("Save", mock.Anything).Return(nil)
+	userRepositoryMock.Mock.On("FindRolesByUserId", mock.Anything).Return([]entity.Role{}, nil)
+	// The bcrypt fixtures are upgraded to argon2id on sign in.
+	userRepositoryMock.Mock.On("UpdatePassword", mock.Anything, mock.Anything).Return(nil)
+
+	t.Run("TOTP matches the RFC 6238 test vector", func(t *testing.T) {
+		code, err := helper.HOTP(rfcSecret, helper.TOTPStep(time.Unix(59, 0)), 8)
+		require.Nil(t, err)
+		require.Equal(t, "94287082", code)
+
+		step, ok := helper.ValidateTOTP(rfcSecret, "287082", time.Unix(59, 0), 1)
+		require.True(t, ok)
+		require.Equal(t, int64(1), step)
+
+		_, ok = helper.ValidateTOTP(rfcSecret, "287082", time.Unix(200, 0), 1)
+		require.False(t, ok)
+	})
+
+	t.Run("Enroll", func(t *testing.T) {
+		t.Run("Should store a pending secret", func(t *testing.T) {
+			findUserReturns("enroll-user", &entity.User{Id: "enroll-user", Email: "enroll@gmail.com"})
+			userRepositoryMock.Mock.On("UpdateTotp", "enroll-user", mock.Anything, (*time.Time)(nil)).Return(nil)
+
+			result, err := mfaUsecase.Enroll("enroll-user")
+			require.Nil(t, err)
+			require.NotEmpty(t, result.Secret)
+			require.Contains(t, result.OtpauthURI, "otpauth://totp/")
+			userRepositoryMock.Mock.AssertCalled(t, "UpdateTotp", "enroll-user", result.Secret, (*time.Time)(nil))
+		})
+
+		t.Run("Should reject a user that already enabled it", func(t *testing.T) {
+			enabledAt := time.Now()
+			findUserReturns("enrolled-user", &entity.User{Id: "enrolled-user", TotpSecret: rfcSecret, TotpEnabledAt: &enabledAt})
+
+			result, err := mfaUsecase.Enroll("enrolled-user")
+			require.Nil(t, result)
+			require.Equal(t, &models.ErrorResponse{Code: 400, Message: "Two-factor authentication is already enabled", Status: "Bad Request"}, err)
+		})
+	})
+
+	t.Run("Confirm", func(t *testing.T) {
+		t.Run("Should enable totp and return recovery codes", func(t *testing.T) {
+			findUserReturns("confirm-user", &entity.User{Id: "confirm-user", TotpSecret: rfcSecret})
+			userRepositoryMock.Mock.On("UpdateTotp", "confirm-user", rfcSecret, mock.Anything).Return(nil)
+			userRepositoryMock.Mock.On("UseTotpStep", "confirm-user", int64(1)).Return(true, nil)
+			recoveryCodeRepositoryMock.Mock.On("ReplaceAll", "confirm-user", mock.Anything).Return(nil)
+
+			result, err := mfaUsecase.Confirm("confirm-user", &models.TotpCodeRequest{Code: "287082"})
+			require.Nil(t, err)
+			require.Len(t, result.Codes, 10)
+			recoveryCodeRepositoryMock.Mock.AssertCalled(t, "ReplaceAll", "confirm-user", mock.MatchedBy(func(codes []entity.RecoveryCode) bool {
+				return len(codes) == 10 && codes[0].CodeHash != result.Codes[0]
+			}))
+		})
+
+		t.Run("Should reject a wrong code", func(t *testing.T) {
+			findUserReturns("confirm-wrong-user", &entity.User{Id: "confirm-wrong-user", TotpSecret: rfcSecret})
+
+			result, err := mfaUsecase.Confirm("confirm-wrong-user", &models.TotpCodeRequest{Code: "000000"})
+			require.Nil(t, result)
+			require.Equal(t, &models.ErrorResponse{Code: 401, Message: "Invalid two-factor authentication code", Status: "Unauthorized"}, err)
+		})
+	})
+
+	t.Run("Sign in stops at the second factor for a totp user", func(t *testing.T) {
+		enabledAt := time.Now()
+		user := &entity.User{
+			Id:            "mfa-signin-user",
+			Email:         "mfa@gmail.com",
+			Password:      "$2a$10$aOySpFRuA2uE8gGNNCuAleiBvNRyMJpZuyhZ21kf/Tpy5c8KHNRTe",
+			TotpSecret:    rfcSecret,
+			TotpEnabledAt: &enabledAt,
+		}
+		user.EmailVerifiedAt = &enabledAt
+		userRepositoryMock.Mock.On("FindOneByEmail", "mfa@gmail.com").Return(user, nil)
+
+		result, err := authUsecase.SignIn(&models.SignInRequest{Email: "mfa@gmail.com", Password: "12345678"})
+		require.Nil(t, err)
+		require.True(t, result.MfaRequired)
+		require.NotEmpty(t, result.MfaToken)
+		require.Empty(t, result.AccessToken)
+		require.Empty(t, result.RefreshToken)
+
+		_, err = authUsecase.VerifyAccessToken(result.MfaToken)
+		require.NotNil(t, err)
+	})
+
+	t.Run("Verify", func(t *testing.T) {
+		enabledAt := time.Now()
+		findUserReturns("verify-mfa-user", &entity.User{Id: "verify-mfa-user", TotpSecret: rfcSecret, TotpEnabledAt: &enabledAt})
+
+		t.Run("Should start a session with a totp code", func(t *testing.T) {
+			mfaToken, err := authUsecase.GenerateMfaToken("verify-mfa-user", nil)
+			require.Nil(t, err)
+			userRepositoryMock.Mock.On("UseTotpStep", "verify-mfa-user", int64(1)).Return(true, nil).Once()
+
+			result, err := mfaUsecase.Verify(&models.MfaVerifyRequest{MfaToken: mfaToken, Code: "287082"})
+			require.Nil(t, err)
+			require.NotEmpty(t, result.AccessToken)
+			require.NotEmpty(t, result.RefreshToken)
+			sessionRepositoryMock.Mock.AssertCalled(t, "Save", mock.MatchedBy(func(session *entity.Session) bool {
+				return session.UserId == "verify-mfa-user" && session.Methods == "pwd otp mfa"
+			}))
+		})
+
+		t.Run("Should reject a replayed totp code", func(t *testing.T) {
+			mfaToken, err := authUsecase.GenerateMfaToken("verify-mfa-user", nil)
+			require.Nil(t, err)
+			userRepositoryMock.Mock.On("UseTotpStep", "verify-mfa-user", int64(1)).Return(false, nil)
+
+			result, err := mfaUsecase.Verify(&models.MfaVerifyRequest{MfaToken: mfaToken, Code: "287082"})
+			require.Nil(t, result)
+			require.Equal(t, &models.ErrorResponse{Code: 401, Message: "Invalid two-factor authentication code", Status: "Unauthorized"}, err)
+		})
+
+		t.Run("Should accept an unused recovery code once", func(t *testing.T) {
+			mfaToken, err := authUsecase.GenerateMfaToken("verify-mfa-user", nil)
+			require.Nil(t, err)
+			stored := &entity.RecoveryCode{Id: "recovery-id", UserId: "verify-mfa-user"}
+			recoveryCodeRepositoryMock.Mock.On("FindOneUnused", "verify-mfa-user", helper.HashToken("abcdefghij")).Return(stored, nil)
+			recoveryCodeRepositoryMock.Mock.On("MarkAsUsed", "recovery-id").Return(true, nil)
+
+			result, err := mfaUsecase.Verify(&models.MfaVerifyRequest{MfaToken: mfaToken, RecoveryCode: "ABCDE-FGHIJ"})
+			require.Nil(t, err)
+			require.NotEmpty(t, result.AccessToken)
+			recoveryCodeRepositoryMock.Mock.AssertCalled(t, "MarkAsUsed", "recovery-id")
+		})
+
+		t.Run("Should reject an unknown recovery code", func(t *testing.T) {
+			mfaToken, err := authUsecase.GenerateMfaToken("verify-mfa-user", nil)
+			require.Nil(t, err)
+			recoveryCodeRepositoryMock.Mock.On("FindOneUnused", "verify-mfa-user", helper.HashToken("unknowncode")).Return(nil, gorm.ErrRecordNotFound)
+
+			result, err := mfaUsecase.Verify(&models.MfaVerifyRequest{MfaToken: mfaToken, RecoveryCode: "unknown-code"})
+			require.Nil(t, result)
+			require.Equal(t, &models.ErrorResponse{Code: 401, Message: "Invalid two-factor authentication code", Status: "Unauthorized"}, err)
+		})
+
+		t.Run("Should reject an access token in place of the mfa token", func(t *testing.T) {
+			accessToken, err := authUsecase.GenerateAccessToken(&models.AccessTokenClaims{Subject: "verify-mfa-user"})
+			require.Nil(t, err)
+
+			result, err := mfaUsecase.Verify(&models.MfaVerifyRequest{MfaToken: accessToken, Code: "287082"})
+			require.Nil(t, result)
+			require.NotNil(t, err)
+		})
+	})
+}
@@ -0,0 +1,65 @@
+package mocks
+
+import (
+	"github.com/stretchr/testify/mock"
+	"go-crud/internal/entity"
+	"time"
+)
+
+type ApiKeyRepositoryMock struct {
+	Mock mock.Mock
+}
+
+func NewApiKeyRepositoryMock() *ApiKeyRepositoryMock {
+	return &ApiKeyRepositoryMock{
+		Mock: mock.Mock{},
+	}
+}
+
+func (r *ApiKeyRepositoryMock) Save(apiKey *entity.ApiKey) error {
+	args := r.Mock.Called(apiKey)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *ApiKeyRepositoryMock) FindOneByHash(hash string) (*entity.ApiKey, error) {
+	args := r.Mock.Called(hash)
+	err := args.Error(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return args.Get(0).(*entity.ApiKey), nil
+}
+
+func (r *ApiKeyRepositoryMock) FindManyActiveByUserId(userID string) ([]entity.ApiKey, error) {
+	args := r.Mock.Called(userID)
+	err := args.Error(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return args.Get(0).([]entity.ApiKey), nil
+}
+
+func (r *ApiKeyRepositoryMock) Revoke(id string, userID string) (bool, error) {
+	args := r.Mock.Called(id, userID)
+	err := args.Error(1)
+	if err != nil {
+		return false, err
+	}
+
+	return args.Bool(0), nil
+}
+
+func (r *ApiKeyRepositoryMock) Touch(id string, lastUsedAt time.Time) error {
+	args := r.Mock.Called(id, lastUsedAt)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package mocks
+
+import (
+	"github.com/stretchr/testify/mock"
+	"go-crud/internal/entity"
+	"go-crud/internal/models"
+)
+
+type AuditLogRepositoryMock struct {
+	Mock mock.Mock
+}
+
+func NewAuditLogRepositoryMock() *AuditLogRepositoryMock {
+	return &AuditLogRepositoryMock{
+		Mock: mock.Mock{},
+	}
+}
+
+func (r *AuditLogRepositoryMock) Save(entry *entity.AuditLog) error {
+	args := r.Mock.Called(entry)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *AuditLogRepositoryMock) FindMany(entries *[]entity.AuditLog, filter *models.AuditLogFilter, offset int, limit int) error {
+	args := r.Mock.Called(entries, filter, offset, limit)
+	err := args.Error(0)
+	if err != nil {
+		return args.Error(0)
+	}
+	return nil
+}
+
+func (r *AuditLogRepositoryMock) Count(filter *models.AuditLogFilter) (int64, error) {
+	args := r.Mock.Called(filter)
+	err := args.Error(1)
+	if err != nil {
+		return -1, err
+	}
+	return args.Get(0).(int64), nil
+}
@@ -0,0 +1,103 @@
+package mocks
+
+import (
+	"github.com/stretchr/testify/mock"
+	"go-crud/internal/entity"
+)
+
+type CategoryRepositoryMock struct {
+	Mock mock.Mock
+}
+
+func NewCategoryRepositoryMock() *CategoryRepositoryMock {
+	return &CategoryRepositoryMock{
+		Mock: mock.Mock{},
+	}
+}
+
+func (r *CategoryRepositoryMock) Save(category *entity.Category) error {
+	args := r.Mock.Called(category)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *CategoryRepositoryMock) FindOneById(id string) (*entity.Category, error) {
+	args := r.Mock.Called(id)
+	err := args.Error(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return args.Get(0).(*entity.Category), nil
+}
+
+func (r *CategoryRepositoryMock) FindOneBySlug(slug string) (*entity.Category, error) {
+	args := r.Mock.Called(slug)
+	err := args.Error(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return args.Get(0).(*entity.Category), nil
+}
+
+func (r *CategoryRepositoryMock) FindAll() ([]entity.Category, error) {
+	args := r.Mock.Called()
+	err := args.Error(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return args.Get(0).([]entity.Category), nil
+}
+
+func (r *CategoryRepositoryMock) FindSubtree(path string) ([]entity.Category, error) {
+	args := r.Mock.Called(path)
+	err := args.Error(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return args.Get(0).([]entity.Category), nil
+}
+
+func (r *CategoryRepositoryMock) FindManyByPaths(paths []string) ([]entity.Category, error) {
+	args := r.Mock.Called(paths)
+	err := args.Error(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return args.Get(0).([]entity.Category), nil
+}
+
+func (r *CategoryRepositoryMock) Update(category *entity.Category, oldPath string) error {
+	args := r.Mock.Called(category, oldPath)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *CategoryRepositoryMock) DeleteById(id string) error {
+	args := r.Mock.Called(id)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *CategoryRepositoryMock) CountChildren(id string) (int64, error) {
+	args := r.Mock.Called(id)
+	err := args.Error(1)
+	if err != nil {
+		return 0, err
+	}
+
+	return args.Get(0).(int64), nil
+}
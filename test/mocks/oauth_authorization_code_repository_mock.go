@@ -0,0 +1,45 @@
+package mocks
+
+import (
+	"github.com/stretchr/testify/mock"
+	"go-crud/internal/entity"
+)
+
+type OAuthAuthorizationCodeRepositoryMock struct {
+	Mock mock.Mock
+}
+
+func NewOAuthAuthorizationCodeRepositoryMock() *OAuthAuthorizationCodeRepositoryMock {
+	return &OAuthAuthorizationCodeRepositoryMock{
+		Mock: mock.Mock{},
+	}
+}
+
+func (r *OAuthAuthorizationCodeRepositoryMock) Save(code *entity.OAuthAuthorizationCode) error {
+	args := r.Mock.Called(code)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *OAuthAuthorizationCodeRepositoryMock) FindOneByHash(hash string) (*entity.OAuthAuthorizationCode, error) {
+	args := r.Mock.Called(hash)
+	err := args.Error(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return args.Get(0).(*entity.OAuthAuthorizationCode), nil
+}
+
+func (r *OAuthAuthorizationCodeRepositoryMock) MarkAsUsed(id string) (bool, error) {
+	args := r.Mock.Called(id)
+	err := args.Error(1)
+	if err != nil {
+		return false, err
+	}
+
+	return args.Bool(0), nil
+}
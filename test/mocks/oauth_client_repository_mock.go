@@ -0,0 +1,55 @@
+package mocks
+
+import (
+	"github.com/stretchr/testify/mock"
+	"go-crud/internal/entity"
+)
+
+type OAuthClientRepositoryMock struct {
+	Mock mock.Mock
+}
+
+func NewOAuthClientRepositoryMock() *OAuthClientRepositoryMock {
+	return &OAuthClientRepositoryMock{
+		Mock: mock.Mock{},
+	}
+}
+
+func (r *OAuthClientRepositoryMock) Save(client *entity.OAuthClient) error {
+	args := r.Mock.Called(client)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *OAuthClientRepositoryMock) FindOneById(id string) (*entity.OAuthClient, error) {
+	args := r.Mock.Called(id)
+	err := args.Error(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return args.Get(0).(*entity.OAuthClient), nil
+}
+
+func (r *OAuthClientRepositoryMock) FindManyActiveByUserId(userID string) ([]entity.OAuthClient, error) {
+	args := r.Mock.Called(userID)
+	err := args.Error(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return args.Get(0).([]entity.OAuthClient), nil
+}
+
+func (r *OAuthClientRepositoryMock) Revoke(id string, userID string) (bool, error) {
+	args := r.Mock.Called(id, userID)
+	err := args.Error(1)
+	if err != nil {
+		return false, err
+	}
+
+	return args.Bool(0), nil
+}
@@ -0,0 +1,217 @@
+package mocks
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/golang-jwt/jwt/v5"
+	"go-crud/internal/helper"
+	"go-crud/internal/keyset"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// OidcProviderFake is an in-process OpenID Connect provider. It serves
+// discovery, the JWKS, an authorization endpoint that signs in whoever was
+// set with SignInAs, and a token endpoint that checks the client and PKCE.
+type OidcProviderFake struct {
+	Server       *httptest.Server
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+	KeyId        string
+	Key          *rsa.PrivateKey
+
+	mu       sync.Mutex
+	claims   jwt.MapClaims
+	grants   map[string]oidcGrant
+	jwksHits int
+}
+
+type oidcGrant struct {
+	nonce         string
+	codeChallenge string
+	redirectUri   string
+	claims        jwt.MapClaims
+}
+
+func NewOidcProviderFake(clientID string, clientSecret string) *OidcProviderFake {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+
+	fake := &OidcProviderFake{
+		ClientId:     clientID,
+		ClientSecret: clientSecret,
+		KeyId:        "fake-1",
+		Key:          key,
+		grants:       map[string]oidcGrant{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", fake.discovery)
+	mux.HandleFunc("/jwks", fake.jwks)
+	mux.HandleFunc("/authorize", fake.authorize)
+	mux.HandleFunc("/token", fake.token)
+	fake.Server = httptest.NewServer(mux)
+	fake.Issuer = fake.Server.URL
+
+	return fake
+}
+
+func (f *OidcProviderFake) Close() {
+	f.Server.Close()
+}
+
+// SignInAs sets the claims of the user the next authorization request signs
+// in, such as sub, email and email_verified.
+func (f *OidcProviderFake) SignInAs(claims jwt.MapClaims) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.claims = claims
+}
+
+// RotateKey replaces the signing key, as a provider does from time to time.
+func (f *OidcProviderFake) RotateKey(kid string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.KeyId = kid
+	f.Key = key
+}
+
+func (f *OidcProviderFake) JwksHits() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.jwksHits
+}
+
+// SignIDToken signs an ID token for the client, with claims overriding the
+// defaults.
+func (f *OidcProviderFake) SignIDToken(claims jwt.MapClaims) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	all := jwt.MapClaims{
+		"iss": f.Issuer,
+		"aud": f.ClientId,
+		"iat": now.Unix(),
+		"exp": now.Add(5 * time.Minute).Unix(),
+	}
+	for name, value := range claims {
+		all[name] = value
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, all)
+	token.Header["kid"] = f.KeyId
+	signed, err := token.SignedString(f.Key)
+	if err != nil {
+		panic(err)
+	}
+	return signed
+}
+
+func (f *OidcProviderFake) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                f.Issuer,
+		"authorization_endpoint":                f.Issuer + "/authorize",
+		"token_endpoint":                        f.Issuer + "/token",
+		"jwks_uri":                              f.Issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (f *OidcProviderFake) jwks(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.jwksHits++
+
+	public := f.Key.PublicKey
+	writeJSON(w, http.StatusOK, keyset.JWKS{Keys: []keyset.JWK{{
+		Kty: "RSA",
+		Kid: f.KeyId,
+		Use: "sig",
+		Alg: "RS256",
+		N:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+	}}})
+}
+
+func (f *OidcProviderFake) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirect, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || query.Get("client_id") != f.ClientId || query.Get("code_challenge_method") != "S256" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+
+	code, err := helper.GenerateRandomToken(32)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	f.mu.Lock()
+	f.grants[code] = oidcGrant{
+		nonce:         query.Get("nonce"),
+		codeChallenge: query.Get("code_challenge"),
+		redirectUri:   query.Get("redirect_uri"),
+		claims:        f.claims,
+	}
+	f.mu.Unlock()
+
+	params := redirect.Query()
+	params.Set("code", code)
+	params.Set("state", query.Get("state"))
+	redirect.RawQuery = params.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (f *OidcProviderFake) token(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok || clientID != f.ClientId || clientSecret != f.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	f.mu.Lock()
+	grant, ok := f.grants[r.PostFormValue("code")]
+	delete(f.grants, r.PostFormValue("code"))
+	f.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if !ok || r.PostFormValue("grant_type") != "authorization_code" ||
+		r.PostFormValue("redirect_uri") != grant.redirectUri ||
+		base64.RawURLEncoding.EncodeToString(sum[:]) != grant.codeChallenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := jwt.MapClaims{"nonce": grant.nonce}
+	for name, value := range grant.claims {
+		claims[name] = value
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": "fake-access-token",
+		"token_type":   "Bearer",
+		"id_token":     f.SignIDToken(claims),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,66 @@
+package mocks
+
+import (
+	"github.com/stretchr/testify/mock"
+	"go-crud/internal/entity"
+	"time"
+)
+
+type OrganizationInvitationRepositoryMock struct {
+	Mock mock.Mock
+}
+
+func NewOrganizationInvitationRepositoryMock() *OrganizationInvitationRepositoryMock {
+	return &OrganizationInvitationRepositoryMock{
+		Mock: mock.Mock{},
+	}
+}
+
+func (r *OrganizationInvitationRepositoryMock) Save(invitation *entity.OrganizationInvitation) error {
+	args := r.Mock.Called(invitation)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *OrganizationInvitationRepositoryMock) FindOneByHash(hash string) (*entity.OrganizationInvitation, error) {
+	args := r.Mock.Called(hash)
+	err := args.Error(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return args.Get(0).(*entity.OrganizationInvitation), nil
+}
+
+func (r *OrganizationInvitationRepositoryMock) FindManyPendingByOrganizationId(organizationID string, now time.Time) ([]entity.OrganizationInvitation, error) {
+	args := r.Mock.Called(organizationID, now)
+	err := args.Error(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return args.Get(0).([]entity.OrganizationInvitation), nil
+}
+
+func (r *OrganizationInvitationRepositoryMock) MarkAsAccepted(id string) (bool, error) {
+	args := r.Mock.Called(id)
+	err := args.Error(1)
+	if err != nil {
+		return false, err
+	}
+
+	return args.Bool(0), nil
+}
+
+func (r *OrganizationInvitationRepositoryMock) Delete(id string, organizationID string) (bool, error) {
+	args := r.Mock.Called(id, organizationID)
+	err := args.Error(1)
+	if err != nil {
+		return false, err
+	}
+
+	return args.Bool(0), nil
+}
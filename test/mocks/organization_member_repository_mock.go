@@ -0,0 +1,93 @@
+package mocks
+
+import (
+	"github.com/stretchr/testify/mock"
+	"go-crud/internal/entity"
+)
+
+type OrganizationMemberRepositoryMock struct {
+	Mock mock.Mock
+}
+
+func NewOrganizationMemberRepositoryMock() *OrganizationMemberRepositoryMock {
+	return &OrganizationMemberRepositoryMock{
+		Mock: mock.Mock{},
+	}
+}
+
+func (r *OrganizationMemberRepositoryMock) Save(member *entity.OrganizationMember) error {
+	args := r.Mock.Called(member)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *OrganizationMemberRepositoryMock) FindOne(organizationID string, userID string) (*entity.OrganizationMember, error) {
+	args := r.Mock.Called(organizationID, userID)
+	err := args.Error(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return args.Get(0).(*entity.OrganizationMember), nil
+}
+
+func (r *OrganizationMemberRepositoryMock) FindManyByUserId(userID string) ([]entity.OrganizationMember, error) {
+	args := r.Mock.Called(userID)
+	err := args.Error(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return args.Get(0).([]entity.OrganizationMember), nil
+}
+
+func (r *OrganizationMemberRepositoryMock) FindManyByOrganizationId(organizationID string) ([]entity.OrganizationMember, error) {
+	args := r.Mock.Called(organizationID)
+	err := args.Error(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return args.Get(0).([]entity.OrganizationMember), nil
+}
+
+func (r *OrganizationMemberRepositoryMock) UpdateRole(organizationID string, userID string, role string) error {
+	args := r.Mock.Called(organizationID, userID, role)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *OrganizationMemberRepositoryMock) Delete(organizationID string, userID string) error {
+	args := r.Mock.Called(organizationID, userID)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *OrganizationMemberRepositoryMock) CountByRole(organizationID string, role string) (int64, error) {
+	args := r.Mock.Called(organizationID, role)
+	err := args.Error(1)
+	if err != nil {
+		return -1, err
+	}
+
+	return args.Get(0).(int64), nil
+}
+
+func (r *OrganizationMemberRepositoryMock) CountSoleByRole(userID string, role string) (int64, error) {
+	args := r.Mock.Called(userID, role)
+	err := args.Error(1)
+	if err != nil {
+		return -1, err
+	}
+
+	return args.Get(0).(int64), nil
+}
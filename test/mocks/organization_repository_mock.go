@@ -0,0 +1,44 @@
+package mocks
+
+import (
+	"github.com/stretchr/testify/mock"
+	"go-crud/internal/entity"
+)
+
+type OrganizationRepositoryMock struct {
+	Mock mock.Mock
+}
+
+func NewOrganizationRepositoryMock() *OrganizationRepositoryMock {
+	return &OrganizationRepositoryMock{
+		Mock: mock.Mock{},
+	}
+}
+
+func (r *OrganizationRepositoryMock) Create(organization *entity.Organization, owner *entity.OrganizationMember) error {
+	args := r.Mock.Called(organization, owner)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *OrganizationRepositoryMock) FindOneById(id string) (*entity.Organization, error) {
+	args := r.Mock.Called(id)
+	err := args.Error(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return args.Get(0).(*entity.Organization), nil
+}
+
+func (r *OrganizationRepositoryMock) DeleteById(id string) error {
+	args := r.Mock.Called(id)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
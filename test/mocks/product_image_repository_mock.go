@@ -0,0 +1,73 @@
+package mocks
+
+import (
+	"github.com/stretchr/testify/mock"
+	"go-crud/internal/entity"
+)
+
+type ProductImageRepositoryMock struct {
+	Mock mock.Mock
+}
+
+func NewProductImageRepositoryMock() *ProductImageRepositoryMock {
+	return &ProductImageRepositoryMock{
+		Mock: mock.Mock{},
+	}
+}
+
+func (r *ProductImageRepositoryMock) Save(image *entity.ProductImage) error {
+	args := r.Mock.Called(image)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *ProductImageRepositoryMock) FindOne(productID string, imageID string) (*entity.ProductImage, error) {
+	args := r.Mock.Called(productID, imageID)
+	err := args.Error(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return args.Get(0).(*entity.ProductImage), nil
+}
+
+func (r *ProductImageRepositoryMock) FindManyByProductId(productID string) ([]entity.ProductImage, error) {
+	args := r.Mock.Called(productID)
+	err := args.Error(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return args.Get(0).([]entity.ProductImage), nil
+}
+
+func (r *ProductImageRepositoryMock) FindManyByProductIds(productIDs []string) ([]entity.ProductImage, error) {
+	args := r.Mock.Called(productIDs)
+	err := args.Error(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return args.Get(0).([]entity.ProductImage), nil
+}
+
+func (r *ProductImageRepositoryMock) UpdatePositions(productID string, imageIDs []string) error {
+	args := r.Mock.Called(productID, imageIDs)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *ProductImageRepositoryMock) DeleteById(imageID string) error {
+	args := r.Mock.Called(imageID)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package mocks
 import (
 	"github.com/stretchr/testify/mock"
 	"go-crud/internal/entity"
+	"go-crud/internal/models"
 )
 
 type ProductRepositoryMock struct {
@@ -33,8 +34,16 @@ func (r *ProductRepositoryMock) FindOneById(product *entity.Product, id string)
 	return nil
 }
 
-func (r *ProductRepositoryMock) FindMany(products *[]entity.Product, offset int, limit int) error {
-	args := r.Mock.Called(products, offset, limit)
+func (r *ProductRepositoryMock) FindMany(products *[]entity.Product, filter *models.ProductFilter, offset int, limit int) error {
+	args := r.Mock.Called(products, filter, offset, limit)
+	if args.Error(0) != nil {
+		return args.Error(0)
+	}
+	return nil
+}
+
+func (r *ProductRepositoryMock) FindManyByIds(products *[]entity.Product, ids []string) error {
+	args := r.Mock.Called(products, ids)
 	if args.Error(0) != nil {
 		return args.Error(0)
 	}
@@ -50,6 +59,22 @@ func (r *ProductRepositoryMock) UpdateById(product entity.Product, productID str
 	return args.Get(0).(*entity.Product), nil
 }
 
+func (r *ProductRepositoryMock) UpdateCategoryById(productID string, categoryID *string) error {
+	args := r.Mock.Called(productID, categoryID)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *ProductRepositoryMock) ReplaceTags(productID string, tags []entity.Tag) error {
+	args := r.Mock.Called(productID, tags)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+	return nil
+}
+
 func (r *ProductRepositoryMock) DeleteById(productID string) error {
 	args := r.Mock.Called(productID)
 	err := args.Error(0)
@@ -58,8 +83,18 @@ func (r *ProductRepositoryMock) DeleteById(productID string) error {
 	}
 	return nil
 }
-func (r *ProductRepositoryMock) Count() (int64, error) {
-	args := r.Mock.Called()
+func (r *ProductRepositoryMock) Count(filter *models.ProductFilter) (int64, error) {
+	args := r.Mock.Called(filter)
+	err := args.Error(1)
+	if err != nil {
+		return -1, err
+	}
+
+	return args.Get(0).(int64), nil
+}
+
+func (r *ProductRepositoryMock) CountByUserId(userID string) (int64, error) {
+	args := r.Mock.Called(userID)
 	err := args.Error(1)
 	if err != nil {
 		return -1, err
@@ -67,3 +102,23 @@ func (r *ProductRepositoryMock) Count() (int64, error) {
 
 	return args.Get(0).(int64), nil
 }
+
+func (r *ProductRepositoryMock) FindPersonalIds(userID string) ([]string, error) {
+	args := r.Mock.Called(userID)
+	err := args.Error(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return args.Get(0).([]string), nil
+}
+
+func (r *ProductRepositoryMock) FindIdsByOrganizationId(organizationID string) ([]string, error) {
+	args := r.Mock.Called(organizationID)
+	err := args.Error(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return args.Get(0).([]string), nil
+}
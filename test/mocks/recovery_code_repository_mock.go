@@ -0,0 +1,54 @@
+package mocks
+
+import (
+	"github.com/stretchr/testify/mock"
+	"go-crud/internal/entity"
+)
+
+type RecoveryCodeRepositoryMock struct {
+	Mock mock.Mock
+}
+
+func NewRecoveryCodeRepositoryMock() *RecoveryCodeRepositoryMock {
+	return &RecoveryCodeRepositoryMock{
+		Mock: mock.Mock{},
+	}
+}
+
+func (r *RecoveryCodeRepositoryMock) ReplaceAll(userID string, codes []entity.RecoveryCode) error {
+	args := r.Mock.Called(userID, codes)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *RecoveryCodeRepositoryMock) FindOneUnused(userID string, hash string) (*entity.RecoveryCode, error) {
+	args := r.Mock.Called(userID, hash)
+	err := args.Error(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return args.Get(0).(*entity.RecoveryCode), nil
+}
+
+func (r *RecoveryCodeRepositoryMock) MarkAsUsed(id string) (bool, error) {
+	args := r.Mock.Called(id)
+	err := args.Error(1)
+	if err != nil {
+		return false, err
+	}
+
+	return args.Bool(0), nil
+}
+
+func (r *RecoveryCodeRepositoryMock) DeleteByUserId(userID string) error {
+	args := r.Mock.Called(userID)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
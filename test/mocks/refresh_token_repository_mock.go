@@ -0,0 +1,72 @@
+package mocks
+
+import (
+	"github.com/stretchr/testify/mock"
+	"go-crud/internal/entity"
+)
+
+type RefreshTokenRepositoryMock struct {
+	Mock mock.Mock
+}
+
+func NewRefreshTokenRepositoryMock() *RefreshTokenRepositoryMock {
+	return &RefreshTokenRepositoryMock{
+		Mock: mock.Mock{},
+	}
+}
+
+func (r *RefreshTokenRepositoryMock) Save(token *entity.RefreshToken) error {
+	args := r.Mock.Called(token)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *RefreshTokenRepositoryMock) FindOneByHash(hash string) (*entity.RefreshToken, error) {
+	args := r.Mock.Called(hash)
+	err := args.Error(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return args.Get(0).(*entity.RefreshToken), nil
+}
+
+func (r *RefreshTokenRepositoryMock) MarkAsUsed(id string) (bool, error) {
+	args := r.Mock.Called(id)
+	err := args.Error(1)
+	if err != nil {
+		return false, err
+	}
+
+	return args.Bool(0), nil
+}
+
+func (r *RefreshTokenRepositoryMock) RevokeFamily(familyID string) error {
+	args := r.Mock.Called(familyID)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *RefreshTokenRepositoryMock) RevokeAllByUserId(userID string) error {
+	args := r.Mock.Called(userID)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *RefreshTokenRepositoryMock) RevokeAllByUserIdExcept(userID string, familyID string) error {
+	args := r.Mock.Called(userID, familyID)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
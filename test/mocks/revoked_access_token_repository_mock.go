@@ -0,0 +1,45 @@
+package mocks
+
+import (
+	"github.com/stretchr/testify/mock"
+	"go-crud/internal/entity"
+	"time"
+)
+
+type RevokedAccessTokenRepositoryMock struct {
+	Mock mock.Mock
+}
+
+func NewRevokedAccessTokenRepositoryMock() *RevokedAccessTokenRepositoryMock {
+	return &RevokedAccessTokenRepositoryMock{
+		Mock: mock.Mock{},
+	}
+}
+
+func (r *RevokedAccessTokenRepositoryMock) Save(token *entity.RevokedAccessToken) error {
+	args := r.Mock.Called(token)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *RevokedAccessTokenRepositoryMock) Exists(jti string) (bool, error) {
+	args := r.Mock.Called(jti)
+	err := args.Error(1)
+	if err != nil {
+		return false, err
+	}
+
+	return args.Bool(0), nil
+}
+
+func (r *RevokedAccessTokenRepositoryMock) DeleteExpired(now time.Time) error {
+	args := r.Mock.Called(now)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
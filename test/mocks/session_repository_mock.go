@@ -0,0 +1,91 @@
+package mocks
+
+import (
+	"github.com/stretchr/testify/mock"
+	"go-crud/internal/entity"
+	"time"
+)
+
+type SessionRepositoryMock struct {
+	Mock mock.Mock
+}
+
+func NewSessionRepositoryMock() *SessionRepositoryMock {
+	return &SessionRepositoryMock{
+		Mock: mock.Mock{},
+	}
+}
+
+func (r *SessionRepositoryMock) Save(session *entity.Session) error {
+	args := r.Mock.Called(session)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *SessionRepositoryMock) FindOneById(id string) (*entity.Session, error) {
+	args := r.Mock.Called(id)
+	err := args.Error(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return args.Get(0).(*entity.Session), nil
+}
+
+func (r *SessionRepositoryMock) FindManyActiveByUserId(userID string) ([]entity.Session, error) {
+	args := r.Mock.Called(userID)
+	err := args.Error(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return args.Get(0).([]entity.Session), nil
+}
+
+func (r *SessionRepositoryMock) Touch(id string, lastSeenAt time.Time) error {
+	args := r.Mock.Called(id, lastSeenAt)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *SessionRepositoryMock) Revoke(id string) error {
+	args := r.Mock.Called(id)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *SessionRepositoryMock) RevokeAllByUserId(userID string) error {
+	args := r.Mock.Called(userID)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *SessionRepositoryMock) RevokeAllByUserIdExcept(userID string, sessionID string) error {
+	args := r.Mock.Called(userID, sessionID)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *SessionRepositoryMock) RevokeAllByClientId(clientID string) error {
+	args := r.Mock.Called(clientID)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package mocks
+
+import (
+	"github.com/stretchr/testify/mock"
+	"go-crud/internal/entity"
+)
+
+type TagRepositoryMock struct {
+	Mock mock.Mock
+}
+
+func NewTagRepositoryMock() *TagRepositoryMock {
+	return &TagRepositoryMock{
+		Mock: mock.Mock{},
+	}
+}
+
+func (r *TagRepositoryMock) Save(tag *entity.Tag) error {
+	args := r.Mock.Called(tag)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *TagRepositoryMock) FindOneById(id string) (*entity.Tag, error) {
+	args := r.Mock.Called(id)
+	err := args.Error(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return args.Get(0).(*entity.Tag), nil
+}
+
+func (r *TagRepositoryMock) FindOneBySlug(slug string) (*entity.Tag, error) {
+	args := r.Mock.Called(slug)
+	err := args.Error(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return args.Get(0).(*entity.Tag), nil
+}
+
+func (r *TagRepositoryMock) FindAll() ([]entity.Tag, error) {
+	args := r.Mock.Called()
+	err := args.Error(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return args.Get(0).([]entity.Tag), nil
+}
+
+func (r *TagRepositoryMock) FindManyByIds(ids []string) ([]entity.Tag, error) {
+	args := r.Mock.Called(ids)
+	err := args.Error(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return args.Get(0).([]entity.Tag), nil
+}
+
+func (r *TagRepositoryMock) UpdateById(tag *entity.Tag) error {
+	args := r.Mock.Called(tag)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *TagRepositoryMock) DeleteById(id string) error {
+	args := r.Mock.Called(id)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package mocks
+
+import (
+	"github.com/stretchr/testify/mock"
+	"go-crud/internal/entity"
+)
+
+type UserIdentityRepositoryMock struct {
+	Mock mock.Mock
+}
+
+func NewUserIdentityRepositoryMock() *UserIdentityRepositoryMock {
+	return &UserIdentityRepositoryMock{
+		Mock: mock.Mock{},
+	}
+}
+
+func (r *UserIdentityRepositoryMock) Save(identity *entity.UserIdentity) error {
+	args := r.Mock.Called(identity)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *UserIdentityRepositoryMock) FindOneBySubject(issuer string, subject string) (*entity.UserIdentity, error) {
+	args := r.Mock.Called(issuer, subject)
+	err := args.Error(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return args.Get(0).(*entity.UserIdentity), nil
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/stretchr/testify/mock"
 	"go-crud/internal/entity"
 	"go-crud/internal/models"
+	"time"
 )
 
 type UserRepositoryMock struct {
@@ -40,7 +41,7 @@ func (r *UserRepositoryMock) FindOneByEmail(email string) (*entity.User, error)
 }
 
 func (r *UserRepositoryMock) FindOneById(user *entity.User, id string) error {
-	args := r.Mock.Called()
+	args := r.Mock.Called(user, id)
 	err := args.Error(0)
 	if err != nil {
 		return args.Error(0)
@@ -49,10 +50,109 @@ func (r *UserRepositoryMock) FindOneById(user *entity.User, id string) error {
 }
 
 func (r *UserRepositoryMock) DeleteOneById(id string) error {
-	args := r.Mock.Called()
+	args := r.Mock.Called(id)
 	err := args.Error(0)
 	if err != nil {
 		return args.Error(0)
 	}
 	return nil
 }
+
+func (r *UserRepositoryMock) UpdatePassword(id string, password string) error {
+	args := r.Mock.Called(id, password)
+	err := args.Error(0)
+	if err != nil {
+		return args.Error(0)
+	}
+	return nil
+}
+
+func (r *UserRepositoryMock) MarkEmailVerified(id string, verifiedAt time.Time) error {
+	args := r.Mock.Called(id, verifiedAt)
+	err := args.Error(0)
+	if err != nil {
+		return args.Error(0)
+	}
+	return nil
+}
+
+func (r *UserRepositoryMock) UpdateTotp(id string, secret string, enabledAt *time.Time) error {
+	args := r.Mock.Called(id, secret, enabledAt)
+	err := args.Error(0)
+	if err != nil {
+		return args.Error(0)
+	}
+	return nil
+}
+
+func (r *UserRepositoryMock) UseTotpStep(id string, step int64) (bool, error) {
+	args := r.Mock.Called(id, step)
+	err := args.Error(1)
+	if err != nil {
+		return false, err
+	}
+	return args.Bool(0), nil
+}
+
+func (r *UserRepositoryMock) FindRolesByUserId(id string) ([]entity.Role, error) {
+	args := r.Mock.Called(id)
+	err := args.Error(1)
+	if err != nil {
+		return nil, err
+	}
+	return args.Get(0).([]entity.Role), nil
+}
+
+func (r *UserRepositoryMock) UpdateProfile(user *entity.User) error {
+	args := r.Mock.Called(user)
+	err := args.Error(0)
+	if err != nil {
+		return args.Error(0)
+	}
+	return nil
+}
+
+func (r *UserRepositoryMock) ConfirmPendingEmail(id string, email string, verifiedAt time.Time) (bool, error) {
+	args := r.Mock.Called(id, email, verifiedAt)
+	err := args.Error(1)
+	if err != nil {
+		return false, err
+	}
+	return args.Bool(0), nil
+}
+
+func (r *UserRepositoryMock) FindMany(users *[]entity.User, search string, offset int, limit int) error {
+	args := r.Mock.Called(users, search, offset, limit)
+	err := args.Error(0)
+	if err != nil {
+		return args.Error(0)
+	}
+	return nil
+}
+
+func (r *UserRepositoryMock) Count(search string) (int64, error) {
+	args := r.Mock.Called(search)
+	err := args.Error(1)
+	if err != nil {
+		return -1, err
+	}
+	return args.Get(0).(int64), nil
+}
+
+func (r *UserRepositoryMock) UpdateDisabledAt(id string, disabledAt *time.Time) error {
+	args := r.Mock.Called(id, disabledAt)
+	err := args.Error(0)
+	if err != nil {
+		return args.Error(0)
+	}
+	return nil
+}
+
+func (r *UserRepositoryMock) IsDisabled(id string) (bool, error) {
+	args := r.Mock.Called(id)
+	err := args.Error(1)
+	if err != nil {
+		return false, err
+	}
+	return args.Bool(0), nil
+}
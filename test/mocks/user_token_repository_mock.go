@@ -0,0 +1,64 @@
+package mocks
+
+import (
+	"github.com/stretchr/testify/mock"
+	"go-crud/internal/entity"
+)
+
+type UserTokenRepositoryMock struct {
+	Mock mock.Mock
+}
+
+func NewUserTokenRepositoryMock() *UserTokenRepositoryMock {
+	return &UserTokenRepositoryMock{
+		Mock: mock.Mock{},
+	}
+}
+
+func (r *UserTokenRepositoryMock) Save(token *entity.UserToken) error {
+	args := r.Mock.Called(token)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *UserTokenRepositoryMock) FindOneByHash(hash string, purpose string) (*entity.UserToken, error) {
+	args := r.Mock.Called(hash, purpose)
+	err := args.Error(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return args.Get(0).(*entity.UserToken), nil
+}
+
+func (r *UserTokenRepositoryMock) MarkAsUsed(id string) (bool, error) {
+	args := r.Mock.Called(id)
+	err := args.Error(1)
+	if err != nil {
+		return false, err
+	}
+
+	return args.Bool(0), nil
+}
+
+func (r *UserTokenRepositoryMock) InvalidateByUserId(userID string, purpose string) error {
+	args := r.Mock.Called(userID, purpose)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *UserTokenRepositoryMock) FindLatestByUserId(userID string, purpose string) (*entity.UserToken, error) {
+	args := r.Mock.Called(userID, purpose)
+	err := args.Error(1)
+	if err != nil {
+		return nil, err
+	}
+
+	return args.Get(0).(*entity.UserToken), nil
+}
@@ -0,0 +1,388 @@
+package test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go-crud/internal/delivery/http/controllers"
+	"go-crud/internal/delivery/http/middleware"
+	"go-crud/internal/entity"
+	"go-crud/internal/helper"
+	"go-crud/internal/models"
+	"go-crud/internal/usecase"
+	"go-crud/test/mocks"
+	"gorm.io/gorm"
+	"io"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOAuth(t *testing.T) {
+	clientRepository := mocks.NewOAuthClientRepositoryMock()
+	codeRepository := mocks.NewOAuthAuthorizationCodeRepositoryMock()
+	sessionRepository := mocks.NewSessionRepositoryMock()
+	authUsecase := usecase.NewAuthUsecase(userRepositoryMock, refreshTokenRepositoryMock, sessionRepository, revokedAccessTokenRepositoryMock, loginAttemptUsecase, auditLogUsecase, passwordHasher, keySet, validate, viperConfig, log)
+	oauthUsecase := usecase.NewOAuthUsecase(clientRepository, codeRepository, sessionRepository, authUsecase, validate, viperConfig, log)
+	refreshTokenRepositoryMock.Mock.On("Save", mock.Anything).Return(nil)
+
+	// Sessions are kept by id so tokens issued in a test can be verified.
+	sessions := make(map[string]*entity.Session)
+	sessionRepository.Mock.On("Save", mock.Anything).Run(func(args mock.Arguments) {
+		session := args.Get(0).(*entity.Session)
+		sessions[session.Id] = session
+	}).Return(nil)
+	sessionRepository.Mock.On("Touch", mock.Anything, mock.Anything).Return(nil)
+
+	secret := usecase.OAuthClientSecretPrefix + "partner-secret"
+	partner := &entity.OAuthClient{
+		Id:           "partner-app",
+		UserId:       "partner-owner",
+		Name:         "Partner",
+		SecretHash:   helper.HashToken(secret),
+		RedirectUris: "https://partner.example/callback",
+		Scopes:       models.ScopeProductsRead + " " + models.ScopeProductsWrite,
+	}
+	mobile := &entity.OAuthClient{
+		Id:           "mobile-app",
+		UserId:       "partner-owner",
+		Name:         "Mobile",
+		RedirectUris: "com.partner.app:/callback",
+		Scopes:       models.ScopeProductsRead,
+	}
+	revokedAt := time.Now()
+	clientRepository.Mock.On("FindOneById", "partner-app").Return(partner, nil)
+	clientRepository.Mock.On("FindOneById", "mobile-app").Return(mobile, nil)
+	clientRepository.Mock.On("FindOneById", "revoked-app").Return(&entity.OAuthClient{Id: "revoked-app", RevokedAt: &revokedAt}, nil)
+	clientRepository.Mock.On("FindOneById", "unknown-app").Return(nil, gorm.ErrRecordNotFound)
+
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+	authorizeRequest := func(clientID string, redirectUri string) models.AuthorizeRequest {
+		return models.AuthorizeRequest{
+			ResponseType:        "code",
+			ClientId:            clientID,
+			RedirectUri:         redirectUri,
+			State:               "xyz",
+			CodeChallenge:       challenge,
+			CodeChallengeMethod: "S256",
+		}
+	}
+
+	// authorize approves a request of the partner app and returns the code
+	// handed back to it, keeping the stored code for the exchange.
+	authorize := func(t *testing.T, userID string, scope string) string {
+		var saved *entity.OAuthAuthorizationCode
+		codeRepository.Mock.On("Save", mock.MatchedBy(func(code *entity.OAuthAuthorizationCode) bool {
+			return code.UserId == userID
+		})).Run(func(args mock.Arguments) {
+			saved = args.Get(0).(*entity.OAuthAuthorizationCode)
+		}).Return(nil).Once()
+
+		request := authorizeRequest("partner-app", "https://partner.example/callback")
+		request.Scope = scope
+		result, err := oauthUsecase.Decide(userID, []string{"pwd"}, &models.AuthorizeDecisionRequest{AuthorizeRequest: request, Approved: true})
+		require.Nil(t, err)
+
+		redirect, err := url.Parse(result.RedirectUri)
+		require.Nil(t, err)
+		require.Equal(t, "partner.example", redirect.Host)
+		require.Equal(t, "xyz", redirect.Query().Get("state"))
+		code := redirect.Query().Get("code")
+		require.NotEmpty(t, code)
+		require.Equal(t, helper.HashToken(code), saved.CodeHash)
+		codeRepository.Mock.On("FindOneByHash", saved.CodeHash).Return(saved, nil)
+		return code
+	}
+
+	t.Run("Register client", func(t *testing.T) {
+		t.Run("Should return the secret of a confidential client once", func(t *testing.T) {
+			clientRepository.Mock.On("Save", mock.MatchedBy(func(client *entity.OAuthClient) bool {
+				return client.Name == "Confidential"
+			})).Return(nil)
+
+			result, err := oauthUsecase.CreateClient("partner-owner", &models.CreateOAuthClientRequest{
+				Name:         "Confidential",
+				RedirectUris: []string{"https://partner.example/callback"},
+				Scopes:       []string{models.ScopeProductsRead},
+			})
+			require.Nil(t, err)
+			require.True(t, strings.HasPrefix(result.Secret, usecase.OAuthClientSecretPrefix))
+			require.False(t, result.Public)
+			clientRepository.Mock.AssertCalled(t, "Save", mock.MatchedBy(func(client *entity.OAuthClient) bool {
+				return client.SecretHash == helper.HashToken(result.Secret)
+			}))
+		})
+
+		t.Run("Should give no secret to a public client", func(t *testing.T) {
+			clientRepository.Mock.On("Save", mock.MatchedBy(func(client *entity.OAuthClient) bool {
+				return client.Name == "Public"
+			})).Return(nil)
+
+			result, err := oauthUsecase.CreateClient("partner-owner", &models.CreateOAuthClientRequest{
+				Name:         "Public",
+				RedirectUris: []string{"com.partner.app:/callback"},
+				Scopes:       []string{models.ScopeProductsRead},
+				Public:       true,
+			})
+			require.Nil(t, err)
+			require.Empty(t, result.Secret)
+			require.True(t, result.Public)
+		})
+
+		t.Run("Should reject a redirect URI with a fragment", func(t *testing.T) {
+			result, err := oauthUsecase.CreateClient("partner-owner", &models.CreateOAuthClientRequest{
+				Name:         "Fragment",
+				RedirectUris: []string{"https://partner.example/callback#token"},
+				Scopes:       []string{models.ScopeProductsRead},
+			})
+			require.Nil(t, result)
+			require.Equal(t, 400, err.(*models.ErrorResponse).Code)
+		})
+	})
+
+	t.Run("Authorize", func(t *testing.T) {
+		t.Run("Should describe the consent", func(t *testing.T) {
+			request := authorizeRequest("partner-app", "https://partner.example/callback")
+			request.Scope = models.ScopeProductsRead
+			result, err := oauthUsecase.Authorize(&request)
+			require.Nil(t, err)
+			require.Equal(t, "Partner", result.ClientName)
+			require.Equal(t, []string{models.ScopeProductsRead}, result.Scopes)
+		})
+
+		t.Run("Should reject an unregistered redirect URI", func(t *testing.T) {
+			request := authorizeRequest("partner-app", "https://attacker.example/callback")
+			result, err := oauthUsecase.Authorize(&request)
+			require.Nil(t, result)
+			require.Equal(t, &models.ErrorResponse{Code: 400, Message: "Redirect URI is not registered for this client", Status: "Bad Request"}, err)
+		})
+
+		t.Run("Should reject unknown and revoked clients", func(t *testing.T) {
+			for _, clientID := range []string{"unknown-app", "revoked-app"} {
+				request := authorizeRequest(clientID, "https://partner.example/callback")
+				_, err := oauthUsecase.Authorize(&request)
+				require.Equal(t, "Unknown client", err.Error())
+			}
+		})
+
+		t.Run("Should require PKCE with S256", func(t *testing.T) {
+			request := authorizeRequest("partner-app", "https://partner.example/callback")
+			request.CodeChallenge = ""
+			_, err := oauthUsecase.Authorize(&request)
+			require.Equal(t, "PKCE code challenge is required", err.Error())
+
+			request = authorizeRequest("partner-app", "https://partner.example/callback")
+			request.CodeChallengeMethod = "plain"
+			_, err = oauthUsecase.Authorize(&request)
+			require.Equal(t, "Only the S256 code challenge method is supported", err.Error())
+		})
+
+		t.Run("Should reject a scope the client wasn't registered with", func(t *testing.T) {
+			request := authorizeRequest("mobile-app", "com.partner.app:/callback")
+			request.Scope = models.ScopeProductsWrite
+			_, err := oauthUsecase.Authorize(&request)
+			require.Equal(t, "Scope products:write is not allowed for this client", err.Error())
+		})
+
+		t.Run("Should send the user back with access_denied when they refuse", func(t *testing.T) {
+			result, err := oauthUsecase.Decide("consent-user", []string{"pwd"}, &models.AuthorizeDecisionRequest{
+				AuthorizeRequest: authorizeRequest("partner-app", "https://partner.example/callback"),
+			})
+			require.Nil(t, err)
+			require.Equal(t, "https://partner.example/callback?error=access_denied&state=xyz", result.RedirectUri)
+		})
+	})
+
+	t.Run("Authorization code grant", func(t *testing.T) {
+		t.Run("Should issue tokens limited to the granted scopes", func(t *testing.T) {
+			code := authorize(t, "code-user", models.ScopeProductsRead)
+			codeRepository.Mock.On("MarkAsUsed", mock.Anything).Return(true, nil).Once()
+
+			result, err := oauthUsecase.Token(&models.TokenRequest{
+				GrantType:    usecase.GrantTypeAuthorizationCode,
+				Code:         code,
+				RedirectUri:  "https://partner.example/callback",
+				CodeVerifier: verifier,
+				ClientId:     "partner-app",
+				ClientSecret: secret,
+			})
+			require.Nil(t, err)
+			require.Equal(t, "Bearer", result.TokenType)
+			require.Equal(t, 3600, result.ExpiresIn)
+			require.Equal(t, models.ScopeProductsRead, result.Scope)
+			require.NotEmpty(t, result.RefreshToken)
+
+			sessionRepository.Mock.On("FindOneById", mock.Anything).Return(&entity.Session{}, nil).Once()
+			claims, err := authUsecase.VerifyAccessToken(result.AccessToken)
+			require.Nil(t, err)
+			require.Equal(t, "code-user", claims.Subject)
+			require.Equal(t, "partner-app", claims.ClientId)
+			require.Equal(t, []string{models.ScopeProductsRead}, claims.Scopes)
+			require.Empty(t, claims.Roles)
+			require.Equal(t, "Partner", sessions[claims.SessionId].UserAgent)
+		})
+
+		t.Run("Should reject a wrong code verifier", func(t *testing.T) {
+			code := authorize(t, "verifier-user", "")
+			codeRepository.Mock.On("MarkAsUsed", mock.Anything).Return(true, nil).Once()
+
+			result, err := oauthUsecase.Token(&models.TokenRequest{
+				GrantType:    usecase.GrantTypeAuthorizationCode,
+				Code:         code,
+				RedirectUri:  "https://partner.example/callback",
+				CodeVerifier: "another-verifier-another-verifier-another-v",
+				ClientId:     "partner-app",
+				ClientSecret: secret,
+			})
+			require.Nil(t, result)
+			require.Equal(t, &models.OAuthError{Code: 400, Err: "invalid_grant", Description: "Code verifier doesn't match the code challenge"}, err)
+		})
+
+		t.Run("Should reject a code used twice", func(t *testing.T) {
+			code := authorize(t, "reuse-user", "")
+			codeRepository.Mock.On("MarkAsUsed", mock.Anything).Return(false, nil).Once()
+
+			_, err := oauthUsecase.Token(&models.TokenRequest{
+				GrantType:    usecase.GrantTypeAuthorizationCode,
+				Code:         code,
+				RedirectUri:  "https://partner.example/callback",
+				CodeVerifier: verifier,
+				ClientId:     "partner-app",
+				ClientSecret: secret,
+			})
+			require.Equal(t, "invalid_grant", err.(*models.OAuthError).Err)
+		})
+
+		t.Run("Should reject a wrong client secret", func(t *testing.T) {
+			_, err := oauthUsecase.Token(&models.TokenRequest{
+				GrantType:    usecase.GrantTypeAuthorizationCode,
+				ClientId:     "partner-app",
+				ClientSecret: "wrong",
+			})
+			require.Equal(t, &models.OAuthError{Code: 401, Err: "invalid_client", Description: "Client authentication failed"}, err)
+		})
+	})
+
+	t.Run("Refresh token grant rejects another client", func(t *testing.T) {
+		clientID := "partner-app"
+		refreshToken, err := authUsecase.GenerateRefreshToken("refresh-user", "oauth-family")
+		require.Nil(t, err)
+		stored := &entity.RefreshToken{Id: "oauth-refresh", UserId: "refresh-user", FamilyId: "oauth-family"}
+		refreshTokenRepositoryMock.Mock.On("FindOneByHash", helper.HashToken(refreshToken)).Return(stored, nil)
+		refreshTokenRepositoryMock.Mock.On("MarkAsUsed", "oauth-refresh").Return(true, nil)
+		refreshTokenRepositoryMock.Mock.On("RevokeFamily", "oauth-family").Return(nil)
+		sessionRepository.Mock.On("FindOneById", "oauth-family").Return(&entity.Session{Id: "oauth-family", UserId: "refresh-user", ClientId: &clientID}, nil)
+
+		_, err = oauthUsecase.Token(&models.TokenRequest{
+			GrantType:    usecase.GrantTypeRefreshToken,
+			RefreshToken: refreshToken,
+			ClientId:     "mobile-app",
+		})
+		require.Equal(t, "invalid_grant", err.(*models.OAuthError).Err)
+		refreshTokenRepositoryMock.Mock.AssertCalled(t, "RevokeFamily", "oauth-family")
+	})
+
+	t.Run("Client credentials grant", func(t *testing.T) {
+		t.Run("Should act for the owner of a confidential client", func(t *testing.T) {
+			result, err := oauthUsecase.Token(&models.TokenRequest{
+				GrantType:    usecase.GrantTypeClientCredentials,
+				Scope:        models.ScopeProductsWrite,
+				ClientId:     "partner-app",
+				ClientSecret: secret,
+			})
+			require.Nil(t, err)
+			require.Empty(t, result.RefreshToken)
+			require.Equal(t, models.ScopeProductsWrite, result.Scope)
+
+			sessionRepository.Mock.On("FindOneById", mock.Anything).Return(&entity.Session{}, nil).Once()
+			claims, err := authUsecase.VerifyAccessToken(result.AccessToken)
+			require.Nil(t, err)
+			require.Equal(t, "partner-owner", claims.Subject)
+		})
+
+		t.Run("Should refuse public clients", func(t *testing.T) {
+			_, err := oauthUsecase.Token(&models.TokenRequest{
+				GrantType: usecase.GrantTypeClientCredentials,
+				ClientId:  "mobile-app",
+			})
+			require.Equal(t, "unauthorized_client", err.(*models.OAuthError).Err)
+		})
+	})
+
+	t.Run("Token endpoint speaks OAuth", func(t *testing.T) {
+		oauthController := controllers.NewOAuthController(log, oauthUsecase)
+		app := fiber.New()
+		app.Post("/oauth/token", oauthController.Token)
+
+		form := url.Values{"grant_type": {usecase.GrantTypeClientCredentials}}
+		request := httptest.NewRequest("POST", "/oauth/token", strings.NewReader(form.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		request.SetBasicAuth("partner-app", secret)
+		response, err := app.Test(request)
+		require.Nil(t, err)
+		require.Equal(t, 200, response.StatusCode)
+		require.Equal(t, "no-store", response.Header.Get("Cache-Control"))
+		token := new(models.TokenResponse)
+		require.Nil(t, json.NewDecoder(response.Body).Decode(token))
+		require.Equal(t, "Bearer", token.TokenType)
+
+		request = httptest.NewRequest("POST", "/oauth/token", strings.NewReader(form.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		request.SetBasicAuth("partner-app", "wrong")
+		response, err = app.Test(request)
+		require.Nil(t, err)
+		require.Equal(t, 401, response.StatusCode)
+		require.NotEmpty(t, response.Header.Get("WWW-Authenticate"))
+		body, err := io.ReadAll(response.Body)
+		require.Nil(t, err)
+		require.JSONEq(t, `{"error":"invalid_client","error_description":"Client authentication failed"}`, string(body))
+	})
+
+	t.Run("Revoking a client signs it out everywhere", func(t *testing.T) {
+		clientRepository.Mock.On("Revoke", "partner-app", "partner-owner").Return(true, nil)
+		sessionRepository.Mock.On("RevokeAllByClientId", "partner-app").Return(nil)
+
+		err := oauthUsecase.RevokeClient("partner-owner", "partner-app")
+		require.Nil(t, err)
+		sessionRepository.Mock.AssertCalled(t, "RevokeAllByClientId", "partner-app")
+	})
+
+	t.Run("Middleware enforces the scopes of third-party applications", func(t *testing.T) {
+		result, err := oauthUsecase.Token(&models.TokenRequest{
+			GrantType:    usecase.GrantTypeClientCredentials,
+			Scope:        models.ScopeProductsRead,
+			ClientId:     "partner-app",
+			ClientSecret: secret,
+		})
+		require.Nil(t, err)
+		sessionRepository.Mock.On("FindOneById", mock.Anything).Return(&entity.Session{}, nil)
+
+		authMiddleware := middleware.NewAuthMiddleware(authUsecase, usecase.NewApiKeyUsecase(apiKeyRepositoryMock, validate, log), auditLogUsecase, log)
+		app := fiber.New()
+		ok := func(ctx *fiber.Ctx) error {
+			return ctx.SendString(ctx.Locals("user_id").(string))
+		}
+		app.Get("/read", authMiddleware.Auth, authMiddleware.RequireScope(models.ScopeProductsRead), ok)
+		app.Post("/write", authMiddleware.Auth, authMiddleware.RequireScope(models.ScopeProductsWrite), ok)
+		app.Get("/account", authMiddleware.Auth, authMiddleware.RequireSession, ok)
+
+		for path, status := range map[string]int{"/read": 200, "/write": 403, "/account": 403} {
+			method := "GET"
+			if path == "/write" {
+				method = "POST"
+			}
+			request := httptest.NewRequest(method, path, nil)
+			request.Header.Set("Authorization", "Bearer "+result.AccessToken)
+			response, err := app.Test(request)
+			require.Nil(t, err)
+			require.Equal(t, status, response.StatusCode, path)
+		}
+	})
+}
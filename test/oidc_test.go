@@ -0,0 +1,364 @@
+package test
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go-crud/internal/delivery/http/controllers"
+	"go-crud/internal/delivery/http/routes"
+	"go-crud/internal/entity"
+	"go-crud/internal/models"
+	"go-crud/internal/oidc"
+	"go-crud/internal/usecase"
+	"go-crud/test/mocks"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const oidcRedirectUrl = "http://localhost:8080/auth/oidc/callback"
+
+func newOidcProvider(fake *mocks.OidcProviderFake) *oidc.Provider {
+	return oidc.New(oidc.Config{
+		Issuer:       fake.Issuer,
+		ClientId:     fake.ClientId,
+		ClientSecret: fake.ClientSecret,
+		RedirectUrl:  oidcRedirectUrl,
+		Scopes:       []string{"openid", "email", "profile"},
+	}, fake.Server.Client())
+}
+
+// followAuthorization plays the browser: it visits the authorization URL of
+// the fake provider and returns the callback it gets redirected to.
+func followAuthorization(t *testing.T, authorizationUrl string) *url.URL {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	response, err := client.Get(authorizationUrl)
+	require.Nil(t, err)
+	defer response.Body.Close()
+	require.Equal(t, http.StatusFound, response.StatusCode)
+
+	callback, err := url.Parse(response.Header.Get("Location"))
+	require.Nil(t, err)
+	require.True(t, strings.HasPrefix(callback.String(), oidcRedirectUrl))
+	return callback
+}
+
+func oidcCallback(t *testing.T, oidcUsecase *usecase.OidcUsecase) *models.OidcCallbackRequest {
+	login, err := oidcUsecase.Login()
+	require.Nil(t, err)
+	callback := followAuthorization(t, login.AuthorizationUrl)
+
+	return &models.OidcCallbackRequest{
+		Code:       callback.Query().Get("code"),
+		State:      callback.Query().Get("state"),
+		StateToken: login.StateToken,
+	}
+}
+
+func TestOidc(t *testing.T) {
+	fake := mocks.NewOidcProviderFake("go-crud", "fake-secret")
+	defer fake.Close()
+
+	userRepository := mocks.NewRepositoryMock()
+	identityRepository := mocks.NewUserIdentityRepositoryMock()
+	sessionRepository := mocks.NewSessionRepositoryMock()
+	refreshTokenRepository := mocks.NewRefreshTokenRepositoryMock()
+	sessionRepository.Mock.On("Save", mock.Anything).Return(nil)
+	refreshTokenRepository.Mock.On("Save", mock.Anything).Return(nil)
+	userRepository.Mock.On("FindRolesByUserId", mock.Anything).Return([]entity.Role{}, nil)
+
+	authUsecase := usecase.NewAuthUsecase(userRepository, refreshTokenRepository, sessionRepository, revokedAccessTokenRepositoryMock, loginAttemptUsecase, auditLogUsecase, passwordHasher, keySet, validate, viperConfig, log)
+	provider := newOidcProvider(fake)
+	oidcUsecase := usecase.NewOidcUsecase(provider, userRepository, identityRepository, authUsecase, validate, viperConfig, log)
+
+	verifiedAt := time.Now()
+	noIdentity := func(subject string) {
+		identityRepository.Mock.On("FindOneBySubject", fake.Issuer, subject).Return(nil, gorm.ErrRecordNotFound)
+	}
+	linksIdentity := func(subject string, userID string) {
+		identityRepository.Mock.On("Save", mock.MatchedBy(func(identity *entity.UserIdentity) bool {
+			return identity.Subject == subject && identity.UserId == userID
+		})).Return(nil)
+	}
+	sessionStartedWith := func(userID string, methods string) {
+		sessionRepository.Mock.AssertCalled(t, "Save", mock.MatchedBy(func(session *entity.Session) bool {
+			return session.UserId == userID && session.Methods == methods
+		}))
+	}
+
+	t.Run("Login", func(t *testing.T) {
+		login, err := oidcUsecase.Login()
+		require.Nil(t, err)
+		require.True(t, strings.HasPrefix(login.AuthorizationUrl, fake.Issuer+"/authorize?"))
+
+		authorizationUrl, err := url.Parse(login.AuthorizationUrl)
+		require.Nil(t, err)
+		query := authorizationUrl.Query()
+		require.Equal(t, "code", query.Get("response_type"))
+		require.Equal(t, "go-crud", query.Get("client_id"))
+		require.Equal(t, oidcRedirectUrl, query.Get("redirect_uri"))
+		require.Equal(t, "openid email profile", query.Get("scope"))
+		require.Equal(t, "S256", query.Get("code_challenge_method"))
+		require.Len(t, query.Get("code_challenge"), 43)
+		require.NotEmpty(t, query.Get("state"))
+		require.NotEmpty(t, query.Get("nonce"))
+
+		t.Run("Should keep the state token from being used as an access token", func(t *testing.T) {
+			result, err := authUsecase.VerifyAccessToken(login.StateToken)
+			require.Nil(t, result)
+			require.Equal(t, 401, err.(*models.ErrorResponse).Code)
+		})
+	})
+
+	t.Run("Callback", func(t *testing.T) {
+		t.Run("Should link the account with the same verified email", func(t *testing.T) {
+			fake.SignInAs(jwt.MapClaims{"sub": "link-subject", "email": "oidc-link@gmail.com", "email_verified": true})
+			noIdentity("link-subject")
+			userRepository.Mock.On("FindOneByEmail", "oidc-link@gmail.com").Return(&entity.User{Id: "oidc-link-user", Email: "oidc-link@gmail.com", EmailVerifiedAt: &verifiedAt}, nil)
+			linksIdentity("link-subject", "oidc-link-user")
+
+			result, err := oidcUsecase.Callback(oidcCallback(t, oidcUsecase))
+			require.Nil(t, err)
+			require.NotEmpty(t, result.AccessToken)
+			require.NotEmpty(t, result.RefreshToken)
+			sessionStartedWith("oidc-link-user", "oidc")
+		})
+
+		t.Run("Should sign in a linked identity even after the email changed", func(t *testing.T) {
+			fake.SignInAs(jwt.MapClaims{"sub": "known-subject", "email": "renamed@gmail.com", "email_verified": false})
+			identityRepository.Mock.On("FindOneBySubject", fake.Issuer, "known-subject").Return(&entity.UserIdentity{UserId: "oidc-known-user", Subject: "known-subject"}, nil)
+			userRepository.Mock.On("FindOneById", mock.Anything, "oidc-known-user").Return(nil).Run(func(args mock.Arguments) {
+				*args.Get(0).(*entity.User) = entity.User{Id: "oidc-known-user", Email: "old@gmail.com", EmailVerifiedAt: &verifiedAt}
+			})
+
+			result, err := oidcUsecase.Callback(oidcCallback(t, oidcUsecase))
+			require.Nil(t, err)
+			require.NotEmpty(t, result.AccessToken)
+			userRepository.Mock.AssertNotCalled(t, "FindOneByEmail", "renamed@gmail.com")
+		})
+
+		t.Run("Should provision a new account without a password", func(t *testing.T) {
+			fake.SignInAs(jwt.MapClaims{"sub": "new-subject", "email": "oidc-new@gmail.com", "email_verified": "true", "name": "Danar"})
+			noIdentity("new-subject")
+			userRepository.Mock.On("FindOneByEmail", "oidc-new@gmail.com").Return(nil, nil)
+			userRepository.Mock.On("Save", mock.MatchedBy(func(user *entity.User) bool {
+				return user.Email == "oidc-new@gmail.com"
+			})).Return(nil)
+			identityRepository.Mock.On("Save", mock.MatchedBy(func(identity *entity.UserIdentity) bool {
+				return identity.Subject == "new-subject"
+			})).Return(nil)
+
+			result, err := oidcUsecase.Callback(oidcCallback(t, oidcUsecase))
+			require.Nil(t, err)
+			require.NotEmpty(t, result.AccessToken)
+			userRepository.Mock.AssertCalled(t, "Save", mock.MatchedBy(func(user *entity.User) bool {
+				return user.Email == "oidc-new@gmail.com" && user.Name == "Danar" && user.Password == "" && user.EmailVerifiedAt != nil
+			}))
+		})
+
+		t.Run("Should take an unverified account away from whoever signed it up", func(t *testing.T) {
+			fake.SignInAs(jwt.MapClaims{"sub": "claim-subject", "email": "oidc-claim@gmail.com", "email_verified": true})
+			noIdentity("claim-subject")
+			userRepository.Mock.On("FindOneByEmail", "oidc-claim@gmail.com").Return(&entity.User{Id: "oidc-claim-user", Email: "oidc-claim@gmail.com", Password: "attacker-hash"}, nil)
+			userRepository.Mock.On("UpdatePassword", "oidc-claim-user", "").Return(nil)
+			userRepository.Mock.On("MarkEmailVerified", "oidc-claim-user", mock.Anything).Return(nil)
+			sessionRepository.Mock.On("RevokeAllByUserIdExcept", "oidc-claim-user", "").Return(nil)
+			refreshTokenRepository.Mock.On("RevokeAllByUserIdExcept", "oidc-claim-user", "").Return(nil)
+			linksIdentity("claim-subject", "oidc-claim-user")
+
+			result, err := oidcUsecase.Callback(oidcCallback(t, oidcUsecase))
+			require.Nil(t, err)
+			require.NotEmpty(t, result.AccessToken)
+			userRepository.Mock.AssertCalled(t, "UpdatePassword", "oidc-claim-user", "")
+			sessionRepository.Mock.AssertCalled(t, "RevokeAllByUserIdExcept", "oidc-claim-user", "")
+			refreshTokenRepository.Mock.AssertCalled(t, "RevokeAllByUserIdExcept", "oidc-claim-user", "")
+			userRepository.Mock.AssertCalled(t, "MarkEmailVerified", "oidc-claim-user", mock.Anything)
+		})
+
+		t.Run("Should refuse an email the provider hasn't verified", func(t *testing.T) {
+			fake.SignInAs(jwt.MapClaims{"sub": "unverified-subject", "email": "oidc-unverified@gmail.com", "email_verified": false})
+			noIdentity("unverified-subject")
+
+			result, err := oidcUsecase.Callback(oidcCallback(t, oidcUsecase))
+			require.Nil(t, result)
+			require.Equal(t, &models.ErrorResponse{Code: 403, Message: "Identity provider hasn't verified your email", Status: "Forbidden"}, err)
+			userRepository.Mock.AssertNotCalled(t, "FindOneByEmail", "oidc-unverified@gmail.com")
+		})
+
+		t.Run("Should ask for the local second factor", func(t *testing.T) {
+			fake.SignInAs(jwt.MapClaims{"sub": "totp-subject", "email": "oidc-totp@gmail.com", "email_verified": true})
+			noIdentity("totp-subject")
+			userRepository.Mock.On("FindOneByEmail", "oidc-totp@gmail.com").Return(&entity.User{Id: "oidc-totp-user", Email: "oidc-totp@gmail.com", EmailVerifiedAt: &verifiedAt, TotpEnabledAt: &verifiedAt}, nil)
+			linksIdentity("totp-subject", "oidc-totp-user")
+
+			result, err := oidcUsecase.Callback(oidcCallback(t, oidcUsecase))
+			require.Nil(t, err)
+			require.True(t, result.MfaRequired)
+			require.Empty(t, result.AccessToken)
+
+			userID, methods, _, err := authUsecase.VerifyMfaToken(result.MfaToken)
+			require.Nil(t, err)
+			require.Equal(t, "oidc-totp-user", userID)
+			require.Equal(t, []string{"oidc"}, methods)
+		})
+
+		t.Run("Should trust the second factor of the provider", func(t *testing.T) {
+			fake.SignInAs(jwt.MapClaims{"sub": "amr-subject", "email": "oidc-amr@gmail.com", "email_verified": true, "amr": []string{"pwd", "mfa"}})
+			noIdentity("amr-subject")
+			userRepository.Mock.On("FindOneByEmail", "oidc-amr@gmail.com").Return(&entity.User{Id: "oidc-amr-user", Email: "oidc-amr@gmail.com", EmailVerifiedAt: &verifiedAt, TotpEnabledAt: &verifiedAt}, nil)
+			linksIdentity("amr-subject", "oidc-amr-user")
+
+			result, err := oidcUsecase.Callback(oidcCallback(t, oidcUsecase))
+			require.Nil(t, err)
+			require.NotEmpty(t, result.AccessToken)
+			sessionStartedWith("oidc-amr-user", "oidc mfa")
+		})
+
+		t.Run("Should reject a state that doesn't match the cookie", func(t *testing.T) {
+			request := oidcCallback(t, oidcUsecase)
+			other := oidcCallback(t, oidcUsecase)
+			request.StateToken = other.StateToken
+
+			result, err := oidcUsecase.Callback(request)
+			require.Nil(t, result)
+			require.Equal(t, 401, err.(*models.ErrorResponse).Code)
+		})
+
+		t.Run("Should reject a replayed code", func(t *testing.T) {
+			fake.SignInAs(jwt.MapClaims{"sub": "link-subject", "email": "oidc-link@gmail.com", "email_verified": true})
+			request := oidcCallback(t, oidcUsecase)
+			_, err := oidcUsecase.Callback(request)
+			require.Nil(t, err)
+
+			result, err := oidcUsecase.Callback(request)
+			require.Nil(t, result)
+			require.Equal(t, &models.ErrorResponse{Code: 401, Message: "Sign in with the identity provider failed", Status: "Unauthorized"}, err)
+		})
+
+		t.Run("Should reject an ID token for another sign in", func(t *testing.T) {
+			fake.SignInAs(jwt.MapClaims{"sub": "link-subject", "email": "oidc-link@gmail.com", "email_verified": true, "nonce": "someone-elses-nonce"})
+
+			result, err := oidcUsecase.Callback(oidcCallback(t, oidcUsecase))
+			require.Nil(t, result)
+			require.Equal(t, 401, err.(*models.ErrorResponse).Code)
+		})
+
+		t.Run("Should pass on a refusal of the provider", func(t *testing.T) {
+			result, err := oidcUsecase.Callback(&models.OidcCallbackRequest{Error: "access_denied"})
+			require.Nil(t, result)
+			require.Equal(t, &models.ErrorResponse{Code: 401, Message: "Sign in was refused by the identity provider", Status: "Unauthorized"}, err)
+		})
+	})
+
+	t.Run("Verify ID token", func(t *testing.T) {
+		valid := jwt.MapClaims{"sub": "subject", "nonce": "nonce"}
+		with := func(name string, value any) jwt.MapClaims {
+			claims := jwt.MapClaims{}
+			for key, claim := range valid {
+				claims[key] = claim
+			}
+			claims[name] = value
+			return claims
+		}
+
+		idToken, err := provider.VerifyIDToken(fake.SignIDToken(valid), "nonce")
+		require.Nil(t, err)
+		require.Equal(t, "subject", idToken.Subject)
+
+		for name, claims := range map[string]jwt.MapClaims{
+			"another issuer":   with("iss", "https://evil.example.com"),
+			"another audience": with("aud", "another-client"),
+			"expired":          with("exp", time.Now().Add(-time.Hour).Unix()),
+			"another nonce":    with("nonce", "other"),
+			"no subject":       with("sub", ""),
+			"unauthorized azp": with("aud", []string{"go-crud", "another-client"}),
+		} {
+			_, err := provider.VerifyIDToken(fake.SignIDToken(claims), "nonce")
+			require.ErrorIs(t, err, oidc.ErrInvalidIDToken, name)
+		}
+
+		t.Run("Should refuse an unsigned token", func(t *testing.T) {
+			unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, with("iss", fake.Issuer)).SignedString(jwt.UnsafeAllowNoneSignatureType)
+			require.Nil(t, err)
+
+			_, err = provider.VerifyIDToken(unsigned, "nonce")
+			require.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+		})
+
+		t.Run("Should throttle fetching the keys of the provider", func(t *testing.T) {
+			rotated := mocks.NewOidcProviderFake("go-crud", "fake-secret")
+			defer rotated.Close()
+			rotatedProvider := newOidcProvider(rotated)
+
+			_, err := rotatedProvider.VerifyIDToken(rotated.SignIDToken(valid), "nonce")
+			require.Nil(t, err)
+			require.Equal(t, 1, rotated.JwksHits())
+
+			// An unknown key only triggers a fetch once the interval since
+			// the last one has passed.
+			rotated.RotateKey("fake-2")
+			_, err = rotatedProvider.VerifyIDToken(rotated.SignIDToken(valid), "nonce")
+			require.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+			require.Equal(t, 1, rotated.JwksHits())
+		})
+	})
+
+	t.Run("Should report an unreachable provider", func(t *testing.T) {
+		down := mocks.NewOidcProviderFake("go-crud", "fake-secret")
+		down.Close()
+		downUsecase := usecase.NewOidcUsecase(newOidcProvider(down), userRepository, identityRepository, authUsecase, validate, viperConfig, log)
+
+		result, err := downUsecase.Login()
+		require.Nil(t, result)
+		require.Equal(t, &models.ErrorResponse{Code: 502, Message: "Identity provider is unavailable, please try again later", Status: "Bad Gateway"}, err)
+	})
+
+	t.Run("Controller", func(t *testing.T) {
+		app := fiber.New()
+		routes.NewOidcRoute(app, controllers.NewOidcController(log, oidcUsecase)).Setup()
+		fake.SignInAs(jwt.MapClaims{"sub": "link-subject", "email": "oidc-link@gmail.com", "email_verified": true})
+
+		response, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/auth/oidc/login", nil))
+		require.Nil(t, err)
+		require.Equal(t, fiber.StatusFound, response.StatusCode)
+		var stateCookie *http.Cookie
+		for _, cookie := range response.Cookies() {
+			if cookie.Name == "oidc_state" {
+				stateCookie = cookie
+			}
+		}
+		require.NotNil(t, stateCookie)
+		require.True(t, stateCookie.HttpOnly)
+
+		callback := followAuthorization(t, response.Header.Get(fiber.HeaderLocation))
+
+		request := httptest.NewRequest(fiber.MethodGet, "/auth/oidc/callback?"+callback.RawQuery, nil)
+		request.AddCookie(&http.Cookie{Name: "oidc_state", Value: stateCookie.Value})
+		response, err = app.Test(request)
+		require.Nil(t, err)
+		require.Equal(t, fiber.StatusOK, response.StatusCode)
+		cookies := map[string]string{}
+		for _, cookie := range response.Cookies() {
+			cookies[cookie.Name] = cookie.Value
+		}
+		require.NotEmpty(t, cookies["refresh_token"])
+		require.Empty(t, cookies["oidc_state"])
+
+		t.Run("Should refuse a callback without the state cookie", func(t *testing.T) {
+			response, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/auth/oidc/login", nil))
+			require.Nil(t, err)
+			callback := followAuthorization(t, response.Header.Get(fiber.HeaderLocation))
+
+			response, err = app.Test(httptest.NewRequest(fiber.MethodGet, "/auth/oidc/callback?"+callback.RawQuery, nil))
+			require.Nil(t, err)
+			require.Equal(t, fiber.StatusUnauthorized, response.StatusCode)
+		})
+	})
+}
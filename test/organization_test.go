@@ -0,0 +1,263 @@
+package test
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go-crud/internal/delivery/http/middleware"
+	"go-crud/internal/entity"
+	"go-crud/internal/helper"
+	"go-crud/internal/mail"
+	"go-crud/internal/models"
+	"go-crud/internal/repository"
+	"go-crud/internal/storage"
+	"go-crud/internal/usecase"
+	"go-crud/test/mocks"
+	"gorm.io/gorm"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestOrganization(t *testing.T) {
+	mailSender := mail.NewFileSender(t.TempDir())
+	organizationRepository := mocks.NewOrganizationRepositoryMock()
+	memberRepository := mocks.NewOrganizationMemberRepositoryMock()
+	invitationRepository := mocks.NewOrganizationInvitationRepositoryMock()
+	userRepository := mocks.NewRepositoryMock()
+	organizationProducts := mocks.NewProductRepositoryMock()
+	searchIndex := repository.NewMemoryProductSearchRepository()
+	organizationImages := mocks.NewProductImageRepositoryMock()
+	imageDirectory := t.TempDir()
+	imageStorage := storage.NewLocalStorage(imageDirectory, "http://localhost:8080/uploads")
+	productCleanup := usecase.NewProductUsecase(organizationProducts, searchIndex, memberRepository, mocks.NewCategoryRepositoryMock(), mocks.NewTagRepositoryMock(), organizationImages, imageStorage, validate, viperConfig, log)
+	organizationUsecase := usecase.NewOrganizationUsecase(organizationRepository, memberRepository, invitationRepository, userRepository, productCleanup, mailSender, validate, viperConfig, log)
+
+	organization := entity.Organization{Id: "org-catalog", Name: "Catalog Team"}
+	members := map[string]string{
+		"org-owner":  models.OrganizationRoleOwner,
+		"org-admin":  models.OrganizationRoleAdmin,
+		"org-member": models.OrganizationRoleMember,
+		"org-other":  models.OrganizationRoleMember,
+	}
+	for userID, role := range members {
+		memberRepository.Mock.On("FindOne", organization.Id, userID).Return(&entity.OrganizationMember{OrganizationId: organization.Id, UserId: userID, Role: role, Organization: organization}, nil)
+	}
+	// The invitee only becomes a member once their invitation is accepted.
+	memberRepository.Mock.On("FindOne", organization.Id, "org-invitee").Return(nil, gorm.ErrRecordNotFound).Once()
+	memberRepository.Mock.On("FindOne", organization.Id, "org-invitee").Return(&entity.OrganizationMember{OrganizationId: organization.Id, UserId: "org-invitee", Role: models.OrganizationRoleAdmin, Organization: organization}, nil)
+	memberRepository.Mock.On("FindOne", organization.Id, mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+	memberRepository.Mock.On("FindOne", mock.Anything, mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+	memberRepository.Mock.On("CountByRole", organization.Id, models.OrganizationRoleOwner).Return(int64(1), nil)
+
+	t.Run("Create organization makes the user its owner", func(t *testing.T) {
+		organizationRepository.Mock.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+		result, err := organizationUsecase.CreateOrganization("org-founder", &models.CreateOrganizationRequest{Name: "New Team"})
+		require.Nil(t, err)
+		require.Equal(t, "New Team", result.Name)
+		require.Equal(t, models.OrganizationRoleOwner, result.Role)
+		organizationRepository.Mock.AssertCalled(t, "Create", mock.MatchedBy(func(created *entity.Organization) bool {
+			return created.Id == result.Id
+		}), mock.MatchedBy(func(owner *entity.OrganizationMember) bool {
+			return owner.OrganizationId == result.Id && owner.UserId == "org-founder" && owner.Role == models.OrganizationRoleOwner
+		}))
+
+		_, err = organizationUsecase.CreateOrganization("org-founder", &models.CreateOrganizationRequest{})
+		require.Equal(t, "Name required", err.Error())
+	})
+
+	t.Run("Organizations are hidden from non-members", func(t *testing.T) {
+		result, err := organizationUsecase.GetOrganization("org-member", organization.Id)
+		require.Nil(t, err)
+		require.Equal(t, models.OrganizationRoleMember, result.Role)
+
+		_, err = organizationUsecase.GetOrganization("org-stranger", organization.Id)
+		require.Equal(t, &models.ErrorResponse{Code: 404, Message: "Organization not found", Status: "Not Found"}, err)
+
+		err = organizationUsecase.DeleteOrganization("org-admin", organization.Id)
+		require.Equal(t, 403, err.(*models.ErrorResponse).Code)
+	})
+
+	t.Run("Members", func(t *testing.T) {
+		t.Run("Only owners grant ownership", func(t *testing.T) {
+			err := organizationUsecase.UpdateMember("org-admin", organization.Id, "org-member", &models.UpdateOrganizationMemberRequest{Role: models.OrganizationRoleOwner})
+			require.Equal(t, &models.ErrorResponse{Code: 403, Message: "Only owners can grant or revoke ownership", Status: "Forbidden"}, err)
+
+			err = organizationUsecase.UpdateMember("org-member", organization.Id, "org-other", &models.UpdateOrganizationMemberRequest{Role: models.OrganizationRoleAdmin})
+			require.Equal(t, &models.ErrorResponse{Code: 403, Message: "You're not allowed to manage this organization", Status: "Forbidden"}, err)
+
+			memberRepository.Mock.On("UpdateRole", organization.Id, "org-member", models.OrganizationRoleAdmin).Return(nil).Once()
+			err = organizationUsecase.UpdateMember("org-admin", organization.Id, "org-member", &models.UpdateOrganizationMemberRequest{Role: models.OrganizationRoleAdmin})
+			require.Nil(t, err)
+			memberRepository.Mock.AssertCalled(t, "UpdateRole", organization.Id, "org-member", models.OrganizationRoleAdmin)
+		})
+
+		t.Run("Should validate the role", func(t *testing.T) {
+			err := organizationUsecase.UpdateMember("org-owner", organization.Id, "org-member", &models.UpdateOrganizationMemberRequest{Role: "superuser"})
+			require.Equal(t, "Role oneof owner admin member", err.Error())
+		})
+
+		t.Run("Should keep the last owner", func(t *testing.T) {
+			err := organizationUsecase.UpdateMember("org-owner", organization.Id, "org-owner", &models.UpdateOrganizationMemberRequest{Role: models.OrganizationRoleMember})
+			require.Equal(t, 409, err.(*models.ErrorResponse).Code)
+
+			err = organizationUsecase.RemoveMember("org-owner", organization.Id, "org-owner")
+			require.Equal(t, 409, err.(*models.ErrorResponse).Code)
+		})
+
+		t.Run("Members may only remove themselves", func(t *testing.T) {
+			err := organizationUsecase.RemoveMember("org-member", organization.Id, "org-other")
+			require.Equal(t, 403, err.(*models.ErrorResponse).Code)
+
+			err = organizationUsecase.RemoveMember("org-admin", organization.Id, "org-owner")
+			require.Equal(t, &models.ErrorResponse{Code: 403, Message: "Only owners can remove an owner", Status: "Forbidden"}, err)
+
+			memberRepository.Mock.On("Delete", organization.Id, "org-other").Return(nil).Once()
+			err = organizationUsecase.RemoveMember("org-other", organization.Id, "org-other")
+			require.Nil(t, err)
+			memberRepository.Mock.AssertCalled(t, "Delete", organization.Id, "org-other")
+		})
+	})
+
+	t.Run("Invitations", func(t *testing.T) {
+		t.Run("Should mail an invitation and store only its hash", func(t *testing.T) {
+			invitationRepository.Mock.On("Save", mock.Anything).Return(nil)
+
+			result, err := organizationUsecase.CreateInvitation("org-admin", organization.Id, &models.CreateInvitationRequest{Email: "invitee@gmail.com", Role: models.OrganizationRoleMember})
+			require.Nil(t, err)
+			require.Equal(t, "invitee@gmail.com", result.Email)
+			require.True(t, result.ExpiresAt.After(time.Now().Add(6*24*time.Hour)))
+
+			message, err := mailSender.Last("invitee@gmail.com")
+			require.Nil(t, err)
+			require.Contains(t, message, "Catalog Team")
+			token := regexp.MustCompile(`Invitation token: (\S+)`).FindStringSubmatch(message)[1]
+			invitationRepository.Mock.AssertCalled(t, "Save", mock.MatchedBy(func(saved *entity.OrganizationInvitation) bool {
+				return saved.OrganizationId == organization.Id &&
+					saved.Role == models.OrganizationRoleMember &&
+					saved.TokenHash == helper.HashToken(token) &&
+					*saved.InvitedBy == "org-admin"
+			}))
+		})
+
+		t.Run("Should only let admins invite, and owners invite owners", func(t *testing.T) {
+			_, err := organizationUsecase.CreateInvitation("org-member", organization.Id, &models.CreateInvitationRequest{Email: "invitee@gmail.com", Role: models.OrganizationRoleMember})
+			require.Equal(t, 403, err.(*models.ErrorResponse).Code)
+
+			_, err = organizationUsecase.CreateInvitation("org-admin", organization.Id, &models.CreateInvitationRequest{Email: "invitee@gmail.com", Role: models.OrganizationRoleOwner})
+			require.Equal(t, 403, err.(*models.ErrorResponse).Code)
+
+			_, err = organizationUsecase.CreateInvitation("org-stranger", organization.Id, &models.CreateInvitationRequest{Email: "invitee@gmail.com", Role: models.OrganizationRoleMember})
+			require.Equal(t, 404, err.(*models.ErrorResponse).Code)
+		})
+
+		t.Run("Should add the user who owns the email", func(t *testing.T) {
+			verifiedAt := time.Now()
+			invitationRepository.Mock.On("FindOneByHash", helper.HashToken("join-token")).Return(&entity.OrganizationInvitation{Id: "join-invitation", OrganizationId: organization.Id, Email: "Invitee@gmail.com", Role: models.OrganizationRoleAdmin, ExpiresAt: time.Now().Add(time.Hour)}, nil)
+			invitationRepository.Mock.On("MarkAsAccepted", "join-invitation").Return(true, nil)
+			userRepository.Mock.On("FindOneById", mock.Anything, "org-invitee").Return(nil).Run(func(args mock.Arguments) {
+				*args.Get(0).(*entity.User) = entity.User{Id: "org-invitee", Email: "invitee@gmail.com", EmailVerifiedAt: &verifiedAt}
+			})
+			userRepository.Mock.On("FindOneById", mock.Anything, "org-impostor").Return(nil).Run(func(args mock.Arguments) {
+				*args.Get(0).(*entity.User) = entity.User{Id: "org-impostor", Email: "impostor@gmail.com", EmailVerifiedAt: &verifiedAt}
+			})
+			userRepository.Mock.On("FindOneById", mock.Anything, "org-member").Return(nil).Run(func(args mock.Arguments) {
+				*args.Get(0).(*entity.User) = entity.User{Id: "org-member", Email: "invitee@gmail.com", EmailVerifiedAt: &verifiedAt}
+			})
+
+			_, err := organizationUsecase.AcceptInvitation("org-impostor", &models.AcceptInvitationRequest{Token: "join-token"})
+			require.Equal(t, 403, err.(*models.ErrorResponse).Code)
+
+			_, err = organizationUsecase.AcceptInvitation("org-member", &models.AcceptInvitationRequest{Token: "join-token"})
+			require.Equal(t, &models.ErrorResponse{Code: 409, Message: "You're already a member of this organization", Status: "Conflict"}, err)
+
+			memberRepository.Mock.On("Save", mock.Anything).Return(nil)
+			result, err := organizationUsecase.AcceptInvitation("org-invitee", &models.AcceptInvitationRequest{Token: "join-token"})
+			require.Nil(t, err)
+			require.Equal(t, organization.Id, result.Id)
+			require.Equal(t, models.OrganizationRoleAdmin, result.Role)
+			invitationRepository.Mock.AssertCalled(t, "MarkAsAccepted", "join-invitation")
+			memberRepository.Mock.AssertCalled(t, "Save", mock.MatchedBy(func(member *entity.OrganizationMember) bool {
+				return member.OrganizationId == organization.Id && member.UserId == "org-invitee" && member.Role == models.OrganizationRoleAdmin
+			}))
+		})
+
+		t.Run("Should reject an expired invitation", func(t *testing.T) {
+			invitationRepository.Mock.On("FindOneByHash", helper.HashToken("expired-invitation")).Return(&entity.OrganizationInvitation{Id: "expired-invitation", OrganizationId: organization.Id, ExpiresAt: time.Now().Add(-time.Minute)}, nil)
+			_, err := organizationUsecase.AcceptInvitation("org-invitee", &models.AcceptInvitationRequest{Token: "expired-invitation"})
+			require.Equal(t, &models.ErrorResponse{Code: 400, Message: "Invitation is invalid or expired", Status: "Bad Request"}, err)
+		})
+	})
+
+	t.Run("Products", func(t *testing.T) {
+		productRepository := mocks.NewProductRepositoryMock()
+		organizationID := organization.Id
+
+		t.Run("Should only create products for organizations of the user", func(t *testing.T) {
+			productUsecase := usecase.NewProductUsecase(productRepository, repository.NewMemoryProductSearchRepository(), memberRepository, mocks.NewCategoryRepositoryMock(), mocks.NewTagRepositoryMock(), productImageRepositoryMock, fileStorage, validate, viperConfig, log)
+			productRepository.Mock.On("Save", mock.Anything).Return(nil)
+
+			_, err := productUsecase.CreateProduct(&models.ProductRequest{Name: "Shared", OrganizationId: organizationID}, "org-stranger")
+			require.Equal(t, &models.ErrorResponse{Code: 403, Message: "You're not a member of this organization", Status: "Forbidden"}, err)
+
+			result, err := productUsecase.CreateProduct(&models.ProductRequest{Name: "Shared", OrganizationId: organizationID}, "org-member")
+			require.Nil(t, err)
+			require.Equal(t, organizationID, result.OrganizationId)
+			productRepository.Mock.AssertCalled(t, "Save", mock.MatchedBy(func(product *entity.Product) bool {
+				return product.UserId == "org-member" && product.OrganizationId != nil && *product.OrganizationId == organizationID
+			}))
+		})
+
+		t.Run("ProductAuth authorizes by membership role", func(t *testing.T) {
+			authUsecase := usecase.NewAuthUsecase(userRepositoryMock, refreshTokenRepositoryMock, sessionRepositoryMock, revokedAccessTokenRepositoryMock, loginAttemptUsecase, auditLogUsecase, passwordHasher, keySet, validate, viperConfig, log)
+			authMiddleware := middleware.NewAuthMiddleware(authUsecase, usecase.NewApiKeyUsecase(apiKeyRepositoryMock, validate, log), auditLogUsecase, log)
+			productMiddleware := middleware.NewProductMiddleware(productRepository, memberRepository, auditLogUsecase, log)
+			productRepository.Mock.On("FindOneById", mock.Anything, "org-product").Return(nil).Run(func(args mock.Arguments) {
+				*args.Get(0).(*entity.Product) = entity.Product{Id: "org-product", UserId: "org-member", OrganizationId: &organizationID}
+			})
+
+			app := fiber.New()
+			app.Put("/products/:id", authMiddleware.Auth, productMiddleware.ProductAuth, func(ctx *fiber.Ctx) error {
+				return ctx.SendStatus(fiber.StatusOK)
+			})
+			send := func(userID string) int {
+				token, err := authUsecase.GenerateAccessToken(&models.AccessTokenClaims{Subject: userID})
+				require.Nil(t, err)
+				request := httptest.NewRequest(fiber.MethodPut, "/products/org-product", nil)
+				request.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+				response, err := app.Test(request)
+				require.Nil(t, err)
+				return response.StatusCode
+			}
+
+			require.Equal(t, 200, send("org-owner"))
+			require.Equal(t, 200, send("org-admin"))
+			require.Equal(t, 200, send("org-member"))
+			require.Equal(t, 403, send("org-other"))
+			require.Equal(t, 403, send("org-stranger"))
+		})
+
+		t.Run("Should clean up after the products of a deleted organization", func(t *testing.T) {
+			require.Nil(t, searchIndex.Index(&entity.Product{Id: "org-boots", Name: "Team boots"}))
+			require.Nil(t, searchIndex.Index(&entity.Product{Id: "other-boots", Name: "Other boots"}))
+			organizationProducts.Mock.On("FindIdsByOrganizationId", organizationID).Return([]string{"org-boots"}, nil)
+			teamImage := entity.ProductImage{Id: "team-image", ProductId: "org-boots", Variants: map[string]string{"original": "products/org-boots/team-image/original.png", "small": "products/org-boots/team-image/small.png"}}
+			for _, key := range teamImage.Variants {
+				require.Nil(t, imageStorage.Put(key, "image/png", []byte("content")))
+			}
+			organizationImages.Mock.On("FindManyByProductIds", []string{"org-boots"}).Return([]entity.ProductImage{teamImage}, nil)
+			organizationRepository.Mock.On("DeleteById", organizationID).Return(nil)
+
+			require.Nil(t, organizationUsecase.DeleteOrganization("org-owner", organizationID))
+			hits, total, err := searchIndex.Search([]string{"boots"}, 0, 10)
+			require.Nil(t, err)
+			require.Equal(t, int64(1), total)
+			require.Equal(t, "other-boots", hits[0].ProductId)
+			files, _ := filepath.Glob(filepath.Join(imageDirectory, "products", "org-boots", "*", "*"))
+			require.Empty(t, files)
+		})
+	})
+}
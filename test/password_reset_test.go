@@ -0,0 +1,112 @@
+package test
+
+import (
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go-crud/internal/entity"
+	"go-crud/internal/helper"
+	"go-crud/internal/mail"
+	"go-crud/internal/models"
+	"go-crud/internal/usecase"
+	"gorm.io/gorm"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestPasswordReset(t *testing.T) {
+	mailSender := mail.NewFileSender(t.TempDir())
+	passwordResetUsecase := usecase.NewPasswordResetUsecase(userRepositoryMock, userTokenRepositoryMock, sessionRepositoryMock, refreshTokenRepositoryMock, loginAttemptUsecase, auditLogUsecase, passwordHasher, mailSender, validate, viperConfig, log)
+
+	t.Run("Forgot password", func(t *testing.T) {
+		t.Run("Should mail a reset token and store only its hash", func(t *testing.T) {
+			user := &entity.User{Id: "reset-user", Name: "Danar", Email: "reset@gmail.com"}
+			userRepositoryMock.Mock.On("FindOneByEmail", "reset@gmail.com").Return(user, nil)
+			userTokenRepositoryMock.Mock.On("InvalidateByUserId", "reset-user", entity.UserTokenPasswordReset).Return(nil)
+			userTokenRepositoryMock.Mock.On("Save", mock.Anything).Return(nil).Once()
+
+			err := passwordResetUsecase.ForgotPassword(&models.ForgotPasswordRequest{Email: "reset@gmail.com"})
+			require.Nil(t, err)
+
+			message, err := mailSender.Last("reset@gmail.com")
+			require.Nil(t, err)
+			token := regexp.MustCompile(`Reset token: (\S+)`).FindStringSubmatch(message)[1]
+
+			userTokenRepositoryMock.Mock.AssertCalled(t, "Save", mock.MatchedBy(func(saved *entity.UserToken) bool {
+				return saved.UserId == "reset-user" &&
+					saved.Purpose == entity.UserTokenPasswordReset &&
+					saved.TokenHash == helper.HashToken(token) &&
+					saved.ExpiresAt.After(time.Now())
+			}))
+		})
+
+		t.Run("Should succeed without sending anything for unknown email", func(t *testing.T) {
+			userRepositoryMock.Mock.On("FindOneByEmail", "nobody@gmail.com").Return(nil, nil)
+			err := passwordResetUsecase.ForgotPassword(&models.ForgotPasswordRequest{Email: "nobody@gmail.com"})
+			require.Nil(t, err)
+
+			_, err = mailSender.Last("nobody@gmail.com")
+			require.NotNil(t, err)
+		})
+
+		t.Run("Should validate the email", func(t *testing.T) {
+			err := passwordResetUsecase.ForgotPassword(&models.ForgotPasswordRequest{Email: "invalid"})
+			require.Equal(t, &models.ErrorResponse{Code: 400, Message: "Email format is invalid", Status: "Bad Request"}, err)
+		})
+	})
+
+	t.Run("Reset password", func(t *testing.T) {
+		t.Run("Should update the password, revoke every session and unlock the account", func(t *testing.T) {
+			token := &entity.UserToken{Id: "valid-reset", UserId: "reset-user", Purpose: entity.UserTokenPasswordReset, ExpiresAt: time.Now().Add(time.Hour)}
+			userTokenRepositoryMock.Mock.On("FindOneByHash", helper.HashToken("valid-token"), entity.UserTokenPasswordReset).Return(token, nil)
+			userTokenRepositoryMock.Mock.On("MarkAsUsed", "valid-reset").Return(true, nil)
+			userRepositoryMock.Mock.On("UpdatePassword", "reset-user", mock.Anything).Return(nil)
+			sessionRepositoryMock.Mock.On("RevokeAllByUserId", "reset-user").Return(nil)
+			refreshTokenRepositoryMock.Mock.On("RevokeAllByUserId", "reset-user").Return(nil)
+			userRepositoryMock.Mock.On("FindOneById", mock.Anything, "reset-user").Return(nil).Run(func(args mock.Arguments) {
+				*args.Get(0).(*entity.User) = entity.User{Id: "reset-user", Email: "reset@gmail.com"}
+			})
+
+			for i := 0; i < viperConfig.GetInt("auth.lockout.max_attempts"); i++ {
+				loginAttemptUsecase.RegisterFailure("reset@gmail.com", "")
+			}
+			require.NotNil(t, loginAttemptUsecase.Check("reset@gmail.com", ""))
+
+			err := passwordResetUsecase.ResetPassword(&models.ResetPasswordRequest{Token: "valid-token", Password: "new-password"})
+			require.Nil(t, err)
+			require.Nil(t, loginAttemptUsecase.Check("reset@gmail.com", ""))
+
+			userRepositoryMock.Mock.AssertCalled(t, "UpdatePassword", "reset-user", mock.MatchedBy(func(hash string) bool {
+				return verified(hash, "new-password")
+			}))
+			sessionRepositoryMock.Mock.AssertCalled(t, "RevokeAllByUserId", "reset-user")
+			refreshTokenRepositoryMock.Mock.AssertCalled(t, "RevokeAllByUserId", "reset-user")
+		})
+
+		t.Run("Should reject an expired token", func(t *testing.T) {
+			token := &entity.UserToken{Id: "expired-reset", UserId: "reset-user", ExpiresAt: time.Now().Add(-time.Minute)}
+			userTokenRepositoryMock.Mock.On("FindOneByHash", helper.HashToken("expired-token"), entity.UserTokenPasswordReset).Return(token, nil)
+			err := passwordResetUsecase.ResetPassword(&models.ResetPasswordRequest{Token: "expired-token", Password: "new-password"})
+			require.Equal(t, &models.ErrorResponse{Code: 400, Message: "Reset token is invalid or expired", Status: "Bad Request"}, err)
+		})
+
+		t.Run("Should reject a token that was already used", func(t *testing.T) {
+			token := &entity.UserToken{Id: "raced-reset", UserId: "reset-user", ExpiresAt: time.Now().Add(time.Hour)}
+			userTokenRepositoryMock.Mock.On("FindOneByHash", helper.HashToken("raced-token"), entity.UserTokenPasswordReset).Return(token, nil)
+			userTokenRepositoryMock.Mock.On("MarkAsUsed", "raced-reset").Return(false, nil)
+			err := passwordResetUsecase.ResetPassword(&models.ResetPasswordRequest{Token: "raced-token", Password: "new-password"})
+			require.Equal(t, "Reset token is invalid or expired", err.Error())
+		})
+
+		t.Run("Should reject an unknown token", func(t *testing.T) {
+			userTokenRepositoryMock.Mock.On("FindOneByHash", helper.HashToken("unknown-token"), entity.UserTokenPasswordReset).Return(nil, gorm.ErrRecordNotFound)
+			err := passwordResetUsecase.ResetPassword(&models.ResetPasswordRequest{Token: "unknown-token", Password: "new-password"})
+			require.Equal(t, "Reset token is invalid or expired", err.Error())
+		})
+
+		t.Run("Should validate the new password", func(t *testing.T) {
+			err := passwordResetUsecase.ResetPassword(&models.ResetPasswordRequest{Token: "valid-token", Password: "short"})
+			require.Equal(t, "Password min 8 character", err.Error())
+		})
+	})
+}
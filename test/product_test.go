@@ -1,20 +1,28 @@
 package test
 
 import (
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"go-crud/internal/delivery/http/controllers"
 	"go-crud/internal/entity"
 	"go-crud/internal/helper"
 	"go-crud/internal/models"
+	"go-crud/internal/repository"
 	"go-crud/internal/usecase"
+	"go-crud/test/mocks"
 	"gorm.io/gorm"
 	"math"
+	"net/http/httptest"
+	"net/url"
 	"testing"
+	"time"
 )
 
 func TestProduct(t *testing.T) {
-	productUsecase := usecase.NewProductUsecase(productRepositoryMock, validate, viperConfig, log)
+	productUsecase := usecase.NewProductUsecase(productRepositoryMock, repository.NewMemoryProductSearchRepository(), organizationMemberRepositoryMock, mocks.NewCategoryRepositoryMock(), mocks.NewTagRepositoryMock(), productImageRepositoryMock, fileStorage, validate, viperConfig, log)
 	t.Run("Validate request", func(t *testing.T) {
 		t.Run("Empty name", func(t *testing.T) {
 			req := &models.ProductRequest{
@@ -170,11 +178,11 @@ func TestProduct(t *testing.T) {
 		}
 
 		t.Run("Should return products with user entity", func(t *testing.T) {
-			productRepositoryMock.Mock.On("FindMany", mock.Anything, mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			productRepositoryMock.Mock.On("FindMany", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
 				productsPtr := args.Get(0).(*[]entity.Product)
 				*productsPtr = productMock
 			})
-			result, err := productUsecase.GetProducts(0, 2)
+			result, err := productUsecase.GetProducts(&models.ProductFilter{}, 0, 2)
 			require.Nil(t, err)
 			require.Equal(t, expectedResult, result)
 
@@ -197,8 +205,8 @@ func TestProduct(t *testing.T) {
 		t.Run("Should return metadata", func(t *testing.T) {
 			var returnArgs int64 = 500
 			var pageSize int64 = int64(math.Ceil(float64(returnArgs / 50)))
-			productRepositoryMock.Mock.On("Count").Return(returnArgs, nil)
-			metadata, err := productUsecase.GetMetadataPagination(1, 50)
+			productRepositoryMock.Mock.On("Count", mock.Anything).Return(returnArgs, nil)
+			metadata, err := productUsecase.GetMetadataPagination(&models.ProductFilter{}, 1, 50)
 			require.Nil(t, err)
 			require.Equal(t, 1, metadata.PageNumber)
 			require.Equal(t, returnArgs, metadata.TotalItemCount)
@@ -211,8 +219,8 @@ func TestProduct(t *testing.T) {
 			var count int64 = 500
 			size := float64(count) / float64(50)
 			pageSize := int64(math.Ceil(size))
-			productRepositoryMock.Mock.On("Count").Return(count, nil)
-			metadata, err := productUsecase.GetMetadataPagination(5, 50)
+			productRepositoryMock.Mock.On("Count", mock.Anything).Return(count, nil)
+			metadata, err := productUsecase.GetMetadataPagination(&models.ProductFilter{}, 5, 50)
 			require.Nil(t, err)
 			require.Equal(t, 5, metadata.PageNumber)
 			require.Equal(t, count, metadata.TotalItemCount)
@@ -225,8 +233,8 @@ func TestProduct(t *testing.T) {
 			var returnArgs int64 = 500
 			size := float64(returnArgs) / float64(50)
 			pageSize := int64(math.Ceil(size))
-			productRepositoryMock.Mock.On("Count").Return(returnArgs, nil)
-			metadata, err := productUsecase.GetMetadataPagination(10, 50)
+			productRepositoryMock.Mock.On("Count", mock.Anything).Return(returnArgs, nil)
+			metadata, err := productUsecase.GetMetadataPagination(&models.ProductFilter{}, 10, 50)
 			require.Nil(t, err)
 			require.Equal(t, 10, metadata.PageNumber)
 			require.Equal(t, returnArgs, metadata.TotalItemCount)
@@ -251,6 +259,7 @@ func TestProduct(t *testing.T) {
 			productMock.Name = "Product 1"
 			productMock.Price = 1500
 			productMock.Stock = 120
+			productMock.CreatedAt = time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
 			productMock.User = entity.User{
 				Id:   "user-id",
 				Name: "Danar",
@@ -265,9 +274,212 @@ func TestProduct(t *testing.T) {
 			require.Equal(t, productMock.Name, result.Name)
 			require.Equal(t, productMock.Price, result.Price)
 			require.Equal(t, productMock.Stock, result.Stock)
+			require.Equal(t, productMock.CreatedAt, result.CreatedAt)
 			require.Equal(t, productMock.User.Id, result.User.Id)
 			require.Equal(t, productMock.User.Name, result.User.Name)
 		})
 
 	})
+
+	t.Run("Filter products", func(t *testing.T) {
+		productRepository := mocks.NewProductRepositoryMock()
+		filterUsecase := usecase.NewProductUsecase(productRepository, repository.NewMemoryProductSearchRepository(), organizationMemberRepositoryMock, mocks.NewCategoryRepositoryMock(), mocks.NewTagRepositoryMock(), productImageRepositoryMock, fileStorage, validate, viperConfig, log)
+
+		t.Run("Should parse filters and sort", func(t *testing.T) {
+			filter, err := filterUsecase.ParseFilter(map[string]string{
+				"page":           "2",
+				"limit":          "10",
+				"price[gte]":     "1000",
+				"stock[lt]":      "5",
+				"name[contains]": "shoe",
+				"owner":          "user-id",
+				"sort":           "-price,name",
+			})
+			require.Nil(t, err)
+			require.Equal(t, &models.ProductFilter{
+				Conditions: []models.ProductCondition{
+					{Field: "name", Operator: "contains", Value: "shoe"},
+					{Field: "owner", Operator: "eq", Value: "user-id"},
+					{Field: "price", Operator: "gte", Value: 1000},
+					{Field: "stock", Operator: "lt", Value: 5},
+				},
+				Sort: []models.ProductSort{
+					{Field: "price", Descending: true},
+					{Field: "name"},
+				},
+			}, filter)
+		})
+
+		t.Run("Should refuse invalid filters", func(t *testing.T) {
+			for query, message := range map[string]string{
+				"color":           "Unknown query parameter color",
+				"user_id[eq]":     "Unknown query parameter user_id[eq]",
+				"name[gte]":       "name can't be filtered with gte",
+				"owner[contains]": "owner can't be filtered with contains",
+				"price[lt]":       "price[lt] must be a number",
+				"sort":            `Products can't be sorted by "user_id"`,
+			} {
+				value := "abc"
+				if query == "sort" {
+					value = "-price,user_id"
+				}
+				_, err := filterUsecase.ParseFilter(map[string]string{query: value})
+				require.Equal(t, &models.ErrorResponse{Code: 400, Message: message, Status: "Bad Request"}, err, query)
+			}
+		})
+
+		t.Run("Should keep the filters on the pagination links", func(t *testing.T) {
+			filter := &models.ProductFilter{
+				Conditions: []models.ProductCondition{{Field: "price", Operator: "gte", Value: 1000}, {Field: "owner", Operator: "eq", Value: "user-id"}},
+				Sort:       []models.ProductSort{{Field: "price", Descending: true}, {Field: "name"}},
+			}
+			productRepository.Mock.On("Count", filter).Return(int64(30), nil).Once()
+
+			metadata, err := filterUsecase.GetMetadataPagination(filter, 2, 10)
+			require.Nil(t, err)
+			require.Equal(t, int64(30), metadata.TotalItemCount)
+			require.Equal(t, int64(3), metadata.PageSize)
+			require.Equal(t, "http://localhost:8080/products?page=3&limit=10&owner=user-id&price%5Bgte%5D=1000&sort=-price%2Cname", metadata.Next)
+			require.Equal(t, "http://localhost:8080/products?page=1&limit=10&owner=user-id&price%5Bgte%5D=1000&sort=-price%2Cname", metadata.Prev)
+		})
+
+		t.Run("Route", func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/products", controllers.NewProductController(log, filterUsecase).GetProducts)
+			get := func(target string) int {
+				response, err := app.Test(httptest.NewRequest(fiber.MethodGet, target, nil))
+				require.Nil(t, err)
+				return response.StatusCode
+			}
+
+			t.Run("Should refuse unknown query parameters", func(t *testing.T) {
+				require.Equal(t, 400, get("/products?color=red"))
+				require.Equal(t, 400, get("/products?sort=password"))
+			})
+
+			t.Run("Should refuse pages below 1", func(t *testing.T) {
+				require.Equal(t, 400, get("/products?page=0"))
+				require.Equal(t, 400, get("/products?page=-1"))
+			})
+
+			t.Run("Should list the products with the same filter it counts", func(t *testing.T) {
+				var listed, counted *models.ProductFilter
+				productRepository.Mock.On("FindMany", mock.Anything, mock.Anything, 0, 50).Return(nil).Once().Run(func(args mock.Arguments) {
+					listed = args.Get(1).(*models.ProductFilter)
+				})
+				productRepository.Mock.On("Count", mock.Anything).Return(int64(1), nil).Once().Run(func(args mock.Arguments) {
+					counted = args.Get(0).(*models.ProductFilter)
+				})
+
+				response, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/products?stock%5Blt%5D=5&sort=-created_at", nil))
+				require.Nil(t, err)
+				require.Equal(t, 200, response.StatusCode)
+				body := new(models.Response[*[]models.ProductResponse])
+				require.Nil(t, json.NewDecoder(response.Body).Decode(body))
+				require.Equal(t, int64(1), body.Metadata.TotalItemCount)
+
+				require.Equal(t, []models.ProductCondition{{Field: "stock", Operator: "lt", Value: 5}}, listed.Conditions)
+				require.Equal(t, []models.ProductSort{{Field: "created_at", Descending: true}}, listed.Sort)
+				require.Same(t, listed, counted)
+			})
+		})
+	})
+
+	t.Run("Cursor pagination", func(t *testing.T) {
+		productRepository := mocks.NewProductRepositoryMock()
+		cursorUsecase := usecase.NewProductUsecase(productRepository, repository.NewMemoryProductSearchRepository(), organizationMemberRepositoryMock, mocks.NewCategoryRepositoryMock(), mocks.NewTagRepositoryMock(), productImageRepositoryMock, fileStorage, validate, viperConfig, log)
+		app := fiber.New()
+		app.Get("/products", controllers.NewProductController(log, cursorUsecase).GetProducts)
+		get := func(t *testing.T, query url.Values) (int, *models.Response[*[]models.ProductResponse]) {
+			response, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/products?"+query.Encode(), nil))
+			require.Nil(t, err)
+			body := new(models.Response[*[]models.ProductResponse])
+			_ = json.NewDecoder(response.Body).Decode(body)
+			return response.StatusCode, body
+		}
+		// list answers the next FindMany with products and returns the filter
+		// it was called with.
+		list := func(limit int, products ...entity.Product) *models.ProductFilter {
+			filter := new(models.ProductFilter)
+			productRepository.Mock.On("FindMany", mock.Anything, mock.Anything, 0, limit+1).Return(nil).Once().Run(func(args mock.Arguments) {
+				*args.Get(0).(*[]entity.Product) = products
+				*filter = *args.Get(1).(*models.ProductFilter)
+			})
+			return filter
+		}
+		createdAt := time.Date(2024, 5, 9, 9, 0, 0, 0, time.UTC)
+		first := entity.Product{Id: "cursor-1", Name: "Boots", Price: 3000, CreatedAt: createdAt}
+		second := entity.Product{Id: "cursor-2", Name: "Shoes", Price: 2000, CreatedAt: createdAt}
+		third := entity.Product{Id: "cursor-3", Name: "Socks", Price: 1000, CreatedAt: createdAt}
+
+		var nextCursor, prevCursor string
+		t.Run("Should start the list with its filters", func(t *testing.T) {
+			filter := list(2, first, second, third)
+			status, body := get(t, url.Values{"cursor": {""}, "limit": {"2"}, "price[gte]": {"1000"}, "sort": {"-price"}})
+			require.Equal(t, 200, status)
+			require.Len(t, *body.Data, 2)
+			require.Equal(t, "cursor-2", (*body.Data)[1].Id)
+			require.Nil(t, filter.Cursor)
+			require.Equal(t, []models.ProductSort{{Field: "price", Descending: true}}, filter.Sort)
+
+			require.NotEmpty(t, body.Metadata.NextCursor)
+			require.Empty(t, body.Metadata.PrevCursor)
+			require.Equal(t, helper.FormatCursorURLPagination("products", body.Metadata.NextCursor, 2), body.Metadata.Next)
+			require.Equal(t, "", body.Metadata.Prev)
+			require.Zero(t, body.Metadata.PageNumber)
+			nextCursor = body.Metadata.NextCursor
+		})
+
+		t.Run("Should continue after the last product with the same filters", func(t *testing.T) {
+			filter := list(2, third)
+			status, body := get(t, url.Values{"cursor": {nextCursor}, "limit": {"2"}})
+			require.Equal(t, 200, status)
+			require.Len(t, *body.Data, 1)
+			require.Equal(t, &models.ProductCursor{Values: []any{2000}, Id: "cursor-2"}, filter.Cursor)
+			require.Equal(t, []models.ProductCondition{{Field: "price", Operator: "gte", Value: 1000}}, filter.Conditions)
+			require.Equal(t, []models.ProductSort{{Field: "price", Descending: true}}, filter.Sort)
+
+			require.Empty(t, body.Metadata.NextCursor)
+			require.NotEmpty(t, body.Metadata.PrevCursor)
+			prevCursor = body.Metadata.PrevCursor
+		})
+
+		t.Run("Should go back before the first product", func(t *testing.T) {
+			filter := list(2, first, second)
+			status, body := get(t, url.Values{"cursor": {prevCursor}, "limit": {"2"}})
+			require.Equal(t, 200, status)
+			require.Len(t, *body.Data, 2)
+			require.Equal(t, &models.ProductCursor{Values: []any{1000}, Id: "cursor-3", Backward: true}, filter.Cursor)
+
+			require.NotEmpty(t, body.Metadata.NextCursor)
+			require.Empty(t, body.Metadata.PrevCursor)
+		})
+
+		t.Run("Should sort by creation time by default", func(t *testing.T) {
+			list(1, first, second)
+			_, body := get(t, url.Values{"cursor": {""}, "limit": {"1"}})
+
+			filter := list(1)
+			status, _ := get(t, url.Values{"cursor": {body.Metadata.NextCursor}, "limit": {"1"}})
+			require.Equal(t, 200, status)
+			require.Equal(t, &models.ProductCursor{Values: []any{createdAt}, Id: "cursor-1"}, filter.Cursor)
+		})
+
+		t.Run("Should refuse tampered cursors", func(t *testing.T) {
+			forged := helper.SignCursor([]byte(`{"q":"","v":["2024-05-09T09:00:00Z"],"id":"cursor-1"}`), []byte("another key"))
+			for _, cursor := range []string{"garbage", nextCursor + "x", forged} {
+				status, _ := get(t, url.Values{"cursor": {cursor}})
+				require.Equal(t, 400, status, cursor)
+			}
+		})
+
+		t.Run("Should refuse to change the list of a cursor", func(t *testing.T) {
+			status, _ := get(t, url.Values{"cursor": {nextCursor}, "sort": {"name"}})
+			require.Equal(t, 400, status)
+			status, _ = get(t, url.Values{"cursor": {nextCursor}, "page": {"2"}})
+			require.Equal(t, 400, status)
+			status, _ = get(t, url.Values{"cursor": {""}, "color": {"red"}})
+			require.Equal(t, 400, status)
+		})
+	})
 }
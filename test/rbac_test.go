@@ -0,0 +1,94 @@
+package test
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go-crud/internal/delivery/http/middleware"
+	"go-crud/internal/entity"
+	"go-crud/internal/helper"
+	"go-crud/internal/models"
+	"go-crud/internal/usecase"
+	"go-crud/test/mocks"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRbac(t *testing.T) {
+	userRepository := mocks.NewRepositoryMock()
+	authUsecase := usecase.NewAuthUsecase(userRepository, refreshTokenRepositoryMock, sessionRepositoryMock, revokedAccessTokenRepositoryMock, loginAttemptUsecase, auditLogUsecase, passwordHasher, keySet, validate, viperConfig, log)
+	apiKeyUsecase := usecase.NewApiKeyUsecase(apiKeyRepositoryMock, validate, log)
+	refreshTokenRepositoryMock.Mock.On("Save", mock.Anything).Return(nil)
+	sessionRepositoryMock.Mock.On("Save", mock.Anything).Return(nil)
+
+	admin := entity.Role{Id: "admin", Name: "admin", Permissions: []entity.Permission{
+		{Id: models.PermissionProductUpdateAny, Name: models.PermissionProductUpdateAny},
+		{Id: models.PermissionProductDeleteAny, Name: models.PermissionProductDeleteAny},
+	}}
+	userRepository.Mock.On("FindRolesByUserId", "rbac-admin").Return([]entity.Role{admin}, nil)
+	userRepository.Mock.On("FindRolesByUserId", "rbac-user").Return([]entity.Role{}, nil)
+	userRepository.Mock.On("IsDisabled", mock.Anything).Return(false, nil)
+
+	t.Run("Access token carries the roles and permissions of the user", func(t *testing.T) {
+		result, err := authUsecase.StartSession("rbac-admin", models.ClientInfo{}, "pwd")
+		require.Nil(t, err)
+
+		sessionRepositoryMock.Mock.On("FindOneById", mock.Anything).Return(&entity.Session{UserId: "rbac-admin"}, nil).Once()
+		claims, err := authUsecase.VerifyAccessToken(result.AccessToken)
+		require.Nil(t, err)
+		require.Equal(t, []string{"admin"}, claims.Roles)
+		require.Equal(t, []string{models.PermissionProductUpdateAny, models.PermissionProductDeleteAny}, claims.Permissions)
+	})
+
+	t.Run("Product routes compose ownership with permissions", func(t *testing.T) {
+		authMiddleware := middleware.NewAuthMiddleware(authUsecase, apiKeyUsecase, auditLogUsecase, log)
+		productMiddleware := middleware.NewProductMiddleware(productRepositoryMock, organizationMemberRepositoryMock, auditLogUsecase, log)
+		productRepositoryMock.Mock.On("FindOneById", mock.Anything, "rbac-product").Return(nil).Run(func(args mock.Arguments) {
+			*args.Get(0).(*entity.Product) = entity.Product{Id: "rbac-product", UserId: "rbac-owner"}
+		})
+
+		app := fiber.New()
+		ok := func(ctx *fiber.Ctx) error {
+			return ctx.SendStatus(fiber.StatusOK)
+		}
+		app.Delete("/products/:id", authMiddleware.Auth, productMiddleware.OwnerOrPermission(models.PermissionProductDeleteAny), ok)
+		app.Get("/admin", authMiddleware.Auth, authMiddleware.RequirePermission(models.PermissionProductDeleteAny), ok)
+
+		send := func(method string, path string, claims *models.AccessTokenClaims) int {
+			token, err := authUsecase.GenerateAccessToken(claims)
+			require.Nil(t, err)
+			request := httptest.NewRequest(method, path, nil)
+			request.Header.Set("Authorization", "Bearer "+token)
+			response, err := app.Test(request)
+			require.Nil(t, err)
+			return response.StatusCode
+		}
+
+		owner := &models.AccessTokenClaims{Subject: "rbac-owner"}
+		stranger := &models.AccessTokenClaims{Subject: "rbac-user"}
+		moderator := &models.AccessTokenClaims{Subject: "rbac-admin", Roles: []string{"admin"}, Permissions: []string{models.PermissionProductDeleteAny}}
+
+		require.Equal(t, 200, send("DELETE", "/products/rbac-product", owner))
+		require.Equal(t, 403, send("DELETE", "/products/rbac-product", stranger))
+		require.Equal(t, 200, send("DELETE", "/products/rbac-product", moderator))
+		require.Equal(t, 403, send("GET", "/admin", stranger))
+		require.Equal(t, 200, send("GET", "/admin", moderator))
+	})
+
+	t.Run("API keys never carry permissions", func(t *testing.T) {
+		authMiddleware := middleware.NewAuthMiddleware(authUsecase, apiKeyUsecase, auditLogUsecase, log)
+		apiKeyRepositoryMock.Mock.On("Touch", mock.Anything, mock.Anything).Return(nil)
+		apiKeyRepositoryMock.Mock.On("FindOneByHash", helper.HashToken("gck_rbac")).Return(&entity.ApiKey{Id: "rbac-key", UserId: "rbac-admin"}, nil)
+
+		app := fiber.New()
+		app.Get("/admin", authMiddleware.Auth, authMiddleware.RequirePermission(models.PermissionProductDeleteAny), func(ctx *fiber.Ctx) error {
+			return ctx.SendStatus(fiber.StatusOK)
+		})
+
+		request := httptest.NewRequest("GET", "/admin", nil)
+		request.Header.Set("X-API-Key", "gck_rbac")
+		response, err := app.Test(request)
+		require.Nil(t, err)
+		require.Equal(t, 403, response.StatusCode)
+	})
+}
@@ -0,0 +1,203 @@
+package test
+
+import (
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go-crud/internal/delivery/http/controllers"
+	"go-crud/internal/delivery/http/middleware"
+	"go-crud/internal/delivery/http/routes"
+	"go-crud/internal/entity"
+	"go-crud/internal/helper"
+	"go-crud/internal/models"
+	"go-crud/internal/usecase"
+	"go-crud/test/mocks"
+	"gorm.io/gorm"
+	"io"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenRevocation(t *testing.T) {
+	clientRepository := mocks.NewOAuthClientRepositoryMock()
+	sessionRepository := mocks.NewSessionRepositoryMock()
+	refreshTokenRepository := mocks.NewRefreshTokenRepositoryMock()
+	revokedRepository := mocks.NewRevokedAccessTokenRepositoryMock()
+	authUsecase := usecase.NewAuthUsecase(userRepositoryMock, refreshTokenRepository, sessionRepository, revokedRepository, loginAttemptUsecase, auditLogUsecase, passwordHasher, keySet, validate, viperConfig, log)
+	apiKeyUsecase := usecase.NewApiKeyUsecase(apiKeyRepositoryMock, validate, log)
+	oauthUsecase := usecase.NewOAuthUsecase(clientRepository, mocks.NewOAuthAuthorizationCodeRepositoryMock(), sessionRepository, authUsecase, validate, viperConfig, log)
+
+	// The deny-list is kept in memory so a revocation is seen by the next
+	// verification.
+	revoked := make(map[string]bool)
+	revokedRepository.Mock.On("Save", mock.Anything).Run(func(args mock.Arguments) {
+		revoked[args.Get(0).(*entity.RevokedAccessToken).Jti] = true
+	}).Return(nil)
+	revokedRepository.Mock.On("DeleteExpired", mock.Anything).Return(nil)
+	revokedRepository.Mock.On("Exists", mock.MatchedBy(func(jti string) bool { return revoked[jti] })).Return(true, nil)
+	revokedRepository.Mock.On("Exists", mock.MatchedBy(func(jti string) bool { return !revoked[jti] })).Return(false, nil)
+
+	secret := usecase.OAuthClientSecretPrefix + "service-secret"
+	clientRepository.Mock.On("FindOneById", "internal-service").Return(&entity.OAuthClient{Id: "internal-service", UserId: "service-owner", SecretHash: helper.HashToken(secret), Scopes: models.ScopeProductsRead}, nil)
+	clientRepository.Mock.On("FindOneById", "public-app").Return(&entity.OAuthClient{Id: "public-app", UserId: "service-owner", Scopes: models.ScopeProductsRead}, nil)
+
+	accessToken := func(t *testing.T, claims *models.AccessTokenClaims) string {
+		token, err := authUsecase.GenerateAccessToken(claims)
+		require.Nil(t, err)
+		return token
+	}
+	tokenId := func(t *testing.T, accessToken string) string {
+		token, _, err := jwt.NewParser().ParseUnverified(accessToken, jwt.MapClaims{})
+		require.Nil(t, err)
+		jti, _ := token.Claims.(jwt.MapClaims)["jti"].(string)
+		return jti
+	}
+
+	t.Run("Access tokens carry a unique jti", func(t *testing.T) {
+		first := accessToken(t, &models.AccessTokenClaims{Subject: "jti-user"})
+		second := accessToken(t, &models.AccessTokenClaims{Subject: "jti-user"})
+		require.NotEmpty(t, tokenId(t, first))
+		require.NotEqual(t, tokenId(t, first), tokenId(t, second))
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		t.Run("Should deny a revoked access token until it expires", func(t *testing.T) {
+			token := accessToken(t, &models.AccessTokenClaims{Subject: "revoke-user"})
+			_, err := authUsecase.VerifyAccessToken(token)
+			require.Nil(t, err)
+
+			err = oauthUsecase.Revoke(&models.TokenHintRequest{Token: token})
+			require.Nil(t, err)
+			revokedRepository.Mock.AssertCalled(t, "Save", mock.MatchedBy(func(entry *entity.RevokedAccessToken) bool {
+				return entry.Jti == tokenId(t, token) && entry.ExpiresAt.After(time.Now().Add(59*time.Minute))
+			}))
+
+			claims, err := authUsecase.VerifyAccessToken(token)
+			require.Nil(t, claims)
+			require.Equal(t, &models.ErrorResponse{Code: 401, Message: "Token has been revoked", Status: "Unauthorized"}, err)
+
+			app := fiber.New()
+			authMiddleware := middleware.NewAuthMiddleware(authUsecase, apiKeyUsecase, auditLogUsecase, log)
+			app.Get("/protected", authMiddleware.Auth, func(ctx *fiber.Ctx) error {
+				return ctx.SendStatus(fiber.StatusOK)
+			})
+			request := httptest.NewRequest(fiber.MethodGet, "/protected", nil)
+			request.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+			response, err := app.Test(request)
+			require.Nil(t, err)
+			require.Equal(t, fiber.StatusUnauthorized, response.StatusCode)
+		})
+
+		t.Run("Should only let a client revoke its own tokens", func(t *testing.T) {
+			firstParty := accessToken(t, &models.AccessTokenClaims{Subject: "revoke-user"})
+			err := oauthUsecase.Revoke(&models.TokenHintRequest{Token: firstParty, ClientId: "internal-service", ClientSecret: secret})
+			require.Equal(t, &models.OAuthError{Code: 400, Err: "unauthorized_client", Description: "Token was issued to another client"}, err)
+
+			thirdParty := accessToken(t, &models.AccessTokenClaims{Subject: "revoke-user", ClientId: "public-app", Scopes: []string{models.ScopeProductsRead}})
+			err = oauthUsecase.Revoke(&models.TokenHintRequest{Token: thirdParty})
+			require.Equal(t, "unauthorized_client", err.(*models.OAuthError).Err)
+
+			err = oauthUsecase.Revoke(&models.TokenHintRequest{Token: thirdParty, ClientId: "public-app"})
+			require.Nil(t, err)
+			require.True(t, revoked[tokenId(t, thirdParty)])
+		})
+
+		t.Run("Should revoke the session of a refresh token", func(t *testing.T) {
+			refreshTokenRepository.Mock.On("FindOneByHash", helper.HashToken("revoke-refresh-token")).Return(&entity.RefreshToken{Id: "revoke-refresh", FamilyId: "revoke-family"}, nil)
+			sessionRepository.Mock.On("FindOneById", "revoke-family").Return(&entity.Session{Id: "revoke-family", UserId: "revoke-user"}, nil)
+			sessionRepository.Mock.On("Revoke", "revoke-family").Return(nil)
+			refreshTokenRepository.Mock.On("RevokeFamily", "revoke-family").Return(nil)
+
+			err := oauthUsecase.Revoke(&models.TokenHintRequest{Token: "revoke-refresh-token", TokenTypeHint: "refresh_token"})
+			require.Nil(t, err)
+			sessionRepository.Mock.AssertCalled(t, "Revoke", "revoke-family")
+			refreshTokenRepository.Mock.AssertCalled(t, "RevokeFamily", "revoke-family")
+		})
+
+		t.Run("Should ignore an unknown token", func(t *testing.T) {
+			refreshTokenRepository.Mock.On("FindOneByHash", helper.HashToken("unknown-token")).Return(nil, gorm.ErrRecordNotFound)
+
+			err := oauthUsecase.Revoke(&models.TokenHintRequest{Token: "unknown-token"})
+			require.Nil(t, err)
+		})
+
+		t.Run("Should authenticate the client", func(t *testing.T) {
+			err := oauthUsecase.Revoke(&models.TokenHintRequest{Token: "unknown-token", ClientId: "internal-service", ClientSecret: "wrong"})
+			require.Equal(t, "invalid_client", err.(*models.OAuthError).Err)
+		})
+	})
+
+	t.Run("Introspect", func(t *testing.T) {
+		t.Run("Should describe an active token", func(t *testing.T) {
+			token := accessToken(t, &models.AccessTokenClaims{Subject: "introspect-user", ClientId: "public-app", Scopes: []string{models.ScopeProductsRead}})
+
+			result, err := oauthUsecase.Introspect(&models.TokenHintRequest{Token: token, ClientId: "internal-service", ClientSecret: secret})
+			require.Nil(t, err)
+			require.True(t, result.Active)
+			require.Equal(t, "introspect-user", result.Sub)
+			require.Equal(t, models.ScopeProductsRead, result.Scope)
+			require.Equal(t, "public-app", result.ClientId)
+			require.Equal(t, tokenId(t, token), result.Jti)
+			require.InDelta(t, time.Now().Add(usecase.AccessTokenLifetime).Unix(), result.Exp, 5)
+			require.NotZero(t, result.Iat)
+		})
+
+		t.Run("Should report revoked and invalid tokens as inactive", func(t *testing.T) {
+			token := accessToken(t, &models.AccessTokenClaims{Subject: "introspect-user"})
+			err := oauthUsecase.Revoke(&models.TokenHintRequest{Token: token})
+			require.Nil(t, err)
+
+			for _, inactive := range []string{token, "not-a-token", ""} {
+				result, err := oauthUsecase.Introspect(&models.TokenHintRequest{Token: inactive, ClientId: "internal-service", ClientSecret: secret})
+				require.Nil(t, err)
+				require.Equal(t, &models.IntrospectionResponse{Active: false}, result)
+			}
+		})
+
+		t.Run("Should only answer confidential clients", func(t *testing.T) {
+			token := accessToken(t, &models.AccessTokenClaims{Subject: "introspect-user"})
+
+			result, err := oauthUsecase.Introspect(&models.TokenHintRequest{Token: token, ClientId: "public-app"})
+			require.Nil(t, result)
+			require.Equal(t, 401, err.(*models.OAuthError).Code)
+
+			result, err = oauthUsecase.Introspect(&models.TokenHintRequest{Token: token})
+			require.Nil(t, result)
+			require.Equal(t, "invalid_client", err.(*models.OAuthError).Err)
+		})
+	})
+
+	t.Run("Endpoints speak OAuth", func(t *testing.T) {
+		app := fiber.New()
+		oauthRoute := routes.NewOAuthRoute(app, controllers.NewOAuthController(log, oauthUsecase), middleware.NewAuthMiddleware(authUsecase, apiKeyUsecase, auditLogUsecase, log))
+		oauthRoute.Setup()
+		token := accessToken(t, &models.AccessTokenClaims{Subject: "endpoint-user"})
+
+		post := func(path string, form url.Values) (int, []byte) {
+			request := httptest.NewRequest(fiber.MethodPost, path, strings.NewReader(form.Encode()))
+			request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationForm)
+			request.SetBasicAuth("internal-service", secret)
+			response, err := app.Test(request)
+			require.Nil(t, err)
+			body, err := io.ReadAll(response.Body)
+			require.Nil(t, err)
+			return response.StatusCode, body
+		}
+
+		status, body := post("/auth/introspect", url.Values{"token": {token}})
+		require.Equal(t, fiber.StatusOK, status)
+		introspection := map[string]any{}
+		require.Nil(t, json.Unmarshal(body, &introspection))
+		require.Equal(t, true, introspection["active"])
+		require.Equal(t, "endpoint-user", introspection["sub"])
+
+		status, body = post("/auth/revoke", url.Values{"token": {token}})
+		require.Equal(t, fiber.StatusBadRequest, status)
+		require.Contains(t, string(body), "unauthorized_client")
+	})
+}
@@ -0,0 +1,133 @@
+package test
+
+import (
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go-crud/internal/delivery/http/middleware"
+	"go-crud/internal/entity"
+	"go-crud/internal/helper"
+	"go-crud/internal/models"
+	"go-crud/internal/usecase"
+	"go-crud/test/mocks"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestScope(t *testing.T) {
+	userRepository := mocks.NewRepositoryMock()
+	sessionRepository := mocks.NewSessionRepositoryMock()
+	refreshTokenRepository := mocks.NewRefreshTokenRepositoryMock()
+	authUsecase := usecase.NewAuthUsecase(userRepository, refreshTokenRepository, sessionRepository, revokedAccessTokenRepositoryMock, loginAttemptUsecase, auditLogUsecase, passwordHasher, keySet, validate, viperConfig, log)
+	authMiddleware := middleware.NewAuthMiddleware(authUsecase, usecase.NewApiKeyUsecase(apiKeyRepositoryMock, validate, log), auditLogUsecase, log)
+
+	hashedPassword, err := passwordHasher.Hash("scope-password")
+	require.Nil(t, err)
+	now := time.Now()
+	userRepository.Mock.On("FindOneByEmail", "scope@gmail.com").Return(&entity.User{Id: "scope-user", Email: "scope@gmail.com", Password: hashedPassword, EmailVerifiedAt: &now}, nil)
+	userRepository.Mock.On("FindOneByEmail", "scope-mfa@gmail.com").Return(&entity.User{Id: "scope-mfa-user", Email: "scope-mfa@gmail.com", Password: hashedPassword, EmailVerifiedAt: &now, TotpEnabledAt: &now}, nil)
+	userRepository.Mock.On("FindRolesByUserId", mock.Anything).Return([]entity.Role{}, nil)
+	userRepository.Mock.On("IsDisabled", mock.Anything).Return(false, nil)
+	refreshTokenRepository.Mock.On("Save", mock.Anything).Return(nil)
+	sessionRepository.Mock.On("Touch", mock.Anything, mock.Anything).Return(nil)
+	// Sessions can be found as soon as they are saved, so their tokens verify.
+	sessionRepository.Mock.On("Save", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		session := args.Get(0).(*entity.Session)
+		sessionRepository.Mock.On("FindOneById", session.Id).Return(session, nil)
+	})
+
+	signIn := func(t *testing.T, scopes []string) string {
+		result, err := authUsecase.SignIn(&models.SignInRequest{Email: "scope@gmail.com", Password: "scope-password", Scopes: scopes})
+		require.Nil(t, err)
+		return result.AccessToken
+	}
+	verify := func(t *testing.T, accessToken string) *models.AccessTokenClaims {
+		claims, err := authUsecase.VerifyAccessToken(accessToken)
+		require.Nil(t, err)
+		return claims
+	}
+
+	t.Run("Tokens", func(t *testing.T) {
+		t.Run("Should carry every scope by default", func(t *testing.T) {
+			require.Equal(t, models.AllScopes, verify(t, signIn(t, nil)).Scopes)
+		})
+
+		t.Run("Should carry the scopes asked for at sign in", func(t *testing.T) {
+			claims := verify(t, signIn(t, []string{models.ScopeProductsRead, models.ScopeProductsRead}))
+			require.Equal(t, []string{models.ScopeProductsRead}, claims.Scopes)
+		})
+
+		t.Run("Should refuse unknown scopes", func(t *testing.T) {
+			_, err := authUsecase.SignIn(&models.SignInRequest{Email: "scope@gmail.com", Password: "scope-password", Scopes: []string{"admin"}})
+			require.NotNil(t, err)
+			require.Equal(t, 400, err.(*models.ErrorResponse).Code)
+		})
+
+		t.Run("Should keep the scopes through the second factor", func(t *testing.T) {
+			result, err := authUsecase.SignIn(&models.SignInRequest{Email: "scope-mfa@gmail.com", Password: "scope-password", Scopes: []string{models.ScopeProductsRead}})
+			require.Nil(t, err)
+			require.True(t, result.MfaRequired)
+
+			userID, methods, scopes, err := authUsecase.VerifyMfaToken(result.MfaToken)
+			require.Nil(t, err)
+			require.Equal(t, "scope-mfa-user", userID)
+			require.Equal(t, []string{"pwd"}, methods)
+			require.Equal(t, []string{models.ScopeProductsRead}, scopes)
+		})
+
+		t.Run("Should keep the scopes when refreshed", func(t *testing.T) {
+			claims := verify(t, signIn(t, []string{models.ScopeProductsRead}))
+			refreshToken, err := authUsecase.GenerateRefreshToken("scope-user", claims.SessionId)
+			require.Nil(t, err)
+			refreshTokenRepository.Mock.On("FindOneByHash", helper.HashToken(refreshToken)).Return(&entity.RefreshToken{Id: "scope-refresh", UserId: "scope-user", FamilyId: claims.SessionId}, nil)
+			refreshTokenRepository.Mock.On("MarkAsUsed", "scope-refresh").Return(true, nil)
+
+			result, err := authUsecase.RefreshToken(refreshToken, models.ClientInfo{})
+			require.Nil(t, err)
+			require.Equal(t, []string{models.ScopeProductsRead}, verify(t, result.AccessToken).Scopes)
+		})
+	})
+
+	t.Run("Routes", func(t *testing.T) {
+		app := fiber.New()
+		ok := func(ctx *fiber.Ctx) error {
+			return ctx.SendStatus(fiber.StatusOK)
+		}
+		app.Get("/products", authMiddleware.Auth, authMiddleware.RequireScope(models.ScopeProductsRead), ok)
+		app.Post("/products", authMiddleware.Auth, authMiddleware.RequireScope(models.ScopeProductsWrite), ok)
+		send := func(method string, accessToken string) *http.Response {
+			request := httptest.NewRequest(method, "/products", nil)
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+			response, err := app.Test(request)
+			require.Nil(t, err)
+			return response
+		}
+
+		t.Run("Should refuse a missing scope with insufficient_scope", func(t *testing.T) {
+			readOnly := signIn(t, []string{models.ScopeProductsRead})
+			require.Equal(t, 200, send(fiber.MethodGet, readOnly).StatusCode)
+
+			response := send(fiber.MethodPost, readOnly)
+			require.Equal(t, 403, response.StatusCode)
+			require.Equal(t, `Bearer error="insufficient_scope", scope="products:write"`, response.Header.Get(fiber.HeaderWWWAuthenticate))
+			body := new(models.OAuthError)
+			require.Nil(t, json.NewDecoder(response.Body).Decode(body))
+			require.Equal(t, "insufficient_scope", body.Err)
+			require.Equal(t, "Access token is missing the products:write scope", body.Description)
+		})
+
+		t.Run("Should let every scope through by default", func(t *testing.T) {
+			require.Equal(t, 200, send(fiber.MethodPost, signIn(t, nil)).StatusCode)
+		})
+
+		t.Run("Should let tokens issued before scopes through", func(t *testing.T) {
+			legacy, err := authUsecase.GenerateAccessToken(&models.AccessTokenClaims{Subject: "scope-user"})
+			require.Nil(t, err)
+			require.Nil(t, verify(t, legacy).Scopes)
+			require.Equal(t, 200, send(fiber.MethodPost, legacy).StatusCode)
+		})
+	})
+}
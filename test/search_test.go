@@ -0,0 +1,214 @@
+package test
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go-crud/internal/delivery/http/controllers"
+	"go-crud/internal/entity"
+	"go-crud/internal/helper"
+	"go-crud/internal/models"
+	"go-crud/internal/repository"
+	"go-crud/internal/usecase"
+	"go-crud/test/mocks"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	t.Run("Matching", func(t *testing.T) {
+		t.Run("Should split text into lower case words", func(t *testing.T) {
+			require.Equal(t, []string{"red", "running", "shoes", "42"}, helper.SearchTerms("Red running-shoes, RED (42)"))
+		})
+
+		t.Run("Should rank exact words above prefixes above typos", func(t *testing.T) {
+			exact := helper.MatchSearchTerm("shoes", "shoes")
+			prefix := helper.MatchSearchTerm("shoe", "shoes")
+			typo := helper.MatchSearchTerm("shose", "shoes")
+			require.Equal(t, 1.0, exact)
+			require.True(t, prefix < exact && prefix > 0.5)
+			require.True(t, typo > 0 && typo < prefix)
+		})
+
+		t.Run("Should allow more typos in longer words only", func(t *testing.T) {
+			require.Zero(t, helper.MatchSearchTerm("cap", "cup"))
+			require.NotZero(t, helper.MatchSearchTerm("snekers", "sneakers"))
+			require.Zero(t, helper.MatchSearchTerm("snkrs", "sneakers"))
+			require.NotZero(t, helper.MatchSearchTerm("keybaords", "keyboards"))
+		})
+
+		t.Run("Should highlight matching words and escape the rest", func(t *testing.T) {
+			highlight := helper.HighlightSearch([]string{"shoe", "red"}, "Red <b>Shoes</b> & socks")
+			require.Equal(t, "<mark>Red</mark> &lt;b&gt;<mark>Shoes</mark>&lt;/b&gt; &amp; socks", highlight)
+		})
+	})
+
+	t.Run("Memory index", func(t *testing.T) {
+		index := repository.NewMemoryProductSearchRepository()
+		for _, product := range []entity.Product{
+			{Id: "search-1", Name: "Red running shoes"},
+			{Id: "search-2", Name: "Blue shoes"},
+			{Id: "search-3", Name: "Shoelaces"},
+			{Id: "search-4", Name: "White sneakers"},
+		} {
+			require.Nil(t, index.Index(&product))
+		}
+		ids := func(hits []models.ProductSearchHit) []string {
+			result := make([]string, len(hits))
+			for i, hit := range hits {
+				result[i] = hit.ProductId
+			}
+			return result
+		}
+
+		t.Run("Should rank exact words first", func(t *testing.T) {
+			hits, count, err := index.Search([]string{"shoes"}, 0, 10)
+			require.Nil(t, err)
+			require.Equal(t, int64(2), count)
+			require.Equal(t, []string{"search-1", "search-2"}, ids(hits))
+		})
+
+		t.Run("Should match prefixes", func(t *testing.T) {
+			hits, _, err := index.Search([]string{"shoe"}, 0, 10)
+			require.Nil(t, err)
+			require.Equal(t, []string{"search-1", "search-2", "search-3"}, ids(hits))
+		})
+
+		t.Run("Should match typos", func(t *testing.T) {
+			hits, _, err := index.Search([]string{"snekers"}, 0, 10)
+			require.Nil(t, err)
+			require.Equal(t, []string{"search-4"}, ids(hits))
+		})
+
+		t.Run("Should need every term", func(t *testing.T) {
+			hits, _, err := index.Search([]string{"red", "shoes"}, 0, 10)
+			require.Nil(t, err)
+			require.Equal(t, []string{"search-1"}, ids(hits))
+		})
+
+		t.Run("Should page through the hits", func(t *testing.T) {
+			hits, count, err := index.Search([]string{"shoe"}, 2, 2)
+			require.Nil(t, err)
+			require.Equal(t, int64(3), count)
+			require.Equal(t, []string{"search-3"}, ids(hits))
+
+			hits, _, err = index.Search([]string{"shoe"}, 4, 2)
+			require.Nil(t, err)
+			require.Empty(t, hits)
+		})
+
+		t.Run("Should follow renamed and removed products", func(t *testing.T) {
+			require.Nil(t, index.Index(&entity.Product{Id: "search-2", Name: "Blue boots"}))
+			require.Nil(t, index.Remove("search-3"))
+
+			hits, _, err := index.Search([]string{"shoe"}, 0, 10)
+			require.Nil(t, err)
+			require.Equal(t, []string{"search-1"}, ids(hits))
+			hits, _, err = index.Search([]string{"boots"}, 0, 10)
+			require.Nil(t, err)
+			require.Equal(t, []string{"search-2"}, ids(hits))
+		})
+	})
+
+	t.Run("Search products", func(t *testing.T) {
+		productRepository := mocks.NewProductRepositoryMock()
+		searchUsecase := usecase.NewProductUsecase(productRepository, repository.NewMemoryProductSearchRepository(), organizationMemberRepositoryMock, mocks.NewCategoryRepositoryMock(), mocks.NewTagRepositoryMock(), productImageRepositoryMock, fileStorage, validate, viperConfig, log)
+		// The repository keeps what is saved, so found products can be loaded.
+		saved := map[string]entity.Product{}
+		productRepository.Mock.On("Save", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			product := args.Get(0).(*entity.Product)
+			saved[product.Id] = *product
+		})
+		productRepository.Mock.On("DeleteById", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			delete(saved, args.String(0))
+		})
+		productRepository.Mock.On("FindManyByIds", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			var products []entity.Product
+			for _, id := range args.Get(1).([]string) {
+				if product, ok := saved[id]; ok {
+					products = append(products, product)
+				}
+			}
+			*args.Get(0).(*[]entity.Product) = products
+		})
+		create := func(name string) string {
+			product, err := searchUsecase.CreateProduct(&models.ProductRequest{Name: name, Price: 100, Stock: 1}, "search-user")
+			require.Nil(t, err)
+			return product.Id
+		}
+		leather := create("Leather boots")
+		create("Rain boots")
+		polish := create("Boot polish")
+
+		t.Run("Should return highlighted products with pagination", func(t *testing.T) {
+			results, metadata, err := searchUsecase.SearchProducts("boots", 1, 2)
+			require.Nil(t, err)
+			require.Len(t, *results, 2)
+			require.Equal(t, "<mark>boots</mark>", strings.Fields((*results)[0].Highlight)[1])
+			require.Equal(t, 1.0, (*results)[0].Score)
+			require.Equal(t, int64(3), metadata.TotalItemCount)
+			require.Equal(t, int64(2), metadata.PageSize)
+			require.Equal(t, "http://localhost:8080/products/search?page=2&limit=2&q=boots", metadata.Next)
+			require.Equal(t, "", metadata.Prev)
+
+			// Boot is a typo away from boots, so it comes last.
+			results, _, err = searchUsecase.SearchProducts("boots", 2, 2)
+			require.Nil(t, err)
+			require.Len(t, *results, 1)
+			require.Equal(t, polish, (*results)[0].Id)
+			require.Equal(t, "<mark>Boot</mark> polish", (*results)[0].Highlight)
+		})
+
+		t.Run("Should find prefixes and typos", func(t *testing.T) {
+			results, _, err := searchUsecase.SearchProducts("lether boo", 1, 10)
+			require.Nil(t, err)
+			require.Len(t, *results, 1)
+			require.Equal(t, leather, (*results)[0].Id)
+			require.Equal(t, "<mark>Leather</mark> <mark>boots</mark>", (*results)[0].Highlight)
+		})
+
+		t.Run("Should follow updated products", func(t *testing.T) {
+			updated := entity.Product{Id: leather, Name: "Leather sandals"}
+			productRepository.Mock.On("UpdateById", mock.Anything, leather).Return(&updated, nil).Once()
+			_, err := searchUsecase.UpdateProduct(&models.ProductRequest{Name: "Leather sandals"}, leather)
+			require.Nil(t, err)
+			saved[leather] = updated
+
+			results, _, err := searchUsecase.SearchProducts("sandals", 1, 10)
+			require.Nil(t, err)
+			require.Len(t, *results, 1)
+			_, metadata, err := searchUsecase.SearchProducts("boots", 1, 10)
+			require.Nil(t, err)
+			require.Equal(t, int64(2), metadata.TotalItemCount)
+		})
+
+		t.Run("Should drop deleted products", func(t *testing.T) {
+			require.Nil(t, searchUsecase.DeleteProduct(polish))
+
+			results, metadata, err := searchUsecase.SearchProducts("polish", 1, 10)
+			require.Nil(t, err)
+			require.Empty(t, *results)
+			require.Zero(t, metadata.TotalItemCount)
+		})
+
+		t.Run("Should refuse a search without words", func(t *testing.T) {
+			_, _, err := searchUsecase.SearchProducts(" ,- ", 1, 10)
+			require.Equal(t, &models.ErrorResponse{Code: 400, Message: "q must have at least one word", Status: "Bad Request"}, err)
+		})
+
+		t.Run("Route", func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/products/search", controllers.NewProductController(log, searchUsecase).SearchProducts)
+			get := func(target string) int {
+				response, err := app.Test(httptest.NewRequest(fiber.MethodGet, target, nil))
+				require.Nil(t, err)
+				return response.StatusCode
+			}
+
+			require.Equal(t, 400, get("/products/search"))
+			require.Equal(t, 400, get("/products/search?q=boots&page=0"))
+			require.Equal(t, 400, get("/products/search?q=boots&limit=101"))
+		})
+	})
+}
@@ -0,0 +1,64 @@
+package test
+
+import (
+	"github.com/stretchr/testify/require"
+	"go-crud/internal/entity"
+	"go-crud/internal/models"
+	"go-crud/internal/usecase"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestSession(t *testing.T) {
+	sessionUsecase := usecase.NewSessionUsecase(sessionRepositoryMock, refreshTokenRepositoryMock, log)
+
+	t.Run("Get sessions marks the current one", func(t *testing.T) {
+		sessions := []entity.Session{
+			{Id: "session-1", UserId: "list-user", UserAgent: "Firefox", LastSeenAt: time.Now()},
+			{Id: "session-2", UserId: "list-user", UserAgent: "Chrome", LastSeenAt: time.Now()},
+		}
+		sessionRepositoryMock.Mock.On("FindManyActiveByUserId", "list-user").Return(sessions, nil)
+		result, err := sessionUsecase.GetSessions("list-user", "session-2")
+		require.Nil(t, err)
+		require.Len(t, *result, 2)
+		require.False(t, (*result)[0].Current)
+		require.True(t, (*result)[1].Current)
+	})
+
+	t.Run("Revoke session", func(t *testing.T) {
+		t.Run("Should revoke the session and its refresh tokens", func(t *testing.T) {
+			session := &entity.Session{Id: "own-session", UserId: "owner"}
+			sessionRepositoryMock.Mock.On("FindOneById", "own-session").Return(session, nil)
+			sessionRepositoryMock.Mock.On("Revoke", "own-session").Return(nil)
+			refreshTokenRepositoryMock.Mock.On("RevokeFamily", "own-session").Return(nil)
+			err := sessionUsecase.RevokeSession("owner", "own-session")
+			require.Nil(t, err)
+			sessionRepositoryMock.Mock.AssertCalled(t, "Revoke", "own-session")
+			refreshTokenRepositoryMock.Mock.AssertCalled(t, "RevokeFamily", "own-session")
+		})
+
+		t.Run("Should return not found for another user's session", func(t *testing.T) {
+			session := &entity.Session{Id: "other-session", UserId: "someone-else"}
+			sessionRepositoryMock.Mock.On("FindOneById", "other-session").Return(session, nil)
+			err := sessionUsecase.RevokeSession("owner", "other-session")
+			require.Equal(t, &models.ErrorResponse{Code: 404, Message: "Session not found", Status: "Not Found"}, err)
+			sessionRepositoryMock.Mock.AssertNotCalled(t, "Revoke", "other-session")
+		})
+
+		t.Run("Should return not found for unknown session", func(t *testing.T) {
+			sessionRepositoryMock.Mock.On("FindOneById", "missing-session").Return(nil, gorm.ErrRecordNotFound)
+			err := sessionUsecase.RevokeSession("owner", "missing-session")
+			require.Equal(t, "Session not found", err.Error())
+		})
+	})
+
+	t.Run("Sign out everywhere", func(t *testing.T) {
+		sessionRepositoryMock.Mock.On("RevokeAllByUserId", "everywhere-user").Return(nil)
+		refreshTokenRepositoryMock.Mock.On("RevokeAllByUserId", "everywhere-user").Return(nil)
+		err := sessionUsecase.RevokeAllSessions("everywhere-user")
+		require.Nil(t, err)
+		sessionRepositoryMock.Mock.AssertCalled(t, "RevokeAllByUserId", "everywhere-user")
+		refreshTokenRepositoryMock.Mock.AssertCalled(t, "RevokeAllByUserId", "everywhere-user")
+	})
+}
@@ -3,13 +3,15 @@ package test
 import (
 	"github.com/stretchr/testify/require"
 	"go-crud/internal/entity"
+	"go-crud/internal/mail"
 	"go-crud/internal/models"
 	"go-crud/internal/usecase"
 	"testing"
 )
 
 func TestSignupUsecase(t *testing.T) {
-	signupUsecase := usecase.NewSignUpUsecase(userRepositoryMock, validate, log)
+	emailVerificationUsecase := usecase.NewEmailVerificationUsecase(userRepositoryMock, userTokenRepositoryMock, mail.NewFileSender(t.TempDir()), validate, viperConfig, log)
+	signupUsecase := usecase.NewSignUpUsecase(userRepositoryMock, emailVerificationUsecase, passwordHasher, validate, log)
 	t.Run("Validate request", func(t *testing.T) {
 		t.Run("Empty name", func(t *testing.T) {
 			req := &models.SignUpRequest{
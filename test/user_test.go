@@ -0,0 +1,194 @@
+package test
+
+import (
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go-crud/internal/entity"
+	"go-crud/internal/mail"
+	"go-crud/internal/models"
+	"go-crud/internal/repository"
+	"go-crud/internal/storage"
+	"go-crud/internal/usecase"
+	"go-crud/test/mocks"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUser(t *testing.T) {
+	mailSender := mail.NewFileSender(t.TempDir())
+	emailVerificationUsecase := usecase.NewEmailVerificationUsecase(userRepositoryMock, userTokenRepositoryMock, mailSender, validate, viperConfig, log)
+	loginAttempts := usecase.NewLoginAttemptUsecase(repository.NewMemoryLoginAttemptRepository(), viperConfig, log)
+	userProducts := mocks.NewProductRepositoryMock()
+	searchIndex := repository.NewMemoryProductSearchRepository()
+	userImages := mocks.NewProductImageRepositoryMock()
+	imageDirectory := t.TempDir()
+	imageStorage := storage.NewLocalStorage(imageDirectory, "http://localhost:8080/uploads")
+	productUsecase := usecase.NewProductUsecase(userProducts, searchIndex, organizationMemberRepositoryMock, mocks.NewCategoryRepositoryMock(), mocks.NewTagRepositoryMock(), userImages, imageStorage, validate, viperConfig, log)
+	userUsecase := usecase.NewUserUsecase(userRepositoryMock, userTokenRepositoryMock, sessionRepositoryMock, refreshTokenRepositoryMock, organizationMemberRepositoryMock, emailVerificationUsecase, loginAttempts, auditLogUsecase, productUsecase, passwordHasher, validate, log)
+
+	verifiedAt := time.Now()
+	profile := func(id string, email string) *entity.User {
+		return &entity.User{
+			Id:              id,
+			Name:            "Danar",
+			Email:           email,
+			Password:        "$2a$10$aOySpFRuA2uE8gGNNCuAleiBvNRyMJpZuyhZ21kf/Tpy5c8KHNRTe",
+			EmailVerifiedAt: &verifiedAt,
+		}
+	}
+
+	t.Run("Get profile", func(t *testing.T) {
+		findUserReturns("me-user", profile("me-user", "me@gmail.com"))
+
+		result, err := userUsecase.GetProfile("me-user")
+		require.Nil(t, err)
+		require.Equal(t, "me@gmail.com", result.Email)
+		require.True(t, result.EmailVerified)
+		require.False(t, result.TwoFactorEnabled)
+	})
+
+	t.Run("Update profile", func(t *testing.T) {
+		t.Run("Should keep the email verified when only the name changes", func(t *testing.T) {
+			findUserReturns("rename-user", profile("rename-user", "rename@gmail.com"))
+			userRepositoryMock.Mock.On("UpdateProfile", mock.MatchedBy(func(user *entity.User) bool {
+				return user.Id == "rename-user"
+			})).Return(nil)
+
+			name := "Cahyadi"
+			result, err := userUsecase.UpdateProfile("rename-user", &models.UpdateProfileRequest{Name: &name})
+			require.Nil(t, err)
+			require.Equal(t, "Cahyadi", result.Name)
+			require.True(t, result.EmailVerified)
+		})
+
+		t.Run("Should refuse an email change without the current password", func(t *testing.T) {
+			findUserReturns("email-password-user", profile("email-password-user", "owner@gmail.com"))
+
+			email := "attacker@gmail.com"
+			_, err := userUsecase.UpdateProfile("email-password-user", &models.UpdateProfileRequest{Email: &email})
+			require.Equal(t, &models.ErrorResponse{Code: 400, Message: "current_password is required to change the email", Status: "Bad Request"}, err)
+			_, err = userUsecase.UpdateProfile("email-password-user", &models.UpdateProfileRequest{Email: &email, CurrentPassword: "wrong-password"})
+			require.Equal(t, &models.ErrorResponse{Code: 401, Message: "Password is incorrect", Status: "Unauthorized"}, err)
+			userRepositoryMock.Mock.AssertNotCalled(t, "UpdateProfile", mock.MatchedBy(func(user *entity.User) bool {
+				return user.Id == "email-password-user"
+			}))
+			_, err = mailSender.Last("attacker@gmail.com")
+			require.NotNil(t, err)
+		})
+
+		t.Run("Should keep the email until the new one is confirmed", func(t *testing.T) {
+			findUserReturns("email-user", profile("email-user", "old@gmail.com"))
+			userRepositoryMock.Mock.On("FindOneByEmail", "new@gmail.com").Return(nil, nil)
+			userRepositoryMock.Mock.On("UpdateProfile", mock.MatchedBy(func(user *entity.User) bool {
+				return user.Id == "email-user"
+			})).Return(nil)
+			userTokenRepositoryMock.Mock.On("InvalidateByUserId", "email-user", entity.UserTokenEmailChange).Return(nil)
+			userTokenRepositoryMock.Mock.On("Save", mock.MatchedBy(func(token *entity.UserToken) bool {
+				return token.UserId == "email-user"
+			})).Return(nil)
+
+			email := "new@gmail.com"
+			result, err := userUsecase.UpdateProfile("email-user", &models.UpdateProfileRequest{Email: &email, CurrentPassword: "12345678"})
+			require.Nil(t, err)
+			require.Equal(t, "old@gmail.com", result.Email)
+			require.Equal(t, "new@gmail.com", result.PendingEmail)
+			require.True(t, result.EmailVerified)
+			userRepositoryMock.Mock.AssertCalled(t, "UpdateProfile", mock.MatchedBy(func(user *entity.User) bool {
+				return user.Id == "email-user" && user.Email == "old@gmail.com" && *user.PendingEmail == "new@gmail.com"
+			}))
+			userTokenRepositoryMock.Mock.AssertCalled(t, "Save", mock.MatchedBy(func(token *entity.UserToken) bool {
+				return token.UserId == "email-user" && token.Purpose == entity.UserTokenEmailChange
+			}))
+
+			message, err := mailSender.Last("new@gmail.com")
+			require.Nil(t, err)
+			require.Contains(t, message, "Confirmation token:")
+			message, err = mailSender.Last("old@gmail.com")
+			require.Nil(t, err)
+			require.Contains(t, message, "new@gmail.com")
+		})
+
+		t.Run("Should reject an email used by another account", func(t *testing.T) {
+			findUserReturns("taken-user", profile("taken-user", "mine@gmail.com"))
+			userRepositoryMock.Mock.On("FindOneByEmail", "taken@gmail.com").Return(profile("someone-else", "taken@gmail.com"), nil)
+
+			email := "taken@gmail.com"
+			result, err := userUsecase.UpdateProfile("taken-user", &models.UpdateProfileRequest{Email: &email, CurrentPassword: "12345678"})
+			require.Nil(t, result)
+			require.Equal(t, &models.ErrorResponse{Code: 400, Message: "Email already exists", Status: "Bad Request"}, err)
+		})
+	})
+
+	t.Run("Change password", func(t *testing.T) {
+		t.Run("Should require the current password", func(t *testing.T) {
+			findUserReturns("password-wrong-user", profile("password-wrong-user", "password-wrong@gmail.com"))
+
+			err := userUsecase.ChangePassword("password-wrong-user", "current-session", &models.ChangePasswordRequest{CurrentPassword: "wrong-password", NewPassword: "new-password"})
+			require.Equal(t, &models.ErrorResponse{Code: 401, Message: "Password is incorrect", Status: "Unauthorized"}, err)
+			userRepositoryMock.Mock.AssertNotCalled(t, "UpdatePassword", "password-wrong-user", mock.Anything)
+		})
+
+		t.Run("Should update the password and sign out other sessions", func(t *testing.T) {
+			findUserReturns("password-user", profile("password-user", "password@gmail.com"))
+			userRepositoryMock.Mock.On("UpdatePassword", "password-user", mock.Anything).Return(nil)
+			sessionRepositoryMock.Mock.On("RevokeAllByUserIdExcept", "password-user", "current-session").Return(nil)
+			refreshTokenRepositoryMock.Mock.On("RevokeAllByUserIdExcept", "password-user", "current-session").Return(nil)
+
+			err := userUsecase.ChangePassword("password-user", "current-session", &models.ChangePasswordRequest{CurrentPassword: "12345678", NewPassword: "new-password"})
+			require.Nil(t, err)
+			userRepositoryMock.Mock.AssertCalled(t, "UpdatePassword", "password-user", mock.MatchedBy(func(hash string) bool {
+				return verified(hash, "new-password")
+			}))
+			sessionRepositoryMock.Mock.AssertCalled(t, "RevokeAllByUserIdExcept", "password-user", "current-session")
+			refreshTokenRepositoryMock.Mock.AssertCalled(t, "RevokeAllByUserIdExcept", "password-user", "current-session")
+		})
+	})
+
+	t.Run("Delete account", func(t *testing.T) {
+		t.Run("Should require the password", func(t *testing.T) {
+			findUserReturns("delete-wrong-user", profile("delete-wrong-user", "delete-wrong@gmail.com"))
+
+			err := userUsecase.DeleteAccount("delete-wrong-user", &models.DeleteAccountRequest{Password: "wrong-password"})
+			require.Equal(t, 401, err.(*models.ErrorResponse).Code)
+			userRepositoryMock.Mock.AssertNotCalled(t, "DeleteOneById", "delete-wrong-user")
+		})
+
+		t.Run("Should refuse while the user is the only owner of an organization", func(t *testing.T) {
+			findUserReturns("sole-owner-user", profile("sole-owner-user", "sole-owner@gmail.com"))
+			organizationMemberRepositoryMock.Mock.On("CountSoleByRole", "sole-owner-user", models.OrganizationRoleOwner).Return(int64(1), nil)
+
+			err := userUsecase.DeleteAccount("sole-owner-user", &models.DeleteAccountRequest{Password: "12345678"})
+			require.Equal(t, &models.ErrorResponse{Code: 409, Message: "You're the only owner of an organization, transfer ownership first", Status: "Conflict"}, err)
+			userRepositoryMock.Mock.AssertNotCalled(t, "DeleteOneById", "sole-owner-user")
+		})
+
+		t.Run("Should delete the user", func(t *testing.T) {
+			findUserReturns("delete-user", profile("delete-user", "delete@gmail.com"))
+			organizationMemberRepositoryMock.Mock.On("CountSoleByRole", "delete-user", models.OrganizationRoleOwner).Return(int64(0), nil)
+			userRepositoryMock.Mock.On("DeleteOneById", "delete-user").Return(nil)
+			require.Nil(t, searchIndex.Index(&entity.Product{Id: "personal-boots", Name: "Personal boots"}))
+			require.Nil(t, searchIndex.Index(&entity.Product{Id: "kept-boots", Name: "Kept boots"}))
+			userProducts.Mock.On("FindPersonalIds", "delete-user").Return([]string{"personal-boots"}, nil)
+			personalImage := entity.ProductImage{Id: "personal-image", ProductId: "personal-boots", Variants: map[string]string{"original": "products/personal-boots/personal-image/original.png", "small": "products/personal-boots/personal-image/small.png"}}
+			for _, key := range personalImage.Variants {
+				require.Nil(t, imageStorage.Put(key, "image/png", []byte("content")))
+			}
+			userImages.Mock.On("FindManyByProductIds", []string{"personal-boots"}).Return([]entity.ProductImage{personalImage}, nil)
+
+			err := userUsecase.DeleteAccount("delete-user", &models.DeleteAccountRequest{Password: "12345678"})
+			require.Nil(t, err)
+			userRepositoryMock.Mock.AssertCalled(t, "DeleteOneById", "delete-user")
+
+			// The deleted products don't count in the search results anymore.
+			hits, total, err := searchIndex.Search([]string{"boots"}, 0, 10)
+			require.Nil(t, err)
+			require.Equal(t, int64(1), total)
+			require.Equal(t, "kept-boots", hits[0].ProductId)
+
+			// So are the files of their images.
+			files, _ := filepath.Glob(filepath.Join(imageDirectory, "products", "personal-boots", "*", "*"))
+			require.Empty(t, files)
+		})
+	})
+}